@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mattlemmone/yt-tx/internal"
+)
+
+// runCleanOnly cleans every VTT file already present in srcDir into cleanedDir,
+// without touching yt-dlp. It returns the process exit code.
+func runCleanOnly(srcDir, cleanedDir string, opts internal.CleanOptions) int {
+	vttFiles, err := filepath.Glob(internal.GetNewestVTTPattern(srcDir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing VTT files in %s: %v\n", srcDir, err)
+		return 1
+	}
+	if len(vttFiles) == 0 {
+		fmt.Fprintf(os.Stderr, "No .vtt files found in %s\n", srcDir)
+		return 1
+	}
+	if err := internal.CheckWritable(cleanedDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error preparing directories: output directory %s is not writable: %v\n", cleanedDir, err)
+		return 1
+	}
+
+	failed := 0
+	for i, vttPath := range vttFiles {
+		outPath, err := internal.SaveCleanedTranscriptWithOptions(vttPath, cleanedDir, opts)
+		if err != nil {
+			failed++
+			fmt.Printf("[%d/%d] %s: failed (Error: %v)\n", i+1, len(vttFiles), vttPath, err)
+			continue
+		}
+		fmt.Printf("[%d/%d] %s -> %s\n", i+1, len(vttFiles), vttPath, outPath)
+	}
+
+	fmt.Printf("Cleaned %d/%d files\n", len(vttFiles)-failed, len(vttFiles))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/mattlemmone/yt-tx/internal"
+)
+
+// runDoctor checks the environment yt-tx runs in (`yt-tx doctor`) and writes
+// one PASS or FAIL line per check to w, with a hint under each failure,
+// after the proxy in effect. The -proxy, -cookies-from-browser and
+// -yt-dlp-extra arguments apply,
+// since they can be what fixes a failure. It returns the process exit code,
+// which is non-zero if any check failed.
+func runDoctor(w io.Writer, cleanedDir, tempDir, proxy, cookieBrowser, ytDlpExtra string) int {
+	args, err := ytDlpArgs(proxy, cookieBrowser, ytDlpExtra)
+	if err != nil {
+		fmt.Fprintf(w, "Invalid %v\n", err)
+		return 1
+	}
+	internal.YtDlpArgs = args
+
+	fmt.Fprintf(w, "Proxy: %s\n", describeProxy(proxy))
+	failed := 0
+	for _, check := range internal.RunChecks([]string{cleanedDir, tempDir}, proxy) {
+		if check.Err == nil {
+			fmt.Fprintf(w, "PASS %s: %s\n", check.Name, check.Detail)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "FAIL %s: %v\n     hint: %s\n", check.Name, check.Err, check.Hint)
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "%d check(s) failed\n", failed)
+		return 1
+	}
+	fmt.Fprintln(w, "All checks passed")
+	return 0
+}
+
+// describeProxy describes the proxy yt-dlp will use given a -proxy of
+// flagVal and where it comes from, with any password in it hidden.
+func describeProxy(flagVal string) string {
+	proxy := internal.EffectiveProxy(flagVal)
+	if proxy == "" {
+		return "none (direct connection)"
+	}
+	if u, err := url.Parse(proxy); err == nil {
+		proxy = u.Redacted()
+	}
+	if flagVal != "" {
+		return proxy + " (from -proxy)"
+	}
+	return proxy + " (from HTTPS_PROXY)"
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/mattlemmone/yt-tx/internal"
+)
+
+// videoInfo is one line of `yt-tx info -json` output.
+type videoInfo struct {
+	URL     string `json:"url"`
+	VideoID string `json:"video_id,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// runInfo prints the video ID and title of each URL in args (`yt-tx info
+// [-json] <url>...`) to w, one "<id>\t<title>" line per URL, or with -json
+// one JSON object per line, without creating any directories or downloading
+// subtitles. A URL the ID can't be parsed from is resolved by yt-dlp. Failed
+// URLs are reported on errW (or in their JSON object's "error"). It returns
+// the process exit code, which is non-zero if any URL failed.
+func runInfo(w, errW io.Writer, args []string, proxy, cookieBrowser, ytDlpExtra string) int {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	fs.SetOutput(errW)
+	asJSON := fs.Bool("json", false, "Print one JSON object per URL instead of tab-separated lines")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(errW, "usage: yt-tx info [-json] <url>...")
+		return 2
+	}
+	ytArgs, err := ytDlpArgs(proxy, cookieBrowser, ytDlpExtra)
+	if err != nil {
+		fmt.Fprintf(errW, "Invalid %v\n", err)
+		return 1
+	}
+	internal.YtDlpArgs = ytArgs
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	code := 0
+	for _, url := range fs.Args() {
+		info := videoInfo{URL: url}
+		err := fetchInfo(&info)
+		if err != nil {
+			code = 1
+			info.Error = err.Error()
+		}
+		switch {
+		case *asJSON:
+			enc.Encode(info)
+		case err != nil:
+			fmt.Fprintf(errW, "%s: %v\n", url, err)
+		default:
+			fmt.Fprintf(w, "%s\t%s\n", info.VideoID, info.Title)
+		}
+	}
+	return code
+}
+
+// fetchInfo fills in info's video ID and title from its URL.
+func fetchInfo(info *videoInfo) error {
+	videoID, err := internal.ExtractVideoID(info.URL)
+	if err != nil {
+		if videoID, err = internal.FetchVideoID(info.URL); err != nil {
+			return err
+		}
+	}
+	info.VideoID = videoID
+	title, err := internal.FetchTitle(info.URL)
+	if err != nil {
+		return err
+	}
+	info.Title = title
+	return nil
+}
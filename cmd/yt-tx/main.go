@@ -1,18 +1,31 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/mattlemmone/yt-tx/internal"
 )
 
-const (
-	defaultCleanedDir = "cleaned"
-	tempDirName       = "tmp"
-)
+const defaultCleanedDir = "cleaned"
+
+// shutdownGrace is how long yt-tx waits, after the user quits mid-batch, for
+// jobs cleaning up their downloads or writing a transcript to finish.
+const shutdownGrace = 5 * time.Second
+
+// exitInterrupted is the exit code of a run the user quit before every job
+// finished, the shell's code for a process stopped by Ctrl+C.
+const exitInterrupted = 130
 
 // TranscriptApp wraps the workflow
 type TranscriptApp struct {
@@ -41,33 +54,931 @@ func main() {
 	var (
 		cleanedDir      string
 		parallelWorkers int
+		downloadWorkers int
+		cleanWorkers    int
+		prefetchTitles  int
+		thumbnail       bool
+		metadata        bool
+		rateLimit       int
+		perHost         int
+		dlProgress      bool
+		byChannel       bool
+		fuzzyDedupe     bool
+		keepBreaks      bool
+		ascii           bool
+		fixStutter      bool
+		joinCueLines    bool
+		stitchCues      bool
+		preserveStyling bool
+		allowEmpty      bool
+		maxBytes        int64
+		stripRegex      stringsFlag
+		keepDoubles     bool
+		noColor         bool
+		progressStyle   string
+		showIDs         bool
+		lang            string
+		autoLang        bool
+		exactLang       bool
+		allLangs        bool
+		langDirs        bool
+		detectLang      bool
+		translateTo     string
+		manualOnly      bool
+		markAuto        bool
+		track           int
+		rawFormat       string
+		subSource       string
+		debug           bool
+		dedupeReport    bool
+		verbose         bool
+		clean           bool
+		yes             bool
+		tree            bool
+		noSummary       bool
+		chapters        bool
+		cleanOnly       string
+		maxFilename     int
+		quiet           bool
+		jsonProgress    bool
+		allowAnyURL     bool
+		output          string
+		trimIntro       time.Duration
+		trimOutro       time.Duration
+		start           string
+		end             string
+		caseMode        string
+		keepRaw         bool
+		reclean         bool
+		noRawFiles      bool
+		tempDir         string
+		ytDlpExtra      string
+		proxy           string
+		cookieBrowser   string
+		limit           int
+		latest          int
+		allowDuplicates bool
+		refreshOlder    time.Duration
+		format          string
+		dedupeWindow    int
+		showVersion     bool
+		bom             bool
+		newline         string
+		speakers        bool
+		combine         string
+		combineSort     string
+		archive         string
+		dbFile          string
+		alongside       string
+		manifest        string
+		preset          string
+		execCmd         string
+		sinceFile       string
+		zipPath         string
+		retries         int
+		failFast        bool
+		prettyNames     bool
+		originalNames   bool
+		channel         string
+		search          string
+		searchCount     int
+		since           string
+		probe           bool
+		titleSidecar    bool
+		minDuration     time.Duration
+		maxDuration     time.Duration
+		noDedupe        bool
+		keepIndent      bool
+		checksum        bool
 	)
 
 	flag.StringVar(&cleanedDir, "cleaned_dir", defaultCleanedDir, "Directory for deduplicated transcript files")
+	flag.BoolVar(&clean, "clean", false, "Delete everything in the output directory before the run (asks first unless -yes); by default new transcripts are added to what is there")
+	flag.BoolVar(&yes, "yes", false, "Don't ask before -clean empties a non-empty output directory (needed when not running in a terminal)")
 	flag.IntVar(&parallelWorkers, "p", 1, "Number of parallel workers to process videos")
+	flag.IntVar(&downloadWorkers, "download-workers", 1, "Number of workers downloading subtitles; same as -p")
+	flag.IntVar(&prefetchTitles, "concurrent-titles", 0, "Fetch up to this many titles at once before downloading, so every title shows right away (0 = each worker fetches its own; doubles the initial yt-dlp load)")
+	flag.IntVar(&cleanWorkers, "clean-workers", 0, "Clean transcripts in a separate pool of this many workers (0 = each download worker cleans its own)")
+	flag.BoolVar(&thumbnail, "thumbnail", false, "Also download the video thumbnail next to each transcript")
+	flag.StringVar(&lang, "lang", internal.DefaultLang, "Subtitle language to download")
+	flag.BoolVar(&autoLang, "auto-lang", false, "If the requested language is unavailable, fall back to the video's primary caption language")
+	flag.BoolVar(&exactLang, "exact-lang", false, "Only download the exact -lang code, never the closest regional variant (en-US for en) when it's missing")
+	flag.BoolVar(&allLangs, "all-langs", false, "Download every available subtitle language, writing one <title>.<lang>.txt per language")
+	flag.BoolVar(&langDirs, "lang-dirs", false, "With -all-langs, write each language to <lang>/<title>.txt instead of <title>.<lang>.txt")
+	flag.BoolVar(&detectLang, "detect-lang", false, "Guess the language each transcript is written in and report ones that differ from their captions'")
+	flag.StringVar(&translateTo, "translate-to", "", "Fetch captions machine-translated into this language when no native track exists")
+	flag.BoolVar(&manualOnly, "manual-only", false, "Use only uploaded (human) captions, never auto-generated ones; videos without them are skipped as having no subtitles")
+	flag.BoolVar(&markAuto, "mark-auto", false, "Start text transcripts of auto-generated or machine-translated captions with a '# NOTE: auto-generated captions' line")
+	flag.IntVar(&track, "track", 0, "When a video has several caption tracks in -lang (e.g. forced and full), download this one; see yt-tx tracks <url> (0 = yt-dlp's pick)")
+	flag.StringVar(&format, "format", internal.FormatText, "Output format: text, words-json for per-word timings (auto-generated captions only), jsonl for one {start,end,text} object per cue, timed-txt for one [HH:MM:SS] line per cue, or srt or vtt for cleaned subtitles")
+	flag.StringVar(&rawFormat, "raw-format", internal.DefaultSubFormat, "Subtitle format to have yt-dlp convert to before cleaning (vtt or srt)")
+	flag.StringVar(&subSource, "sub-format", "", "Preference order of the subtitle formats yt-dlp fetches before converting to -raw-format, e.g. srv3/vtt/best (passed as yt-dlp --sub-format)")
+	flag.IntVar(&retries, "retries", 0, "Retry a job up to this many times after a network error or timeout")
+	flag.BoolVar(&failFast, "fail-fast", false, "Stop the whole run at the first failed job (after its retries) and exit non-zero")
+	flag.IntVar(&rateLimit, "rate-limit", 0, "Max yt-dlp invocations per minute across all workers (0 = unlimited)")
+	flag.BoolVar(&dlProgress, "download-progress", false, "Show each subtitle download's percentage, parsed from yt-dlp's progress output (download_progress events with -json-progress)")
+	flag.IntVar(&perHost, "per-host", 0, "Max videos from the same host processed at once, whatever the worker count (0 = unlimited)")
+	flag.BoolVar(&byChannel, "by-channel", false, "Write transcripts into a subdirectory per channel/uploader")
+	flag.BoolVar(&fuzzyDedupe, "fuzzy-dedupe", false, "Treat consecutive lines differing only by case or trailing punctuation as duplicates")
+	flag.IntVar(&dedupeWindow, "dedupe-window", 1, "Also drop a line that repeats any of the previous N lines (1 = consecutive repeats only)")
+	flag.BoolVar(&checksum, "checksum", false, "Keep a .sha256 sidecar per transcript and don't rewrite transcripts whose content is unchanged (keeps mtimes stable for sync tools)")
+	flag.BoolVar(&keepIndent, "keep-indent", false, "Keep caption lines' indentation (beyond a single leading space) instead of trimming every line")
+	flag.BoolVar(&noDedupe, "no-dedupe", false, "Keep every cleaned caption line, even repeats (overrides -fuzzy-dedupe and -dedupe-window)")
+	flag.BoolVar(&keepBreaks, "keep-breaks", false, "Keep intentional gaps (multiple blank lines between captions) as paragraph breaks")
+	flag.BoolVar(&speakers, "speakers", false, "Start a new line at each speaker label (\">>\", \"JOHN:\") and keep labels out of -case")
+	flag.BoolVar(&fixStutter, "fix-stutter", false, "Collapse words repeated back to back within a line, e.g. \"the the cat\" -> \"the cat\"")
+	flag.BoolVar(&keepDoubles, "keep-doubles", false, "With -fix-stutter, leave intentional doubles such as \"had had\" and \"that that\" alone")
+	flag.Int64Var(&maxBytes, "max-bytes", 0, "Don't clean downloaded captions larger than this many bytes (e.g. a pathological livestream's), reporting the video as oversized (0 = no limit)")
+	flag.BoolVar(&allowEmpty, "allow-empty", false, "Still write a transcript that cleaning left empty (e.g. all music cues); it is reported as empty either way")
+	flag.BoolVar(&stitchCues, "stitch-cues", false, "Drop the words each caption cue repeats from the end of the one before, joining rolling auto-captions cleanly")
+	flag.BoolVar(&preserveStyling, "preserve-styling", false, "With -format srt or vtt, keep the captions' <i>, <b> and <u> tags and VTT cue settings (position, alignment); YouTube's color and word-timing tags are dropped either way")
+	flag.BoolVar(&joinCueLines, "join-cue-lines", false, "Join the lines of each caption cue into one line, for captions that wrap sentences across lines (not for rolling auto-captions)")
+	flag.Var(&stripRegex, "strip-regex", "Remove text matching this regular expression from every caption line, e.g. '\\[CC BY [^]]*\\]' (repeatable)")
+	flag.BoolVar(&ascii, "ascii", false, "Replace smart quotes, dashes and ellipses with plain ASCII")
+	flag.BoolVar(&bom, "bom", false, "Start transcripts with a UTF-8 byte order mark (for Windows tools that expect one)")
+	flag.StringVar(&newline, "newline", internal.NewlineLF, "Line endings of written transcripts: lf or crlf")
+	flag.StringVar(&caseMode, "case", internal.CaseKeep, "Re-case the transcript: keep, lower, upper or sentence")
+	flag.BoolVar(&showIDs, "show-ids", false, "Show each video's ID instead of its URL in the job list, and after titles in the summary")
+	flag.StringVar(&progressStyle, "progress-style", "", "Progress bar style: gradient, solid or ascii (default: gradient, or ascii when colour is off)")
+	flag.BoolVar(&noColor, "no-color", false, "Disable coloured job statuses (also disabled by NO_COLOR or when stdout isn't a terminal)")
+	flag.BoolVar(&tree, "tree", false, "When done, list output files as a tree of directories (e.g. channels with -by-channel)")
+	flag.BoolVar(&noSummary, "no-summary", false, "When done, print only the output files: no completion banner, progress bar or counts; failures go to stderr")
+	flag.BoolVar(&dedupeReport, "dedupe-report", false, "Print each transcript's line count as read, after removing subtitle artifacts and after dedupe, with the overall reduction")
+	flag.BoolVar(&verbose, "verbose", false, "Print how long each job spent fetching its title, downloading and processing")
+	flag.BoolVar(&debug, "debug", false, "Print per-job cleaning diagnostics (lines read, dropped and deduplicated)")
+	flag.BoolVar(&chapters, "chapters", false, "Insert a \"## <chapter title>\" heading where each video chapter begins")
+	flag.StringVar(&cleanOnly, "clean-only", "", "Clean the .vtt files already in this directory instead of downloading (works offline)")
+	flag.BoolVar(&prettyNames, "pretty-names", false, "Keep spaces and punctuation in transcript filenames, removing only characters the OS doesn't allow")
+	flag.BoolVar(&originalNames, "prefer-original-title", false, "Keep transcript filenames as close to the title as possible, percent-encoding only characters filenames can't hold")
+	flag.BoolVar(&titleSidecar, "title-sidecar", false, "Write each video's original title to a .title file next to its transcript")
+	flag.IntVar(&maxFilename, "max-filename", internal.DefaultMaxFilename, "Maximum length of transcript filenames derived from video titles")
+	flag.BoolVar(&quiet, "quiet", false, "No progress output; print failures to stderr, the transcripts' paths to stdout, and exit non-zero if any job failed")
+	flag.BoolVar(&jsonProgress, "json-progress", false, "Instead of the TUI, print one JSON object per job state transition to stdout")
+	flag.BoolVar(&allowAnyURL, "allow-any-url", false, "Accept any URL yt-dlp supports, not just YouTube video links")
+	flag.BoolVar(&allowDuplicates, "allow-duplicates", false, "Process a video every time it is listed, instead of skipping repeats of the same video ID")
+	flag.StringVar(&output, "o", "", "Output file for a single URL (its extension picks the -format), or output directory (like -cleaned_dir) for several")
+	flag.DurationVar(&trimIntro, "trim-intro", 0, "Drop captions that end before this point, e.g. 30s")
+	flag.DurationVar(&trimOutro, "trim-outro", 0, "Drop captions that start within this long of the video's end, e.g. 1m")
+	flag.StringVar(&start, "start", "", "Keep only captions from this point on, as HH:MM:SS or seconds")
+	flag.StringVar(&end, "end", "", "Keep only captions up to this point, as HH:MM:SS or seconds")
+	flag.DurationVar(&minDuration, "min-duration", 0, "Skip videos shorter than this, e.g. 10m (needs -metadata)")
+	flag.DurationVar(&maxDuration, "max-duration", 0, "Skip videos longer than this, e.g. 2h (needs -metadata)")
+	flag.DurationVar(&refreshOlder, "refresh-older-than", 0, "Re-download existing transcripts last written longer ago than this, e.g. 720h (others are still skipped)")
+	flag.BoolVar(&keepRaw, "keep-raw", false, "Keep the raw subtitle downloads in the temp directory instead of deleting them after cleaning")
+	flag.BoolVar(&noRawFiles, "no-raw-files", false, "Clean captions as yt-dlp writes them to its output, never saving the raw subtitles (text transcripts of one language only)")
+	flag.BoolVar(&reclean, "overwrite-cleaned-only", false, "Re-clean the raw subtitles an earlier -keep-raw run left in -tempdir, overwriting the transcripts, without downloading again")
+	flag.StringVar(&tempDir, "tempdir", "", "Directory for raw subtitle downloads, e.g. on a tmpfs (default: a fresh directory under the system temp dir, removed on exit)")
+	flag.StringVar(&proxy, "proxy", "", "Proxy URL for yt-dlp, e.g. socks5://127.0.0.1:1080 (default: HTTPS_PROXY from the environment, if set)")
+	flag.StringVar(&cookieBrowser, "cookies-from-browser", "", "Let yt-dlp read YouTube cookies from this browser, as BROWSER[+KEYRING][:PROFILE], e.g. chrome or firefox:work")
+	flag.StringVar(&ytDlpExtra, "yt-dlp-extra", "", "Extra arguments appended to every yt-dlp call, e.g. \"--sleep-requests 2\" (quotes are respected)")
+	flag.StringVar(&zipPath, "zip", "", "When done, bundle the transcripts (and the -combine file) into this zip file")
+	flag.StringVar(&search, "search", "", "Also process the top YouTube search results for this query, e.g. \"go concurrency\" (see -search-count)")
+	flag.IntVar(&searchCount, "search-count", 5, "Number of -search results to process")
+	flag.StringVar(&channel, "channel", "", "Also process the uploads of this YouTube channel (e.g. https://www.youtube.com/@name); pair with -since and -archive to sync it")
+	flag.BoolVar(&probe, "probe", false, "Check each video is available before downloading; private and removed videos are skipped as unavailable")
+	flag.StringVar(&since, "since", "", "Skip videos uploaded before this date, as YYYY-MM-DD (fetches metadata for the upload date)")
+	flag.StringVar(&sinceFile, "since-file", "", "Also process the URLs in this file (one per line) that earlier runs haven't, remembering them in <file>.seen; for cron jobs on a growing list")
+	flag.StringVar(&execCmd, "exec", "", "Run this shell command for each transcript written, with {path}, {title} and {id} replaced (quoted), e.g. \"indexer add {path}\"")
+	flag.StringVar(&manifest, "manifest", "", "CSV (url,output) or JSON file giving listed videos their own output paths, relative to the cleaned dir; others are named as usual")
+	flag.StringVar(&alongside, "alongside", "", "Write each transcript next to the media file with the video's id in its name in this directory (e.g. a Plex library), falling back to the cleaned dir")
+	flag.StringVar(&archive, "archive", "", "Skip videos whose id is recorded in this file, and record each video processed (like yt-dlp's --download-archive)")
+	flag.StringVar(&dbFile, "db", "", "Also add each transcript to this SQLite database (table transcripts, full-text searchable via transcripts_fts)")
+	flag.StringVar(&combine, "combine", "", "Also write every transcript into this one file, in input order or the -sort order (markdown if it ends in .md)")
+	flag.StringVar(&combineSort, "sort", internal.CombineSortInput, "Order of the -combine sections: input, title, date or duration (date and duration fetch metadata)")
+	flag.IntVar(&limit, "limit", 0, "Process only the first N URLs (0 = all)")
+	flag.IntVar(&latest, "latest", 0, "Take only the N newest uploads of -channel, and the first N videos of each playlist (0 = all)")
+	flag.BoolVar(&showVersion, "version", false, "Print the yt-tx, commit and yt-dlp versions and exit (also: yt-tx version)")
+	flag.BoolVar(&metadata, "metadata", false, "Fetch video metadata and write a .info.json sidecar next to each transcript")
+	flag.StringVar(&preset, "preset", "", "Apply a bundle of flags for a common use, one of: "+strings.Join(internal.PresetNames(), ", ")+" (flags given explicitly override it)")
 	flag.Parse()
+	var err error
+	if explicitFlags, err = applyPreset(flag.CommandLine, preset); err != nil {
+		fmt.Printf("Invalid -preset: %v\n", err)
+		os.Exit(1)
+	}
+
+	if showVersion || (flag.NArg() == 1 && flag.Arg(0) == "version") {
+		os.Exit(printVersion(os.Stdout))
+	}
+	if flag.NArg() == 1 && flag.Arg(0) == "doctor" {
+		os.Exit(runDoctor(os.Stdout, cleanedDir, cmp.Or(tempDir, os.TempDir()), proxy, cookieBrowser, ytDlpExtra))
+	}
+	if flag.NArg() >= 1 && flag.Arg(0) == "info" {
+		os.Exit(runInfo(os.Stdout, os.Stderr, flag.Args()[1:], proxy, cookieBrowser, ytDlpExtra))
+	}
+	if flag.NArg() == 2 && flag.Arg(0) == "tracks" {
+		os.Exit(runTracks(os.Stdout, flag.Arg(1), lang, manualOnly, proxy, cookieBrowser, ytDlpExtra))
+	}
+
+	if !slices.Contains(internal.CaseModes, caseMode) {
+		fmt.Printf("Unsupported -case %q (want one of: %s)\n", caseMode, strings.Join(internal.CaseModes, ", "))
+		os.Exit(1)
+	}
+
+	if !slices.Contains(internal.Newlines, newline) {
+		fmt.Printf("Unsupported -newline %q (want one of: %s)\n", newline, strings.Join(internal.Newlines, ", "))
+		os.Exit(1)
+	}
+
+	if keepDoubles && !fixStutter {
+		fmt.Fprintln(os.Stderr, "warning: -keep-doubles only applies with -fix-stutter; it is ignored")
+	}
+
+	stripPatterns, err := internal.CompileStripPatterns(stripRegex)
+	if err != nil {
+		fmt.Printf("Invalid -strip-regex: %v\n", err)
+		os.Exit(1)
+	}
+
+	cleanOpts := internal.CleanOptions{
+		FuzzyDedupe:     fuzzyDedupe,
+		DedupeWindow:    dedupeWindow,
+		KeepBreaks:      keepBreaks,
+		ASCII:           ascii,
+		Case:            caseMode,
+		BOM:             bom,
+		Newline:         newline,
+		Speakers:        speakers,
+		NoDedupe:        noDedupe,
+		Checksum:        checksum,
+		KeepIndent:      keepIndent,
+		FixStutter:      fixStutter,
+		KeepDoubles:     keepDoubles,
+		JoinCueLines:    joinCueLines,
+		StitchCues:      stitchCues,
+		PreserveStyling: preserveStyling,
+		AllowEmpty:      allowEmpty,
+
+		StripPatterns: stripPatterns,
+	}
+
+	// A single output file's extension picks the format, unless -format is given
+	if output != "" && !flagSet("format") && !strings.HasSuffix(output, string(os.PathSeparator)) {
+		if info, err := os.Stat(output); err != nil || !info.IsDir() {
+			inferred, ok := internal.FormatForPath(output)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "warning: no output format writes %s files; writing text to %s (pass -format to choose)\n", filepath.Ext(output), output)
+			}
+			format = inferred
+		}
+	}
+
+	startAt, endAt, err := parseTimeRange(start, end)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if (startAt > 0 || endAt > 0) && (cleanOnly != "" || format == internal.FormatWordsJSON) {
+		fmt.Fprintln(os.Stderr, "warning: -start/-end need cue timings, which -clean-only and -format words-json don't parse; they are ignored")
+	}
+
+	if cleanOnly != "" {
+		os.Exit(runCleanOnly(cleanOnly, cleanedDir, cleanOpts))
+	}
 
 	urls := flag.Args()
-	if len(urls) == 0 {
+	var urlList *internal.URLList
+	if sinceFile != "" {
+		urlList, err = internal.LoadURLList(sinceFile)
+		if err != nil {
+			fmt.Printf("Error loading -since-file: %v\n", err)
+			os.Exit(1)
+		}
+		if len(urls) == 0 && channel == "" && search == "" && len(urlList.New) == 0 {
+			fmt.Fprintf(os.Stderr, "no new URLs in %s\n", sinceFile)
+			os.Exit(0)
+		}
+		urls = append(urls, urlList.New...)
+	}
+	if len(urls) == 0 && channel == "" && search == "" {
 		fmt.Println("Usage: yt-tx [flags] <youtube-url> [<youtube-url>...]")
+		fmt.Println("       yt-tx [flags] -channel <channel-url> [-since YYYY-MM-DD]")
+		fmt.Println("       yt-tx [flags] -search <query> [-search-count N]")
+		fmt.Println("       yt-tx [flags] -since-file <url-list>")
+		fmt.Println("       yt-tx [flags] -clean-only <dir>")
+		fmt.Println("       yt-tx doctor")
+		fmt.Println("       yt-tx [-lang <lang>] tracks <youtube-url>")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	// Create/clean directories
-	if err := internal.CleanDirectories(tempDirName, cleanedDir); err != nil {
-		fmt.Printf("Error preparing directories: %v\n", err)
+	if retries < 0 {
+		fmt.Printf("-retries must be 0 or more, got %d\n", retries)
 		os.Exit(1)
 	}
 
+	if latest < 0 {
+		fmt.Printf("-latest must be 0 or more, got %d\n", latest)
+		os.Exit(1)
+	}
+	if limit < 0 {
+		fmt.Printf("-limit must be 0 or more, got %d\n", limit)
+		os.Exit(1)
+	}
+	if searchCount < 1 {
+		fmt.Printf("-search-count must be at least 1, got %d\n", searchCount)
+		os.Exit(1)
+	}
+
+	if flagSet("download-workers") {
+		if flagSet("p") && downloadWorkers != parallelWorkers {
+			fmt.Println("-download-workers is another name for -p; give only one of them")
+			os.Exit(1)
+		}
+		parallelWorkers = downloadWorkers
+	}
+	if reclean && tempDir == "" {
+		fmt.Println("-overwrite-cleaned-only needs the -tempdir an earlier -keep-raw run kept its raw subtitles in")
+		os.Exit(1)
+	}
+	if noRawFiles && (allLangs || format != internal.FormatText || keepRaw || reclean) {
+		fmt.Println("-no-raw-files cleans one language's captions into text as they arrive; it can't be used with -all-langs, -format words-json, jsonl, timed-txt, srt or vtt, -keep-raw or -overwrite-cleaned-only")
+		os.Exit(1)
+	}
+	if noRawFiles && (chapters || trimIntro > 0 || trimOutro > 0 || startAt > 0 || endAt > 0 || stitchCues) {
+		fmt.Println("-no-raw-files never has the raw captions to read cue timings from; it can't be used with -chapters, -trim-intro, -trim-outro, -start, -end or -stitch-cues")
+		os.Exit(1)
+	}
+	if noRawFiles && (flagSet("raw-format") || flagSet("sub-format")) {
+		fmt.Println("-no-raw-files always reads VTT, which yt-dlp can't convert without a file; it can't be used with -raw-format or -sub-format")
+		os.Exit(1)
+	}
+	if noRawFiles && maxBytes > 0 {
+		fmt.Fprintln(os.Stderr, "warning: -max-bytes guards against holding huge raw files, which -no-raw-files never does; it is ignored")
+	}
+	if prefetchTitles < 0 {
+		fmt.Printf("-concurrent-titles must be 0 or more, got %d\n", prefetchTitles)
+		os.Exit(1)
+	}
+	if cleanWorkers < 0 {
+		fmt.Printf("-clean-workers must be 0 or more, got %d\n", cleanWorkers)
+		os.Exit(1)
+	}
+
+	if !slices.Contains(internal.Formats, format) {
+		fmt.Printf("Unsupported -format %q (want one of: %s)\n", format, strings.Join(internal.Formats, ", "))
+		os.Exit(1)
+	}
+	if combine != "" && (!internal.CombinesFormat(format) || allLangs) {
+		fmt.Println("-combine needs plain text transcripts (-format text or timed-txt); it can't be used with -format words-json, jsonl, srt, vtt or -all-langs")
+		os.Exit(1)
+	}
+	if progressStyle != "" && !slices.Contains(internal.ProgressStyles, progressStyle) {
+		fmt.Printf("Unsupported -progress-style %q (want one of: %s)\n", progressStyle, strings.Join(internal.ProgressStyles, ", "))
+		os.Exit(1)
+	}
+	if !slices.Contains(internal.CombineSorts, combineSort) {
+		fmt.Printf("Unsupported -sort %q (want one of: %s)\n", combineSort, strings.Join(internal.CombineSorts, ", "))
+		os.Exit(1)
+	}
+	if combine == "" && combineSort != internal.CombineSortInput {
+		fmt.Fprintln(os.Stderr, "warning: -sort orders the -combine file; without -combine it is ignored")
+	}
+	if langDirs && !allLangs {
+		fmt.Fprintln(os.Stderr, "warning: -lang-dirs only applies with -all-langs; it is ignored")
+	}
+	if stitchCues && format == internal.FormatWordsJSON {
+		fmt.Fprintln(os.Stderr, "warning: -stitch-cues doesn't apply to -format words-json; it is ignored")
+	}
+	if preserveStyling && !internal.IsSubtitleFormat(format) {
+		fmt.Fprintln(os.Stderr, "warning: -preserve-styling only applies to -format srt or vtt; it is ignored")
+	} else if preserveStyling && stitchCues {
+		fmt.Fprintln(os.Stderr, "warning: -stitch-cues strips the tags of the cues it compares, so -preserve-styling keeps no styling with it")
+	}
+	if detectLang && format != internal.FormatText && format != internal.FormatTimedText {
+		fmt.Fprintf(os.Stderr, "warning: -detect-lang only reads text transcripts; it is ignored with -format %s\n", format)
+	}
+	if manualOnly && (translateTo != "" || format == internal.FormatWordsJSON) {
+		fmt.Println("-manual-only can't be used with -translate-to or -format words-json, which need auto-generated captions")
+		os.Exit(1)
+	}
+	if dbFile != "" && format != internal.FormatText && format != internal.FormatTimedText {
+		fmt.Printf("-db stores text transcripts; it can't be used with -format %s\n", format)
+		os.Exit(1)
+	}
+	if dedupeReport && (format != internal.FormatText || allLangs) {
+		fmt.Fprintln(os.Stderr, "warning: -dedupe-report counts the lines of single-language text transcripts; it is ignored with -format words-json, jsonl, timed-txt, srt, vtt or -all-langs")
+	}
+	if markAuto && (format == internal.FormatWordsJSON || format == internal.FormatJSONL || format == internal.FormatSRT) {
+		fmt.Fprintf(os.Stderr, "warning: -mark-auto only marks text transcripts; it is ignored with -format %s\n", format)
+	}
+	if track < 0 {
+		fmt.Printf("-track must be 0 or more, got %d\n", track)
+		os.Exit(1)
+	}
+	if track > 0 && (allLangs || translateTo != "") {
+		fmt.Println("-track picks one track of -lang; it can't be used with -all-langs or -translate-to")
+		os.Exit(1)
+	}
+	if prettyNames && originalNames {
+		fmt.Println("-pretty-names and -prefer-original-title can't be used together")
+		os.Exit(1)
+	}
+	if !slices.Contains(internal.SubFormats, rawFormat) {
+		fmt.Printf("Unsupported -raw-format %q (want one of: %s)\n", rawFormat, strings.Join(internal.SubFormats, ", "))
+		os.Exit(1)
+	}
+	if flagSet("sub-format") {
+		if err := internal.ValidateSubSource(subSource); err != nil {
+			fmt.Printf("Invalid -sub-format: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if rawFormat == "srt" && format == internal.FormatWordsJSON {
+		fmt.Fprintln(os.Stderr, "warning: yt-dlp's srt conversion drops the per-word timings -format words-json reads; transcripts will have no words")
+	}
+
+	internal.YtDlpArgs, err = ytDlpArgs(proxy, cookieBrowser, ytDlpExtra)
+	if err != nil {
+		fmt.Printf("Invalid %v\n", err)
+		os.Exit(1)
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "proxy: %s\n", describeProxy(proxy))
+	}
+
+	// Playlists are replaced by their videos, so every later step sees only video URLs
+	urls, playlists, err := internal.ExpandPlaylistsN(urls, latest)
+	if err != nil {
+		fmt.Printf("Error expanding playlist: %v\n", err)
+		os.Exit(1)
+	}
+	for _, playlist := range playlists {
+		fmt.Fprintf(os.Stderr, "playlist %q: %d videos\n", playlist.Title, len(playlist.Videos))
+	}
+	if channel != "" {
+		uploads, err := internal.FetchChannelN(channel, latest)
+		if err != nil {
+			fmt.Printf("Error listing channel: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "channel %q: %d videos\n", uploads.Title, len(uploads.Videos))
+		playlists = append(playlists, uploads)
+		for _, video := range uploads.Videos {
+			urls = append(urls, video.URL())
+		}
+	}
+	if search != "" {
+		results, err := internal.SearchVideos(search, searchCount)
+		if err != nil {
+			fmt.Printf("Error searching: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "search %q: %d videos\n", search, len(results))
+		urls = append(urls, results...)
+	}
+
+	if limit > 0 && limit < len(urls) {
+		fmt.Fprintf(os.Stderr, "processing %d of %d (limited)\n", limit, len(urls))
+		urls = urls[:limit]
+	}
+
+	var sinceDate time.Time
+	if since != "" {
+		if sinceDate, err = time.Parse(time.DateOnly, since); err != nil {
+			fmt.Printf("Invalid -since %q (want YYYY-MM-DD)\n", since)
+			os.Exit(1)
+		}
+	}
+
+	if maxBytes < 0 {
+		fmt.Printf("-max-bytes must be 0 or more, got %d\n", maxBytes)
+		os.Exit(1)
+	}
+	if maxDuration > 0 && minDuration > maxDuration {
+		fmt.Printf("-min-duration %v is longer than -max-duration %v\n", minDuration, maxDuration)
+		os.Exit(1)
+	}
+
+	// -o is a directory (used as the cleaned dir) if it is one or ends in a
+	// separator; otherwise it names the transcript file of a single URL
+	var outputFile string
+	if output != "" {
+		if info, err := os.Stat(output); (err == nil && info.IsDir()) || strings.HasSuffix(output, string(os.PathSeparator)) {
+			cleanedDir = output
+		} else if len(urls) > 1 {
+			fmt.Printf("-o %s names a file, but %d URLs were given; pass a directory to write several transcripts\n", output, len(urls))
+			os.Exit(1)
+		} else {
+			outputFile = output
+		}
+	}
+
+	// A templated cleaned directory is expanded per video under its fixed root
+	var dirTemplate string
+	if internal.IsDirTemplate(cleanedDir) {
+		if err := internal.ValidateDirTemplate(cleanedDir); err != nil {
+			fmt.Printf("Invalid -cleaned_dir: %v\n", err)
+			os.Exit(1)
+		}
+		if byChannel {
+			fmt.Println("-by-channel can't be used with a -cleaned_dir template; use {channel} in the template instead")
+			os.Exit(1)
+		}
+		dirTemplate, cleanedDir = cleanedDir, internal.DirTemplateRoot(cleanedDir)
+	}
+
+	if clean {
+		if outputFile != "" {
+			fmt.Println("-clean empties the output directory; it can't be used with -o <file>")
+			os.Exit(1)
+		}
+		ok, err := confirmClean(cleanedDir, yes, os.Stdin, os.Stderr)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !ok {
+			fmt.Println("Aborted; nothing was deleted")
+			os.Exit(1)
+		}
+		if err := internal.EmptyDirectory(cleanedDir); err != nil {
+			fmt.Printf("Error emptying %s: %v\n", cleanedDir, err)
+			os.Exit(1)
+		}
+	}
+
+	exit := os.Exit
+	// Raw downloads go to -tempdir, or else a fresh directory removed on exit.
+	// A -tempdir is never wiped, as it may be shared; each job works in, and
+	// removes, its own subdirectory of it.
+	if noRawFiles {
+		if tempDir != "" {
+			fmt.Fprintln(os.Stderr, "warning: -no-raw-files downloads nothing to -tempdir; it is ignored")
+		}
+		tempDir = ""
+	} else if tempDir == "" {
+		if tempDir, err = os.MkdirTemp("", "yt-tx-"); err != nil {
+			fmt.Printf("Error creating temp directory: %v\n", err)
+			os.Exit(1)
+		}
+		exit = func(code int) {
+			if keepRaw {
+				fmt.Fprintf(os.Stderr, "raw subtitles kept in %s\n", tempDir)
+			} else {
+				os.RemoveAll(tempDir)
+			}
+			os.Exit(code)
+		}
+	}
+	if err := internal.EnsureDirectories(tempDir, cleanedDir); err != nil {
+		fmt.Printf("Error preparing directories: %v\n", err)
+		exit(1)
+	}
+
+	opts := internal.Options{
+		TempDir:          tempDir,
+		CleanedDir:       cleanedDir,
+		DirTemplate:      dirTemplate,
+		ParallelWorkers:  parallelWorkers,
+		CleanWorkers:     cleanWorkers,
+		PrefetchTitles:   prefetchTitles,
+		Thumbnail:        thumbnail,
+		Metadata:         metadata,
+		RateLimit:        rateLimit,
+		PerHost:          perHost,
+		DownloadProgress: dlProgress,
+		Retries:          retries,
+		FailFast:         failFast,
+		ByChannel:        byChannel,
+		NoColor:          noColor,
+		ProgressStyle:    progressStyle,
+		ShowIDs:          showIDs,
+		Lang:             lang,
+		AutoLang:         autoLang,
+		ExactLang:        exactLang,
+		AllLangs:         allLangs,
+		LangDirs:         langDirs,
+		DetectLang:       langDetector(detectLang),
+		TranslateTo:      translateTo,
+		ManualOnly:       manualOnly,
+		MarkAuto:         markAuto,
+		Track:            track,
+		RawFormat:        rawFormat,
+		SubSource:        subSource,
+		Debug:            debug,
+		DedupeReport:     dedupeReport,
+		Verbose:          verbose,
+		Tree:             tree,
+		NoSummary:        noSummary,
+		Interactive:      internal.StdinIsTerminal(),
+		Chapters:         chapters,
+		MaxFilename:      maxFilename,
+		PrettyNames:      prettyNames,
+		OriginalNames:    originalNames,
+		TitleSidecar:     titleSidecar,
+		AllowAnyURL:      allowAnyURL,
+		AllowDuplicates:  allowDuplicates,
+		OutputFile:       outputFile,
+		TrimIntro:        trimIntro,
+		TrimOutro:        trimOutro,
+		Start:            startAt,
+		End:              endAt,
+		RefreshOlderThan: refreshOlder,
+		MinDuration:      minDuration,
+		MaxDuration:      maxDuration,
+		MaxBytes:         maxBytes,
+		Since:            sinceDate,
+		Probe:            probe,
+		KeepRaw:          keepRaw,
+		NoRawFiles:       noRawFiles,
+		Reclean:          reclean,
+		Clean:            cleanOpts,
+	}
+
+	if (minDuration > 0 || maxDuration > 0) && !opts.FetchesMetadata() {
+		fmt.Fprintln(os.Stderr, "warning: -min-duration/-max-duration need video durations; add -metadata, or they are ignored")
+	}
+
+	if execCmd != "" {
+		opts.Exec = internal.NewHook(execCmd)
+	}
+
+	if manifest != "" {
+		if outputFile != "" {
+			fmt.Println("-manifest places each listed transcript by its video; it can't be used with -o <file>")
+			exit(1)
+		}
+		loaded, err := internal.LoadManifest(manifest)
+		if err != nil {
+			fmt.Printf("Error loading -manifest file: %v\n", err)
+			exit(1)
+		}
+		opts.Manifest = loaded
+	}
+
+	if alongside != "" {
+		if outputFile != "" {
+			fmt.Println("-alongside places each transcript by its video; it can't be used with -o <file>")
+			exit(1)
+		}
+		index, err := internal.LoadMediaIndex(alongside)
+		if err != nil {
+			fmt.Printf("Error scanning -alongside directory: %v\n", err)
+			exit(1)
+		}
+		opts.Alongside = index
+	}
+
+	if archive != "" {
+		loaded, err := internal.LoadArchive(archive)
+		if err != nil {
+			fmt.Printf("Error loading -archive file: %v\n", err)
+			exit(1)
+		}
+		opts.Archive = loaded
+	}
+
+	if dbFile != "" {
+		db, err := internal.OpenTranscriptDB(dbFile)
+		if err != nil {
+			fmt.Printf("Error opening -db database: %v\n", err)
+			exit(1)
+		}
+		opts.DB = db
+		// Every exit from here on closes the database first
+		exitOpen := exit
+		exit = func(code int) {
+			db.Close()
+			exitOpen(code)
+		}
+	}
+
+	// The combined transcript is appended to as jobs finish, so it survives a
+	// crash; a -sort order is only known, and written, once every job is done
+	if combine != "" {
+		combined, err := internal.NewCombinedWriter(combine, playlists, cleanOpts)
+		if err != nil {
+			fmt.Printf("Error preparing -combine file: %v\n", err)
+			exit(1)
+		}
+		combined.SortBy(combineSort)
+		opts.Combined = combined
+	}
+
+	// Quiet and JSON modes skip the TUI and run the worker pool headlessly
+	if quiet || jsonProgress {
+		if jsonProgress {
+			opts.Events = internal.NewJSONEmitter(os.Stdout)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		results, _ := internal.ProcessURLs(ctx, urls, opts)
+		stop()
+		jobs := make([]internal.TranscriptJob, len(results))
+		for i, result := range results {
+			jobs[i] = result.Job
+		}
+		code := max(reportFailures(results), finishOutputs(jobs, opts, combine, zipPath), markSeen(urlList, jobs))
+		if quiet && !jsonProgress { // stdout is free for the transcripts' paths, e.g. for $(yt-tx -quiet ...)
+			for _, path := range internal.OutputPaths(jobs) {
+				fmt.Println(path)
+			}
+		}
+		view := internal.ProgressView{ShowIDs: showIDs}
+		if dedupeReport {
+			fmt.Fprint(os.Stderr, view.RenderDedupeReport(jobs))
+		}
+		if verbose {
+			fmt.Fprint(os.Stderr, view.RenderTimings(jobs)+view.RenderPanics(jobs))
+		}
+		if !quiet {
+			fmt.Fprintln(os.Stderr, internal.ExitSummary(jobs))
+		}
+		exit(code)
+	}
+
 	// Create a new program
-	p := tea.NewProgram(TranscriptApp{
-		workflow: internal.NewWorkflow(urls, tempDirName, cleanedDir, parallelWorkers), // Pass the full urls slice
-	})
+	programEvents := &internal.ProgramEmitter{}
+	opts.Events = programEvents
+	// Without an explicit language choice, let the user pick for videos offering
+	// several, if there's a terminal to answer on
+	langChooser := &internal.ProgramLangChooser{}
+	if !flagSet("lang") && !autoLang && !allLangs && translateTo == "" && opts.Interactive {
+		opts.ChooseLang = langChooser
+	}
+	p := tea.NewProgram(TranscriptApp{workflow: internal.NewWorkflow(urls, opts)})
+	programEvents.Program = p
+	langChooser.Program = p
 
 	// Run the program
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error running program: %v\n", err)
-		os.Exit(1)
+	model, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
+		exit(1)
+	}
+	workflow := model.(TranscriptApp).workflow
+	if workflow.Interrupted && !workflow.WaitForWorkers(shutdownGrace) {
+		fmt.Fprintln(os.Stderr, "warning: some jobs were still stopping; their temp files may be left behind")
+	}
+	jobs := workflow.Jobs
+	code := max(finishOutputs(jobs, opts, combine, zipPath), markSeen(urlList, jobs))
+	if workflow.Aborted {
+		code = 1
+	}
+	if workflow.Interrupted {
+		code = exitInterrupted
+	}
+	if noSummary {
+		// The final view left failures out, so list them on stderr
+		results := make([]internal.Result, len(jobs))
+		for i, job := range jobs {
+			results[i] = internal.Result{URL: job.URL, Title: job.Title, Err: job.Error}
+		}
+		code = max(code, reportFailures(results))
+	}
+	fmt.Fprintln(os.Stderr, internal.ExitSummary(jobs))
+	exit(code)
+}
+
+// markSeen records the -since-file URLs the run is done with, so the next run
+// skips them, and returns the exit code: 1 if they couldn't be recorded.
+func markSeen(list *internal.URLList, jobs []internal.TranscriptJob) int {
+	if list == nil {
+		return 0
+	}
+	if err := list.MarkSeen(list.Finished(jobs)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error updating -since-file: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parseTimeRange parses the -start and -end flags; either may be empty.
+func parseTimeRange(start, end string) (time.Duration, time.Duration, error) {
+	var startAt, endAt time.Duration
+	var err error
+	if start != "" {
+		if startAt, err = internal.ParseClockTime(start); err != nil {
+			return 0, 0, fmt.Errorf("-start: %w", err)
+		}
+	}
+	if end != "" {
+		if endAt, err = internal.ParseClockTime(end); err != nil {
+			return 0, 0, fmt.Errorf("-end: %w", err)
+		}
+		if endAt <= startAt {
+			return 0, 0, fmt.Errorf("-end %v must come after -start %v", endAt, startAt)
+		}
+	}
+	return startAt, endAt, nil
+}
+
+// stringsFlag is a flag that may be given several times, collecting each value.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, ", ") }
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// explicitFlags holds the flags given on the command line, recorded before
+// -preset sets its own; see flagSet.
+var explicitFlags map[string]bool
+
+// flagSet reports whether the named flag was given on the command line. A
+// flag set only by -preset wasn't.
+func flagSet(name string) bool {
+	return explicitFlags[name]
+}
+
+// applyPreset applies the named preset (if any) to the parsed fs and returns
+// the flags given explicitly, which the preset left alone. They are recorded
+// first, as setting a flag through fs makes it look given.
+func applyPreset(fs *flag.FlagSet, preset string) (map[string]bool, error) {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	if preset == "" {
+		return explicit, nil
+	}
+	return explicit, internal.ApplyPreset(fs, preset)
+}
+
+// finishOutputs closes the -combine file, then writes the -zip file, if
+// either was asked for, and returns the exit code: 1 if either failed. The
+// zip leaves out a combined file that couldn't be completed.
+func finishOutputs(jobs []internal.TranscriptJob, opts internal.Options, combine, zipPath string) int {
+	code := closeCombined(opts.Combined)
+	if zipPath == "" {
+		return code
+	}
+	if code != 0 {
+		combine = ""
+	}
+	if err := internal.ZipOutputs(zipPath, opts.CleanedDir, jobs, combine); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return code
+}
+
+// closeCombined finishes the -combine file, if any, and returns the exit
+// code: 1 if it couldn't be written, 0 otherwise.
+func closeCombined(combined *internal.CombinedWriter) int {
+	if combined == nil {
+		return 0
+	}
+	if err := combined.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// reportFailures prints one line per failed job to stderr and returns the
+// exit code: 1 if any job failed, 0 otherwise. Jobs -fail-fast stopped
+// before they finished are counted in one line instead.
+func reportFailures(results []internal.Result) int {
+	failed, aborted := 0, 0
+	for _, result := range results {
+		if errors.Is(result.Err, internal.ErrAborted) {
+			aborted++
+			continue
+		}
+		if result.Err == nil {
+			continue
+		}
+		failed++
+		name := result.URL
+		if result.Title != "" && result.Title != result.URL {
+			name = fmt.Sprintf("%s (%s)", result.URL, result.Title)
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, result.Err)
+	}
+	if aborted > 0 {
+		fmt.Fprintf(os.Stderr, "aborted early at the first failure: %d jobs not finished\n", aborted)
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d jobs failed\n", failed, len(results))
+		return 1
+	}
+	return 0
+}
+
+// langDetector returns the detector -detect-lang enables, or nil without it.
+func langDetector(enabled bool) internal.LangDetector {
+	if !enabled {
+		return nil
+	}
+	return internal.StopwordDetector{}
+}
+
+// ytDlpArgs returns the arguments -proxy, -cookies-from-browser and
+// -yt-dlp-extra add to every yt-dlp call. Errors start with the flag at fault.
+func ytDlpArgs(proxy, cookieBrowser, ytDlpExtra string) ([]string, error) {
+	extraArgs, err := internal.SplitArgs(ytDlpExtra)
+	if err != nil {
+		return nil, fmt.Errorf("-yt-dlp-extra: %w", err)
+	}
+	cookieArgs, err := internal.CookiesFromBrowserArgs(cookieBrowser)
+	if err != nil {
+		return nil, fmt.Errorf("-cookies-from-browser: %w", err)
+	}
+	if cookieBrowser != "" && slices.ContainsFunc(extraArgs, func(arg string) bool { return strings.HasPrefix(arg, "--cookies") }) {
+		return nil, errors.New("-cookies-from-browser: can't be combined with --cookies in -yt-dlp-extra; give one source of cookies")
 	}
+	return slices.Concat(internal.ProxyArgs(proxy), cookieArgs, extraArgs), nil
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/mattlemmone/yt-tx/internal"
+)
+
+func TestApplyPreset_ExplicitFlags(t *testing.T) {
+	fs := flag.NewFlagSet("yt-tx", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", internal.FormatText, "")
+	rawFormat := fs.String("raw-format", internal.DefaultSubFormat, "")
+	fs.Bool("no-dedupe", false, "")
+	fs.Bool("no-raw-files", false, "")
+	if err := fs.Parse([]string{"-format", internal.FormatText, "-no-raw-files"}); err != nil {
+		t.Fatal(err)
+	}
+
+	explicit, err := applyPreset(fs, "subtitle")
+	if err != nil {
+		t.Fatalf("applyPreset(subtitle) error = %v", err)
+	}
+	if *format != internal.FormatText || *rawFormat != "srt" {
+		t.Errorf("-format, -raw-format = %q, %q, want the explicit text and the preset's srt", *format, *rawFormat)
+	}
+	for name, want := range map[string]bool{"format": true, "no-raw-files": true, "raw-format": false, "no-dedupe": false} {
+		if explicit[name] != want {
+			t.Errorf("explicit[%s] = %v, want %v: only flags on the command line count", name, explicit[name], want)
+		}
+	}
+}
+
+// TestPresets_FlagsExist runs main with each preset and no URLs, which stops
+// at the usage message once the preset is applied, so a preset naming a flag
+// main doesn't define fails here rather than for the user.
+func TestPresets_FlagsExist(t *testing.T) {
+	if args := os.Getenv("YT_TX_TEST_MAIN_ARGS"); args != "" {
+		os.Args = append([]string{"yt-tx"}, strings.Fields(args)...)
+		main()
+		return
+	}
+	for _, name := range internal.PresetNames() {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestPresets_FlagsExist$")
+		cmd.Env = append(os.Environ(), "YT_TX_TEST_MAIN_ARGS=-preset "+name)
+		out, _ := cmd.CombinedOutput()
+		if strings.Contains(string(out), "Invalid -preset") || !strings.Contains(string(out), "Usage:") {
+			t.Errorf("yt-tx -preset %s printed %q, want the usage message", name, out)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mattlemmone/yt-tx/internal"
+)
+
+// runTracks lists a video's caption tracks in lang (`yt-tx tracks <url>`),
+// numbered as -track selects them. It returns the process exit code, which
+// is non-zero if the tracks can't be listed or there are none.
+func runTracks(w io.Writer, url, lang string, manualOnly bool, proxy, cookieBrowser, ytDlpExtra string) int {
+	args, err := ytDlpArgs(proxy, cookieBrowser, ytDlpExtra)
+	if err != nil {
+		fmt.Fprintf(w, "Invalid %v\n", err)
+		return 1
+	}
+	internal.YtDlpArgs = args
+
+	available, err := internal.ListSubtitleLanguages(url)
+	if err != nil {
+		fmt.Fprintf(w, "Error listing tracks: %v\n", err)
+		return 1
+	}
+	tracks := available.Tracks(lang, manualOnly)
+	if len(tracks) == 0 {
+		fmt.Fprintf(w, "No '%s' caption tracks (languages: %v)\n", lang, append(available.Manual, available.Auto...))
+		return 1
+	}
+	for i, track := range tracks {
+		kind := "uploaded"
+		if track.Auto {
+			kind = "auto-generated"
+		}
+		fmt.Fprintf(w, "%d  %s  (%s)\n", i+1, track.Lang, kind)
+	}
+	return 0
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+
+	"github.com/mattlemmone/yt-tx/internal"
+)
+
+// version can be stamped at build time with -ldflags "-X main.version=v1.2.3";
+// otherwise the module version from the Go build info is used.
+var version string
+
+// printVersion writes the yt-tx version, the commit it was built from and the
+// yt-dlp version to w, for bug reports. It returns the process exit code,
+// which is non-zero if yt-dlp can't be run.
+func printVersion(w io.Writer) int {
+	v, commit, goVersion := version, "unknown", "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" {
+			v = info.Main.Version
+		}
+		goVersion = info.GoVersion
+		var modified bool
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				commit = setting.Value
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified {
+			commit += " (modified)"
+		}
+	}
+	if v == "" {
+		v = "(devel)"
+	}
+
+	fmt.Fprintf(w, "yt-tx %s\ncommit: %s\ngo: %s\n", v, commit, goVersion)
+	ytDlp, err := internal.YtDlpVersion()
+	if err != nil {
+		fmt.Fprintf(w, "yt-dlp: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(w, "yt-dlp: %s\n", ytDlp)
+	return 0
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// confirmClean decides whether -clean may empty dir. An empty or missing dir
+// needs no confirmation, and -yes gives it up front; otherwise the user is
+// asked on out and answers on in, which must be a terminal: a script has to
+// pass -yes. A dir holding the working directory is never emptied.
+func confirmClean(dir string, yes bool, in *os.File, out io.Writer) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) || (err == nil && len(entries) == 0) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	if cwd, err := os.Getwd(); err == nil && contains(dir, cwd) {
+		return false, fmt.Errorf("-clean won't empty %s, which holds the working directory", dir)
+	}
+	if yes {
+		return true, nil
+	}
+	files := countFiles(dir)
+	if info, err := in.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false, fmt.Errorf("-clean would delete %s in %s; pass -yes to confirm when not running interactively", files, dir)
+	}
+	fmt.Fprintf(out, "Delete %s in %s? [y/N] ", files, filepath.Clean(dir)+string(filepath.Separator))
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// countFiles describes how many files dir holds, subdirectories included,
+// e.g. "12 files"; one it can't read still counts as a file.
+func countFiles(dir string) string {
+	n := 0
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			n++
+		}
+		return nil
+	})
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// contains reports whether path is dir or lies inside it.
+func contains(dir, path string) bool {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return true // Can't tell; err on the side of not deleting
+	}
+	rel, err := filepath.Rel(absDir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
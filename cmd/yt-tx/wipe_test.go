@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pipeInput returns the read end of a pipe, which isn't a terminal.
+func pipeInput(t *testing.T) *os.File {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConfirmCleanEmptyOrMissing(t *testing.T) {
+	empty := t.TempDir()
+	missing := filepath.Join(t.TempDir(), "missing")
+	for _, dir := range []string{empty, missing} {
+		ok, err := confirmClean(dir, false, pipeInput(t), io.Discard)
+		if !ok || err != nil {
+			t.Errorf("confirmClean(%s) = %v, %v; want true, nil without asking", dir, ok, err)
+		}
+	}
+}
+
+func TestConfirmCleanRefusesNonTerminalWithoutYes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.txt", filepath.Join("sub", "b.txt"))
+
+	ok, err := confirmClean(dir, false, pipeInput(t), io.Discard)
+	if ok || err == nil {
+		t.Fatalf("confirmClean() = %v, %v; want false and an error", ok, err)
+	}
+	if !strings.Contains(err.Error(), "-yes") || !strings.Contains(err.Error(), "2 files") {
+		t.Errorf("confirmClean() error = %q, want it to mention -yes and 2 files", err)
+	}
+
+	ok, err = confirmClean(dir, true, pipeInput(t), io.Discard)
+	if !ok || err != nil {
+		t.Errorf("confirmClean() with -yes = %v, %v; want true, nil", ok, err)
+	}
+}
+
+func TestConfirmCleanRefusesWorkingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.txt", filepath.Join("work", "b.txt"))
+	wd := filepath.Join(dir, "work")
+	prev, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(wd); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(prev) })
+
+	for _, target := range []string{dir, wd, "."} {
+		ok, err := confirmClean(target, true, pipeInput(t), io.Discard)
+		if ok || err == nil || !strings.Contains(err.Error(), "working directory") {
+			t.Errorf("confirmClean(%s) = %v, %v; want a refusal over the working directory", target, ok, err)
+		}
+	}
+}
+
+func TestCountFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.txt")
+	if got := countFiles(dir); got != "1 file" {
+		t.Errorf("countFiles() = %q, want %q", got, "1 file")
+	}
+	writeFiles(t, dir, filepath.Join("sub", "b.txt"), filepath.Join("sub", "c.txt"))
+	if got := countFiles(dir); got != "3 files" {
+		t.Errorf("countFiles() = %q, want %q", got, "3 files")
+	}
+}
+
+func TestContains(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{dir, true},
+		{filepath.Join(dir, "a", "b"), true},
+		{filepath.Dir(dir), false},
+		{dir + "-sibling", false},
+		{filepath.Join(filepath.Dir(dir), "..other"), false},
+	}
+	for _, tt := range tests {
+		if got := contains(dir, tt.path); got != tt.want {
+			t.Errorf("contains(%s, %s) = %v, want %v", dir, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Result is the outcome of one URL processed by ProcessURLs.
+type Result struct {
+	URL      string
+	Title    string
+	Status   string   // "completed", "skipped (exists)", "skipped (archived)", "no_subtitles", "filtered", "unavailable", "empty", "oversized" or "failed"
+	File     string   // Cleaned transcript path; empty if the job failed
+	Files    []string // With AllLangs, the transcript of every language
+	Warnings []string // Non-fatal problems, e.g. a missing thumbnail
+	Err      error
+	Job      TranscriptJob // The full job record (language, metadata, stats, ...)
+}
+
+// ProcessURLs runs the worker pool headlessly, with no TUI, and returns one
+// Result per URL in input order. Failed URLs are reported in their Result,
+// not as the returned error. Cancelling ctx stops workers from starting new
+// jobs (jobs already running finish first); the unstarted ones fail with
+// ctx's error, which is also returned. With FailFast, the first failed job
+// stops the run the same way, failing the unfinished ones with ErrAborted.
+//
+// Unset options get library-friendly defaults: one worker, and raw downloads
+// in a fresh directory under the system temp dir. As in the TUI, no more
+// workers are started than there are jobs to run.
+func ProcessURLs(ctx context.Context, urls []string, opts Options) ([]Result, error) {
+	if opts.TempDir == "" {
+		tempDir, err := os.MkdirTemp("", "yt-tx-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+		opts.TempDir = tempDir
+	}
+	if err := os.MkdirAll(opts.TempDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	if opts.CleanedDir != "" {
+		if err := os.MkdirAll(opts.CleanedDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cleaned directory: %w", err)
+		}
+	}
+
+	w := NewWorkflow(urls, opts) // Same pre-flight URL checks and worker clamping as the TUI
+	opts = w.Options
+	jobs := slices.Clone(w.Jobs)
+	runCtx, abort := context.WithCancel(ctx)
+	defer abort()
+	opts.ctx = runCtx // Kills the downloads in flight when the run stops
+	stop := context.AfterFunc(runCtx, func() { close(w.done) })
+	defer stop()
+
+	startWorkers(opts.ParallelWorkers, jobs, w.jobQueue, w.resultsChan, w.done, opts, w.limiter, w.hosts, w.wg)
+	var pending []int
+	for i, job := range jobs {
+		if job.Status == "pending" {
+			pending = append(pending, i)
+		} else {
+			w.addCombined(i, job)
+		}
+	}
+	queueJobs(jobs, pending, w.jobQueue, nil, w.done, opts, w.limiter)
+	go func() {
+		w.wg.Wait()
+		close(w.resultsChan)
+	}()
+
+	for result := range w.resultsChan {
+		jobs[result.OriginalJobIndex] = result.ProcessedJob
+		w.addCombined(result.OriginalJobIndex, result.ProcessedJob)
+		if opts.FailFast && result.ProcessedJob.Error != nil {
+			abort()
+		}
+	}
+	if ctx.Err() == nil && runCtx.Err() != nil {
+		abortUnfinished(jobs)
+	}
+
+	results := make([]Result, len(jobs))
+	for i, job := range jobs {
+		if job.Status == "pending" { // Never started because ctx was cancelled
+			job = failJob(job, ctx.Err())
+		}
+		results[i] = Result{
+			URL:      job.URL,
+			Title:    job.Title,
+			Status:   job.Status,
+			Warnings: job.Warnings,
+			Err:      job.Error,
+			Job:      job,
+		}
+		if job.Error == nil {
+			results[i].File = job.ProcessedFile
+			results[i].Files = job.ProcessedFiles
+		}
+	}
+	return results, ctx.Err()
+}
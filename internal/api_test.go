@@ -0,0 +1,291 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProcessURLs(t *testing.T) {
+	installFakeYtDlp(t, fakeYtDlpScript)
+	cleanedDir := t.TempDir()
+
+	results, err := ProcessURLs(context.Background(), []string{"https://youtu.be/abc", "https://example.com/nope"}, Options{CleanedDir: cleanedDir, ParallelWorkers: 2})
+	if err != nil {
+		t.Fatalf("ProcessURLs() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("ProcessURLs() returned %d results, want 2", len(results))
+	}
+
+	ok := results[0]
+	if ok.URL != "https://youtu.be/abc" || ok.Status != "completed" || ok.Err != nil {
+		t.Errorf("results[0] = %q %q %v, want the YouTube URL completed", ok.URL, ok.Status, ok.Err)
+	}
+	if content, err := os.ReadFile(ok.File); err != nil || string(content) != "hello\n" {
+		t.Errorf("results[0].File %q = %q, %v, want the cleaned transcript", ok.File, content, err)
+	}
+
+	bad := results[1]
+	if bad.Status != "failed" || !errors.Is(bad.Err, ErrUnrecognizedURL) || bad.File != "" {
+		t.Errorf("results[1] = %q %v file=%q, want failed with ErrUnrecognizedURL", bad.Status, bad.Err, bad.File)
+	}
+}
+
+func TestProcessURLs_MoreWorkersThanJobs(t *testing.T) {
+	installFakeYtDlp(t, fakeYtDlpScript)
+
+	results, err := ProcessURLs(context.Background(), []string{"https://youtu.be/abc"}, Options{CleanedDir: t.TempDir(), ParallelWorkers: 16})
+	if err != nil {
+		t.Fatalf("ProcessURLs() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Status != "completed" {
+		t.Errorf("ProcessURLs() = %+v, want the one job completed", results)
+	}
+}
+
+func TestProcessURLs_Combined(t *testing.T) {
+	installFakeYtDlp(t, fakeYtDlpScript)
+	path := filepath.Join(t.TempDir(), "all.md")
+	combined, err := NewCombinedWriter(path, nil, CleanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urls := []string{"https://example.com/nope", "https://youtu.be/abc"}
+	if _, err := ProcessURLs(context.Background(), urls, Options{CleanedDir: t.TempDir(), Combined: combined}); err != nil {
+		t.Fatalf("ProcessURLs() error = %v", err)
+	}
+	if err := combined.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "## Fake Title\n\nhello\n" {
+		t.Errorf("combined transcript = %q, %v, want the one completed job", content, err)
+	}
+}
+
+func TestProcessURLs_Archive(t *testing.T) {
+	installFakeYtDlp(t, fakeYtDlpScript)
+	path := filepath.Join(t.TempDir(), "archive.txt")
+	archive, err := LoadArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{CleanedDir: t.TempDir(), Archive: archive}
+
+	if _, err := ProcessURLs(context.Background(), []string{"https://youtu.be/abc", "https://example.com/nope"}, opts); err != nil {
+		t.Fatalf("ProcessURLs() error = %v", err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "youtube abc\n" {
+		t.Errorf("archive = %q, %v, want only the completed video", content, err)
+	}
+
+	// A fresh output directory would normally download the video again
+	opts.CleanedDir = t.TempDir()
+	results, err := ProcessURLs(context.Background(), []string{"https://youtu.be/abc"}, opts)
+	if err != nil || results[0].Status != "skipped (archived)" {
+		t.Errorf("ProcessURLs() of an archived video = %+v, %v, want skipped (archived)", results, err)
+	}
+}
+
+func TestProcessURLs_Cancelled(t *testing.T) {
+	installFakeYtDlp(t, fakeYtDlpScript)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := ProcessURLs(ctx, []string{"https://youtu.be/abc", "https://youtu.be/def"}, Options{CleanedDir: t.TempDir()})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ProcessURLs() error = %v, want context.Canceled", err)
+	}
+	for i, result := range results {
+		if result.Status == "pending" || (result.Status == "failed" && result.Err == nil) {
+			t.Errorf("results[%d] = %q %v, want every job finished or failed", i, result.Status, result.Err)
+		}
+	}
+}
+
+func TestProcessURLs_InputOrder(t *testing.T) {
+	// The first URL finishes last; IDs starting with x are private videos
+	installFakeYtDlp(t, `for a in "$@"; do [ "$prev" = "-o" ] && out="$a"; case "$a" in https://*) url="$a";; esac; prev="$a"; done
+id="${url##*/}"
+[ "$id" = xslow ] && sleep 0.3
+case "$*" in *"--print title"*) echo "$id"; exit 0;; esac
+case "$id" in x*) echo 'ERROR: Private video' >&2; exit 1;; esac
+printf 'WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n' > "$(dirname "$out")/$id.en.vtt"
+`)
+	urls := []string{"https://youtu.be/xslow", "https://youtu.be/ok1", "https://youtu.be/xfast", "https://youtu.be/ok2"}
+
+	results, err := ProcessURLs(context.Background(), urls, Options{CleanedDir: t.TempDir(), ParallelWorkers: len(urls)})
+	if err != nil {
+		t.Fatalf("ProcessURLs() error = %v", err)
+	}
+	jobs := make([]TranscriptJob, len(results))
+	for i, result := range results {
+		if result.URL != urls[i] {
+			t.Errorf("results[%d].URL = %q, want %q (input order)", i, result.URL, urls[i])
+		}
+		jobs[i] = result.Job
+	}
+
+	failure := ProgressView{}.RenderOverallFailure(jobs)
+	if !strings.Contains(failure, "failed: xslow, xfast\n") {
+		t.Errorf("RenderOverallFailure() = %q, want failed titles in input order", failure)
+	}
+}
+
+func TestProcessURLs_CleanWorkers(t *testing.T) {
+	installFakeRunner(t, subtitleRunner{})
+	urls := []string{"https://youtu.be/aaa", "https://youtu.be/bbb", "https://youtu.be/ccc", "https://youtu.be/ddd", "https://youtu.be/eee"}
+
+	results, err := ProcessURLs(context.Background(), urls, Options{CleanedDir: t.TempDir(), ParallelWorkers: 3, CleanWorkers: 2})
+	if err != nil {
+		t.Fatalf("ProcessURLs() error = %v", err)
+	}
+	if len(results) != len(urls) {
+		t.Fatalf("ProcessURLs() returned %d results, want %d", len(results), len(urls))
+	}
+	for i, result := range results {
+		if result.URL != urls[i] || result.Status != "completed" {
+			t.Errorf("results[%d] = %q %q %v, want %q completed", i, result.URL, result.Status, result.Err, urls[i])
+			continue
+		}
+		if content, err := os.ReadFile(result.File); err != nil || string(content) != "hello\n" {
+			t.Errorf("results[%d].File %q = %q, %v, want the cleaned transcript", i, result.File, content, err)
+		}
+	}
+}
+
+func TestProcessURLs_PanicFailsOnlyItsJob(t *testing.T) {
+	installFakeRunner(t, subtitleRunner{})
+	urls := []string{"https://youtu.be/aaa", "https://youtu.be/bbb", "https://youtu.be/ccc"}
+	// A transform that chokes on one video's captions, as on malformed input
+	explode := func(lines []string) []string {
+		if len(lines) > 0 && strings.Contains(lines[0], "hello") && panicFor.Add(1) == 2 {
+			panic("malformed caption")
+		}
+		return lines
+	}
+
+	for _, cleanWorkers := range []int{0, 2} {
+		panicFor.Store(0)
+		opts := Options{CleanedDir: t.TempDir(), ParallelWorkers: 1, CleanWorkers: cleanWorkers, Clean: CleanOptions{Transforms: []LineTransform{explode}}}
+		results, err := ProcessURLs(context.Background(), urls, opts)
+		if err != nil {
+			t.Fatalf("ProcessURLs(CleanWorkers=%d) error = %v", cleanWorkers, err)
+		}
+		var failed []Result
+		for _, result := range results {
+			if result.Status == "failed" {
+				failed = append(failed, result)
+			} else if result.Status != "completed" {
+				t.Errorf("CleanWorkers=%d: %s = %q, want the others completed", cleanWorkers, result.URL, result.Status)
+			}
+		}
+		if len(failed) != 1 || !strings.Contains(failed[0].Err.Error(), "panic: malformed caption") || !strings.Contains(failed[0].Job.PanicStack, "goroutine") {
+			t.Errorf("CleanWorkers=%d: failed results = %+v, want one failed by the panic, with its stack", cleanWorkers, failed)
+		}
+	}
+}
+
+// panicFor counts the transcripts TestProcessURLs_PanicFailsOnlyItsJob's
+// transform sees, so it panics on the second only.
+var panicFor atomic.Int32
+
+// slowRunner is a YtDlpRunner that waits delay before writing vtt as the
+// downloaded subtitles, standing in for a network-bound download.
+type slowRunner struct {
+	delay time.Duration
+	vtt   []byte
+}
+
+func (r slowRunner) Run(ctx context.Context, stderr io.Writer, args ...string) ([]byte, error) {
+	time.Sleep(r.delay)
+	var id, out string
+	for i, arg := range args {
+		if videoID, err := ExtractVideoID(arg); err == nil {
+			id = videoID
+		}
+		if arg == "-o" && i+1 < len(args) {
+			out = args[i+1]
+		}
+	}
+	if strings.Contains(strings.Join(args, " "), "--print title") {
+		return []byte("Video " + id + "\n"), nil
+	}
+	if out != "" {
+		return nil, os.WriteFile(filepath.Join(filepath.Dir(out), id+".en.vtt"), r.vtt, 0644)
+	}
+	return nil, nil
+}
+
+// BenchmarkProcessURLs_Workers compares each worker cleaning its own
+// download with a separate clean pool, on downloads that take 20ms each.
+func BenchmarkProcessURLs_Workers(b *testing.B) {
+	vtt, err := os.ReadFile(writeLargeVTT(b, 20_000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	installFakeRunner(b, slowRunner{delay: 20 * time.Millisecond, vtt: vtt})
+	urls := make([]string, 16)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://youtu.be/vid%08d", i)
+	}
+
+	for _, bm := range []struct {
+		name                   string
+		download, cleanWorkers int
+	}{
+		{"inline-4", 4, 0},
+		{"inline-8", 8, 0},
+		{"download-8-clean-2", 8, 2},
+		{"download-8-clean-4", 8, 4},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				opts := Options{CleanedDir: b.TempDir(), ParallelWorkers: bm.download, CleanWorkers: bm.cleanWorkers}
+				if _, err := ProcessURLs(context.Background(), urls, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessURLs_FailFast(t *testing.T) {
+	installFakeRunner(t, runnerFunc(func(args []string) ([]byte, error) {
+		joined := strings.Join(args, " ")
+		if strings.Contains(joined, "youtu.be/bad") && !strings.Contains(joined, "--print title") {
+			return nil, errors.New("boom")
+		}
+		time.Sleep(10 * time.Millisecond)
+		return subtitleRunner{}.Run(context.Background(), io.Discard, args...)
+	}))
+	urls := []string{"https://youtu.be/ok", "https://youtu.be/bad"}
+	for i := range 5 {
+		urls = append(urls, fmt.Sprintf("https://youtu.be/later%d", i))
+	}
+
+	results, err := ProcessURLs(context.Background(), urls, Options{CleanedDir: t.TempDir(), FailFast: true})
+	if err != nil {
+		t.Fatalf("ProcessURLs(FailFast) error = %v, want the failure reported per URL", err)
+	}
+	if results[0].Status != "completed" {
+		t.Errorf("first job = %q (%v), want completed before the failure", results[0].Status, results[0].Err)
+	}
+	if results[1].Err == nil || errors.Is(results[1].Err, ErrAborted) {
+		t.Errorf("failing job error = %v, want its own failure", results[1].Err)
+	}
+	// The single worker can be at most one job past the failure when it stops
+	for _, result := range results[3:] {
+		if !errors.Is(result.Err, ErrAborted) {
+			t.Errorf("%s after the failure = %q (%v), want ErrAborted", result.URL, result.Status, result.Err)
+		}
+	}
+}
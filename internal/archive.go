@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ZipOutputs bundles a run's output into a zip file at path: the combined
+// transcript first, if combined is set, then every job's transcripts in input
+// order. Entries are named by their path relative to root, so per-channel
+// subdirectories are kept; files outside root go in by name. Each file is
+// streamed into the zip, and the zip replaces any existing one at path only
+// once complete.
+func ZipOutputs(path, root string, jobs []TranscriptJob, combined string) error {
+	var files []string
+	if combined != "" {
+		files = append(files, combined)
+	}
+	for _, job := range jobs {
+		if job.Error != nil || job.ProcessedFile == "" {
+			continue
+		}
+		if len(job.ProcessedFiles) > 0 {
+			files = append(files, job.ProcessedFiles...)
+		} else {
+			files = append(files, job.ProcessedFile)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := writeZip(tmpPath, root, files); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write zip: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write zip: %w", err)
+	}
+	return nil
+}
+
+// writeZip writes files into a new zip at path, skipping repeated entries
+// (the same video listed twice with AllowDuplicates).
+func writeZip(path, root string, files []string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	seen := make(map[string]bool)
+	for _, file := range files {
+		name := zipEntryName(file, root)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if err := addZipFile(zw, file, name); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// addZipFile copies the file at path into zw as name.
+func addZipFile(zw *zip.Writer, path, name string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
+
+// zipEntryName names file within the zip: relative to root if it lies
+// inside it, otherwise just its base name.
+func zipEntryName(file, root string) string {
+	if rel, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.Base(file)
+}
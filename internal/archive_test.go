@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestZipOutputs(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	combined := write("all.md", "## B\n")
+	jobs := []TranscriptJob{
+		{Status: "completed", ProcessedFile: write("Zed/b.txt", "bee")},
+		{Status: "failed", Error: errors.New("boom")},
+		{Status: "skipped (exists)", ProcessedFile: write("a.txt", "ay")},
+		{Status: "completed", ProcessedFile: filepath.Join(root, "a.txt")}, // Duplicate URL
+		{Status: "completed", ProcessedFile: write("m.de.txt", "de"), ProcessedFiles: []string{filepath.Join(root, "m.de.txt"), write("m.en.txt", "en")}},
+	}
+	zipPath := filepath.Join(t.TempDir(), "out.zip")
+	if err := ZipOutputs(zipPath, root, jobs, combined); err != nil {
+		t.Fatalf("ZipOutputs() error = %v", err)
+	}
+
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if want := []string{"all.md", "Zed/b.txt", "a.txt", "m.de.txt", "m.en.txt"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("zip entries = %q, want %q", names, want)
+	}
+	rc, err := zr.File[1].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if content, err := io.ReadAll(rc); err != nil || string(content) != "bee" {
+		t.Errorf("Zed/b.txt = %q, %v, want %q", content, err, "bee")
+	}
+	if _, err := os.Stat(zipPath + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary zip left behind: %v", err)
+	}
+}
+
+func TestZipOutputs_MissingFile(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "out.zip")
+	jobs := []TranscriptJob{{Status: "completed", ProcessedFile: filepath.Join(t.TempDir(), "gone.txt")}}
+	if err := ZipOutputs(zipPath, t.TempDir(), jobs, ""); err == nil {
+		t.Error("ZipOutputs() with a missing transcript error = nil")
+	}
+	if _, err := os.Stat(zipPath); !os.IsNotExist(err) {
+		t.Errorf("ZipOutputs() left a partial zip: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+)
+
+// autoCaptionsNote and translatedCaptionsNote head the transcripts MarkAuto
+// marks as machine-generated.
+const (
+	autoCaptionsNote       = "# NOTE: auto-generated captions; may contain recognition errors"
+	translatedCaptionsNote = "# NOTE: machine-translated auto-generated captions; may contain errors"
+)
+
+// listCaptionKinds lists the video's caption tracks for MarkAuto, so that
+// transcripts of auto-generated captions can be told from uploaded ones: as
+// yt-dlp takes a language's uploaded track over its auto-generated one, a
+// language without an uploaded track was auto-generated. A job downloaded in
+// one language is marked CaptionsAuto; with AllLangs each language is judged
+// when its transcript is written (see captionsNote). A failed listing is only
+// warned about, leaving the transcripts unmarked.
+func listCaptionKinds(job *TranscriptJob, opts Options, limiter *RateLimiter) {
+	if !opts.MarkAuto || opts.ManualOnly || job.CaptionsKind != "" {
+		return
+	}
+	limiter.Wait()
+	available, err := ListSubtitleLanguages(job.URL)
+	if err != nil {
+		job.Warnings = append(job.Warnings, fmt.Sprintf("couldn't tell whether captions are auto-generated: %v", err))
+		return
+	}
+	job.Captions = &available
+	if !opts.AllLangs && !slices.Contains(available.Manual, job.Language) {
+		job.CaptionsKind = CaptionsAuto
+	}
+}
+
+// captionsNote returns the line MarkAuto puts above the transcript cleaned
+// from rawFile, or "" if it isn't marked: the note for machine-translated
+// captions, or for auto-generated ones.
+func captionsNote(job TranscriptJob, rawFile string, opts Options) string {
+	if !opts.MarkAuto {
+		return ""
+	}
+	kind := job.CaptionsKind
+	if opts.AllLangs && job.Captions != nil && !slices.Contains(job.Captions.Manual, subtitleLang(rawFile, job.VideoID)) {
+		kind = CaptionsAuto
+	}
+	switch kind {
+	case CaptionsAuto:
+		return autoCaptionsNote
+	case CaptionsTranslated:
+		return translatedCaptionsNote
+	}
+	return ""
+}
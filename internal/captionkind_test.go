@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// captionListRunner answers --dump-json with a caption listing and hands
+// every other call to subtitleRunner.
+type captionListRunner string
+
+func (r captionListRunner) Run(ctx context.Context, stderr io.Writer, args ...string) ([]byte, error) {
+	if slices.Contains(args, "--dump-json") {
+		return []byte(r), nil
+	}
+	return subtitleRunner{}.Run(ctx, stderr, args...)
+}
+
+func TestProcessJob_MarkAuto(t *testing.T) {
+	tests := []struct {
+		name     string
+		listing  string
+		wantKind string
+	}{
+		{"auto only", `{"automatic_captions": {"en": []}}`, CaptionsAuto},
+		{"uploaded", `{"subtitles": {"en": []}, "automatic_captions": {"en": []}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installFakeRunner(t, captionListRunner(tt.listing))
+			opts := Options{TempDir: t.TempDir(), CleanedDir: t.TempDir(), Lang: "en", MarkAuto: true}
+			job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+			if job.Status != "completed" || job.CaptionsKind != tt.wantKind {
+				t.Fatalf("workJob() = %q (%v), CaptionsKind %q, want completed with %q", job.Status, job.Error, job.CaptionsKind, tt.wantKind)
+			}
+			data, err := os.ReadFile(job.ProcessedFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if marked := strings.HasPrefix(string(data), autoCaptionsNote+"\n"); marked != (tt.wantKind == CaptionsAuto) {
+				t.Errorf("transcript = %q, want the note only for auto-generated captions", data)
+			}
+		})
+	}
+}
+
+func TestListCaptionKinds_ListingFails(t *testing.T) {
+	installFakeRunner(t, runnerFunc(func(args []string) ([]byte, error) {
+		return nil, io.ErrUnexpectedEOF
+	}))
+	job := TranscriptJob{URL: "https://youtu.be/abc", Language: "en"}
+	listCaptionKinds(&job, Options{MarkAuto: true}, nil)
+	if job.CaptionsKind != "" || job.Captions != nil || len(job.Warnings) != 1 {
+		t.Errorf("listCaptionKinds() kind %q, captions %v, warnings %q, want only a warning", job.CaptionsKind, job.Captions, job.Warnings)
+	}
+}
+
+func TestCaptionsNote(t *testing.T) {
+	captions := &SubtitleLanguages{Manual: []string{"en"}, Auto: []string{"en", "fr"}}
+	tests := []struct {
+		name    string
+		job     TranscriptJob
+		rawFile string
+		opts    Options
+		want    string
+	}{
+		{"off", TranscriptJob{CaptionsKind: CaptionsAuto}, "abc.en.vtt", Options{}, ""},
+		{"auto", TranscriptJob{CaptionsKind: CaptionsAuto}, "abc.en.vtt", Options{MarkAuto: true}, autoCaptionsNote},
+		{"translated", TranscriptJob{CaptionsKind: CaptionsTranslated}, "abc.de.vtt", Options{MarkAuto: true}, translatedCaptionsNote},
+		{"manual", TranscriptJob{CaptionsKind: CaptionsManual}, "abc.en.vtt", Options{MarkAuto: true}, ""},
+		{"all langs uploaded", TranscriptJob{VideoID: "abc", Captions: captions}, "/tmp/abc.en.vtt", Options{MarkAuto: true, AllLangs: true}, ""},
+		{"all langs auto", TranscriptJob{VideoID: "abc", Captions: captions}, "/tmp/abc.fr.vtt", Options{MarkAuto: true, AllLangs: true}, autoCaptionsNote},
+	}
+	for _, tt := range tests {
+		if got := captionsNote(tt.job, tt.rawFile, tt.opts); got != tt.want {
+			t.Errorf("%s: captionsNote() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// selfTestVideoID is a long-lived public video whose id the yt-dlp self-test
+// asks for ("Me at the zoo", the first video on YouTube).
+const selfTestVideoID = "jNQXAC9IVRw"
+
+// reachabilityURL is the page the network check requests; tests point it at
+// a local server.
+var reachabilityURL = "https://www.youtube.com/"
+
+// checkTimeout bounds each network-bound check, so a hung connection fails
+// the check instead of the whole run.
+const checkTimeout = 15 * time.Second
+
+// CheckResult is the outcome of one environment check run by RunChecks.
+type CheckResult struct {
+	Name   string
+	Detail string // What was found, e.g. the yt-dlp version; set when the check passed
+	Err    error  // Why the check failed; nil if it passed
+	Hint   string // How to fix a failure
+}
+
+// RunChecks checks that yt-tx can work in this environment: yt-dlp is
+// installed, each of dirs is writable, youtube.com is reachable (through
+// proxy, a -proxy value, if set) and yt-dlp can resolve a known public video.
+// Every check runs even if an earlier one failed, so one run shows every
+// problem.
+func RunChecks(dirs []string, proxy string) []CheckResult {
+	results := []CheckResult{checkYtDlp()}
+	for _, dir := range dirs {
+		results = append(results, checkDir(dir))
+	}
+	return append(results, checkReachable(proxy), checkVideoID())
+}
+
+// checkYtDlp checks that yt-dlp runs, reporting its version.
+func checkYtDlp() CheckResult {
+	result := CheckResult{Name: "yt-dlp installed"}
+	result.Detail, result.Err = YtDlpVersion()
+	if result.Err == nil && result.Detail == "" {
+		result.Detail = "unknown version"
+	}
+	result.Hint = "install yt-dlp (e.g. `pip install -U yt-dlp` or `brew install yt-dlp`) and make sure it is on your PATH"
+	return result
+}
+
+// checkDir checks that transcripts can be written into dir.
+func checkDir(dir string) CheckResult {
+	result := CheckResult{Name: fmt.Sprintf("%s/ writable", dir), Detail: "ok"}
+	result.Err = CheckWritable(dir)
+	result.Hint = fmt.Sprintf("check the permissions of %s, or choose another directory with -cleaned_dir or -o", dir)
+	return result
+}
+
+// checkReachable checks that youtube.com answers over HTTPS, through proxy
+// if set, or else any proxy set in the environment, as yt-dlp would.
+func checkReachable(proxy string) CheckResult {
+	result := CheckResult{Name: "youtube.com reachable"}
+	result.Hint = "check your internet connection, DNS and any proxy or firewall"
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, reachabilityURL, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	client := http.DefaultClient
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			result.Err = fmt.Errorf("invalid proxy: %w", err)
+			return result
+		}
+		client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		result.Err = fmt.Errorf("HTTP %s", resp.Status)
+		return result
+	}
+	result.Detail = resp.Status
+	return result
+}
+
+// checkVideoID checks that yt-dlp can resolve a known public video end to end.
+func checkVideoID() CheckResult {
+	result := CheckResult{Name: "yt-dlp can fetch video info"}
+	result.Hint = "update yt-dlp (`yt-dlp -U`); YouTube changes often break older versions. If you are rate limited, pass cookies with -yt-dlp-extra"
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	id, err := FetchVideoIDCtx(ctx, "https://www.youtube.com/watch?v="+selfTestVideoID)
+	switch {
+	case err != nil:
+		result.Err = err
+	case id != selfTestVideoID:
+		result.Err = fmt.Errorf("yt-dlp returned id %q, want %q", id, selfTestVideoID)
+	default:
+		result.Detail = id
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunChecks(t *testing.T) {
+	installFakeYtDlp(t, `case "$*" in
+*--version*) echo 2025.01.01;;
+*"--print id"*) echo jNQXAC9IVRw;;
+esac
+`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	old := reachabilityURL
+	reachabilityURL = server.URL
+	t.Cleanup(func() { reachabilityURL = old })
+
+	readOnly := filepath.Join(t.TempDir(), "ro")
+	if err := os.Mkdir(readOnly, 0555); err != nil {
+		t.Fatal(err)
+	}
+	results := RunChecks([]string{t.TempDir(), readOnly}, "")
+	if len(results) != 5 {
+		t.Fatalf("RunChecks() returned %d results, want 5", len(results))
+	}
+	for i, wantPass := range []bool{true, true, os.Geteuid() == 0, true, true} { // root can write anywhere
+		if got := results[i].Err == nil; got != wantPass {
+			t.Errorf("check %q passed = %v (%v), want %v", results[i].Name, got, results[i].Err, wantPass)
+		}
+	}
+	if results[0].Detail != "2025.01.01" {
+		t.Errorf("yt-dlp check detail = %q, want the version", results[0].Detail)
+	}
+	if results[2].Err != nil && results[2].Hint == "" {
+		t.Error("failed check has no hint")
+	}
+}
+
+func TestRunChecks_YtDlpBroken(t *testing.T) {
+	installFakeYtDlp(t, "echo 'ERROR: boom' >&2\nexit 1\n")
+	old := reachabilityURL
+	reachabilityURL = "http://127.0.0.1:1/" // Nothing listens on port 1
+	t.Cleanup(func() { reachabilityURL = old })
+
+	for _, result := range RunChecks(nil, "") {
+		if result.Err == nil {
+			t.Errorf("check %q passed, want every check to fail", result.Name)
+		}
+	}
+}
+
+func TestCheckReachable_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { proxied = r.URL.String() }))
+	defer proxy.Close()
+	old := reachabilityURL
+	reachabilityURL = "http://youtube.invalid/"
+	t.Cleanup(func() { reachabilityURL = old })
+
+	if result := checkReachable(proxy.URL); result.Err != nil {
+		t.Fatalf("checkReachable() error = %v, want the request to go through the proxy", result.Err)
+	}
+	if proxied != reachabilityURL {
+		t.Errorf("proxy saw %q, want %q", proxied, reachabilityURL)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "new", "dir")
+	if err := CheckWritable(dir); err != nil {
+		t.Fatalf("CheckWritable() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("CheckWritable() left %d files behind", len(entries))
+	}
+}
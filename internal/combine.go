@@ -0,0 +1,337 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// contentsHeading titles the table of contents of a combined transcript.
+const contentsHeading = "Contents"
+
+// linkTextEscaper escapes the characters that would end a markdown link's text early.
+var linkTextEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`)
+
+// Section orders for a combined transcript (see CombinedWriter.SortBy).
+const (
+	CombineSortInput    = "input"    // Input order
+	CombineSortTitle    = "title"    // By title, case-insensitively
+	CombineSortDate     = "date"     // By upload date, oldest first; needs metadata
+	CombineSortDuration = "duration" // By duration, shortest first; needs metadata
+)
+
+// CombineSorts lists the supported section orders.
+var CombineSorts = []string{CombineSortInput, CombineSortTitle, CombineSortDate, CombineSortDuration}
+
+// CombinesFormat reports whether transcripts in the output format are plain
+// text a CombinedWriter can join: text, and timed-txt's [HH:MM:SS] lines.
+func CombinesFormat(format string) bool {
+	return format == FormatText || format == FormatTimedText
+}
+
+// combinedSection is one video's transcript within a combined file.
+type combinedSection struct {
+	Title string
+	Body  string
+}
+
+// CombinedWriter writes the transcripts of a run into one file as the jobs
+// finish. Sections are appended in input order, so after a crash the file
+// holds a valid prefix of the combined transcript. A ".md" path gets markdown,
+// anything else plain text. With playlist input, Close prepends a table of
+// contents once every section is known.
+//
+// A CombinedWriter is not safe for concurrent use; the TUI and ProcessURLs
+// both call Add from the single goroutine that collects results.
+type CombinedWriter struct {
+	path     string
+	file     *os.File
+	opts     CleanOptions
+	markdown bool
+	toc      bool
+	sortBy   string                // Section order; anything but input order holds every section until Close
+	titles   map[string]string     // Video titles from playlist listings, by video URL
+	pending  map[int]TranscriptJob // Finished jobs waiting for an earlier job to finish
+	next     int                   // Input index of the next section to write
+	written  []string              // Titles of the sections written so far
+	err      error                 // First write error; later sections are dropped
+}
+
+// NewCombinedWriter creates (or truncates) the combined transcript at path.
+// playlists are the playlists the input was expanded from, if any.
+func NewCombinedWriter(path string, playlists []Playlist, opts CleanOptions) (*CombinedWriter, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for combined transcript: %w", err)
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create combined transcript: %w", err)
+	}
+	c := &CombinedWriter{
+		path:     path,
+		file:     file,
+		opts:     opts,
+		markdown: strings.EqualFold(filepath.Ext(path), ".md"),
+		toc:      len(playlists) > 0,
+		titles:   make(map[string]string),
+		pending:  make(map[int]TranscriptJob),
+	}
+	for _, playlist := range playlists {
+		for _, video := range playlist.Videos {
+			c.titles[video.URL()] = video.Title
+		}
+	}
+	return c, nil
+}
+
+// SortBy sets the order of the sections to one of CombineSorts. In any order
+// but input order nothing is written until Close, once every section is
+// known; jobs that compare equal, or lack the metadata a date or duration
+// order needs, keep their input order after the others. Call it before the
+// first Add.
+func (c *CombinedWriter) SortBy(key string) {
+	c.sortBy = key
+}
+
+// NeedsMetadata reports whether the section order needs video metadata.
+func (c *CombinedWriter) NeedsMetadata() bool {
+	return c != nil && (c.sortBy == CombineSortDate || c.sortBy == CombineSortDuration)
+}
+
+// sorted reports whether sections are written in an order other than input order.
+func (c *CombinedWriter) sorted() bool {
+	return c.sortBy != "" && c.sortBy != CombineSortInput
+}
+
+// Add records the finished job at input position index, then appends every
+// section whose turn has come: a job finishing out of order waits until all
+// jobs before it have been added. Jobs without a transcript (failed, no
+// captions) must still be added, so later ones aren't held back.
+func (c *CombinedWriter) Add(index int, job TranscriptJob) {
+	c.pending[index] = job
+	for !c.sorted() {
+		job, ok := c.pending[c.next]
+		if !ok {
+			return
+		}
+		delete(c.pending, c.next)
+		c.next++
+		c.write(job)
+	}
+}
+
+// write appends job's section, if it has a transcript.
+func (c *CombinedWriter) write(job TranscriptJob) {
+	if c.err != nil {
+		return
+	}
+	section, ok, err := c.section(job)
+	if err != nil {
+		c.err = err
+		return
+	}
+	if !ok {
+		return
+	}
+	text := renderSection(section, c.markdown)
+	if len(c.written) > 0 {
+		text = "\n" + text
+	}
+	encodeOpts := c.opts
+	encodeOpts.BOM = c.opts.BOM && len(c.written) == 0 // The BOM only starts the file
+	if _, err := c.file.WriteString(EncodeOutput(text, encodeOpts)); err != nil {
+		c.err = fmt.Errorf("failed to write combined transcript: %w", err)
+		return
+	}
+	c.written = append(c.written, section.Title)
+}
+
+// section reads the transcript of a job that has one (completed, or skipped
+// because it already existed). Titles fall back to the video's title in its
+// playlist, then to its URL.
+func (c *CombinedWriter) section(job TranscriptJob) (combinedSection, bool, error) {
+	if job.Error != nil || job.ProcessedFile == "" {
+		return combinedSection{}, false, nil
+	}
+	content, err := ReadTextFile(job.ProcessedFile)
+	if err != nil {
+		return combinedSection{}, false, fmt.Errorf("failed to read transcript for combining: %w", err)
+	}
+	return combinedSection{Title: c.title(job), Body: strings.TrimRight(decodeOutput(content), "\n")}, true, nil
+}
+
+// title returns the section title of job: its title, else the video's title
+// in its playlist, else its URL.
+func (c *CombinedWriter) title(job TranscriptJob) string {
+	title := job.Title
+	if title == "" {
+		title = c.titles[job.URL]
+	}
+	if title == "" {
+		title = job.URL
+	}
+	return title
+}
+
+// Close writes any sections still waiting, in the SortBy order (jobs that
+// never finished leave a gap rather than holding the rest back), closes the file and, for playlist
+// input, prepends the table of contents. It returns the first error met.
+func (c *CombinedWriter) Close() error {
+	indices := make([]int, 0, len(c.pending))
+	for index := range c.pending {
+		indices = append(indices, index)
+	}
+	slices.Sort(indices)
+	c.sortIndices(indices)
+	for _, index := range indices {
+		c.write(c.pending[index])
+	}
+	c.pending = nil
+
+	if err := c.file.Close(); err != nil && c.err == nil {
+		c.err = fmt.Errorf("failed to write combined transcript: %w", err)
+	}
+	if c.err != nil || !c.toc || len(c.written) == 0 {
+		return c.err
+	}
+	return c.prependContents()
+}
+
+// sortIndices stably sorts the input indices of pending jobs into the
+// section order.
+func (c *CombinedWriter) sortIndices(indices []int) {
+	switch c.sortBy {
+	case CombineSortTitle:
+		slices.SortStableFunc(indices, func(a, b int) int {
+			return strings.Compare(strings.ToLower(c.title(c.pending[a])), strings.ToLower(c.title(c.pending[b])))
+		})
+	case CombineSortDate:
+		slices.SortStableFunc(indices, func(a, b int) int {
+			return compareKnown(jobUploadDate(c.pending[a]), jobUploadDate(c.pending[b]), "")
+		})
+	case CombineSortDuration:
+		slices.SortStableFunc(indices, func(a, b int) int {
+			return compareKnown(jobDuration(c.pending[a]), jobDuration(c.pending[b]), 0)
+		})
+	}
+}
+
+// compareKnown compares a and b, ordering the unknown value after every other.
+func compareKnown[T cmp.Ordered](a, b, unknown T) int {
+	switch {
+	case a == b:
+		return 0
+	case a == unknown:
+		return 1
+	case b == unknown:
+		return -1
+	}
+	return cmp.Compare(a, b)
+}
+
+// jobUploadDate returns job's YYYYMMDD upload date, or "" without metadata.
+func jobUploadDate(job TranscriptJob) string {
+	if job.Metadata == nil {
+		return ""
+	}
+	return job.Metadata.UploadDate
+}
+
+// jobDuration returns job's duration in seconds, or 0 without metadata.
+func jobDuration(job TranscriptJob) float64 {
+	if job.Metadata == nil {
+		return 0
+	}
+	return job.Metadata.Duration
+}
+
+// prependContents rewrites the file with the table of contents first. The
+// new file replaces the old one only once complete, so a crash midway still
+// leaves the sections intact.
+func (c *CombinedWriter) prependContents() error {
+	content, err := ReadTextFile(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to read combined transcript: %w", err)
+	}
+	full := renderContents(c.written, c.markdown) + decodeOutput(content)
+	tmpPath := c.path + ".tmp"
+	if err := WriteTextFile(tmpPath, EncodeOutput(full, c.opts)); err != nil {
+		return fmt.Errorf("failed to write combined transcript: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write combined transcript: %w", err)
+	}
+	return nil
+}
+
+// decodeOutput undoes EncodeOutput, so text read back from a written
+// transcript is plain LF text again and is encoded only once.
+func decodeOutput(content string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(content, "\ufeff"), "\r\n", "\n")
+}
+
+// renderContents renders a table of contents listing titles, followed by a
+// blank line: in markdown as [title](#anchor) links, otherwise as a plain
+// numbered list.
+func renderContents(titles []string, markdown bool) string {
+	var sb strings.Builder
+	sb.WriteString(combinedHeading(contentsHeading, markdown))
+	used := map[string]int{markdownAnchor(contentsHeading, map[string]int{}): 1}
+	for i, title := range titles {
+		if markdown {
+			fmt.Fprintf(&sb, "%d. [%s](#%s)\n", i+1, linkTextEscaper.Replace(title), markdownAnchor(title, used))
+		} else {
+			fmt.Fprintf(&sb, "%d. %s\n", i+1, title)
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// renderSection renders one section: a "## <title>" heading in markdown or
+// the title underlined in plain text, then the transcript.
+func renderSection(section combinedSection, markdown bool) string {
+	text := combinedHeading(section.Title, markdown)
+	if section.Body != "" {
+		text += section.Body + "\n"
+	}
+	return text
+}
+
+// combinedHeading renders a section heading followed by a blank line.
+func combinedHeading(title string, markdown bool) string {
+	if markdown {
+		return "## " + title + "\n\n"
+	}
+	return title + "\n" + strings.Repeat("=", utf8.RuneCountInString(title)) + "\n\n"
+}
+
+// markdownAnchor returns the anchor GitHub-style renderers give a heading:
+// lowercased, with punctuation dropped and spaces turned into hyphens. A
+// repeated anchor gets "-1", "-2", ... appended; used tracks those taken.
+func markdownAnchor(heading string, used map[string]int) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteByte('-')
+		}
+	}
+	anchor := sb.String()
+	n := used[anchor]
+	used[anchor]++
+	if n > 0 {
+		anchor = fmt.Sprintf("%s-%d", anchor, n)
+	}
+	return anchor
+}
@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// writeTranscripts writes one transcript file per content and returns
+// completed jobs for them, titled "Video 1", "Video 2", ...
+func writeTranscripts(t *testing.T, contents ...string) []TranscriptJob {
+	t.Helper()
+	dir := t.TempDir()
+	jobs := make([]TranscriptJob, len(contents))
+	for i, content := range contents {
+		path := filepath.Join(dir, filepath.Base(t.Name())+string(rune('a'+i))+".txt")
+		if err := WriteTextFile(path, content); err != nil {
+			t.Fatal(err)
+		}
+		jobs[i] = TranscriptJob{URL: "https://youtu.be/" + string(rune('a'+i)), Title: "Video " + string(rune('1'+i)), Status: "completed", ProcessedFile: path}
+	}
+	return jobs
+}
+
+// readCombined returns the content of the combined transcript at path.
+func readCombined(t *testing.T, path string) string {
+	t.Helper()
+	content, err := ReadTextFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestCombinedWriter_OutOfOrderCompletion(t *testing.T) {
+	jobs := writeTranscripts(t, "one", "two", "three")
+	path := filepath.Join(t.TempDir(), "all.txt")
+	c, err := NewCombinedWriter(path, nil, CleanOptions{})
+	if err != nil {
+		t.Fatalf("NewCombinedWriter() error = %v", err)
+	}
+
+	c.Add(2, jobs[2])
+	if got := readCombined(t, path); got != "" {
+		t.Errorf("after the last job only, file = %q, want nothing written yet", got)
+	}
+	c.Add(0, jobs[0])
+	if got, want := readCombined(t, path), "Video 1\n=======\n\none\n"; got != want {
+		t.Errorf("after the first job, file = %q, want %q", got, want)
+	}
+	c.Add(1, jobs[1])
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	want := "Video 1\n=======\n\none\n\nVideo 2\n=======\n\ntwo\n\nVideo 3\n=======\n\nthree\n"
+	if got := readCombined(t, path); got != want {
+		t.Errorf("combined transcript = %q, want %q", got, want)
+	}
+}
+
+func TestCombinedWriter_CloseFlushesGaps(t *testing.T) {
+	jobs := writeTranscripts(t, "one", "two", "three")
+	path := filepath.Join(t.TempDir(), "all.md")
+	c, err := NewCombinedWriter(path, nil, CleanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Add(2, jobs[2])
+	c.Add(1, jobs[1]) // Job 0 never finishes (e.g. the run was cancelled)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got, want := readCombined(t, path), "## Video 2\n\ntwo\n\n## Video 3\n\nthree\n"; got != want {
+		t.Errorf("combined transcript = %q, want %q", got, want)
+	}
+}
+
+func TestCombinedWriter_Contents(t *testing.T) {
+	jobs := writeTranscripts(t, "\ufeffone\r\ntwo\r\n", "gone", "three", "four")
+	jobs[0].Title = "" // Falls back to the playlist's title
+	jobs[1] = failJob(jobs[1], ErrVideoUnavailable)
+	jobs[2].Title = "Intro [Part 1]"
+	jobs[3].Title = "Intro [Part 1]"
+	playlists := []Playlist{{Title: "List", Videos: []PlaylistVideo{{ID: "a", Title: "From Playlist"}}}}
+	jobs[0].URL = playlists[0].Videos[0].URL()
+
+	path := filepath.Join(t.TempDir(), "nested", "all.md")
+	c, err := NewCombinedWriter(path, playlists, CleanOptions{Newline: NewlineCRLF})
+	if err != nil {
+		t.Fatalf("NewCombinedWriter() error = %v", err)
+	}
+	for i := len(jobs) - 1; i >= 0; i-- {
+		c.Add(i, jobs[i])
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := "## Contents\r\n\r\n" +
+		"1. [From Playlist](#from-playlist)\r\n" +
+		"2. [Intro \\[Part 1\\]](#intro-part-1)\r\n" +
+		"3. [Intro \\[Part 1\\]](#intro-part-1-1)\r\n\r\n" +
+		"## From Playlist\r\n\r\none\r\ntwo\r\n\r\n" +
+		"## Intro [Part 1]\r\n\r\nthree\r\n\r\n" +
+		"## Intro [Part 1]\r\n\r\nfour\r\n"
+	if got := readCombined(t, path); got != want {
+		t.Errorf("combined transcript = %q, want %q", got, want)
+	}
+}
+
+func TestCombinedWriter_PlainContents(t *testing.T) {
+	jobs := writeTranscripts(t, "one")
+	path := filepath.Join(t.TempDir(), "all.txt")
+	c, err := NewCombinedWriter(path, []Playlist{{Title: "List"}}, CleanOptions{BOM: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Add(0, jobs[0])
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	want := "\ufeffContents\n========\n\n1. Video 1\n\nVideo 1\n=======\n\none\n"
+	if got := readCombined(t, path); got != want {
+		t.Errorf("combined transcript = %q, want %q", got, want)
+	}
+}
+
+func TestMarkdownAnchor(t *testing.T) {
+	used := map[string]int{}
+	tests := []struct{ heading, want string }{
+		{"Hello, World!", "hello-world"},
+		{"Go 1.23: what's new?", "go-123-whats-new"},
+		{"Hello, World", "hello-world-1"},
+		{"Café — déjà vu", "café--déjà-vu"},
+	}
+	for _, tt := range tests {
+		if got := markdownAnchor(tt.heading, used); got != tt.want {
+			t.Errorf("markdownAnchor(%q) = %q, want %q", tt.heading, got, tt.want)
+		}
+	}
+}
+
+func TestCombinedWriter_SortByTitle(t *testing.T) {
+	jobs := writeTranscripts(t, "b", "a2", "c", "a1")
+	jobs[0].Title = "beta"
+	jobs[1].Title = "Alpha" // Ties with jobs[3] case-insensitively; input order breaks it
+	jobs[2].Title = "Gamma"
+	jobs[3].Title = "alpha"
+
+	// Every completion order gives the same file
+	for _, order := range [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {2, 0, 3, 1}} {
+		path := filepath.Join(t.TempDir(), "all.md")
+		c, err := NewCombinedWriter(path, []Playlist{{Title: "List"}}, CleanOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SortBy(CombineSortTitle)
+		for _, i := range order {
+			c.Add(i, jobs[i])
+		}
+		if got := readCombined(t, path); got != "" {
+			t.Errorf("before Close, file = %q, want nothing written while sorting", got)
+		}
+		if err := c.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		want := "## Contents\n\n1. [Alpha](#alpha)\n2. [alpha](#alpha-1)\n3. [beta](#beta)\n4. [Gamma](#gamma)\n\n" +
+			"## Alpha\n\na2\n\n## alpha\n\na1\n\n## beta\n\nb\n\n## Gamma\n\nc\n"
+		if got := readCombined(t, path); got != want {
+			t.Errorf("completion order %v: combined transcript = %q, want %q", order, got, want)
+		}
+	}
+}
+
+func TestCombinedWriter_SortByDateAndDuration(t *testing.T) {
+	jobs := writeTranscripts(t, "one", "two", "three")
+	jobs[0].Metadata = &VideoMetadata{UploadDate: "20240301", Duration: 30}
+	jobs[2].Metadata = &VideoMetadata{UploadDate: "20230101", Duration: 90}
+	// jobs[1] has no metadata and goes last
+
+	for _, tt := range []struct {
+		sortBy string
+		want   string
+	}{
+		{CombineSortDate, "## Video 3\n\nthree\n\n## Video 1\n\none\n\n## Video 2\n\ntwo\n"},
+		{CombineSortDuration, "## Video 1\n\none\n\n## Video 3\n\nthree\n\n## Video 2\n\ntwo\n"},
+	} {
+		path := filepath.Join(t.TempDir(), "all.md")
+		c, err := NewCombinedWriter(path, nil, CleanOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SortBy(tt.sortBy)
+		if !c.NeedsMetadata() {
+			t.Errorf("SortBy(%s) NeedsMetadata() = false, want true", tt.sortBy)
+		}
+		for i, job := range jobs {
+			c.Add(i, job)
+		}
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := readCombined(t, path); got != tt.want {
+			t.Errorf("SortBy(%s) combined transcript = %q, want %q", tt.sortBy, got, tt.want)
+		}
+	}
+}
+
+func TestCombinesFormat(t *testing.T) {
+	for format, want := range map[string]bool{FormatText: true, FormatTimedText: true, FormatJSONL: false, FormatWordsJSON: false} {
+		if got := CombinesFormat(format); got != want {
+			t.Errorf("CombinesFormat(%q) = %v, want %v", format, got, want)
+		}
+	}
+}
+
+func TestCombinedWriter_TimedText(t *testing.T) {
+	jobs := writeTranscripts(t, "[00:00:01] hello\n[00:00:04] world\n")
+	path := filepath.Join(t.TempDir(), "all.txt")
+	c, err := NewCombinedWriter(path, nil, CleanOptions{})
+	if err != nil {
+		t.Fatalf("NewCombinedWriter() error = %v", err)
+	}
+	c.Add(0, jobs[0])
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got, want := readCombined(t, path), "Video 1\n=======\n\n[00:00:01] hello\n[00:00:04] world\n"; got != want {
+		t.Errorf("combined = %q, want %q", got, want)
+	}
+}
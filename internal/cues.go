@@ -0,0 +1,307 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Cue is a single timed caption block from a subtitle file.
+type Cue struct {
+	Start    time.Duration
+	End      time.Duration
+	Text     string // Caption text, possibly spanning several lines
+	Settings string // VTT cue settings after the timing, e.g. "align:start position:10%"
+}
+
+// ParseVTTCues splits subtitle content into timed cues. Cue identifiers and
+// the WEBVTT header are ignored; SRT timings (comma before the milliseconds)
+// are accepted too, so it works on either raw format.
+func ParseVTTCues(content string) []Cue {
+	var cues []Cue
+	var current *Cue
+	var text []string
+
+	flush := func() {
+		if current != nil {
+			current.Text = strings.Join(text, "\n")
+			cues = append(cues, *current)
+		}
+		current, text = nil, nil
+	}
+
+	for _, rawLine := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(rawLine)
+		if start, end, settings, ok := parseCueTiming(line); ok {
+			flush()
+			current = &Cue{Start: start, End: end, Settings: settings}
+			continue
+		}
+		if rawLine == "" {
+			flush()
+			continue
+		}
+		// A whitespace-only line (YouTube auto-captions have them) doesn't end the cue
+		if current != nil && line != "" {
+			text = append(text, line)
+		}
+	}
+	flush()
+	return cues
+}
+
+// parseCueTiming parses a "start --> end [settings]" timing line.
+func parseCueTiming(line string) (start, end time.Duration, settings string, ok bool) {
+	left, right, found := strings.Cut(line, "-->")
+	if !found {
+		return 0, 0, "", false
+	}
+	rightFields := strings.Fields(right)
+	if len(rightFields) == 0 {
+		return 0, 0, "", false
+	}
+	if start, ok = parseCueTimestamp(strings.TrimSpace(left)); !ok {
+		return 0, 0, "", false
+	}
+	if end, ok = parseCueTimestamp(rightFields[0]); !ok {
+		return 0, 0, "", false
+	}
+	return start, end, strings.Join(rightFields[1:], " "), true
+}
+
+// ParseClockTime parses a point in a video given as "HH:MM:SS", "MM:SS" or a
+// number of seconds, each optionally with a fractional part.
+func ParseClockTime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, ":") {
+		seconds, err := strconv.ParseFloat(s, 64)
+		if err == nil && seconds >= 0 {
+			return time.Duration(seconds * float64(time.Second)), nil
+		}
+	} else if d, ok := parseCueTimestamp(s); ok && d >= 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid time %q (want HH:MM:SS, MM:SS or seconds)", s)
+}
+
+// parseCueTimestamp parses "hh:mm:ss.mmm", "mm:ss.mmm" or their SRT "," variants.
+func parseCueTimestamp(s string) (time.Duration, bool) {
+	parts := strings.Split(strings.Replace(s, ",", ".", 1), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, false
+	}
+	total := time.Duration(seconds * float64(time.Second))
+	unit := time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, false
+		}
+		total += time.Duration(n) * unit
+		unit *= 60
+	}
+	return total, true
+}
+
+// CueOptions controls the cue-level (timing-aware) processing of one video's transcript.
+type CueOptions struct {
+	Chapters  []Chapter     // Insert a heading where each chapter begins
+	TrimIntro time.Duration // Drop cues that end before this point
+	TrimOutro time.Duration // Drop cues that start within this long of the end
+	Start     time.Duration // Keep only cues that end after this point
+	End       time.Duration // Keep only cues that start before this point (0 = the end of the video)
+	Duration  time.Duration // Video length, for TrimOutro; the last cue's end is used if zero
+}
+
+// Enabled reports whether any cue-level processing is requested.
+func (o CueOptions) Enabled() bool {
+	return len(o.Chapters) > 0 || o.TrimIntro > 0 || o.TrimOutro > 0 || o.Start > 0 || o.End > 0
+}
+
+// CleanCues trims cues to the window and time range in opts and cleans them,
+// with chapter headings if opts has chapters. With cleanOpts.StitchCues,
+// overlapping cues are stitched first.
+func CleanCues(cues []Cue, opts CueOptions, cleanOpts CleanOptions) string {
+	cues = RangeCues(TrimCues(cues, opts.TrimIntro, opts.TrimOutro, opts.Duration), opts.Start, opts.End)
+	if cleanOpts.StitchCues {
+		cues = StitchOverlappingCues(cues)
+	}
+	return CleanCuesWithChapters(cues, opts.Chapters, cleanOpts)
+}
+
+// RangeCues keeps the cues that overlap the range from start to end, including
+// those only partly inside it. A zero end leaves the range open-ended.
+func RangeCues(cues []Cue, start, end time.Duration) []Cue {
+	if start == 0 && end == 0 {
+		return cues
+	}
+	kept := make([]Cue, 0, len(cues))
+	for _, cue := range cues {
+		if cue.End > start && (end == 0 || cue.Start < end) {
+			kept = append(kept, cue)
+		}
+	}
+	return kept
+}
+
+// TrimCues drops cues that lie entirely before intro or entirely within the
+// last outro of the video; cues straddling either cutoff are kept. duration
+// is the video's length, falling back to the last cue's end when zero.
+func TrimCues(cues []Cue, intro, outro, duration time.Duration) []Cue {
+	if duration == 0 && len(cues) > 0 {
+		duration = cues[len(cues)-1].End
+	}
+	outroStart := duration - outro
+
+	kept := make([]Cue, 0, len(cues))
+	for _, cue := range cues {
+		if intro > 0 && cue.End <= intro {
+			continue
+		}
+		if outro > 0 && cue.Start >= outroStart {
+			continue
+		}
+		kept = append(kept, cue)
+	}
+	return kept
+}
+
+// minStitchWords is the shortest overlap StitchOverlappingCues removes; a
+// single shared word ("no" ending one cue, "no" starting the next) is as
+// likely said twice as repeated by the captions.
+const minStitchWords = 2
+
+// stitchTailWords is how many of the last words kept StitchOverlappingCues
+// looks back over, enough for the longest line rolling captions repeat.
+const stitchTailWords = 32
+
+// StitchOverlappingCues joins consecutive cues that overlap, as rolling
+// auto-captions do when one cue ends "to the store" and the next begins "to
+// the store and": the longest run of words (at least minStitchWords) that
+// ends the text kept so far and also begins the next cue is dropped from the
+// next cue, so the join reads once. Words match ignoring case and the
+// punctuation around them. A cue the overlap swallows whole is dropped,
+// extending the cue before it to its end. Cue text comes back with its tags
+// removed, as the overlap is found in the words alone; line breaks within a
+// cue are kept.
+func StitchOverlappingCues(cues []Cue) []Cue {
+	stitched := make([]Cue, 0, len(cues))
+	var tail []string // Normalized last words of the stitched cues
+	for _, cue := range cues {
+		lines := strings.Split(StripHTMLTags(cue.Text), "\n")
+		var words []string
+		for _, line := range lines {
+			words = append(words, strings.Fields(line)...)
+		}
+		overlap := longestOverlap(tail, words)
+		if overlap > 0 && overlap == len(words) {
+			if last := len(stitched) - 1; cue.End > stitched[last].End {
+				stitched[last].End = cue.End
+			}
+			continue
+		}
+		cue.Text = strings.Join(dropWords(lines, overlap), "\n")
+		if strings.TrimSpace(cue.Text) == "" {
+			continue
+		}
+		stitched = append(stitched, cue)
+		for _, word := range words[overlap:] {
+			tail = append(tail, normalizeStitchWord(word))
+		}
+		if len(tail) > stitchTailWords {
+			tail = tail[len(tail)-stitchTailWords:]
+		}
+	}
+	return stitched
+}
+
+// longestOverlap returns the length of the longest run of words (at least
+// minStitchWords) that both ends tail and begins words, or 0 if none does.
+func longestOverlap(tail, words []string) int {
+	for n := min(len(tail), len(words)); n >= minStitchWords; n-- {
+		match := true
+		for i, word := range words[:n] {
+			if normalizeStitchWord(word) != tail[len(tail)-n+i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return n
+		}
+	}
+	return 0
+}
+
+// dropWords removes the first n words from lines, dropping lines left empty.
+func dropWords(lines []string, n int) []string {
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if n > 0 {
+			fields := strings.Fields(line)
+			if n >= len(fields) {
+				n -= len(fields)
+				continue
+			}
+			line = strings.Join(fields[n:], " ")
+			n = 0
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
+
+// normalizeStitchWord is how StitchOverlappingCues compares words: lowercased,
+// without the punctuation around them.
+func normalizeStitchWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
+
+// CleanCuesWithChapters cleans cues chapter by chapter, putting a
+// "## <chapter title>" heading before the captions that fall in each chapter.
+// Captions before the first chapter are emitted without a heading.
+func CleanCuesWithChapters(cues []Cue, chapters []Chapter, opts CleanOptions) string {
+	var sections []string
+	next := 0 // Index of the first cue not yet assigned to a section
+
+	section := func(heading string, until time.Duration, last bool) {
+		var lines []string
+		for ; next < len(cues) && (last || cues[next].Start < until); next++ {
+			lines = append(lines, strings.Split(cues[next].Text, "\n")...)
+			if opts.JoinCueLines {
+				lines = append(lines, "") // Keep cue boundaries for joining
+			}
+		}
+		cleaned, _ := removeArtifacts(lines, vttArtifact, opts)
+		body := NewPipeline(opts).Run(cleaned)
+		if heading != "" {
+			body = strings.TrimRight(heading+"\n"+body, "\n")
+		}
+		if body != "" {
+			sections = append(sections, body)
+		}
+	}
+
+	if len(chapters) == 0 {
+		section("", 0, true)
+	} else {
+		section("", chapters[0].Start(), false)
+	}
+	for i, ch := range chapters {
+		last := i == len(chapters)-1
+		var until time.Duration
+		if !last {
+			until = chapters[i+1].Start()
+		}
+		section("## "+ch.Title, until, last)
+	}
+	return strings.Join(sections, "\n\n")
+}
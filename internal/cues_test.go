@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseVTTCues(t *testing.T) {
+	// The last cue opens with a whitespace-only line, as YouTube auto-captions do
+	content := "WEBVTT\nKind: captions\n\n1\n00:00:01.000 --> 00:00:02.500 align:start\n<c>hello</c>\nthere\n\n01:00.000 --> 01:01.000\nworld\n\n01:01.000 --> 01:02.000\n \nagain\n"
+	want := []Cue{
+		{Start: time.Second, End: 2500 * time.Millisecond, Text: "<c>hello</c>\nthere", Settings: "align:start"},
+		{Start: time.Minute, End: time.Minute + time.Second, Text: "world"},
+		{Start: time.Minute + time.Second, End: time.Minute + 2*time.Second, Text: "again"},
+	}
+	if got := ParseVTTCues(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseVTTCues() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseVTTCues_SRT(t *testing.T) {
+	content := "1\r\n00:00:01,000 --> 00:00:02,000\r\nhello\r\n"
+	want := []Cue{{Start: time.Second, End: 2 * time.Second, Text: "hello"}}
+	if got := ParseVTTCues(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseVTTCues(srt) = %+v, want %+v", got, want)
+	}
+}
+
+func TestCleanCuesWithChapters(t *testing.T) {
+	cues := []Cue{
+		{Start: 0, Text: "welcome"},
+		{Start: 5 * time.Second, Text: "first topic"},
+		{Start: 6 * time.Second, Text: "first topic"},
+		{Start: 20 * time.Second, Text: "<c>second</c> topic"},
+	}
+	chapters := []Chapter{
+		{Title: "Intro Part", StartTime: 5},
+		{Title: "Second Part", StartTime: 15},
+	}
+	want := "welcome\n\n## Intro Part\nfirst topic\n\n## Second Part\nsecond topic"
+	if got := CleanCuesWithChapters(cues, chapters, CleanOptions{}); got != want {
+		t.Errorf("CleanCuesWithChapters() = %q, want %q", got, want)
+	}
+}
+
+func TestTrimCues(t *testing.T) {
+	cues := []Cue{
+		{Start: 0, End: 10 * time.Second, Text: "[Music]"},
+		{Start: 25 * time.Second, End: 35 * time.Second, Text: "straddles intro"},
+		{Start: 60 * time.Second, End: 70 * time.Second, Text: "content"},
+		{Start: 85 * time.Second, End: 95 * time.Second, Text: "straddles outro"},
+		{Start: 95 * time.Second, End: 100 * time.Second, Text: "credits"},
+	}
+	got := TrimCues(cues, 30*time.Second, 10*time.Second, 100*time.Second)
+	var texts []string
+	for _, c := range got {
+		texts = append(texts, c.Text)
+	}
+	want := []string{"straddles intro", "content", "straddles outro"}
+	if !reflect.DeepEqual(texts, want) {
+		t.Errorf("TrimCues() kept %q, want %q", texts, want)
+	}
+
+	// Without a known duration, the last cue's end stands in for it.
+	if got := TrimCues(cues, 0, 10*time.Second, 0); len(got) != 4 {
+		t.Errorf("TrimCues() without duration kept %d cues, want 4", len(got))
+	}
+}
+
+func TestCleanCues_NoChapters(t *testing.T) {
+	cues := []Cue{
+		{Start: 0, End: time.Second, Text: "intro"},
+		{Start: 5 * time.Second, End: 6 * time.Second, Text: "hello"},
+		{Start: 6 * time.Second, End: 7 * time.Second, Text: "hello"},
+	}
+	if got := CleanCues(cues, CueOptions{TrimIntro: 2 * time.Second}, CleanOptions{}); got != "hello" {
+		t.Errorf("CleanCues() = %q, want %q", got, "hello")
+	}
+}
+
+func TestRangeCues(t *testing.T) {
+	cues := []Cue{
+		{Start: 0, End: 10 * time.Second, Text: "before"},
+		{Start: 55 * time.Second, End: 65 * time.Second, Text: "straddles start"},
+		{Start: 70 * time.Second, End: 80 * time.Second, Text: "inside"},
+		{Start: 115 * time.Second, End: 125 * time.Second, Text: "straddles end"},
+		{Start: 120 * time.Second, End: 130 * time.Second, Text: "after"},
+	}
+	texts := func(cues []Cue) []string {
+		var texts []string
+		for _, c := range cues {
+			texts = append(texts, c.Text)
+		}
+		return texts
+	}
+	if got, want := texts(RangeCues(cues, time.Minute, 2*time.Minute)), []string{"straddles start", "inside", "straddles end"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RangeCues() kept %q, want %q", got, want)
+	}
+	if got, want := texts(RangeCues(cues, 2*time.Minute, 0)), []string{"straddles end", "after"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RangeCues() without an end kept %q, want %q", got, want)
+	}
+	if got := RangeCues(cues, 0, 0); len(got) != len(cues) {
+		t.Errorf("RangeCues() without a range kept %d cues, want all %d", len(got), len(cues))
+	}
+}
+
+func TestParseClockTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"90", 90 * time.Second},
+		{"2.5", 2500 * time.Millisecond},
+		{"01:30", 90 * time.Second},
+		{"1:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"00:00:01.5", 1500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got, err := ParseClockTime(tt.in); err != nil || got != tt.want {
+			t.Errorf("ParseClockTime(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "abc", "-5", "1:2:3:4", "1m"} {
+		if _, err := ParseClockTime(in); err == nil {
+			t.Errorf("ParseClockTime(%q) error = nil, want an error", in)
+		}
+	}
+}
+
+func TestStitchOverlappingCues(t *testing.T) {
+	cue := func(start, end int, text string) Cue {
+		return Cue{Start: time.Duration(start) * time.Second, End: time.Duration(end) * time.Second, Text: text}
+	}
+	tests := []struct {
+		name string
+		cues []Cue
+		want []Cue
+	}{
+		{
+			name: "suffix repeated as prefix",
+			cues: []Cue{cue(0, 2, "we went to the store"), cue(2, 4, "to the store and bought milk")},
+			want: []Cue{cue(0, 2, "we went to the store"), cue(2, 4, "and bought milk")},
+		},
+		{
+			name: "longest overlap wins",
+			cues: []Cue{cue(0, 2, "the cat saw the cat"), cue(2, 4, "saw the cat run")},
+			want: []Cue{cue(0, 2, "the cat saw the cat"), cue(2, 4, "run")},
+		},
+		{
+			name: "case, punctuation and tags ignored",
+			cues: []Cue{cue(0, 2, "Over there, To The Store."), cue(2, 4, "to <00:00:02.500><c>the store</c> now")},
+			want: []Cue{cue(0, 2, "Over there, To The Store."), cue(2, 4, "now")},
+		},
+		{
+			name: "rolling auto-caption lines",
+			cues: []Cue{cue(0, 2, "\nhello everyone and"), cue(2, 4, "hello everyone and\nwelcome back"), cue(4, 6, "welcome back\nto the show")},
+			want: []Cue{cue(0, 2, "\nhello everyone and"), cue(2, 4, "welcome back"), cue(4, 6, "to the show")},
+		},
+		{
+			name: "single shared word kept",
+			cues: []Cue{cue(0, 2, "he said no"), cue(2, 4, "no way")},
+			want: []Cue{cue(0, 2, "he said no"), cue(2, 4, "no way")},
+		},
+		{
+			name: "cue swallowed whole extends the one before",
+			cues: []Cue{cue(0, 2, "to the store"), cue(2, 3, "the store"), cue(3, 5, "the store today")},
+			want: []Cue{cue(0, 3, "to the store"), cue(3, 5, "today")},
+		},
+		{
+			name: "overlap across a short cue",
+			cues: []Cue{cue(0, 1, "one two"), cue(1, 2, "three"), cue(2, 3, "two three four")},
+			want: []Cue{cue(0, 1, "one two"), cue(1, 2, "three"), cue(2, 3, "four")},
+		},
+		{
+			name: "no overlap",
+			cues: []Cue{cue(0, 1, "first line"), cue(1, 2, "second line")},
+			want: []Cue{cue(0, 1, "first line"), cue(1, 2, "second line")},
+		},
+		{
+			name: "empty",
+			cues: nil,
+			want: []Cue{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StitchOverlappingCues(tt.cues); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StitchOverlappingCues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanCues_StitchCues(t *testing.T) {
+	cues := []Cue{
+		{Start: 0, End: time.Second, Text: "we went to the store"},
+		{Start: time.Second, End: 2 * time.Second, Text: "to the store and bought milk"},
+	}
+	if got, want := CleanCues(cues, CueOptions{}, CleanOptions{StitchCues: true}), "we went to the store\nand bought milk"; got != want {
+		t.Errorf("CleanCues(StitchCues) = %q, want %q", got, want)
+	}
+}
@@ -1,23 +1,138 @@
 package internal
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
+const (
+	progressMargin   = 4  // Columns left free around the progress bar
+	minProgressWidth = 10 // Narrowest bar that still reads as a bar
+	maxProgressWidth = 80 // Widest bar; beyond this it's just noise
+	jobListChrome    = 8  // Terminal rows RenderJobList needs besides job lines, with a couple to spare
+	minJobLines      = 5  // Fewest job lines shown, however short the terminal
+	defaultJobLines  = 20 // Job lines shown until the terminal height is known
+)
+
+// Styles applied to job lines in RenderJobList, keyed by terminal status.
+// In-progress jobs keep the default terminal colour.
+var (
+	completedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("2")) // Green
+	failedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1")) // Red
+	skippedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("3")) // Yellow
+)
+
+// Static glyphs shown beside jobs in RenderJobList; in-progress jobs get the spinner frame instead.
+const (
+	pendingGlyph   = "·"
+	completedGlyph = "✔"
+	failedGlyph    = "✘"
+	skippedGlyph   = "↷"
+)
+
+// Progress bar styles, selected with NewProgressView.
+const (
+	ProgressGradient = "gradient" // Purple to pink gradient, for true-colour terminals
+	ProgressSolid    = "solid"    // One basic terminal colour, for terminals with few colours
+	ProgressASCII    = "ascii"    // Uncoloured '#' and '-', for plain terminals and logs
+)
+
+// ProgressStyles lists the supported progress bar styles.
+var ProgressStyles = []string{ProgressGradient, ProgressSolid, ProgressASCII}
+
 // ProgressView manages displaying progress information for transcript processing
 type ProgressView struct {
 	Progress progress.Model
+	NoColor  bool   // Render job statuses without colour
+	ShowIDs  bool   // Identify jobs by video ID rather than URL, and add it to titles in the summary
+	Spinner  string // Current spinner frame, shown beside jobs that are in progress
+	MaxJobs  int    // Most job lines RenderJobList shows (0 = defaultJobLines); see SetHeight
+	Activity string // What the workers are busy with (see activityLine), shown under the progress bar
 }
 
-// NewProgressView creates a new progress view
-func NewProgressView() ProgressView {
+// NewProgressView creates a new progress view whose bar is drawn in style,
+// one of ProgressStyles ("" means ProgressGradient).
+func NewProgressView(style string) ProgressView {
 	return ProgressView{
-		Progress: progress.New(progress.WithDefaultGradient()),
+		Progress: newProgressBar(style),
+	}
+}
+
+// newProgressBar creates a progress bar drawn in style.
+func newProgressBar(style string) progress.Model {
+	switch style {
+	case ProgressSolid:
+		return progress.New(progress.WithSolidFill("4")) // Blue
+	case ProgressASCII:
+		bar := progress.New(progress.WithSolidFill(""), progress.WithFillCharacters('#', '-'))
+		bar.EmptyColor = ""
+		return bar
+	default:
+		return progress.New(progress.WithDefaultGradient())
+	}
+}
+
+// ColorDisabled reports whether output should be left unstyled: either the
+// user asked for it (flag or NO_COLOR) or stdout isn't a terminal.
+func ColorDisabled(noColorFlag bool) bool {
+	if noColorFlag {
+		return true
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return true
+	}
+	info, err := os.Stdout.Stat()
+	return err != nil || info.Mode()&os.ModeCharDevice == 0
+}
+
+// StdinIsTerminal reports whether stdin is a terminal, so the user can
+// answer prompts; under cron, in CI or with input redirected, it isn't.
+func StdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// statusStyle returns the style for a job status, and false if it should stay unstyled.
+func statusStyle(status string) (lipgloss.Style, bool) {
+	switch {
+	case status == "completed":
+		return completedStyle, true
+	case status == "failed":
+		return failedStyle, true
+	case strings.HasPrefix(status, "skipped"), status == "no_subtitles", status == "filtered", status == "unavailable", status == "empty", status == "oversized":
+		return skippedStyle, true
+	default:
+		return lipgloss.Style{}, false
+	}
+}
+
+// statusGlyph returns the marker shown before a job line: a static glyph for
+// pending and finished jobs, or the spinner frame while the job is in progress.
+func (v ProgressView) statusGlyph(status string) string {
+	switch {
+	case status == "pending":
+		return pendingGlyph
+	case status == "completed":
+		return completedGlyph
+	case status == "failed":
+		return failedGlyph
+	case strings.HasPrefix(status, "skipped"), status == "no_subtitles", status == "filtered", status == "unavailable", status == "empty", status == "oversized":
+		return skippedGlyph
+	case v.Spinner != "":
+		return v.Spinner
+	default:
+		return pendingGlyph
 	}
 }
 
@@ -26,6 +141,349 @@ func (v ProgressView) RenderCompleted() string {
 	return "✅ All done!\n" + v.Progress.ViewAs(1.0) + "\n"
 }
 
+// RenderOutputFiles lists where each job's transcript was written, or already
+// existed, as "title -> path" lines, so users can find their files after a run.
+func (v ProgressView) RenderOutputFiles(jobs []TranscriptJob) string {
+	var b strings.Builder
+	for _, job := range jobs {
+		name := job.Title
+		if name == "" {
+			name = job.URL
+		}
+		for _, file := range outputFiles(job) {
+			b.WriteString(fmt.Sprintf("%s -> %s\n", name, file))
+		}
+	}
+	return b.String()
+}
+
+// OutputPaths returns the absolute path of every transcript the jobs wrote,
+// or found already there, in input order and each once, for scripts to feed
+// to other commands.
+func OutputPaths(jobs []TranscriptJob) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, job := range jobs {
+		for _, file := range outputFiles(job) {
+			if abs, err := filepath.Abs(file); err == nil {
+				file = abs
+			}
+			if !seen[file] {
+				seen[file] = true
+				paths = append(paths, file)
+			}
+		}
+	}
+	return paths
+}
+
+// outputFiles returns the transcripts a job wrote, or found already there:
+// one per language with AllLangs. A failed job has none.
+func outputFiles(job TranscriptJob) []string {
+	if job.Error != nil || job.ProcessedFile == "" {
+		return nil
+	}
+	if len(job.ProcessedFiles) > 0 {
+		return job.ProcessedFiles
+	}
+	return []string{job.ProcessedFile}
+}
+
+// outputTreeEntry is one line below the root of RenderOutputTree: a file, or
+// a directory with the files in it.
+type outputTreeEntry struct {
+	name  string
+	files []string
+}
+
+// RenderOutputTree renders where each job's transcript was written, or
+// already existed, as a tree of the directories under root (with ByChannel,
+// one per channel) and the files in each. Files directly in root come first;
+// directories and the files within each are sorted alphabetically.
+func (v ProgressView) RenderOutputTree(jobs []TranscriptJob, root string) string {
+	byDir := make(map[string][]string)
+	for _, job := range jobs {
+		if job.Error != nil || job.ProcessedFile == "" {
+			continue
+		}
+		files := job.ProcessedFiles
+		if len(files) == 0 {
+			files = []string{job.ProcessedFile}
+		}
+		for _, file := range files {
+			dir := filepath.Dir(file)
+			if rel, err := filepath.Rel(root, dir); err == nil && !strings.HasPrefix(rel, "..") {
+				dir = rel
+			}
+			byDir[dir] = append(byDir[dir], filepath.Base(file))
+		}
+	}
+	if len(byDir) == 0 {
+		return ""
+	}
+
+	// Files directly in root first, then one entry per directory
+	var entries, dirs []outputTreeEntry
+	for _, dir := range slices.Sorted(maps.Keys(byDir)) {
+		files := slices.Compact(slices.Sorted(slices.Values(byDir[dir])))
+		if dir != "." {
+			dirs = append(dirs, outputTreeEntry{name: filepath.ToSlash(dir) + "/", files: files})
+			continue
+		}
+		for _, file := range files {
+			entries = append(entries, outputTreeEntry{name: file})
+		}
+	}
+	entries = append(entries, dirs...)
+
+	var b strings.Builder
+	b.WriteString(strings.TrimSuffix(filepath.ToSlash(root), "/") + "/\n")
+	for i, entry := range entries {
+		branch, indent := "├── ", "│   "
+		if i == len(entries)-1 {
+			branch, indent = "└── ", "    "
+		}
+		b.WriteString(branch + entry.name + "\n")
+		for j, file := range entry.files {
+			if j == len(entry.files)-1 {
+				b.WriteString(indent + "└── " + file + "\n")
+			} else {
+				b.WriteString(indent + "├── " + file + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// RenderSummary renders how many jobs were freshly processed (and how many of
+// those refreshed a stale transcript), skipped (because their transcript
+// already existed, the video has no captions or none left after cleaning, was
+// found unavailable by the probe, was listed twice, fell outside the duration
+// or date filters or is in the archive), and failed. Videos without captions,
+// empty transcripts, unavailable videos and captions over MaxBytes are also
+// listed, as they are not
+// failures; duplicates, filtered and archived
+// videos are counted, as are videos named by their ID for lack of a title.
+// With DetectLang, transcripts whose detected language isn't their captions'
+// are listed too, and with FailFast, a run stopped early says so.
+func (v ProgressView) RenderSummary(jobs []TranscriptJob) string {
+	var processed, refreshed, skipped, failed, aborted int
+	var noCaptions, unavailable, empty, oversized, otherLang []string
+	duplicates, filtered, archived, untitled := 0, 0, 0, 0
+	for _, job := range jobs {
+		if job.TitleFellBack {
+			untitled++
+		}
+		switch {
+		case errors.Is(job.Error, ErrAborted):
+			aborted++
+		case job.Error != nil:
+			failed++
+		case job.Status == "no_subtitles":
+			skipped++
+			noCaptions = append(noCaptions, v.jobName(job))
+		case job.Status == "unavailable":
+			skipped++
+			unavailable = append(unavailable, v.jobName(job))
+		case job.Status == "empty":
+			skipped++
+			empty = append(empty, v.jobName(job))
+		case job.Status == "oversized":
+			skipped++
+			oversized = append(oversized, v.jobName(job))
+		case job.Status == "skipped (duplicate)":
+			skipped++
+			duplicates++
+		case job.Status == "filtered":
+			skipped++
+			filtered++
+		case job.Status == "skipped (archived)":
+			skipped++
+			archived++
+		case strings.HasPrefix(job.Status, "skipped"):
+			skipped++
+		case job.Status == "completed":
+			processed++
+			if job.Refreshed {
+				refreshed++
+			}
+			if mismatches := langMismatches(job); len(mismatches) > 0 {
+				otherLang = append(otherLang, fmt.Sprintf("%s (%s)", v.jobName(job), strings.Join(mismatches, ", ")))
+			}
+		}
+	}
+	processedText := fmt.Sprintf("%d processed", processed)
+	if refreshed > 0 {
+		processedText += fmt.Sprintf(" (%d refreshed)", refreshed)
+	}
+	summary := fmt.Sprintf("%s, %d skipped, %d failed\n", processedText, skipped, failed)
+	if aborted > 0 {
+		summary += fmt.Sprintf("aborted early at the first failure: %d jobs not finished\n", aborted)
+	}
+	if len(noCaptions) > 0 {
+		summary += fmt.Sprintf("skipped: no captions available (%d): %s\n", len(noCaptions), strings.Join(noCaptions, ", "))
+	}
+	if len(unavailable) > 0 {
+		summary += fmt.Sprintf("skipped: private or removed (%d): %s\n", len(unavailable), strings.Join(unavailable, ", "))
+	}
+	if len(empty) > 0 {
+		summary += fmt.Sprintf("skipped: nothing left after cleaning (%d): %s\n", len(empty), strings.Join(empty, ", "))
+	}
+	if len(oversized) > 0 {
+		summary += fmt.Sprintf("skipped: captions over the size limit (%d): %s\n", len(oversized), strings.Join(oversized, ", "))
+	}
+	if duplicates > 0 {
+		summary += fmt.Sprintf("skipped: duplicate URLs (%d)\n", duplicates)
+	}
+	if filtered > 0 {
+		summary += fmt.Sprintf("skipped: outside the duration or date range (%d)\n", filtered)
+	}
+	if archived > 0 {
+		summary += fmt.Sprintf("skipped: already in the archive (%d)\n", archived)
+	}
+	if untitled > 0 {
+		summary += fmt.Sprintf("title unavailable, used ID for %d videos\n", untitled)
+	}
+	if len(otherLang) > 0 {
+		summary += fmt.Sprintf("detected language differs from captions (%d): %s\n", len(otherLang), strings.Join(otherLang, ", "))
+	}
+	return summary
+}
+
+// langMismatches returns "<captions> → <detected>" for each of the job's
+// transcripts whose DetectedLang isn't the language of its captions, compared
+// by primary subtag so en-US matches en and zh-Hans matches zh.
+func langMismatches(job TranscriptJob) []string {
+	if job.DetectedLang == "" {
+		return nil
+	}
+	pairs := strings.Split(job.DetectedLang, ",")
+	if !strings.Contains(job.DetectedLang, ":") {
+		pairs = []string{job.Language + ":" + job.DetectedLang}
+	}
+	var mismatches []string
+	for _, pair := range pairs {
+		captions, detected, _ := strings.Cut(pair, ":")
+		if primaryLang(captions) != primaryLang(detected) {
+			mismatches = append(mismatches, captions+" → "+detected)
+		}
+	}
+	return mismatches
+}
+
+// primaryLang returns the primary subtag of a language code, e.g. "pt" for
+// "pt-BR", lowercased.
+func primaryLang(lang string) string {
+	primary, _, _ := strings.Cut(lang, "-")
+	return strings.ToLower(primary)
+}
+
+// jobVideoID returns the job's video ID: the one the worker resolved, or
+// until then whatever can be parsed from its URL ("" if nothing can).
+func jobVideoID(job TranscriptJob) string {
+	if job.VideoID != "" {
+		return job.VideoID
+	}
+	id, _ := ExtractVideoID(job.URL)
+	return id
+}
+
+// jobName names a job in the summary by its title, followed by its video ID
+// in brackets with ShowIDs, e.g. "Intro [dQw4w9WgXcQ]".
+func (v ProgressView) jobName(job TranscriptJob) string {
+	if id := jobVideoID(job); v.ShowIDs && id != "" && id != job.Title {
+		return fmt.Sprintf("%s [%s]", job.Title, id)
+	}
+	return job.Title
+}
+
+// ExitSummary returns the one-line run summary printed to stderr when yt-tx
+// exits, for scripts to grep, e.g.
+//
+//	yt-tx: total=10 ok=7 skipped=2 failed=1 no_subs=0 empty=0
+//
+// The tokens and their order are stable and always add up to total: ok is
+// completed jobs, no_subs videos without captions, empty videos whose
+// captions cleaned down to nothing, skipped every other job
+// deliberately not transcribed (existing, duplicate, archived, filtered or
+// unavailable videos), and failed the rest, including jobs interrupted
+// before they finished.
+func ExitSummary(jobs []TranscriptJob) string {
+	var ok, skipped, failed, noSubs, empty int
+	for _, job := range jobs {
+		switch {
+		case job.Error != nil:
+			failed++
+		case job.Status == "completed":
+			ok++
+		case job.Status == "no_subtitles":
+			noSubs++
+		case job.Status == "empty":
+			empty++
+		case isTerminalStatus(job.Status):
+			skipped++
+		default:
+			failed++
+		}
+	}
+	return fmt.Sprintf("yt-tx: total=%d ok=%d skipped=%d failed=%d no_subs=%d empty=%d", len(jobs), ok, skipped, failed, noSubs, empty)
+}
+
+// RenderThroughput renders how many bytes of raw captions were read and of
+// transcripts written, and the rate at which captions were processed over
+// elapsed. It renders nothing if no transcript was written.
+func (v ProgressView) RenderThroughput(jobs []TranscriptJob, elapsed time.Duration) string {
+	var raw, cleaned int64
+	for _, job := range jobs {
+		raw += job.RawBytes
+		cleaned += job.CleanedBytes
+	}
+	if raw == 0 {
+		return ""
+	}
+	line := fmt.Sprintf("read %s of captions, wrote %s of transcripts", formatBytes(raw), formatBytes(cleaned))
+	if elapsed > 0 {
+		rate := float64(raw) / elapsed.Seconds() / (1 << 20)
+		line += fmt.Sprintf(" in %s (%.2f MB/s)", elapsed.Round(time.Millisecond), rate)
+	}
+	return line + "\n"
+}
+
+// formatBytes renders n bytes in B, KB or MB.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// RenderInterrupted renders the banner of a run the user quit before every
+// job had finished: how many had, how many were cancelled mid-way and how
+// many never started. Transcripts are written atomically, so those of the
+// finished jobs are complete and no cancelled job leaves a partial one.
+func (v ProgressView) RenderInterrupted(jobs []TranscriptJob) string {
+	var finished, cancelled, notStarted int
+	for _, job := range jobs {
+		switch {
+		case isTerminalStatus(job.Status):
+			finished++
+		case job.Status == "" || job.Status == "pending":
+			notStarted++
+		default:
+			cancelled++
+		}
+	}
+	line := fmt.Sprintf("⏹ Interrupted: %d of %d completed, %d in progress cancelled", finished, len(jobs), cancelled)
+	if notStarted > 0 {
+		line += fmt.Sprintf(", %d not started", notStarted)
+	}
+	return line + "\n"
+}
+
 // RenderFailed renders the UI when a job has failed.
 func (v ProgressView) RenderFailed(err error, title string) string {
 	taskTitle := title
@@ -35,19 +493,143 @@ func (v ProgressView) RenderFailed(err error, title string) string {
 	return fmt.Sprintf("❌ Error processing %s:\n%v\n%s\n", taskTitle, err, v.Progress.ViewAs(v.Progress.Percent()))
 }
 
-// RenderOverallFailure renders a summary if any jobs failed in a batch.
+// RenderOverallFailure renders a summary if any jobs failed in a batch,
+// grouping the failed titles by the cause of their failure. Titles are listed
+// in job (input) order, never completion order. Jobs a FailFast run never
+// finished are left to RenderSummary to count.
 func (v ProgressView) RenderOverallFailure(jobs []TranscriptJob) string {
 	var failedTitles []string
+	byCategory := make(map[string][]string)
 	for _, job := range jobs {
-		if job.Error != nil {
-			failedTitles = append(failedTitles, job.Title)
+		if job.Error != nil && !errors.Is(job.Error, ErrAborted) {
+			failedTitles = append(failedTitles, v.jobName(job))
+			category := ClassifyError(job.Error)
+			byCategory[category] = append(byCategory[category], v.jobName(job))
 		}
 	}
 	if len(failedTitles) == 0 {
 		// Should not be called if no failures, but as a fallback:
 		return "⚠️ Some jobs may have encountered issues. Please check logs.\n" + v.Progress.ViewAs(1.0) + "\n"
 	}
-	return fmt.Sprintf("❌ Some jobs failed: %s\nPlease check individual errors if not displayed above.\n%s\n", strings.Join(failedTitles, ", "), v.Progress.ViewAs(1.0))
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("❌ Some jobs failed: %s\n", strings.Join(failedTitles, ", ")))
+	for _, category := range FailureCategories {
+		if titles := byCategory[category]; len(titles) > 0 {
+			b.WriteString(fmt.Sprintf("  %s (%d): %s\n", category, len(titles), strings.Join(titles, ", ")))
+		}
+	}
+	b.WriteString("Please check individual errors if not displayed above.\n")
+	b.WriteString(v.Progress.ViewAs(1.0) + "\n")
+	return b.String()
+}
+
+// RenderDebugStats renders what the cleaning pipeline did to each processed transcript.
+func (v ProgressView) RenderDebugStats(jobs []TranscriptJob) string {
+	var b strings.Builder
+	b.WriteString("\nCleaning diagnostics:\n")
+	for _, job := range jobs {
+		if job.Stats == nil {
+			continue
+		}
+		name := job.Title
+		if name == "" {
+			name = job.URL
+		}
+		s := job.Stats
+		b.WriteString(fmt.Sprintf("  %s: %d raw lines -> %d final (dropped %d blank, %d header, %d cue numbers, %d timestamps, %d html-only, %d stripped; collapsed %d duplicates, %d stutters)\n",
+			name, s.RawLines, s.FinalLines, s.Blank, s.Headers, s.Numbers, s.Timestamps, s.HTMLOnly, s.Stripped, s.Duplicates, s.Stutters))
+	}
+	return b.String()
+}
+
+// RenderDedupeReport renders how many lines each cleaned transcript had as
+// read, once subtitle artifacts were removed and in the end, with how much
+// smaller cleaning made it, and the totals across jobs, for tuning cleaning
+// options. Jobs that weren't cleaned into text are left out.
+func (v ProgressView) RenderDedupeReport(jobs []TranscriptJob) string {
+	var b strings.Builder
+	var cleaned, raw, captions, final int
+	b.WriteString("\nDedupe report:\n")
+	for _, job := range jobs {
+		if job.Stats == nil {
+			continue
+		}
+		s := *job.Stats
+		cleaned++
+		raw += s.RawLines
+		captions += s.CaptionLines()
+		final += s.FinalLines
+		name := job.URL
+		if job.Title != "" {
+			name = v.jobName(job)
+		}
+		b.WriteString(fmt.Sprintf("  %s: %s\n", name, formatLineCounts(s.RawLines, s.CaptionLines(), s.FinalLines)))
+	}
+	if cleaned == 0 {
+		b.WriteString("  no transcripts were cleaned\n")
+	} else if cleaned > 1 {
+		b.WriteString(fmt.Sprintf("  total: %s\n", formatLineCounts(raw, captions, final)))
+	}
+	return b.String()
+}
+
+// formatLineCounts formats a transcript's line counts for RenderDedupeReport,
+// e.g. "1200 lines -> 400 after artifact removal -> 150 after dedupe (88% fewer)".
+func formatLineCounts(raw, captions, final int) string {
+	s := fmt.Sprintf("%d lines -> %d after artifact removal -> %d after dedupe", raw, captions, final)
+	if raw > 0 {
+		s += fmt.Sprintf(" (%.0f%% fewer)", 100*float64(raw-final)/float64(raw))
+	}
+	return s
+}
+
+// RenderTimings renders how long each job spent fetching its title,
+// downloading and processing, with the totals across jobs, so it shows
+// which phase dominates a run. Jobs that did no work (e.g. archived) are left out.
+func (v ProgressView) RenderTimings(jobs []TranscriptJob) string {
+	var b strings.Builder
+	var total Timings
+	b.WriteString("\nPhase timings:\n")
+	for _, job := range jobs {
+		t := job.Timings
+		if t == (Timings{}) {
+			continue
+		}
+		total.Title += t.Title
+		total.Download += t.Download
+		total.Processing += t.Processing
+		name := job.URL
+		if job.Title != "" {
+			name = v.jobName(job)
+		}
+		b.WriteString(fmt.Sprintf("  %s: %s\n", name, formatTimings(t)))
+	}
+	b.WriteString(fmt.Sprintf("  total: %s\n", formatTimings(total)))
+	return b.String()
+}
+
+// RenderPanics renders the stack trace of each job a panic failed, or "" if
+// none did.
+func (v ProgressView) RenderPanics(jobs []TranscriptJob) string {
+	var b strings.Builder
+	for _, job := range jobs {
+		if job.PanicStack == "" {
+			continue
+		}
+		name := job.URL
+		if job.Title != "" {
+			name = v.jobName(job)
+		}
+		b.WriteString(fmt.Sprintf("\n%s: %v\n%s", name, job.Error, job.PanicStack))
+	}
+	return b.String()
+}
+
+// formatTimings renders a job's phase durations, rounded to the millisecond.
+func formatTimings(t Timings) string {
+	return fmt.Sprintf("title %s, download %s, processing %s",
+		t.Title.Round(time.Millisecond), t.Download.Round(time.Millisecond), t.Processing.Round(time.Millisecond))
 }
 
 // RenderDownloading renders the UI when downloading subtitles
@@ -63,19 +645,16 @@ func (v ProgressView) RenderDownloading(currentJobIndex, totalJobs int, title st
 	return fmt.Sprintf("%s\n%s\n", header, v.Progress.View())
 }
 
-// RenderProcessing renders the UI when processing subtitles
-func (v ProgressView) RenderProcessing(currentJobIndex, totalJobs int, rawVTTDir string) string {
+// RenderProcessing renders the UI when processing the subtitles of the job
+// titled title. The title comes from the job itself, since with parallel
+// workers the raw directory holds several jobs' downloads at once.
+func (v ProgressView) RenderProcessing(currentJobIndex, totalJobs int, title string) string {
 	header := fmt.Sprintf("[%d/%d] ", currentJobIndex+1, totalJobs)
 
-	// Find newest VTT files
-	pattern := GetNewestVTTPattern(rawVTTDir)
-	files, _ := filepath.Glob(pattern)
-
-	if len(files) > 0 {
-		displayTitle := ExtractDisplayTitle(files[len(files)-1])
-		header += fmt.Sprintf("Processing %s...", displayTitle)
-	} else {
+	if title == "" {
 		header += "Processing..."
+	} else {
+		header += fmt.Sprintf("Processing %s...", title)
 	}
 
 	return fmt.Sprintf("%s\n%s\n", header, v.Progress.View())
@@ -86,6 +665,24 @@ func (v *ProgressView) SetProgress(percent float64) tea.Cmd {
 	return v.Progress.SetPercent(percent)
 }
 
+// SetWidth fits the progress bar to the given terminal width, within sensible bounds
+func (v *ProgressView) SetWidth(terminalWidth int) {
+	width := terminalWidth - progressMargin
+	if width < minProgressWidth {
+		width = minProgressWidth
+	}
+	if width > maxProgressWidth {
+		width = maxProgressWidth
+	}
+	v.Progress.Width = width
+}
+
+// SetHeight fits the job list to the given terminal height, so a long batch
+// never scrolls the terminal or redraws hundreds of lines per frame.
+func (v *ProgressView) SetHeight(terminalHeight int) {
+	v.MaxJobs = max(terminalHeight-jobListChrome, minJobLines)
+}
+
 // UpdateProgress updates the progress bar based on animation frame
 func (v *ProgressView) UpdateProgress(msg progress.FrameMsg) (progress.Model, tea.Cmd) {
 	updatedProgress, cmd := v.Progress.Update(msg)
@@ -93,30 +690,149 @@ func (v *ProgressView) UpdateProgress(msg progress.FrameMsg) (progress.Model, te
 	return v.Progress, cmd
 }
 
-// RenderJobList renders the overall progress and a list of job statuses.
+// RenderLangPrompt renders the list of caption languages the user picks from
+// for the video titled title, with the highlighted one marked. waiting is how
+// many more videos are queued for a choice after this one.
+func (v ProgressView) RenderLangPrompt(title string, langs []string, cursor, waiting int) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Choose a caption language for %s (↑/↓, enter):\n", title))
+	for i, lang := range langs {
+		marker := "  "
+		if i == cursor {
+			marker = "> "
+		}
+		b.WriteString(marker + lang + "\n")
+	}
+	if waiting > 0 {
+		b.WriteString(fmt.Sprintf("(%d more video(s) waiting for a choice)\n", waiting))
+	}
+	return b.String()
+}
+
+// RenderJobList renders the overall progress and a list of job statuses. A
+// batch longer than MaxJobs shows only that many jobs (see visibleJobs) and a
+// line counting the rest, so the list fits the terminal however many URLs
+// there are.
 func (v ProgressView) RenderJobList(jobs []TranscriptJob, completedCount, totalJobs, numWorkers int) string {
 	var b strings.Builder
 
 	b.WriteString(fmt.Sprintf("Processing %d URLs with %d worker(s)...\n", totalJobs, numWorkers))
 	b.WriteString(v.Progress.View() + "\n") // Display the overall progress bar
-	b.WriteString(fmt.Sprintf("Completed: %d/%d\n\n", completedCount, totalJobs))
+	b.WriteString(fmt.Sprintf("Completed: %d/%d\n", completedCount, totalJobs))
+	if v.Activity != "" {
+		b.WriteString(v.Activity + "\n")
+	}
+	b.WriteString("\n")
 
-	// Display status for each job (e.g., first 10 or a summary)
-	// For now, let's list all of them. Could be verbose for many jobs.
-	for i, job := range jobs {
+	shown := visibleJobs(jobs, cmp.Or(v.MaxJobs, defaultJobLines))
+	for _, i := range shown {
+		job := jobs[i]
 		status := job.Status
 		if status == "" {
 			status = "pending"
 		}
-		line := fmt.Sprintf("[%d/%d] %s: %s", i+1, totalJobs, job.URL, status)
+		label := status
+		if status == "downloading_subtitles" && job.DownloadPct > 0 {
+			label += fmt.Sprintf(" %.0f%%", job.DownloadPct)
+		}
+		source := job.URL
+		if id := jobVideoID(job); v.ShowIDs && id != "" {
+			source = id
+		}
+		line := fmt.Sprintf("%s [%d/%d] %s: %s", v.statusGlyph(status), i+1, totalJobs, source, label)
 		if job.Title != "" && job.Title != job.URL { // Add title if available and different from URL
-			line = fmt.Sprintf("[%d/%d] %s (%s): %s", i+1, totalJobs, job.URL, job.Title, status)
+			line = fmt.Sprintf("%s [%d/%d] %s (%s): %s", v.statusGlyph(status), i+1, totalJobs, source, job.Title, label)
 		}
 		if job.Error != nil {
 			line += fmt.Sprintf(" (Error: %v)", job.Error)
 		}
+		if len(job.Warnings) > 0 {
+			line += fmt.Sprintf(" (Warning: %s)", strings.Join(job.Warnings, "; "))
+		}
+		if style, ok := statusStyle(status); ok && !v.NoColor {
+			line = style.Render(line)
+		}
 		b.WriteString(line + "\n")
 	}
+	if len(shown) < len(jobs) {
+		b.WriteString(hiddenSummary(jobs, shown) + "\n")
+	}
 
 	return b.String()
 }
+
+// activityLine describes what the workers are busy with, e.g. "active: 3
+// downloading, 1 processing, 0 idle". With cleanWorkers the downloads and the
+// cleaning have pools of their own; a downloaded job waiting for a free clean
+// worker still counts as downloading, so downloads are capped at their pool.
+func activityLine(a workerActivity, workers, cleanWorkers int) string {
+	idle := workers - a.downloading - a.processing
+	if cleanWorkers > 0 {
+		a.downloading = min(a.downloading, workers)
+		a.processing = min(a.processing, cleanWorkers)
+		idle = workers - a.downloading + cleanWorkers - a.processing
+	}
+	return fmt.Sprintf("active: %d downloading, %d processing, %d idle", a.downloading, a.processing, max(idle, 0))
+}
+
+// visibleJobs returns the indices, in order, of the at most limit jobs the
+// job list shows: those in progress first, then failures, then the latest
+// finished (the ones nearest the end of the list, up to a quarter of limit),
+// then the next pending jobs, then earlier finished ones.
+func visibleJobs(jobs []TranscriptJob, limit int) []int {
+	if len(jobs) <= limit {
+		shown := make([]int, len(jobs))
+		for i := range shown {
+			shown[i] = i
+		}
+		return shown
+	}
+	var active, failed, pending, finished []int
+	for i, job := range jobs {
+		switch {
+		case job.Error != nil:
+			failed = append(failed, i)
+		case job.Status == "" || job.Status == "pending":
+			pending = append(pending, i)
+		case isTerminalStatus(job.Status):
+			finished = append(finished, i)
+		default:
+			active = append(active, i)
+		}
+	}
+	slices.Reverse(finished)
+	recent := min(len(finished), limit/4)
+	var shown []int
+	for _, group := range [][]int{active, failed, finished[:recent], pending, finished[recent:]} {
+		shown = append(shown, group[:min(len(group), limit-len(shown))]...)
+	}
+	slices.Sort(shown)
+	return shown
+}
+
+// hiddenSummary describes the jobs visibleJobs left out of shown, e.g.
+// "... 180 more jobs (150 pending, 30 finished)".
+func hiddenSummary(jobs []TranscriptJob, shown []int) string {
+	isShown := make([]bool, len(jobs))
+	for _, i := range shown {
+		isShown[i] = true
+	}
+	var pending, active, finished int
+	for i, job := range jobs {
+		switch {
+		case isShown[i]:
+		case job.Status == "" || job.Status == "pending":
+			pending++
+		case job.Error != nil || isTerminalStatus(job.Status):
+			finished++
+		default:
+			active++
+		}
+	}
+	counts := []string{fmt.Sprintf("%d pending", pending)}
+	if active > 0 {
+		counts = append(counts, fmt.Sprintf("%d in progress", active))
+	}
+	counts = append(counts, fmt.Sprintf("%d finished", finished))
+	return fmt.Sprintf("... %d more jobs (%s)", len(jobs)-len(shown), strings.Join(counts, ", "))
+}
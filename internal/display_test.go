@@ -2,15 +2,20 @@ package internal
 
 import (
 	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/lipgloss"
 )
 
 // TestNewProgressView checks if a ProgressView is initialized.
 func TestNewProgressView(t *testing.T) {
-	pv := NewProgressView()
+	pv := NewProgressView("")
 	// Basic check: ensure the Progress field is not zero/nil.
 	// Since progress.Model is a struct, it won't be nil.
 	// We can check if it can View, or if its Percent is 0 initially.
@@ -19,8 +24,21 @@ func TestNewProgressView(t *testing.T) {
 	}
 }
 
+func TestNewProgressView_Styles(t *testing.T) {
+	ascii := NewProgressView(ProgressASCII).Progress
+	ascii.Width = 20
+	if got := ascii.ViewAs(0.5); got != "########-------  50%" {
+		t.Errorf("ascii bar = %q, want plain '#' and '-' with no escape codes", got)
+	}
+	for _, style := range ProgressStyles {
+		if bar := NewProgressView(style).Progress; !strings.Contains(bar.ViewAs(1), "100%") {
+			t.Errorf("%s bar = %q, want the percentage shown", style, bar.ViewAs(1))
+		}
+	}
+}
+
 func TestProgressView_RenderCompleted(t *testing.T) {
-	pv := NewProgressView()
+	pv := NewProgressView("")
 	_ = pv.Progress.SetPercent(1.0) // Ensure it's at 100% for this view
 	got := pv.RenderCompleted()
 	if !strings.Contains(got, "✅ All done!") {
@@ -32,7 +50,7 @@ func TestProgressView_RenderCompleted(t *testing.T) {
 }
 
 func TestProgressView_RenderFailed(t *testing.T) {
-	pv := NewProgressView()
+	pv := NewProgressView("")
 	_ = pv.Progress.SetPercent(0.6) // Example progress
 	testError := errors.New("something went wrong")
 	testTitle := "Test Video"
@@ -57,7 +75,7 @@ func TestProgressView_RenderFailed(t *testing.T) {
 }
 
 func TestProgressView_RenderOverallFailure(t *testing.T) {
-	pv := NewProgressView()
+	pv := NewProgressView("")
 	_ = pv.Progress.SetPercent(1.0) // Usually at the end
 
 	jobs := []TranscriptJob{
@@ -83,7 +101,7 @@ func TestProgressView_RenderOverallFailure(t *testing.T) {
 }
 
 func TestProgressView_RenderDownloading(t *testing.T) {
-	pv := NewProgressView()
+	pv := NewProgressView("")
 	_ = pv.Progress.SetPercent(0.25) // Example progress
 	tests := []struct {
 		name            string
@@ -114,22 +132,24 @@ func TestProgressView_RenderDownloading(t *testing.T) {
 }
 
 func TestProgressView_RenderProcessing(t *testing.T) {
-	pv := NewProgressView()
+	pv := NewProgressView("")
 	// Manually set the progress for consistent testing of the text part
 	pv.Progress.SetPercent(0.75)
 
-	// Note: This test is limited because GetNewestVTTPattern and filepath.Glob are hard to mock here.
-	// We'll test the part that doesn't depend on actual file system results.
-	gotNoFiles := pv.RenderProcessing(0, 1, "./temp_raw_vtt_dir_for_test_processing")
-	expectedHeaderNoFiles := "[1/1] Processing..."
-	if !strings.Contains(gotNoFiles, expectedHeaderNoFiles) {
-		t.Errorf("RenderProcessing() with no files, header = %q, want to contain %q", gotNoFiles, expectedHeaderNoFiles)
+	gotNoTitle := pv.RenderProcessing(0, 1, "")
+	expectedHeaderNoTitle := "[1/1] Processing..."
+	if !strings.Contains(gotNoTitle, expectedHeaderNoTitle) {
+		t.Errorf("RenderProcessing() with no title, header = %q, want to contain %q", gotNoTitle, expectedHeaderNoTitle)
 	}
 	// Check if progress bar string is part of output
-	if !strings.Contains(gotNoFiles, pv.Progress.View()) {
-		t.Errorf("RenderProcessing() missing progress bar view, got %q", gotNoFiles)
+	if !strings.Contains(gotNoTitle, pv.Progress.View()) {
+		t.Errorf("RenderProcessing() missing progress bar view, got %q", gotNoTitle)
+	}
+
+	// The label is the job's own title, whatever else sits in the raw directory
+	if got := pv.RenderProcessing(2, 4, "My Talk"); !strings.Contains(got, "[3/4] Processing My Talk...") {
+		t.Errorf("RenderProcessing() = %q, want the job's title", got)
 	}
-	// A more thorough test for file-dependent part would require fs mocking or integration test
 }
 
 // Test for SetProgress and UpdateProgress are harder as they return tea.Cmd
@@ -137,7 +157,7 @@ func TestProgressView_RenderProcessing(t *testing.T) {
 // they don't panic and return a non-nil tea.Cmd where appropriate.
 
 func TestProgressView_SetProgress(t *testing.T) {
-	pv := NewProgressView()
+	pv := NewProgressView("")
 	cmd := pv.SetProgress(0.5)
 	if cmd == nil {
 		// For some tea.Cmds, returning nil is valid if there's no action.
@@ -151,7 +171,7 @@ func TestProgressView_SetProgress(t *testing.T) {
 }
 
 func TestProgressView_UpdateProgress(t *testing.T) {
-	pv := NewProgressView()
+	pv := NewProgressView("")
 	// progress.FrameMsg is an empty struct, usually sent by the runtime
 	_, cmd := pv.UpdateProgress(progress.FrameMsg{})
 	if cmd == nil {
@@ -159,3 +179,482 @@ func TestProgressView_UpdateProgress(t *testing.T) {
 	}
 	// We can't easily check the state of progress bar animation here.
 }
+
+func TestProgressView_RenderJobList_Warnings(t *testing.T) {
+	pv := NewProgressView("")
+	jobs := []TranscriptJob{
+		{URL: "http://example.com/video1", Title: "Video 1", Status: "completed", Warnings: []string{"thumbnail unavailable: no image"}},
+		{URL: "http://example.com/video2", Title: "Video 2", Status: "completed"},
+	}
+	got := pv.RenderJobList(jobs, 2, 2, 1)
+
+	if !strings.Contains(got, "Video 1): completed (Warning: thumbnail unavailable: no image)") {
+		t.Errorf("RenderJobList() missing warning for job with warnings, got %q", got)
+	}
+	if strings.Count(got, "Warning:") != 1 {
+		t.Errorf("RenderJobList() should only show warnings for jobs that have them, got %q", got)
+	}
+}
+
+func TestProgressView_RenderJobList_DownloadProgress(t *testing.T) {
+	jobs := []TranscriptJob{{URL: "http://example.com/video1", Title: "Video 1", Status: "downloading_subtitles", DownloadPct: 42.4}}
+	if got := NewProgressView("").RenderJobList(jobs, 0, 1, 1); !strings.Contains(got, "Video 1): downloading_subtitles 42%") {
+		t.Errorf("RenderJobList() = %q, want the download percentage", got)
+	}
+}
+
+func TestProgressView_ShowIDs(t *testing.T) {
+	pv := NewProgressView("")
+	pv.NoColor = true
+	pv.ShowIDs = true
+	jobs := []TranscriptJob{
+		{URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", Title: "Intro", Status: "downloading_subtitles"},
+		{URL: "https://example.com/clip", Title: "Intro", VideoID: "clip42", Status: "no_subtitles"},
+		{URL: "https://example.com/other", Title: "Other", Status: "failed", Error: errors.New("boom")},
+	}
+
+	list := pv.RenderJobList(jobs, 2, 3, 1)
+	for _, want := range []string{"[1/3] dQw4w9WgXcQ (Intro)", "[2/3] clip42 (Intro)", "[3/3] https://example.com/other (Other)"} {
+		if !strings.Contains(list, want) {
+			t.Errorf("RenderJobList() with ShowIDs = %q, want %q (the URL only without an ID)", list, want)
+		}
+	}
+	if summary := pv.RenderSummary(jobs); !strings.Contains(summary, "no captions available (1): Intro [clip42]") {
+		t.Errorf("RenderSummary() with ShowIDs = %q, want the title followed by its ID", summary)
+	}
+
+	pv.ShowIDs = false
+	if summary := pv.RenderSummary(jobs); !strings.Contains(summary, "no captions available (1): Intro\n") {
+		t.Errorf("RenderSummary() = %q, want titles alone without ShowIDs", summary)
+	}
+}
+
+func TestProgressView_SetWidth(t *testing.T) {
+	tests := []struct {
+		name          string
+		terminalWidth int
+		want          int
+	}{
+		{"typical terminal", 60, 60 - progressMargin},
+		{"narrow split pane", 8, minProgressWidth},
+		{"very wide terminal", 300, maxProgressWidth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pv := NewProgressView("")
+			pv.SetWidth(tt.terminalWidth)
+			if pv.Progress.Width != tt.want {
+				t.Errorf("SetWidth(%d) gave width %d, want %d", tt.terminalWidth, pv.Progress.Width, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusStyle(t *testing.T) {
+	tests := []struct {
+		status    string
+		wantStyle bool
+		wantColor lipgloss.TerminalColor
+	}{
+		{"completed", true, lipgloss.Color("2")},
+		{"failed", true, lipgloss.Color("1")},
+		{"skipped (exists)", true, lipgloss.Color("3")},
+		{"downloading_subtitles", false, nil},
+		{"pending", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			style, ok := statusStyle(tt.status)
+			if ok != tt.wantStyle {
+				t.Fatalf("statusStyle(%q) styled = %v, want %v", tt.status, ok, tt.wantStyle)
+			}
+			if ok && style.GetForeground() != tt.wantColor {
+				t.Errorf("statusStyle(%q) colour = %v, want %v", tt.status, style.GetForeground(), tt.wantColor)
+			}
+		})
+	}
+}
+
+func TestProgressView_RenderJobList_NoColor(t *testing.T) {
+	pv := NewProgressView("")
+	pv.NoColor = true
+	jobs := []TranscriptJob{
+		{URL: "http://example.com/video1", Status: "completed"},
+		{URL: "http://example.com/video2", Status: "failed", Error: errors.New("boom")},
+	}
+	got := pv.RenderJobList(jobs, 2, 2, 1)
+	if strings.Contains(got, "\x1b[") {
+		t.Errorf("RenderJobList() with NoColor contains ANSI escapes, got %q", got)
+	}
+}
+
+func TestColorDisabled(t *testing.T) {
+	if !ColorDisabled(true) {
+		t.Error("ColorDisabled(true) = false, want true when the flag is set")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if !ColorDisabled(false) {
+		t.Error("ColorDisabled(false) = false, want true when NO_COLOR is set")
+	}
+}
+
+func TestProgressView_RenderOverallFailure_Categories(t *testing.T) {
+	pv := NewProgressView("")
+	jobs := []TranscriptJob{
+		{Title: "Private One", Error: fmt.Errorf("failed to fetch title: %w", ErrVideoUnavailable)},
+		{Title: "Flaky One", Error: fmt.Errorf("failed to download subtitles: %w", ErrNetwork)},
+		{Title: "Flaky Two", Error: fmt.Errorf("failed to download subtitles: %w", ErrNetwork)},
+		{Title: "Fine One"},
+		{Title: "Odd One", Error: errors.New("something else")},
+	}
+	got := pv.RenderOverallFailure(jobs)
+
+	for _, want := range []string{
+		"video unavailable (1): Private One",
+		"network (2): Flaky One, Flaky Two",
+		"other (1): Odd One",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RenderOverallFailure() missing %q, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, "timeout (") {
+		t.Errorf("RenderOverallFailure() should omit empty categories, got %q", got)
+	}
+	if strings.Index(got, "video unavailable (") > strings.Index(got, "network (") {
+		t.Errorf("RenderOverallFailure() categories out of order, got %q", got)
+	}
+}
+
+func TestProgressView_RenderDebugStats(t *testing.T) {
+	pv := NewProgressView("")
+	jobs := []TranscriptJob{
+		{Title: "Video 1", Stats: &CleanStats{RawLines: 120, Timestamps: 30, Duplicates: 12, FinalLines: 40}},
+		{Title: "Video 2", Error: errors.New("no subtitles")},
+	}
+	got := pv.RenderDebugStats(jobs)
+	if !strings.Contains(got, "Video 1: 120 raw lines -> 40 final") || !strings.Contains(got, "30 timestamps") || !strings.Contains(got, "collapsed 12 duplicates") {
+		t.Errorf("RenderDebugStats() missing stats for cleaned job, got %q", got)
+	}
+	if strings.Contains(got, "Video 2") {
+		t.Errorf("RenderDebugStats() should skip jobs that were never cleaned, got %q", got)
+	}
+}
+
+func TestProgressView_RenderDedupeReport(t *testing.T) {
+	pv := NewProgressView("")
+	jobs := []TranscriptJob{
+		{Title: "Video 1", Stats: &CleanStats{RawLines: 120, Blank: 30, Numbers: 20, Timestamps: 30, FinalLines: 24}},
+		{Title: "Video 2", Stats: &CleanStats{RawLines: 80, Blank: 20, Timestamps: 20, HTMLOnly: 4, Stripped: 6, FinalLines: 26}},
+		{Title: "Video 3", Error: errors.New("no subtitles")},
+	}
+	got := pv.RenderDedupeReport(jobs)
+	for _, want := range []string{
+		"Video 1: 120 lines -> 40 after artifact removal -> 24 after dedupe (80% fewer)",
+		"Video 2: 80 lines -> 30 after artifact removal -> 26 after dedupe (68% fewer)",
+		"total: 200 lines -> 70 after artifact removal -> 50 after dedupe (75% fewer)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RenderDedupeReport() missing %q, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, "Video 3") {
+		t.Errorf("RenderDedupeReport() should skip jobs that were never cleaned, got %q", got)
+	}
+
+	if got := pv.RenderDedupeReport(jobs[2:]); !strings.Contains(got, "no transcripts were cleaned") {
+		t.Errorf("RenderDedupeReport() without cleaned jobs = %q, want it said", got)
+	}
+}
+
+func TestProgressView_RenderTimings(t *testing.T) {
+	pv := NewProgressView("")
+	jobs := []TranscriptJob{
+		{Title: "Video 1", Timings: Timings{Title: 800 * time.Millisecond, Download: 2 * time.Second, Processing: 15 * time.Millisecond}},
+		{Title: "Video 2", Timings: Timings{Title: 200 * time.Millisecond, Download: time.Second}},
+		{Title: "Archived", Status: "skipped (archived)"},
+	}
+	got := pv.RenderTimings(jobs)
+	for _, want := range []string{
+		"Video 1: title 800ms, download 2s, processing 15ms",
+		"Video 2: title 200ms, download 1s, processing 0s",
+		"total: title 1s, download 3s, processing 15ms",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RenderTimings() missing %q, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, "Archived") {
+		t.Errorf("RenderTimings() should skip jobs that did no work, got %q", got)
+	}
+}
+
+func TestProgressView_RenderSummary(t *testing.T) {
+	pv := NewProgressView("")
+	jobs := []TranscriptJob{
+		{Title: "New", Status: "completed"},
+		{Title: "Old 1", Status: "skipped (exists)"},
+		{Title: "Old 2", Status: "skipped (exists)"},
+		{Title: "Broken", Status: "failed", Error: errors.New("boom")},
+	}
+	if got, want := pv.RenderSummary(jobs), "1 processed, 2 skipped, 1 failed\n"; got != want {
+		t.Errorf("RenderSummary() = %q, want %q", got, want)
+	}
+
+	jobs = append(jobs, TranscriptJob{Title: "Silent", Status: "no_subtitles"}, TranscriptJob{Status: "skipped (duplicate)"}, TranscriptJob{Status: "filtered"}, TranscriptJob{Status: "skipped (archived)"}, TranscriptJob{Title: "Gone", Status: "unavailable"}, TranscriptJob{Title: "abc", Status: "completed", TitleFellBack: true})
+	want := "2 processed, 7 skipped, 1 failed\nskipped: no captions available (1): Silent\nskipped: private or removed (1): Gone\nskipped: duplicate URLs (1)\nskipped: outside the duration or date range (1)\nskipped: already in the archive (1)\ntitle unavailable, used ID for 1 videos\n"
+	if got := pv.RenderSummary(jobs); got != want {
+		t.Errorf("RenderSummary() with a captionless video = %q, want %q", got, want)
+	}
+	refreshed := []TranscriptJob{{Status: "completed"}, {Status: "completed", Refreshed: true}}
+	if got, want := pv.RenderSummary(refreshed), "2 processed (1 refreshed), 0 skipped, 0 failed\n"; got != want {
+		t.Errorf("RenderSummary() with a refreshed transcript = %q, want %q", got, want)
+	}
+	if failure := pv.RenderOverallFailure(jobs); strings.Contains(failure, "Silent") {
+		t.Errorf("RenderOverallFailure() = %q, should not list videos without captions", failure)
+	}
+}
+
+func TestExitSummary(t *testing.T) {
+	jobs := []TranscriptJob{
+		{Status: "completed"},
+		{Status: "completed"},
+		{Status: "skipped (exists)"},
+		{Status: "filtered"},
+		{Status: "unavailable"},
+		{Status: "no_subtitles"},
+		{Status: "empty"},
+		{Status: "oversized"},
+		{Status: "failed", Error: errors.New("boom")},
+		{Status: "downloading_subtitles"}, // Interrupted
+	}
+	want := "yt-tx: total=10 ok=2 skipped=4 failed=2 no_subs=1 empty=1"
+	if got := ExitSummary(jobs); got != want {
+		t.Errorf("ExitSummary() = %q, want %q", got, want)
+	}
+	if got, want := ExitSummary(nil), "yt-tx: total=0 ok=0 skipped=0 failed=0 no_subs=0 empty=0"; got != want {
+		t.Errorf("ExitSummary(nil) = %q, want %q", got, want)
+	}
+}
+
+func TestProgressView_RenderJobList_Glyphs(t *testing.T) {
+	pv := NewProgressView("")
+	pv.NoColor = true
+	pv.Spinner = "@"
+	jobs := []TranscriptJob{
+		{URL: "u1", Status: "pending"},
+		{URL: "u2", Status: "downloading_subtitles"},
+		{URL: "u3", Status: "completed"},
+		{URL: "u4", Status: "failed", Error: errors.New("boom")},
+		{URL: "u5", Status: "skipped (exists)"},
+	}
+	got := pv.RenderJobList(jobs, 3, 5, 2)
+	for _, want := range []string{
+		pendingGlyph + " [1/5] u1",
+		"@ [2/5] u2",
+		completedGlyph + " [3/5] u3",
+		failedGlyph + " [4/5] u4",
+		skippedGlyph + " [5/5] u5",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RenderJobList() missing %q, got %q", want, got)
+		}
+	}
+}
+
+func TestProgressView_RenderOutputFiles(t *testing.T) {
+	jobs := []TranscriptJob{
+		{URL: "u1", Title: "New", Status: "completed", ProcessedFile: "cleaned/New.txt"},
+		{URL: "u2", Status: "skipped (exists)", ProcessedFile: "cleaned/Old.txt"},
+		{URL: "u3", Title: "Broken", Status: "failed", Error: errors.New("boom")},
+		{URL: "u4", Title: "Multi", Status: "completed", ProcessedFile: "cleaned/Multi.de.txt", ProcessedFiles: []string{"cleaned/Multi.de.txt", "cleaned/Multi.en.txt"}},
+	}
+	want := "New -> cleaned/New.txt\nu2 -> cleaned/Old.txt\nMulti -> cleaned/Multi.de.txt\nMulti -> cleaned/Multi.en.txt\n"
+	if got := NewProgressView("").RenderOutputFiles(jobs); got != want {
+		t.Errorf("RenderOutputFiles() = %q, want %q", got, want)
+	}
+}
+
+func TestProgressView_RenderOutputTree(t *testing.T) {
+	jobs := []TranscriptJob{
+		{Status: "completed", ProcessedFile: "cleaned/Zed/b.txt"},
+		{Status: "skipped (exists)", ProcessedFile: "cleaned/Alpha/x.txt"},
+		{Status: "failed", Error: errors.New("boom")},
+		{Status: "completed", ProcessedFile: "cleaned/Zed/a.txt"},
+		{Status: "completed", ProcessedFile: "cleaned/loose.txt"},
+		{Status: "completed", ProcessedFile: "cleaned/Alpha/m.de.txt", ProcessedFiles: []string{"cleaned/Alpha/m.de.txt", "cleaned/Alpha/m.en.txt"}},
+	}
+	want := "cleaned/\n" +
+		"├── loose.txt\n" +
+		"├── Alpha/\n" +
+		"│   ├── m.de.txt\n" +
+		"│   ├── m.en.txt\n" +
+		"│   └── x.txt\n" +
+		"└── Zed/\n" +
+		"    ├── a.txt\n" +
+		"    └── b.txt\n"
+	if got := NewProgressView("").RenderOutputTree(jobs, "cleaned"); got != want {
+		t.Errorf("RenderOutputTree() = %q, want %q", got, want)
+	}
+	if got := NewProgressView("").RenderOutputTree(jobs[2:3], "cleaned"); got != "" {
+		t.Errorf("RenderOutputTree() without output files = %q, want empty", got)
+	}
+}
+
+func TestProgressView_RenderThroughput(t *testing.T) {
+	jobs := []TranscriptJob{
+		{Status: "completed", RawBytes: 3 << 20, CleanedBytes: 512 << 10},
+		{Status: "completed", RawBytes: 1 << 20, CleanedBytes: 512 << 10},
+		{Status: "failed", Error: errors.New("boom")},
+	}
+	want := "read 4.0 MB of captions, wrote 1.0 MB of transcripts in 2s (2.00 MB/s)\n"
+	if got := NewProgressView("").RenderThroughput(jobs, 2*time.Second); got != want {
+		t.Errorf("RenderThroughput() = %q, want %q", got, want)
+	}
+	if got := NewProgressView("").RenderThroughput(jobs[2:], 2*time.Second); got != "" {
+		t.Errorf("RenderThroughput() without transcripts = %q, want empty", got)
+	}
+}
+
+func TestRenderPanics(t *testing.T) {
+	v := NewProgressView("")
+	if got := v.RenderPanics([]TranscriptJob{{URL: "https://youtu.be/aaa", Status: "completed"}}); got != "" {
+		t.Errorf("RenderPanics(no panics) = %q, want \"\"", got)
+	}
+	job := failJob(TranscriptJob{URL: "https://youtu.be/bbb"}, errors.New("panic: boom"))
+	job.PanicStack = "goroutine 7 [running]:\n"
+	if got := v.RenderPanics([]TranscriptJob{job}); !strings.Contains(got, "https://youtu.be/bbb: panic: boom\ngoroutine 7") {
+		t.Errorf("RenderPanics() = %q, want the job, its error and its stack", got)
+	}
+}
+
+func TestOutputPaths(t *testing.T) {
+	jobs := []TranscriptJob{
+		{Status: "completed", ProcessedFile: "cleaned/b.txt"},
+		failJob(TranscriptJob{ProcessedFile: "cleaned/failed.txt"}, errors.New("boom")),
+		{Status: "skipped (exists)", ProcessedFile: "/abs/a.txt"},
+		{Status: "completed", ProcessedFile: "cleaned/c.en.txt", ProcessedFiles: []string{"cleaned/c.en.txt", "cleaned/c.de.txt"}},
+		{Status: "no_subtitles"},
+		{Status: "completed", ProcessedFile: "cleaned/b.txt"}, // The same video twice
+	}
+	abs := func(path string) string {
+		p, _ := filepath.Abs(path)
+		return p
+	}
+	want := []string{abs("cleaned/b.txt"), "/abs/a.txt", abs("cleaned/c.en.txt"), abs("cleaned/c.de.txt")}
+	if got := OutputPaths(jobs); !slices.Equal(got, want) {
+		t.Errorf("OutputPaths() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderSummary_DetectedLang(t *testing.T) {
+	pv := NewProgressView("")
+	pv.NoColor = true
+	jobs := []TranscriptJob{
+		{Title: "Same", Status: "completed", Language: "en-US", DetectedLang: "en"},
+		{Title: "Translated", Status: "completed", Language: "en", DetectedLang: "ja"},
+		{Title: "Unknown", Status: "completed", Language: "en"},
+		{Title: "Multi", Status: "completed", Language: "de,en", DetectedLang: "de:de,en:fr"},
+	}
+	want := "detected language differs from captions (2): Translated (en → ja), Multi (en → fr)\n"
+	if summary := pv.RenderSummary(jobs); !strings.Contains(summary, want) {
+		t.Errorf("RenderSummary() = %q, want %q", summary, want)
+	}
+	if summary := pv.RenderSummary(jobs[:1]); strings.Contains(summary, "detected language") {
+		t.Errorf("RenderSummary() = %q, want no language line when every detection matches", summary)
+	}
+}
+
+func TestRenderSummary_Aborted(t *testing.T) {
+	pv := NewProgressView("")
+	pv.NoColor = true
+	jobs := []TranscriptJob{
+		{Title: "Done", Status: "completed"},
+		{Title: "Broken", Status: "failed", Error: errors.New("boom")},
+		failJob(TranscriptJob{URL: "https://youtu.be/a"}, ErrAborted),
+		failJob(TranscriptJob{URL: "https://youtu.be/b"}, ErrAborted),
+	}
+	summary := pv.RenderSummary(jobs)
+	for _, want := range []string{"1 processed, 0 skipped, 1 failed\n", "aborted early at the first failure: 2 jobs not finished\n"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("RenderSummary() = %q, want %q", summary, want)
+		}
+	}
+	if failures := pv.RenderOverallFailure(jobs); !strings.Contains(failures, "Some jobs failed: Broken\n") {
+		t.Errorf("RenderOverallFailure() = %q, want only the job that actually failed listed", failures)
+	}
+}
+
+func TestRenderSummary_Oversized(t *testing.T) {
+	pv := NewProgressView("")
+	pv.NoColor = true
+	jobs := []TranscriptJob{
+		{Title: "Done", Status: "completed"},
+		{Title: "Marathon Stream", Status: "oversized"},
+	}
+	summary := pv.RenderSummary(jobs)
+	for _, want := range []string{"1 processed, 1 skipped, 0 failed\n", "skipped: captions over the size limit (1): Marathon Stream\n"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("RenderSummary() = %q, want %q", summary, want)
+		}
+	}
+}
+
+func TestRenderJobList_Capped(t *testing.T) {
+	pv := NewProgressView("")
+	pv.NoColor = true
+	jobs := make([]TranscriptJob, 500)
+	for i := range jobs {
+		jobs[i] = TranscriptJob{URL: fmt.Sprintf("https://youtu.be/vid%d", i), Status: "pending"}
+	}
+	for i := range 200 {
+		jobs[i].Status = "completed"
+	}
+	jobs[50] = failJob(jobs[50], errors.New("boom"))
+	jobs[200].Status = "downloading_subtitles"
+	jobs[201].Status = "processing_transcript"
+
+	list := pv.RenderJobList(jobs, 200, len(jobs), 2)
+	if lines := strings.Count(list, "\n"); lines > defaultJobLines+jobListChrome {
+		t.Errorf("RenderJobList() of 500 jobs = %d lines, want at most %d", lines, defaultJobLines+jobListChrome)
+	}
+	for _, want := range []string{"[201/500]", "[202/500]", "[51/500] https://youtu.be/vid50", "[203/500]", "[200/500]", "... 480 more jobs ("} {
+		if !strings.Contains(list, want) {
+			t.Errorf("RenderJobList() missing %q (active, failed, next pending and latest finished jobs come first):\n%s", want, list)
+		}
+	}
+	if strings.Contains(list, "[1/500]") || strings.Contains(list, "[500/500]") {
+		t.Errorf("RenderJobList() shows the oldest finished or last pending job:\n%s", list)
+	}
+
+	pv.SetHeight(12)
+	if lines := strings.Count(pv.RenderJobList(jobs, 200, len(jobs), 2), "\n"); lines > 12 {
+		t.Errorf("RenderJobList() after SetHeight(12) = %d lines, want it to fit", lines)
+	}
+}
+
+func TestActivityLine(t *testing.T) {
+	tests := []struct {
+		activity              workerActivity
+		workers, cleanWorkers int
+		want                  string
+	}{
+		{workerActivity{downloading: 3, processing: 1}, 4, 0, "active: 3 downloading, 1 processing, 0 idle"},
+		{workerActivity{downloading: 1}, 4, 0, "active: 1 downloading, 0 processing, 3 idle"},
+		// Downloaded jobs queued for one of the 2 clean workers
+		{workerActivity{downloading: 5, processing: 2}, 4, 2, "active: 4 downloading, 2 processing, 0 idle"},
+		{workerActivity{processing: 1}, 4, 2, "active: 0 downloading, 1 processing, 5 idle"},
+	}
+	for _, tt := range tests {
+		if got := activityLine(tt.activity, tt.workers, tt.cleanWorkers); got != tt.want {
+			t.Errorf("activityLine(%+v, %d, %d) = %q, want %q", tt.activity, tt.workers, tt.cleanWorkers, got, tt.want)
+		}
+	}
+}
+
+func TestVisibleJobs_Short(t *testing.T) {
+	jobs := make([]TranscriptJob, 3)
+	if got := visibleJobs(jobs, 20); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Errorf("visibleJobs() of a short list = %v, want every job", got)
+	}
+}
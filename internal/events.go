@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Event names emitted as a job moves through the pipeline.
+const (
+	EventJobStart         = "job_start"
+	EventDownloadStart    = "download_start"
+	EventDownloadProgress = "download_progress"
+	EventProcessingStart  = "processing_start"
+	EventJobDone          = "job_done"
+)
+
+// Event describes a single job state transition.
+type Event struct {
+	Event    string  `json:"event"`
+	Index    int     `json:"index"` // Position of the job in the input
+	URL      string  `json:"url"`
+	Title    string  `json:"title,omitempty"`
+	VideoID  string  `json:"video_id,omitempty"` // Once resolved by the worker
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	File     string  `json:"file,omitempty"`          // Transcript written (or already present), on job_done
+	Attempts int     `json:"attempts,omitempty"`      // Times the job was run, on job_done
+	Category string  `json:"category,omitempty"`      // Failure category (see ClassifyError), on a failed job_done
+	Detected string  `json:"detected_lang,omitempty"` // Language detected in the transcript (see TranscriptJob.DetectedLang), on job_done
+	Progress float64 `json:"progress,omitempty"`      // Subtitle download percentage, on download_progress
+}
+
+// EventEmitter receives job state transitions from the workers. Emit is
+// called from worker goroutines, so implementations must be safe for
+// concurrent use.
+type EventEmitter interface {
+	Emit(Event)
+}
+
+// newEvent builds the event for a job's current status.
+func newEvent(index int, job TranscriptJob) Event {
+	ev := Event{Index: index, URL: job.URL, Title: job.Title, VideoID: job.VideoID, Status: job.Status, Attempts: job.Attempts, Category: job.Category}
+	switch {
+	case job.Status == "fetching_title":
+		ev.Event = EventJobStart
+	case job.Status == "downloading_subtitles" && job.DownloadPct > 0:
+		ev.Event = EventDownloadProgress
+		ev.Progress = job.DownloadPct
+	case job.Status == "downloading_subtitles":
+		ev.Event = EventDownloadStart
+	case job.Status == "processing_transcript":
+		ev.Event = EventProcessingStart
+	default:
+		ev.Event = EventJobDone
+	}
+	if job.Error != nil {
+		ev.Error = job.Error.Error()
+	} else {
+		ev.File = job.ProcessedFile
+		ev.Detected = job.DetectedLang
+	}
+	return ev
+}
+
+// isTerminalStatus reports whether a job with this status has finished.
+func isTerminalStatus(status string) bool {
+	return status == "completed" || status == "failed" || status == "no_subtitles" || status == "filtered" || status == "unavailable" || status == "empty" || status == "oversized" || strings.HasPrefix(status, "skipped")
+}
+
+// JSONEmitter writes each event as one line of JSON.
+type JSONEmitter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONEmitter returns an emitter writing JSON lines to w.
+func NewJSONEmitter(w io.Writer) *JSONEmitter {
+	return &JSONEmitter{enc: json.NewEncoder(w)}
+}
+
+// Emit writes ev as a single JSON line.
+func (e *JSONEmitter) Emit(ev Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_ = e.enc.Encode(ev) // Progress output is best-effort
+}
+
+// ProgramEmitter forwards events to a running bubbletea program, where
+// WorkflowState.Update applies them to the job list.
+type ProgramEmitter struct {
+	Program *tea.Program
+}
+
+// Emit sends ev to the program as a message.
+func (e *ProgramEmitter) Emit(ev Event) {
+	if e.Program != nil {
+		e.Program.Send(ev)
+	}
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewEvent(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"fetching_title", EventJobStart},
+		{"downloading_subtitles", EventDownloadStart},
+		{"processing_transcript", EventProcessingStart},
+		{"completed", EventJobDone},
+		{"skipped (exists)", EventJobDone},
+	}
+	for _, tt := range tests {
+		if got := newEvent(0, TranscriptJob{Status: tt.status}); got.Event != tt.want {
+			t.Errorf("newEvent(%q).Event = %q, want %q", tt.status, got.Event, tt.want)
+		}
+	}
+
+	progress := newEvent(0, TranscriptJob{Status: "downloading_subtitles", DownloadPct: 42.5})
+	if progress.Event != EventDownloadProgress || progress.Progress != 42.5 {
+		t.Errorf("newEvent(downloading at 42.5%%) = %q, %v, want %q with the percentage", progress.Event, progress.Progress, EventDownloadProgress)
+	}
+
+	done := newEvent(0, TranscriptJob{Status: "completed", ProcessedFile: "cleaned/Title.txt", VideoID: "abc"})
+	if done.File != "cleaned/Title.txt" || done.VideoID != "abc" {
+		t.Errorf("newEvent(completed) File, VideoID = %q, %q, want the transcript path and video ID", done.File, done.VideoID)
+	}
+}
+
+func TestJSONEmitter(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewJSONEmitter(&buf)
+	e.Emit(newEvent(1, TranscriptJob{URL: "https://youtu.be/abc", Status: "downloading_subtitles"}))
+	e.Emit(newEvent(1, TranscriptJob{URL: "https://youtu.be/abc", Status: "failed", Error: errors.New("boom"), Attempts: 3, Category: CategoryNetwork}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		`{"event":"download_start","index":1,"url":"https://youtu.be/abc","status":"downloading_subtitles"}`,
+		`{"event":"job_done","index":1,"url":"https://youtu.be/abc","status":"failed","error":"boom","attempts":3,"category":"network"}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("JSONEmitter wrote %d lines, want %d: %q", len(lines), len(want), buf.String())
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %s, want %s", i, lines[i], want[i])
+		}
+	}
+}
@@ -1,14 +1,35 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// waitForTitleCmd waits for the next prefetched title; once titlesChan is
+// closed it returns nil, so no more are waited for.
+func waitForTitleCmd(titlesChan chan TitleFetchResult) tea.Cmd {
+	return func() tea.Msg {
+		if title, ok := <-titlesChan; ok {
+			return title
+		}
+		return nil
+	}
+}
+
 // Helper function to wait for a job result from the results channel
 func waitForJobResultCmd(resultsChan chan JobProcessingResult) tea.Cmd {
 	return func() tea.Msg {
@@ -16,91 +37,1136 @@ func waitForJobResultCmd(resultsChan chan JobProcessingResult) tea.Cmd {
 	}
 }
 
+// startWorkers launches n workers for the jobs sent on jobQueue, each
+// sending its results to resultsChan, and adds them all to wg. With
+// opts.CleanWorkers set, the workers only download and a separate pool of
+// that many clean workers cleans what they downloaded, so CPU-bound cleaning
+// of large files never holds up the next download; the clean queue is closed
+// once every download worker has exited.
+func startWorkers(n int, jobs []TranscriptJob, jobQueue chan int, resultsChan chan JobProcessingResult, done <-chan struct{}, opts Options, limiter *RateLimiter, hosts *HostLimiter, wg *sync.WaitGroup) {
+	var cleanQueue chan cleanTask
+	downloads := &sync.WaitGroup{}
+	downloads.Add(n) // Before anything waits on it, so the clean queue can't close early
+	if opts.CleanWorkers > 0 {
+		cleanQueue = make(chan cleanTask, len(jobs))
+		wg.Add(opts.CleanWorkers)
+		for i := 0; i < opts.CleanWorkers; i++ {
+			go runCleanWorker(cleanQueue, resultsChan, done, opts, wg)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			downloads.Wait()
+			close(cleanQueue)
+		}()
+	}
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer downloads.Done()
+			runWorker(i, jobs, jobQueue, resultsChan, cleanQueue, done, opts, limiter, hosts, wg)
+		}()
+	}
+}
+
+// cleanTask is a downloaded job waiting for a clean worker.
+type cleanTask struct {
+	index    int
+	job      TranscriptJob
+	finish   finishFunc
+	onStatus func(TranscriptJob)
+}
+
 // runWorker is the function executed by each worker goroutine.
 // It processes jobs from the jobQueue and sends results to resultsChan.
-func runWorker(id int, jobs []TranscriptJob, jobQueue chan int, resultsChan chan JobProcessingResult, tempDir, cleanedDir string, wg *sync.WaitGroup) {
+// With a cleanQueue, a job's cleaning stage is handed to the clean workers
+// (which send its result) instead of being run here.
+// Closing done makes the worker stop picking up new jobs and abandon any
+// pending send, so no worker is left blocked after the UI has quit.
+// jobs must be a snapshot the worker may read freely; it never writes to it.
+func runWorker(id int, jobs []TranscriptJob, jobQueue chan int, resultsChan chan JobProcessingResult, cleanQueue chan<- cleanTask, done <-chan struct{}, opts Options, limiter *RateLimiter, hosts *HostLimiter, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for jobIndex := range jobQueue {
-		job := jobs[jobIndex] // Get a copy of the job to work on
-		job.Status = "fetching_title"
-		// Send an initial update that this job is starting (optional, but good for UI)
-		// resultsChan <- JobProcessingResult{OriginalJobIndex: jobIndex, ProcessedJob: job}
+		select {
+		case <-done:
+			return
+		default:
+		}
 
-		// 1. Fetch Title
-		title, err := FetchTitle(job.URL)
-		if err != nil {
-			job.Error = fmt.Errorf("failed to fetch title: %w", err)
-			job.Status = "failed"
-			resultsChan <- JobProcessingResult{OriginalJobIndex: jobIndex, ProcessedJob: job, Err: job.Error}
-			continue
+		onStatus := func(j TranscriptJob) {
+			if opts.Events != nil {
+				opts.Events.Emit(newEvent(jobIndex, j))
+			}
 		}
-		job.Title = title
-
-		// 2. Extract Video ID (needed for VTT filename)
-		videoID, idErr := ExtractVideoID(job.URL)
-		if idErr != nil {
-			// If title was empty and ID extraction fails, this is a bigger issue.
-			// If title is present, we might proceed but VTT download might fail or use a different ID.
-			// For now, let's consider ID extraction failure critical for finding the VTT.
-			job.Error = fmt.Errorf("failed to extract video ID: %w", idErr)
-			job.Status = "failed"
-			resultsChan <- JobProcessingResult{OriginalJobIndex: jobIndex, ProcessedJob: job, Err: job.Error}
+		release := hosts.Acquire(jobs[jobIndex].URL)
+		job, finish := startJobSafely(jobs[jobIndex], opts, limiter, onStatus, done) // Work on a copy of the job
+		release()
+		if finish != nil && cleanQueue != nil {
+			cleanQueue <- cleanTask{index: jobIndex, job: job, finish: finish, onStatus: onStatus} // Buffered for every job
 			continue
 		}
-		// If title was empty from FetchTitle, use videoID as a fallback title for display/logging
-		if job.Title == "" {
-			job.Title = videoID
+		if finish != nil {
+			job = finishSafely(finish, job)
+		}
+		if !sendResult(jobIndex, job, opts, onStatus, resultsChan, done) {
+			return
 		}
+	}
+}
 
-		// Check if cleaned file already exists
-		expectedCleanedPath, pathErr := GetCleanedFilePathByTitle(job.Title, cleanedDir)
-		if pathErr != nil {
-			job.Error = fmt.Errorf("failed to determine cleaned file path: %w", pathErr)
-			job.Status = "failed"
-			resultsChan <- JobProcessingResult{OriginalJobIndex: jobIndex, ProcessedJob: job, Err: job.Error}
-			continue
+// runCleanWorker runs the cleaning stage of each job on cleanQueue and sends
+// its result, until the queue is closed or done is.
+func runCleanWorker(cleanQueue <-chan cleanTask, resultsChan chan JobProcessingResult, done <-chan struct{}, opts Options, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for task := range cleanQueue {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		job := finishSafely(task.finish, task.job)
+		if !sendResult(task.index, job, opts, task.onStatus, resultsChan, done) {
+			return
 		}
+	}
+}
+
+// startJobSafely is startJobWithRetries for a worker: a panic, e.g. on
+// malformed input, fails just that job (see recoverJob) instead of taking
+// down the whole batch.
+func startJobSafely(job TranscriptJob, opts Options, limiter *RateLimiter, onStatus func(TranscriptJob), done <-chan struct{}) (result TranscriptJob, finish finishFunc) {
+	defer recoverJob(&result, job)
+	return startJobWithRetries(job, opts, limiter, onStatus, done)
+}
+
+// finishSafely runs a job's cleaning stage for a worker, a panic failing
+// just that job like startJobSafely.
+func finishSafely(finish finishFunc, job TranscriptJob) (result TranscriptJob) {
+	defer recoverJob(&result, job)
+	return finish(job)
+}
+
+// recoverJob, deferred, recovers from a panic in a stage run on job and sets
+// *result to job failed with the panic's message, keeping the stack for the
+// verbose summary.
+func recoverJob(result *TranscriptJob, job TranscriptJob) {
+	if r := recover(); r != nil {
+		*result = failJob(job, fmt.Errorf("panic: %v", r))
+		result.PanicStack = string(debug.Stack())
+	}
+}
+
+// sendResult records a finished job in the archive, reports its final
+// status and sends it to resultsChan. It returns false if done was closed
+// first, leaving the result unsent.
+func sendResult(jobIndex int, job TranscriptJob, opts Options, onStatus func(TranscriptJob), resultsChan chan JobProcessingResult, done <-chan struct{}) bool {
+	archiveJob(&job, opts)
+	onStatus(job)
+	select {
+	case resultsChan <- JobProcessingResult{OriginalJobIndex: jobIndex, ProcessedJob: job, Err: job.Error}:
+		return true
+	case <-done:
+		return false
+	}
+}
 
-		if _, statErr := os.Stat(expectedCleanedPath); statErr == nil {
+// retryBackoff is how long a worker waits before retrying a job, multiplied
+// by the number of attempts so far.
+var retryBackoff = 2 * time.Second
+
+// startJobWithRetries runs job's download stage (see startJob), running it
+// again up to opts.Retries times while it fails with a transient error
+// (network or timeout), and records the number of attempts and the category
+// of a final failure on the job. Each attempt starts from the original job;
+// closing done stops further retries. Cleaning never fails transiently, so
+// the cleaning stage is left to the caller, to run once.
+func startJobWithRetries(job TranscriptJob, opts Options, limiter *RateLimiter, onStatus func(TranscriptJob), done <-chan struct{}) (TranscriptJob, finishFunc) {
+	for attempt := 1; ; attempt++ {
+		result, finish := startJob(job, opts, limiter, onStatus)
+		result.Attempts = attempt
+		if result.Error != nil {
+			result.Category = ClassifyError(result.Error)
+		}
+		if attempt > opts.Retries || !isTransient(result.Error) {
+			return result, finish
+		}
+		select {
+		case <-done:
+			return result, finish
+		case <-time.After(time.Duration(attempt) * retryBackoff):
+		}
+	}
+}
+
+// isTransient reports whether err may go away when the job is retried.
+func isTransient(err error) bool {
+	return errors.Is(err, ErrNetwork) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// finishFunc is the cleaning stage of a job whose subtitles are downloaded:
+// it writes the transcript (and sidecars) and returns the finished job.
+type finishFunc func(job TranscriptJob) TranscriptJob
+
+// startJob is the download stage of a job: title fetch, the checks that can
+// skip the job, and the subtitle download. Every yt-dlp invocation first
+// waits on the shared limiter, and onStatus (if non-nil) sees each
+// intermediate status as the job enters it. A job that ends there is
+// returned with a nil finishFunc; otherwise the finishFunc completes it, on
+// this goroutine or on a clean worker's, and removes the raw download.
+func startJob(job TranscriptJob, opts Options, limiter *RateLimiter, onStatus func(TranscriptJob)) (TranscriptJob, finishFunc) {
+	setStatus := func(status string) {
+		job.Status = status
+		if onStatus != nil {
+			onStatus(job)
+		}
+	}
+	// An archived video is skipped before any yt-dlp call when its id is in the URL
+	if videoID, err := ExtractVideoID(job.URL); err == nil && opts.Archive.Contains(videoID) {
+		return archivedJob(job, videoID), nil
+	}
+	if opts.Reclean {
+		if job, finish, ok := startFromKeptRaw(job, opts, limiter, onStatus); ok {
+			return job, finish
+		}
+	}
+	setStatus("fetching_title")
+	phaseStart := time.Now()
+	lap := func() time.Duration {
+		now := time.Now()
+		elapsed := now.Sub(phaseStart)
+		phaseStart = now
+		return elapsed
+	}
+	job.Timings = Timings{}
+
+	// A video yt-dlp can't access is pruned before anything else is fetched
+	if opts.Probe {
+		limiter.Wait()
+		if _, err := ProbeAvailability(job.URL); errors.Is(err, ErrVideoUnavailable) {
+			job.Status = "unavailable"
+			job.Title = job.URL
+			return job, nil
+		}
+	}
+
+	// 1. Fetch Title (metadata carries the title too, so it replaces the title
+	// fetch); a title prefetched by prefetchTitles isn't fetched again
+	if opts.FetchesMetadata() {
+		limiter.Wait()
+		meta, err := FetchMetadata(job.URL)
+		if err != nil {
+			return failJob(job, fmt.Errorf("failed to fetch metadata: %w", err)), nil
+		}
+		job.Metadata = &meta
+		job.Title = meta.Title
+	} else if opts.PrefetchTitles == 0 || job.Title == "" {
+		limiter.Wait()
+		title, err := FetchTitle(job.URL)
+		if err != nil && !errors.Is(err, ErrEmptyTitle) {
+			return failJob(job, fmt.Errorf("failed to fetch title: %w", err)), nil
+		}
+		job.Title = title // Empty falls back to the video ID below
+	}
+
+	// Duration and date filters need metadata; without it they don't apply
+	if job.Metadata != nil && (!inDurationRange(job.Metadata.Duration, opts) || !uploadedSince(job.Metadata.UploadDate, opts)) {
+		job.Status = "filtered"
+		return job, nil
+	}
+
+	// 2. Extract Video ID (needed for VTT filename)
+	videoID, idErr := resolveVideoID(job, opts, limiter)
+	if idErr != nil {
+		// If title was empty and ID extraction fails, this is a bigger issue.
+		// If title is present, we might proceed but VTT download might fail or use a different ID.
+		// For now, let's consider ID extraction failure critical for finding the VTT.
+		return failJob(job, fmt.Errorf("failed to extract video ID: %w", idErr)), nil
+	}
+	// If title was empty from FetchTitle, use videoID as a fallback title for
+	// display/logging (and the filename), flagged so the summary can explain it
+	if job.Title == "" {
+		job.Title = videoID
+		job.TitleFellBack = true
+		job.Warnings = append(job.Warnings, "title unavailable, used the video ID")
+	}
+	if opts.Archive.Contains(videoID) {
+		return archivedJob(job, videoID), nil
+	}
+	job.VideoID = videoID
+
+	// Resolve where this job's output goes (explicit -o file, or per-channel subdirectory if requested)
+	expectedCleanedPath, pathErr := resolveCleanedPath(&job, opts, limiter)
+	if pathErr != nil {
+		return failJob(job, pathErr), nil
+	}
+
+	// Check if cleaned file already exists; an explicit output file is always
+	// (over)written, and with AllLangs each language is checked after download
+	if opts.OutputFile == "" && !opts.AllLangs {
+		skip, stale, err := checkExisting(expectedCleanedPath, opts)
+		if err != nil {
+			return failJob(job, err), nil
+		}
+		if skip {
 			// File exists, skip processing
 			job.Status = "skipped (exists)"
 			job.ProcessedFile = expectedCleanedPath
 			job.Error = nil // Ensure no error for skipped jobs
-			resultsChan <- JobProcessingResult{OriginalJobIndex: jobIndex, ProcessedJob: job, Err: nil}
-			continue // Move to the next job
-		} else if !os.IsNotExist(statErr) {
-			// os.Stat failed for a reason other than file not existing (e.g., permissions)
-			job.Error = fmt.Errorf("error checking existing cleaned file %s: %w", expectedCleanedPath, statErr)
-			job.Status = "failed"
-			resultsChan <- JobProcessingResult{OriginalJobIndex: jobIndex, ProcessedJob: job, Err: job.Error}
+			return job, nil
+		}
+		job.Refreshed = stale
+	}
+	// If os.IsNotExist(statErr) is true, proceed.
+
+	// Each job downloads into its own temp subdirectory, so concurrent jobs
+	// (even for the same video) can never stomp on each other's files; with
+	// NoRawFiles, nothing is downloaded to disk
+	jobOpts := opts
+	removeRaw := func() {}
+	if !opts.NoRawFiles {
+		jobTempDir, err := os.MkdirTemp(opts.TempDir, SanitizeFilename(videoID)+"-*")
+		if err != nil {
+			return failJob(job, fmt.Errorf("failed to create temp directory: %w", err)), nil
+		}
+		removeRaw = func() {
+			if !opts.KeepRaw {
+				os.RemoveAll(jobTempDir)
+			}
+		}
+		jobOpts.TempDir = jobTempDir
+	}
+	if opts.DownloadProgress && onStatus != nil {
+		jobOpts.onDownloadProgress = func(percent float64) {
+			job.DownloadPct = percent
+			onStatus(job)
+		}
+	}
+
+	job.Timings.Title = lap()
+	setStatus("downloading_subtitles")
+
+	// 3. Download Subtitles (will be saved as <videoID>.<lang>.<format>, or
+	// with NoRawFiles read from yt-dlp's output as it is cleaned)
+	var rawFiles []string
+	var stream io.ReadCloser
+	var err error
+	if opts.NoRawFiles {
+		stream, err = streamSubtitles(&job, jobOpts, limiter)
+	} else {
+		rawFiles, err = downloadSubtitles(&job, videoID, jobOpts, limiter)
+	}
+	job.Timings.Download = lap()
+	if errors.Is(err, ErrNoSubtitles) {
+		removeRaw()
+		return noSubtitlesJob(job), nil
+	} else if err != nil {
+		removeRaw()
+		return failJob(job, fmt.Errorf("failed to download subtitles: %w", err)), nil
+	}
+	if opts.ManualOnly {
+		job.CaptionsKind = CaptionsManual
+	}
+	listCaptionKinds(&job, opts, limiter)
+	if opts.KeepRaw && !opts.NoRawFiles {
+		keepTitle(&job, jobOpts.TempDir)
+	}
+	return job, func(job TranscriptJob) TranscriptJob {
+		if stream != nil {
+			return finishStreamJob(job, stream, expectedCleanedPath, opts, limiter, onStatus)
+		}
+		defer removeRaw()
+		return finishJob(job, rawFiles, expectedCleanedPath, opts, limiter, onStatus)
+	}
+}
+
+// finishJob is the cleaning stage of startJob's finishFunc: it cleans the downloaded
+// rawFiles into cleanedFile and writes the requested sidecars.
+func finishJob(job TranscriptJob, rawFiles []string, cleanedFile string, opts Options, limiter *RateLimiter, onStatus func(TranscriptJob)) TranscriptJob {
+	if rawFiles = dropOversized(&job, rawFiles, opts); len(rawFiles) == 0 {
+		job.Status = "oversized"
+		return job
+	}
+	return cleanJob(job, cleanedFile, opts, limiter, onStatus, func(job *TranscriptJob) error {
+		if opts.AllLangs {
+			return cleanAllLangs(job, rawFiles, job.VideoID, cleanedFile, opts)
+		}
+		stats, err := writeTranscript(rawFiles[0], cleanedFile, *job, opts)
+		if err == nil {
+			countBytes(job, rawFiles[0], cleanedFile)
+		}
+		return recordTranscript(job, stats, err, cleanedFile, opts)
+	})
+}
+
+// finishStreamJob is finishJob for NoRawFiles: it cleans the subtitles as
+// they arrive on stream into cleanedFile, then closes it. yt-dlp failing
+// partway fails the job rather than leave a truncated transcript.
+func finishStreamJob(job TranscriptJob, stream io.ReadCloser, cleanedFile string, opts Options, limiter *RateLimiter, onStatus func(TranscriptJob)) TranscriptJob {
+	return cleanJob(job, cleanedFile, opts, limiter, onStatus, func(job *TranscriptJob) error {
+		raw := &countingReader{r: stream}
+		content, stats, err := readCleaned(newCleanReader(raw, vttArtifact, opts.Clean))
+		if closeErr := stream.Close(); closeErr != nil {
+			return fmt.Errorf("failed to download subtitles: %w", closeErr)
+		} else if err != nil {
+			return fmt.Errorf("failed to read subtitles: %w", err)
+		}
+		opts.Clean.Header = captionsNote(*job, "", opts)
+		err = writeCleanedTranscript(content, cleanedFile, opts.Clean)
+		if info, statErr := os.Stat(cleanedFile); err == nil && statErr == nil {
+			job.RawBytes += raw.n
+			job.CleanedBytes += info.Size()
+		}
+		return recordTranscript(job, &stats, err, cleanedFile, opts)
+	})
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// recordTranscript records on the job the outcome err of writing its single
+// transcript to cleanedFile, cleaned with stats. It returns the error that
+// fails the job, or ErrEmptyTranscript if there was nothing to write.
+func recordTranscript(job *TranscriptJob, stats *CleanStats, err error, cleanedFile string, opts Options) error {
+	job.Stats = stats
+	job.Status = "completed"
+	if errors.Is(err, ErrUnchanged) {
+		job.Status = "skipped (unchanged)"
+	} else if errors.Is(err, ErrEmptyTranscript) {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("failed to process transcript: %w", err)
+	} else {
+		job.DetectedLang = detectTranscriptLang(cleanedFile, opts)
+		storeTranscript(job, cleanedFile, job.Language, opts)
+	}
+	job.ProcessedFile = cleanedFile
+	return nil
+}
+
+// cleanJob runs clean, which writes the job's transcripts into or next to
+// cleanedFile, then the hook and the requested sidecars. A transcript that
+// cleans to nothing makes the job empty.
+func cleanJob(job TranscriptJob, cleanedFile string, opts Options, limiter *RateLimiter, onStatus func(TranscriptJob), clean func(job *TranscriptJob) error) TranscriptJob {
+	job.Status = "processing_transcript"
+	if onStatus != nil {
+		onStatus(job)
+	}
+	started := time.Now()
+	videoID := job.VideoID
+
+	// 4. Process Transcript (one per language with AllLangs)
+	if err := clean(&job); errors.Is(err, ErrEmptyTranscript) {
+		return emptyJob(job, cleanedFile, opts)
+	} else if err != nil {
+		return failJob(job, err)
+	}
+	job.Timings.Processing = time.Since(started)
+	if job.Status == "completed" {
+		runHook(&job, opts)
+	}
+
+	// 5. Optionally write the metadata sidecar and fetch the thumbnail; neither fails the job
+	if opts.Metadata && job.Metadata != nil {
+		if err := WriteMetadataFile(metadataSidecarPath(cleanedFile), *job.Metadata); err != nil {
+			job.Warnings = append(job.Warnings, fmt.Sprintf("metadata sidecar not written: %v", err))
+		}
+	}
+	if opts.TitleSidecar {
+		if err := WriteTextFile(titleSidecarPath(cleanedFile), job.Title+"\n"); err != nil {
+			job.Warnings = append(job.Warnings, fmt.Sprintf("title sidecar not written: %v", err))
+		}
+	}
+	if opts.Thumbnail {
+		limiter.Wait()
+		thumbnailFile, thumbErr := saveThumbnail(job.URL, videoID, cleanedFile)
+		if thumbErr != nil {
+			job.Warnings = append(job.Warnings, fmt.Sprintf("thumbnail unavailable: %v", thumbErr))
+		} else {
+			job.ThumbnailFile = thumbnailFile
+		}
+	}
+	return job
+}
+
+// runHook runs the Exec hook, if any, for each transcript a completed job
+// wrote. A failing hook only warns: the transcript itself was written.
+func runHook(job *TranscriptJob, opts Options) {
+	if opts.Exec == nil {
+		return
+	}
+	files := job.ProcessedFiles
+	if len(files) == 0 {
+		files = []string{job.ProcessedFile}
+	}
+	for _, file := range files {
+		if err := opts.Exec.Run(file, *job); err != nil {
+			job.Warnings = append(job.Warnings, fmt.Sprintf("%s: %v", filepath.Base(file), err))
+		}
+	}
+}
+
+// dropOversized returns rawFiles without those larger than opts.MaxBytes,
+// warning about each one left out. Cleaning reads a whole caption file into
+// memory, so a pathological one (some livestreams' run to gigabytes) is
+// skipped rather than allowed to exhaust it.
+func dropOversized(job *TranscriptJob, rawFiles []string, opts Options) []string {
+	if opts.MaxBytes <= 0 {
+		return rawFiles
+	}
+	var kept []string
+	for _, rawFile := range rawFiles {
+		if info, err := os.Stat(rawFile); err == nil && info.Size() > opts.MaxBytes {
+			job.Warnings = append(job.Warnings, fmt.Sprintf("'%s' captions are %s, over the %s limit; not cleaned", subtitleLang(rawFile, job.VideoID), formatBytes(info.Size()), formatBytes(opts.MaxBytes)))
 			continue
 		}
-		// If os.IsNotExist(statErr) is true, proceed.
+		kept = append(kept, rawFile)
+	}
+	return kept
+}
+
+// emptyJob marks a job whose captions cleaned down to nothing. Like a video
+// without captions this is not a failure, but it is reported apart from
+// completed jobs so it isn't mistaken for a useful transcript; cleanedFile
+// was only written, and so is only recorded, with AllowEmpty.
+func emptyJob(job TranscriptJob, cleanedFile string, opts Options) TranscriptJob {
+	job.Error = nil
+	job.Status = "empty"
+	if opts.Clean.AllowEmpty {
+		job.ProcessedFile = cleanedFile
+	}
+	return job
+}
+
+// archivedJob marks job as skipped because its video is in the archive.
+func archivedJob(job TranscriptJob, videoID string) TranscriptJob {
+	job.VideoID = videoID
+	job.Status = "skipped (archived)"
+	return job
+}
+
+// archiveJob appends the video of a job that ended with a transcript to the
+// archive, if one is in use. Failing to do so only warns: the transcript
+// itself was written.
+func archiveJob(job *TranscriptJob, opts Options) {
+	if opts.Archive == nil || job.Error != nil || job.ProcessedFile == "" || job.VideoID == "" {
+		return
+	}
+	if err := opts.Archive.AppendArchive(job.VideoID); err != nil {
+		job.Warnings = append(job.Warnings, fmt.Sprintf("not recorded in archive: %v", err))
+	}
+}
+
+// storeTranscript adds the transcript in lang the job wrote to file to the
+// database, if one is in use. Failing to do so only warns: the transcript
+// itself was written.
+func storeTranscript(job *TranscriptJob, file, lang string, opts Options) {
+	if opts.DB == nil {
+		return
+	}
+	content, err := ReadTextFile(file)
+	if err == nil {
+		err = opts.DB.Add(StoredTranscript{VideoID: job.VideoID, Title: job.Title, URL: job.URL, Lang: lang, Content: content, FetchedAt: time.Now()})
+	}
+	if err != nil {
+		job.Warnings = append(job.Warnings, fmt.Sprintf("not added to database: %v", err))
+	}
+}
+
+// inDurationRange reports whether a video lasting seconds passes the
+// MinDuration and MaxDuration filters. An unknown duration (0, as for some
+// live streams) always passes, since there is nothing to filter on.
+func inDurationRange(seconds float64, opts Options) bool {
+	duration := time.Duration(seconds * float64(time.Second))
+	if duration == 0 {
+		return true
+	}
+	return duration >= opts.MinDuration && (opts.MaxDuration == 0 || duration <= opts.MaxDuration)
+}
+
+// uploadedSince reports whether a video uploaded on uploadDate (YYYYMMDD, as
+// yt-dlp reports it) passes the Since cutoff. An unknown date always passes.
+func uploadedSince(uploadDate string, opts Options) bool {
+	if opts.Since.IsZero() {
+		return true
+	}
+	date, err := time.Parse("20060102", uploadDate)
+	return err != nil || !date.Before(opts.Since)
+}
+
+// resolveVideoID returns the id yt-dlp names the job's subtitle files after.
+// YouTube URLs are parsed directly as an optimization. URLs the parser doesn't
+// recognise (YouTube forms like /live/<id>, or other sites with AllowAnyURL)
+// take the id from metadata if fetched, or else ask yt-dlp for it; only if
+// that fails too is the id unresolved.
+func resolveVideoID(job TranscriptJob, opts Options, limiter *RateLimiter) (string, error) {
+	videoID, err := ExtractVideoID(job.URL)
+	if err == nil || !(opts.AllowAnyURL || IsYouTubeURL(job.URL)) {
+		return videoID, err
+	}
+	if job.Metadata != nil && job.Metadata.ID != "" {
+		return job.Metadata.ID, nil
+	}
+	limiter.Wait()
+	videoID, fetchErr := FetchVideoID(job.URL)
+	if fetchErr != nil {
+		return "", fmt.Errorf("%w; %w", err, fetchErr)
+	}
+	return videoID, nil
+}
+
+// downloadSubtitles downloads the job's subtitles in the configured language
+// (see fetchSubtitles) and returns the raw files; with AllLangs, every
+// language is fetched.
+func downloadSubtitles(job *TranscriptJob, videoID string, opts Options, limiter *RateLimiter) ([]string, error) {
+	if opts.AllLangs {
+		limiter.Wait()
+		return DownloadSubtitlesWithOptions(opts.runContext(), job.URL, videoID, opts.TempDir, subtitleOptions(AllLangs, opts))
+	}
+	var files []string
+	err := fetchSubtitles(job, opts, limiter, func(lang string) (err error) {
+		files, err = DownloadSubtitlesWithOptions(opts.runContext(), job.URL, videoID, opts.TempDir, subtitleOptions(lang, opts))
+		return err
+	})
+	return files, err
+}
+
+// streamSubtitles is downloadSubtitles for NoRawFiles: it returns yt-dlp's
+// output of the subtitles (see StreamSubtitles) rather than files.
+func streamSubtitles(job *TranscriptJob, opts Options, limiter *RateLimiter) (io.ReadCloser, error) {
+	var stream io.ReadCloser
+	err := fetchSubtitles(job, opts, limiter, func(lang string) (err error) {
+		stream, err = StreamSubtitles(opts.runContext(), job.URL, subtitleOptions(lang, opts))
+		return err
+	})
+	return stream, err
+}
+
+// fetchSubtitles calls fetch with the job's subtitle language, recording the
+// language actually fetched on the job. A video lacking that exact language
+// code falls back to its closest variant (see MatchLanguage), unless
+// ExactLang is set, and otherwise with AutoLang to its primary available
+// caption language. With TranslateTo, see fetchTranslatedSubtitles.
+func fetchSubtitles(job *TranscriptJob, opts Options, limiter *RateLimiter, fetch func(lang string) error) error {
+	if opts.TranslateTo != "" {
+		return fetchTranslatedSubtitles(job, opts, limiter, fetch)
+	}
+
+	lang := opts.Lang
+	if lang == "" {
+		lang = DefaultLang
+	}
+	list := listLanguagesOnce(job, limiter)
+	if opts.ChooseLang != nil {
+		chosen, err := chooseLang(job, opts, list)
+		if err != nil {
+			return err
+		}
+		if chosen != "" {
+			lang = chosen
+		}
+	}
+	if opts.Track > 0 {
+		track, err := selectTrack(lang, opts, list)
+		if err != nil {
+			return err
+		}
+		lang = track
+	}
+	job.Language = lang
+
+	limiter.Wait()
+	err := fetch(lang)
+	regional := !opts.ExactLang && opts.Track == 0
+	if err == nil || !errors.Is(err, ErrNoSubtitles) || (!regional && !opts.AutoLang) {
+		return err
+	}
+
+	available, listErr := list()
+	if listErr != nil && !opts.AutoLang {
+		return err // The regional fallback is only a bonus
+	} else if listErr != nil {
+		return fmt.Errorf("%w (listing languages for fallback also failed: %v)", err, listErr)
+	}
+	fallback, warning := "", ""
+	if match, ok := MatchLanguage(lang, available.Codes(opts.ManualOnly)); regional && ok && match != lang {
+		fallback, warning = match, fmt.Sprintf("no '%s' subtitles, used the '%s' variant instead", lang, match)
+	} else if opts.AutoLang {
+		fallback = available.Primary()
+		warning = fmt.Sprintf("no '%s' subtitles, used '%s' instead", lang, fallback)
+	}
+	if fallback == "" || fallback == lang {
+		return err
+	}
+
+	limiter.Wait()
+	job.Language = fallback
+	if err := fetch(fallback); err != nil {
+		return err
+	}
+	job.Warnings = append(job.Warnings, warning)
+	return nil
+}
+
+// listLanguagesOnce returns a function listing the job's caption languages
+// (see ListSubtitleLanguages), asking yt-dlp only the first time it's called,
+// so choosing a language, picking a track and falling back share one listing.
+func listLanguagesOnce(job *TranscriptJob, limiter *RateLimiter) func() (SubtitleLanguages, error) {
+	var (
+		listed    bool
+		available SubtitleLanguages
+		err       error
+	)
+	return func() (SubtitleLanguages, error) {
+		if !listed {
+			limiter.Wait()
+			available, err = ListSubtitleLanguages(job.URL)
+			listed = true
+		}
+		return available, err
+	}
+}
+
+// selectTrack returns the code of track opts.Track among the video's tracks in
+// lang, failing with ErrNoSubtitles if it has fewer.
+func selectTrack(lang string, opts Options, list func() (SubtitleLanguages, error)) (string, error) {
+	available, err := list()
+	if err != nil {
+		return "", err
+	}
+	tracks := available.Tracks(lang, opts.ManualOnly)
+	if opts.Track > len(tracks) {
+		return "", fmt.Errorf("%w: video has %d '%s' caption track(s), no track %d", ErrNoSubtitles, len(tracks), lang, opts.Track)
+	}
+	return tracks[opts.Track-1].Lang, nil
+}
+
+// subtitleOptions builds the yt-dlp subtitle request for lang from opts.
+func subtitleOptions(lang string, opts Options) SubtitleOptions {
+	return SubtitleOptions{Lang: lang, Format: opts.RawFormat, Source: opts.SubSource, ManualOnly: opts.ManualOnly, OnProgress: opts.onDownloadProgress}
+}
+
+// chooseLang asks opts.ChooseLang to pick one of the video's own caption
+// languages when it has several. It returns "" (use the configured language)
+// when there is nothing to choose between or the languages can't be listed.
+func chooseLang(job *TranscriptJob, opts Options, list func() (SubtitleLanguages, error)) (string, error) {
+	available, err := list()
+	if err != nil {
+		job.Warnings = append(job.Warnings, fmt.Sprintf("couldn't list caption languages to choose from: %v", err))
+		return "", nil
+	}
+	langs := available.Native()
+	if len(langs) < 2 {
+		return "", nil
+	}
+	return opts.ChooseLang.ChooseLang(*job, langs)
+}
+
+// fetchTranslatedSubtitles fetches captions in opts.TranslateTo, preferring
+// a native track and otherwise YouTube's auto-translation, in which case the
+// job's CaptionsKind is marked translated. If neither exists it falls back to
+// the video's primary language with a warning.
+func fetchTranslatedSubtitles(job *TranscriptJob, opts Options, limiter *RateLimiter, fetch func(lang string) error) error {
+	target := opts.TranslateTo
+	job.Language = target
+
+	limiter.Wait()
+	available, err := ListSubtitleLanguages(job.URL)
+	if err != nil {
+		return err
+	}
+
+	lang := target
+	switch {
+	case available.HasNative(target):
+	case available.HasTranslation(target):
+		job.CaptionsKind = CaptionsTranslated
+	default:
+		lang = available.Primary()
+		if lang == "" {
+			return fmt.Errorf("%w: video has no captions to translate to '%s'", ErrNoSubtitles, target)
+		}
+		job.Warnings = append(job.Warnings, fmt.Sprintf("translation to '%s' unavailable, used '%s' instead", target, lang))
+	}
 
-		job.Status = "downloading_subtitles"
-		// resultsChan <- JobProcessingResult{OriginalJobIndex: jobIndex, ProcessedJob: job} // Update UI
+	limiter.Wait()
+	job.Language = lang
+	return fetch(lang)
+}
 
-		// 3. Download Subtitles (will be saved as <videoID>.vtt)
-		err = DownloadSubtitles(job.URL, videoID, tempDir) // Pass videoID and use tempDir from runWorker's params
+// cleanAllLangs cleans each raw subtitle file from an AllLangs download into
+// <name>.<lang>.txt next to cleanedFile. Languages whose transcript already
+// exists (or, with checksums, is unchanged) are skipped; the job is only
+// skipped as a whole if all of them were. Languages that clean down to nothing
+// are warned about, and the job is empty if every language was.
+func cleanAllLangs(job *TranscriptJob, rawFiles []string, videoID, cleanedFile string, opts Options) error {
+	var langs, detected []string
+	written, unchanged, empty := 0, 0, 0
+	for _, rawFile := range rawFiles {
+		lang := subtitleLang(rawFile, videoID)
+		langs = append(langs, lang)
+		langFile, err := langFilePath(cleanedFile, lang, opts)
 		if err != nil {
-			job.Error = fmt.Errorf("failed to download subtitles: %w", err)
-			job.Status = "failed"
-			resultsChan <- JobProcessingResult{OriginalJobIndex: jobIndex, ProcessedJob: job, Err: job.Error}
+			return err
+		}
+		job.ProcessedFiles = append(job.ProcessedFiles, langFile)
+
+		if opts.OutputFile == "" && !opts.Reclean {
+			skip, stale, err := checkExisting(langFile, opts)
+			if err != nil {
+				return err
+			}
+			if skip {
+				continue
+			}
+			job.Refreshed = job.Refreshed || stale
+		}
+		if _, err := writeTranscript(rawFile, langFile, *job, opts); errors.Is(err, ErrUnchanged) {
+			unchanged++
 			continue
+		} else if errors.Is(err, ErrEmptyTranscript) {
+			empty++
+			job.Warnings = append(job.Warnings, fmt.Sprintf("'%s' transcript is empty after cleaning", lang))
+			if !opts.Clean.AllowEmpty {
+				job.ProcessedFiles = job.ProcessedFiles[:len(job.ProcessedFiles)-1]
+			}
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to process %s transcript: %w", lang, err)
+		}
+		countBytes(job, rawFile, langFile)
+		storeTranscript(job, langFile, lang, opts)
+		if detectedLang := detectTranscriptLang(langFile, opts); detectedLang != "" {
+			detected = append(detected, lang+":"+detectedLang)
 		}
-		job.Status = "processing_transcript"
-		// resultsChan <- JobProcessingResult{OriginalJobIndex: jobIndex, ProcessedJob: job} // Update UI
+		written++
+	}
+
+	job.Language = strings.Join(langs, ",")
+	job.DetectedLang = strings.Join(detected, ",")
+	if len(job.ProcessedFiles) > 0 {
+		job.ProcessedFile = job.ProcessedFiles[0]
+	}
+	job.Status = "completed"
+	if written == 0 && empty == len(rawFiles) {
+		job.Status = "empty"
+	} else if written == 0 && unchanged > 0 {
+		job.Status = "skipped (unchanged)"
+	} else if written == 0 {
+		job.Status = "skipped (exists)"
+	}
+	return nil
+}
+
+// langFilePath returns where the lang transcript of a job whose single
+// transcript would be cleanedFile goes: <title>.<lang>.txt next to it, or with
+// LangDirs <lang>/<title>.txt in a per-language directory, which is created.
+func langFilePath(cleanedFile, lang string, opts Options) (string, error) {
+	if !opts.LangDirs {
+		base := transcriptBase(cleanedFile)
+		return base + "." + lang + strings.TrimPrefix(cleanedFile, base), nil
+	}
+	dir := filepath.Join(filepath.Dir(cleanedFile), lang)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create language directory %s: %w", dir, err)
+	}
+	return filepath.Join(dir, filepath.Base(cleanedFile)), nil
+}
+
+// countBytes adds the sizes of a raw subtitle file and the transcript written
+// from it to the job's byte counts.
+func countBytes(job *TranscriptJob, rawFile, outFile string) {
+	if info, err := os.Stat(rawFile); err == nil {
+		job.RawBytes += info.Size()
+	}
+	if info, err := os.Stat(outFile); err == nil {
+		job.CleanedBytes += info.Size()
+	}
+}
+
+// checkExisting reports whether the cleaned transcript at path already exists
+// and should be kept (skip), or exists but was last written before the
+// RefreshOlderThan cutoff and should be downloaded again (stale).
+func checkExisting(path string, opts Options) (skip, stale bool, err error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, false, nil
+	} else if err != nil {
+		// os.Stat failed for a reason other than file not existing (e.g., permissions)
+		return false, false, fmt.Errorf("error checking existing cleaned file %s: %w", path, err)
+	}
+	if opts.RefreshOlderThan > 0 && time.Since(info.ModTime()) > opts.RefreshOlderThan {
+		return false, true, nil
+	}
+	return true, false, nil
+}
 
-		// 4. Process Transcript
-		cleanedFile, err := ProcessSingleTranscript(videoID, job.Title, tempDir, cleanedDir)
+// subtitleLang returns the language code of a raw <videoID>.<lang>.<format> file.
+func subtitleLang(rawFile, videoID string) string {
+	name := strings.TrimPrefix(filepath.Base(rawFile), videoID+".")
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// writeTranscript turns a raw subtitle file into the configured output format
+// at outFile. Cleaning stats are returned for the text format only. Styling
+// is only preserved for the subtitle formats; every other format strips it.
+func writeTranscript(rawFile, outFile string, job TranscriptJob, opts Options) (*CleanStats, error) {
+	opts.Clean.Header = captionsNote(job, rawFile, opts)
+	opts.Clean.PreserveStyling = opts.Clean.PreserveStyling && IsSubtitleFormat(opts.Format)
+	switch opts.Format {
+	case FormatWordsJSON:
+		return nil, WriteWordTimingsFile(rawFile, outFile)
+	case FormatJSONL:
+		return nil, WriteCuesJSONLFile(rawFile, outFile, cueOptions(job, opts), opts.Clean)
+	case FormatTimedText:
+		return nil, WriteCuesTimedTextFile(rawFile, outFile, cueOptions(job, opts), opts.Clean)
+	case FormatSRT:
+		return nil, WriteCuesSRTFile(rawFile, outFile, cueOptions(job, opts), opts.Clean)
+	case FormatVTT:
+		return nil, WriteCuesVTTFile(rawFile, outFile, cueOptions(job, opts), opts.Clean)
+	}
+	stats, err := ProcessSingleTranscript(rawFile, outFile, cueOptions(job, opts), opts.Clean)
+	return &stats, err
+}
+
+// cueOptions builds the cue-level processing settings for a job from its metadata.
+func cueOptions(job TranscriptJob, opts Options) CueOptions {
+	cueOpts := CueOptions{TrimIntro: opts.TrimIntro, TrimOutro: opts.TrimOutro, Start: opts.Start, End: opts.End}
+	if job.Metadata != nil {
+		if opts.Chapters {
+			cueOpts.Chapters = job.Metadata.Chapters
+		}
+		cueOpts.Duration = time.Duration(job.Metadata.Duration * float64(time.Second))
+	}
+	return cueOpts
+}
+
+// subFormat returns the raw subtitle format configured in opts.
+func subFormat(opts Options) string {
+	if opts.RawFormat == "" {
+		return DefaultSubFormat
+	}
+	return opts.RawFormat
+}
+
+// resolveCleanedPath returns the file the job's cleaned transcript is written
+// to: opts.OutputFile if set, else the path opts.Manifest lists for the video
+// if any, else a file named after the job's media file
+// next to it if opts.Alongside has one, else a file named after the title in
+// the job's cleaned directory (.txt, .words.json for word timings, .jsonl
+// for cues, or .srt or .vtt for subtitles), cut to fit the OS's path limit. Parent directories are created
+// as needed.
+func resolveCleanedPath(job *TranscriptJob, opts Options, limiter *RateLimiter) (string, error) {
+	if opts.OutputFile != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.OutputFile), 0755); err != nil {
+			return "", fmt.Errorf("failed to prepare output directory: %w", err)
+		}
+		return opts.OutputFile, nil
+	}
+	if path := opts.Manifest.Output(job.URL, job.VideoID, opts.CleanedDir); path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", fmt.Errorf("failed to prepare output directory: %w", err)
+		}
+		return path, nil
+	}
+
+	var path string
+	if media := opts.Alongside.Find(job.VideoID); media != "" {
+		path = strings.TrimSuffix(media, filepath.Ext(media)) + ".txt"
+	} else if cleanedDir, err := resolveCleanedDir(job, opts, limiter); err != nil {
+		return "", fmt.Errorf("failed to prepare output directory: %w", err)
+	} else if opts.PrettyNames {
+		path = filepath.Join(cleanedDir, PrettyFilename(job.Title, opts.MaxFilename)+".txt")
+	} else if opts.OriginalNames {
+		path = filepath.Join(cleanedDir, SanitizeFilenameMinimalN(job.Title, opts.MaxFilename)+".txt")
+	} else if path, err = GetCleanedFilePathByTitleN(job.Title, cleanedDir, opts.MaxFilename); err != nil {
+		return "", fmt.Errorf("failed to determine cleaned file path: %w", err)
+	}
+	switch opts.Format {
+	case FormatWordsJSON:
+		path = transcriptBase(path) + wordsJSONExt
+	case FormatJSONL:
+		path = transcriptBase(path) + jsonlExt
+	case FormatSRT:
+		path = transcriptBase(path) + srtExt
+	case FormatVTT:
+		path = transcriptBase(path) + vttExt
+	}
+	return fitPathLength(path, maxPathBytes(runtime.GOOS)), nil
+}
+
+// resolveCleanedDir returns the directory a job's transcript is written to.
+// With ByChannel it is a sanitized per-uploader subdirectory of CleanedDir,
+// and with DirTemplate the template expanded for the job; either is only
+// known at runtime and so is created here.
+func resolveCleanedDir(job *TranscriptJob, opts Options, limiter *RateLimiter) (string, error) {
+	if opts.DirTemplate != "" {
+		sanitize := SanitizeFilename
+		if opts.PrettyNames {
+			sanitize = func(name string) string { return PrettyFilename(name, DefaultMaxFilename) }
+		} else if opts.OriginalNames {
+			sanitize = SanitizeFilenameMinimal
+		}
+		dir, err := ExpandDirTemplate(opts.DirTemplate, dirTemplateValues(*job, job.VideoID), sanitize)
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+		return dir, nil
+	}
+	if !opts.ByChannel {
+		return opts.CleanedDir, nil
+	}
+
+	if job.Metadata != nil {
+		job.Uploader = job.Metadata.Uploader
+	} else {
+		limiter.Wait()
+		uploader, err := FetchUploader(job.URL)
 		if err != nil {
-			job.Error = fmt.Errorf("failed to process transcript: %w", err)
-			job.Status = "failed"
+			return "", err
+		}
+		job.Uploader = uploader
+	}
+
+	channel := job.Uploader
+	if channel == "" {
+		channel = "unknown"
+	}
+	dirName := SanitizeFilename(channel)
+	if opts.PrettyNames {
+		dirName = PrettyFilename(channel, DefaultMaxFilename)
+	} else if opts.OriginalNames {
+		dirName = SanitizeFilenameMinimal(channel)
+	}
+	dir := filepath.Join(opts.CleanedDir, dirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// metadataSidecarPath returns the <name>.info.json path next to a cleaned transcript.
+func metadataSidecarPath(cleanedFile string) string {
+	return transcriptBase(cleanedFile) + ".info.json"
+}
+
+// titleSidecarPath returns the <name>.title path next to a cleaned transcript.
+func titleSidecarPath(cleanedFile string) string {
+	return transcriptBase(cleanedFile) + ".title"
+}
+
+// saveThumbnail downloads the thumbnail for a video and renames it to sit
+// next to the cleaned transcript, sharing its base name.
+func saveThumbnail(url, videoID, cleanedFile string) (string, error) {
+	downloaded, err := DownloadThumbnail(url, videoID, filepath.Dir(cleanedFile))
+	if err != nil {
+		return "", err
+	}
+	target := transcriptBase(cleanedFile) + filepath.Ext(downloaded)
+	if err := os.Rename(downloaded, target); err != nil {
+		return "", fmt.Errorf("failed to rename thumbnail %s: %w", downloaded, err)
+	}
+	return target, nil
+}
+
+// failJob marks a job as failed with the given error.
+func failJob(job TranscriptJob, err error) TranscriptJob {
+	job.Error = err
+	job.Status = "failed"
+	return job
+}
+
+// ErrAborted fails the jobs a FailFast run stopped before they finished.
+var ErrAborted = errors.New("not finished: the run stopped at the first failure")
+
+// abortUnfinished fails every job in jobs that hasn't finished with ErrAborted.
+func abortUnfinished(jobs []TranscriptJob) {
+	for i, job := range jobs {
+		if !isTerminalStatus(job.Status) {
+			jobs[i] = failJob(job, ErrAborted)
+		}
+	}
+}
+
+// workerActivity counts the jobs a worker is busy with in each phase. Update
+// keeps it current from the workers' status events and results, for the job
+// list's "active:" line.
+type workerActivity struct {
+	downloading int // Fetching a title or downloading subtitles
+	processing  int // Cleaning a transcript
+}
+
+// workerPhase returns the activity counter a job with this status counts
+// towards, or nil if a worker isn't busy with it (pending or finished).
+func (a *workerActivity) workerPhase(status string) *int {
+	switch status {
+	case "fetching_title", "downloading_subtitles":
+		return &a.downloading
+	case "processing_transcript":
+		return &a.processing
+	}
+	return nil
+}
+
+// move records a job going from status from to status to.
+func (a *workerActivity) move(from, to string) {
+	if phase := a.workerPhase(from); phase != nil && *phase > 0 {
+		*phase--
+	}
+	if phase := a.workerPhase(to); phase != nil {
+		*phase++
+	}
+}
+
+// phaseProgress is how far through a job each in-progress status is, as a
+// fraction of the job. Downloading is usually the slow phase, so it spans the
+// most; finished jobs count fully and pending ones not at all.
+var phaseProgress = map[string]float64{
+	"fetching_title":        0.1,
+	"downloading_subtitles": 0.2,
+	"processing_transcript": 0.8,
+}
+
+// percentComplete returns overall progress, with each job worth an equal share
+// that it fills in as it moves through its phases.
+func (w WorkflowState) percentComplete() float64 {
+	if w.TotalJobs == 0 {
+		return 1
+	}
+	var done float64
+	for _, job := range w.Jobs {
+		if isTerminalStatus(job.Status) {
+			done++
 		} else {
-			job.Status = "completed"
-			job.ProcessedFile = cleanedFile
+			done += phaseProgress[job.Status]
 		}
-		resultsChan <- JobProcessingResult{OriginalJobIndex: jobIndex, ProcessedJob: job, Err: job.Error}
+	}
+	return done / float64(w.TotalJobs)
+}
+
+// noSubtitlesJob marks a job whose video has no captions to download. This is
+// not a failure: the job has no Error, since there is nothing the user can fix.
+func noSubtitlesJob(job TranscriptJob) TranscriptJob {
+	job.Error = nil
+	job.Status = "no_subtitles"
+	return job
+}
+
+// addCombined hands a finished job to the combined transcript writer, if any.
+func (w WorkflowState) addCombined(index int, job TranscriptJob) {
+	if w.Options.Combined != nil {
+		w.Options.Combined.Add(index, job)
 	}
 }
 
@@ -110,37 +1176,58 @@ func (w WorkflowState) Init() tea.Cmd {
 		return tea.Quit
 	}
 
-	// Launch workers if ParallelWorkers > 0
-	if w.ParallelWorkers > 0 {
-		w.wg.Add(w.ParallelWorkers)
-		for i := 0; i < w.ParallelWorkers; i++ {
-			go runWorker(i, w.Jobs, w.jobQueue, w.resultsChan, w.TempDir, w.CleanedDir, w.wg)
-		}
+	if w.jobsCompleted == w.TotalJobs { // Every URL was rejected before reaching a worker
+		return tea.Quit
+	}
+
+	// Launch the workers; NewWorkflow guarantees at least one, and no more than there are jobs
+	jobs := slices.Clone(w.Jobs) // Workers read a snapshot; w.Jobs is only touched by Update
+	startWorkers(w.Options.ParallelWorkers, jobs, w.jobQueue, w.resultsChan, w.done, w.Options, w.limiter, w.hosts, w.wg)
 
-		// Populate job queue
-		for i := 0; i < w.TotalJobs; i++ {
-			w.jobQueue <- i
+	// Populate job queue, skipping jobs that already failed the pre-flight
+	var pending []int
+	for i := 0; i < w.TotalJobs; i++ {
+		if w.Jobs[i].Status == "pending" {
+			pending = append(pending, i)
+		} else {
+			w.addCombined(i, w.Jobs[i])
 		}
-		close(w.jobQueue) // Close jobQueue once all jobs are sent
+	}
+	queueJobs(jobs, pending, w.jobQueue, w.titlesChan, w.done, w.Options, w.limiter) // Closes jobQueue once all jobs are sent
+
+	// Start listening for the first result (and prefetched title), and animate in-progress jobs meanwhile
+	return tea.Batch(waitForJobResultCmd(w.resultsChan), waitForTitleCmd(w.titlesChan), w.Spinner.Tick)
+}
 
-		// Start listening for the first result
-		return waitForJobResultCmd(w.resultsChan)
+// stopWorkers closes done, letting workers exit without sending their
+// in-flight result, kills the subtitle downloads in flight, and cancels any
+// language prompts they wait on.
+// resultsChan is also buffered to TotalJobs, so a worker that is mid-send can
+// never block forever either way.
+func (w *WorkflowState) stopWorkers() {
+	close(w.done)
+	w.cancel()
+	for _, prompt := range w.langPrompts { // Unblock workers waiting on the user
+		prompt.Reply <- LangChoice{Err: ErrLangChoiceCancelled}
 	}
+	w.langPrompts = nil
+}
 
-	// Fallback to sequential processing if ParallelWorkers is 0 or less (legacy behavior)
-	// This part needs to be carefully re-evaluated or removed if parallel is the only mode.
-	// For now, let's assume we only support parallelWorkers >= 1.
-	// The CLI defaults it to 1, so this should be fine.
-	if w.TotalJobs > 0 {
-		w.Jobs[0].Status = "fetching_title" // Should be handled by worker
-		w.CurrentStage = "fetching_title"   // CurrentStage also needs rethink for parallel
-		// This sequential path is problematic with the new structure.
-		// Best to ensure ParallelWorkers is always at least 1.
-		// The CLI defaults it to 1, so this should be fine.
+// WaitForWorkers waits up to timeout for the workers to exit after a quit,
+// so that a job that was writing its transcript gets to finish (or clean up
+// its temp files) before the process ends. It reports whether they all did.
+func (w WorkflowState) WaitForWorkers(timeout time.Duration) bool {
+	exited := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+		return true
+	case <-time.After(timeout):
+		return false
 	}
-	// This return is effectively a no-op if parallel workers are started.
-	// The real first command for parallel is waitForJobResultCmd.
-	return nil
 }
 
 // View renders the UI for the current workflow state
@@ -149,6 +1236,20 @@ func (w WorkflowState) View() string {
 		return "No URLs provided. Exiting."
 	}
 
+	// With NoSummary the final view is just the output files, for scripts
+	// reading stdout; failures are reported on stderr by the caller instead
+	if w.jobsCompleted == w.TotalJobs && w.Options.NoSummary {
+		return w.outputFilesView() + w.debugView() + w.timingsView()
+	}
+
+	// Quitting mid-batch: say how far the run got, and list what was written
+	if w.Interrupted {
+		if w.Options.NoSummary {
+			return w.outputFilesView() + w.debugView() + w.timingsView()
+		}
+		return w.ProgressView.RenderInterrupted(w.Jobs) + w.outputFilesView() + w.ProgressView.RenderSummary(w.Jobs) + w.debugView() + w.timingsView()
+	}
+
 	// If all jobs are completed, show final status
 	if w.jobsCompleted == w.TotalJobs && !w.ReadyToQuit { // Added !w.ReadyToQuit to prevent premature completed view
 		allSuccess := true
@@ -159,10 +1260,10 @@ func (w WorkflowState) View() string {
 			}
 		}
 		if allSuccess {
-			return w.ProgressView.RenderCompleted() // Assumes this is a generic success message
+			return w.ProgressView.RenderCompleted() + w.outputFilesView() + w.ProgressView.RenderSummary(w.Jobs) + w.throughputView() + w.debugView() + w.timingsView() // Assumes this is a generic success message
 		}
 		// If some jobs failed, RenderOverallFailure will list them.
-		return w.ProgressView.RenderOverallFailure(w.Jobs)
+		return w.ProgressView.RenderOverallFailure(w.Jobs) + w.outputFilesView() + w.ProgressView.RenderSummary(w.Jobs) + w.throughputView() + w.debugView() + w.timingsView() + w.retryView()
 	}
 
 	if w.ReadyToQuit { // After all jobs processed and we're ready to quit
@@ -175,16 +1276,24 @@ func (w WorkflowState) View() string {
 			}
 		}
 		if allSuccess {
-			return w.ProgressView.RenderCompleted() + "\nQuitting..."
+			return w.ProgressView.RenderCompleted() + w.outputFilesView() + w.ProgressView.RenderSummary(w.Jobs) + w.throughputView() + w.debugView() + w.timingsView() + "\nQuitting..."
 		}
-		return w.ProgressView.RenderOverallFailure(w.Jobs) + "\nQuitting..."
+		return w.ProgressView.RenderOverallFailure(w.Jobs) + w.outputFilesView() + w.ProgressView.RenderSummary(w.Jobs) + w.throughputView() + w.debugView() + w.timingsView() + "\nQuitting..."
 	}
 
 	// For ongoing processing, show progress and status of jobs
 	// The `percent` variable previously here is no longer needed as RenderJobList handles it.
 
 	// Default view during parallel processing:
-	return w.ProgressView.RenderJobList(w.Jobs, w.jobsCompleted, w.TotalJobs, w.ParallelWorkers)
+	view := w.ProgressView
+	view.Spinner = w.Spinner.View()
+	view.Activity = activityLine(w.activity, w.Options.ParallelWorkers, w.Options.CleanWorkers)
+	jobList := view.RenderJobList(w.Jobs, w.jobsCompleted, w.TotalJobs, w.Options.ParallelWorkers)
+	if len(w.langPrompts) > 0 {
+		prompt := w.langPrompts[0]
+		return jobList + "\n" + view.RenderLangPrompt(prompt.Title, prompt.Langs, w.langCursor, len(w.langPrompts)-1)
+	}
+	return jobList
 }
 
 // Update handles state transitions in the workflow
@@ -206,20 +1315,57 @@ func (w WorkflowState) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.Type {
 		case tea.KeyCtrlC:
-			w.ReadyToQuit = true // Signal workers to stop (not implemented yet by closing jobQueue early or context)
-			// We should ideally wait for workers here using w.wg.Wait()
-			// but that blocks the UI thread.
-			// A better approach for Ctrl+C is to signal workers and let them finish,
-			// then quit when all results are in or a timeout.
-			// For now, just set ReadyToQuit and let the main loop quit.
-			// This means workers might be cut off.
-			// A more graceful shutdown would involve closing jobQueue (done in Init if all jobs sent),
-			// and workers checking a quit signal.
-			return w, tea.Quit // Force quit for now
+			w.Interrupted = w.jobsCompleted < w.TotalJobs
+			w.ReadyToQuit = true
+			w.stopWorkers()
+			return w, tea.Quit
 		default:
+			if len(w.langPrompts) > 0 {
+				return w.updateLangPrompt(msg), nil
+			}
+			if w.jobsCompleted == w.TotalJobs { // Finished with failures; see retryableJobs
+				switch msg.String() {
+				case "r":
+					return w.retryFailed()
+				case "q", "esc", "enter":
+					w.ReadyToQuit = true
+					return w, tea.Quit
+				}
+			}
 			return w, nil
 		}
 
+	case LangChoiceRequest: // A worker needs the user to pick a caption language
+		w.langPrompts = append(w.langPrompts, msg)
+		return w, nil
+
+	case Event: // Intermediate status from a worker (via ProgramEmitter)
+		// Results are authoritative: never let a late intermediate event
+		// overwrite a job that has already finished.
+		if msg.Index >= 0 && msg.Index < len(w.Jobs) && !isTerminalStatus(w.Jobs[msg.Index].Status) && !isTerminalStatus(msg.Status) {
+			w.activity.move(w.Jobs[msg.Index].Status, msg.Status)
+			w.Jobs[msg.Index].Status = msg.Status
+			w.Jobs[msg.Index].DownloadPct = msg.Progress
+			if msg.Title != "" {
+				w.Jobs[msg.Index].Title = msg.Title
+			}
+			if msg.VideoID != "" {
+				w.Jobs[msg.Index].VideoID = msg.VideoID
+			}
+			return w, w.ProgressView.Progress.SetPercent(w.percentComplete())
+		}
+		return w, nil
+
+	case spinner.TickMsg: // Advance the in-progress spinner
+		var cmd tea.Cmd
+		w.Spinner, cmd = w.Spinner.Update(msg)
+		return w, cmd
+
+	case tea.WindowSizeMsg: // Terminal resized; fit the progress bar to it
+		w.ProgressView.SetWidth(msg.Width)
+		w.ProgressView.SetHeight(msg.Height)
+		return w, nil
+
 	case progress.FrameMsg: // For progress bar animation
 		// Assuming Progress is always initialized by NewProgressView
 		progModel, cmd := w.ProgressView.Progress.Update(msg) // Update the progress.Model directly
@@ -234,9 +1380,16 @@ func (w WorkflowState) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return w, tea.Batch(cmds...)
 
 	case JobProcessingResult:
+		// A job finishes exactly once; ignore a repeated result so it can't be double counted
+		if msg.OriginalJobIndex >= 0 && msg.OriginalJobIndex < len(w.Jobs) && isTerminalStatus(w.Jobs[msg.OriginalJobIndex].Status) {
+			return w, waitForJobResultCmd(w.resultsChan)
+		}
+
 		// Update the specific job in the Jobs slice
 		if msg.OriginalJobIndex >= 0 && msg.OriginalJobIndex < len(w.Jobs) {
+			w.activity.move(w.Jobs[msg.OriginalJobIndex].Status, msg.ProcessedJob.Status)
 			w.Jobs[msg.OriginalJobIndex] = msg.ProcessedJob
+			w.addCombined(msg.OriginalJobIndex, msg.ProcessedJob)
 			if msg.ProcessedJob.Status == "completed" && msg.ProcessedJob.Error == nil {
 				// Optionally collect successfully processed files
 				// w.ProcessedFiles = append(w.ProcessedFiles, msg.ProcessedJob.ProcessedFile)
@@ -245,16 +1398,32 @@ func (w WorkflowState) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		w.jobsCompleted++
 
+		// With FailFast the first failure ends the run: nothing else is started
+		// and the jobs still underway are abandoned
+		if w.Options.FailFast && msg.ProcessedJob.Error != nil && w.jobsCompleted < w.TotalJobs {
+			abortUnfinished(w.Jobs)
+			w.activity = workerActivity{}
+			w.Aborted = true
+			w.jobsCompleted = w.TotalJobs
+			w.finishedAt = time.Now()
+			w.CurrentStage = "aborted"
+			w.ReadyToQuit = true
+			w.stopWorkers()
+			return w, tea.Quit
+		}
+
 		// Update overall progress
-		percentComplete := float64(w.jobsCompleted) / float64(w.TotalJobs)
 		// Assuming Progress is always initialized
-		cmds = append(cmds, w.ProgressView.Progress.SetPercent(percentComplete)) // Call SetPercent on the progress.Model
-		cmds = append(cmds, func() tea.Msg { return progress.FrameMsg{} })       // Trigger re-render of progress
+		cmds = append(cmds, w.ProgressView.Progress.SetPercent(w.percentComplete())) // Call SetPercent on the progress.Model
+		cmds = append(cmds, func() tea.Msg { return progress.FrameMsg{} })           // Trigger re-render of progress
 
 		if w.jobsCompleted == w.TotalJobs {
 			// All jobs are processed
+			w.finishedAt = time.Now()
 			w.CurrentStage = "completed" // Set overall workflow stage to completed
-			w.ReadyToQuit = true         // Signal that we can quit after this update cycle
+			// Signal that we can quit after this update cycle, unless the user
+			// may want to retry failed jobs first
+			w.ReadyToQuit = w.retryableJobs() == 0
 			// No new command, View will show completed status, next empty msg or keypress might lead to quit
 			// Or send a WorkflowCompletedMsg for consistency if something listens to it.
 			// For now, ReadyToQuit should be enough.
@@ -274,7 +1443,17 @@ func (w WorkflowState) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return w, tea.Batch(cmds...)
 
-	// Old messages (TitleFetchResult, DownloadCompletedMsg, ProcessingCompletedMsg) are no longer primary drivers.
+	case TitleFetchResult: // A prefetched title: show it on the URL's jobs that haven't finished
+		if msg.Err == nil {
+			for i, job := range w.Jobs {
+				if job.URL == msg.URL && job.Title == "" && !isTerminalStatus(job.Status) {
+					w.Jobs[i].Title = msg.Title
+				}
+			}
+		}
+		return w, waitForTitleCmd(w.titlesChan)
+
+	// Old messages (DownloadCompletedMsg, ProcessingCompletedMsg) are no longer primary drivers.
 	// They are handled within the worker.
 	// If any old tea.Cmds that produced these are still around, they might need to be removed.
 	// The WorkflowCompletedMsg might still be useful to signal the TUI loop to prepare for shutdown.
@@ -287,34 +1466,173 @@ func (w WorkflowState) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 }
 
-// ProcessSingleTranscript takes a videoID and title, finds its raw VTT file,
-// cleans it, and saves it to the cleaned directory.
-func ProcessSingleTranscript(videoID, videoTitle, tempDir, cleanedDir string) (string, error) {
-	// 1. Determine the raw VTT file path using videoID
-	rawFilePath, err := GetLocalVTTPathByVideoID(videoID, tempDir)
-	if err != nil {
-		return "", fmt.Errorf("failed to determine raw VTT file path for video ID %s: %w", videoID, err)
+// retryable reports whether a failed job may succeed if run again: URLs that
+// failed the pre-flight in NewWorkflow never will.
+func retryable(job TranscriptJob) bool {
+	return job.Status == "failed" && !errors.Is(job.Error, ErrUnrecognizedURL)
+}
+
+// retryableJobs returns how many failed jobs the user can retry once every
+// job has finished. Retrying is offered only in the interactive view: not
+// when stdin isn't a terminal, as no key will ever come (bubbletea ignores
+// EOF), nor with NoSummary, whose output is read by scripts, nor with a
+// combined transcript, which has already moved past the failed jobs.
+func (w WorkflowState) retryableJobs() int {
+	if !w.Options.Interactive || w.Options.NoSummary || w.Options.Combined != nil {
+		return 0
+	}
+	n := 0
+	for _, job := range w.Jobs {
+		if retryable(job) {
+			n++
+		}
 	}
+	return n
+}
 
-	// 2. Determine the cleaned file path using videoTitle
-	cleanedFilePath, err := GetCleanedFilePathByTitle(videoTitle, cleanedDir)
-	if err != nil {
-		return "", fmt.Errorf("failed to determine cleaned file path for title %s: %w", videoTitle, err)
+// retryFailed resets the retryable failed jobs to pending and runs them again
+// on a fresh pool of workers, as Init does for the first run.
+func (w WorkflowState) retryFailed() (WorkflowState, tea.Cmd) {
+	var failed []int
+	for i, job := range w.Jobs {
+		if retryable(job) {
+			failed = append(failed, i)
+			w.Jobs[i] = TranscriptJob{URL: job.URL, Status: "pending"}
+		}
 	}
+	if len(failed) == 0 {
+		return w, nil
+	}
+	w.jobsCompleted -= len(failed)
+	w.finishedAt = time.Time{}
+	w.CurrentStage = "fetching_title"
 
-	// 3. Clean the VTT file content
-	cleanedContent, err := CleanVTTFile(rawFilePath) // From internal/transcript.go
-	if err != nil {
-		return "", fmt.Errorf("failed to clean VTT file %s: %w", rawFilePath, err)
+	// The first pool has exited with its queue closed, so start another
+	w.jobQueue = make(chan int, len(failed))
+	w.resultsChan = make(chan JobProcessingResult, len(failed))
+	w.wg = &sync.WaitGroup{}
+	workers := min(w.Options.ParallelWorkers, len(failed))
+	jobs := slices.Clone(w.Jobs)
+	startWorkers(workers, jobs, w.jobQueue, w.resultsChan, w.done, w.Options, w.limiter, w.hosts, w.wg)
+	queueJobs(jobs, failed, w.jobQueue, nil, w.done, w.Options, w.limiter)
+
+	return w, tea.Batch(waitForJobResultCmd(w.resultsChan), w.ProgressView.Progress.SetPercent(w.percentComplete()), w.Spinner.Tick)
+}
+
+// updateLangPrompt moves the highlight of the shown language prompt with the
+// arrow keys (or j/k) and answers it on enter, moving on to the next prompt.
+func (w WorkflowState) updateLangPrompt(msg tea.KeyMsg) WorkflowState {
+	prompt := w.langPrompts[0]
+	switch msg.String() {
+	case "up", "k":
+		w.langCursor = max(0, w.langCursor-1)
+	case "down", "j":
+		w.langCursor = min(len(prompt.Langs)-1, w.langCursor+1)
+	case "enter":
+		prompt.Reply <- LangChoice{Lang: prompt.Langs[w.langCursor]}
+		w.langPrompts = w.langPrompts[1:]
+		w.langCursor = 0
+	}
+	return w
+}
+
+// outputFilesView lists the output files, as a tree when -tree is set.
+func (w WorkflowState) outputFilesView() string {
+	if w.Options.Tree {
+		return w.ProgressView.RenderOutputTree(w.Jobs, w.Options.CleanedDir)
+	}
+	return w.ProgressView.RenderOutputFiles(w.Jobs)
+}
+
+// throughputView renders the bytes read and written and the overall rate,
+// timed from the workflow's creation to its last result.
+func (w WorkflowState) throughputView() string {
+	return w.ProgressView.RenderThroughput(w.Jobs, w.finishedAt.Sub(w.startedAt))
+}
+
+// retryView offers to retry the failed jobs while the final view waits for a key.
+func (w WorkflowState) retryView() string {
+	if n := w.retryableJobs(); n > 0 {
+		return fmt.Sprintf("\nPress r to retry %d failed job(s), or q to quit.\n", n)
+	}
+	return ""
+}
+
+// timingsView renders per-job phase timings, and the stack of each job a
+// panic failed, when -verbose is set.
+func (w WorkflowState) timingsView() string {
+	if !w.Options.Verbose {
+		return ""
+	}
+	return w.ProgressView.RenderTimings(w.Jobs) + w.ProgressView.RenderPanics(w.Jobs)
+}
+
+// debugView renders per-job cleaning diagnostics when -debug is set, and the
+// line counts of -dedupe-report.
+func (w WorkflowState) debugView() string {
+	var view string
+	if w.Options.Debug {
+		view += w.ProgressView.RenderDebugStats(w.Jobs)
+	}
+	if w.Options.DedupeReport {
+		view += w.ProgressView.RenderDedupeReport(w.Jobs)
 	}
+	return view
+}
 
-	// 4. Write the cleaned content to the destination file
-	err = WriteTextFile(cleanedFilePath, cleanedContent) // Assuming WriteTextFile is in internal/files.go
+// ProcessSingleTranscript takes a raw subtitle file (VTT or SRT), cleans it,
+// and saves it to cleanedFilePath. If cueOpts asks for chapters or trimming,
+// or cleanOpts for stitching cues, the transcript is built from the timed
+// cues instead of line by line. If
+// nothing is left after cleaning it returns ErrEmptyTranscript, having written
+// the empty file only with cleanOpts.AllowEmpty.
+func ProcessSingleTranscript(rawFilePath, cleanedFilePath string, cueOpts CueOptions, cleanOpts CleanOptions) (CleanStats, error) {
+	// 1. Clean the VTT file content
+	cleanedContent, stats, err := CleanSubtitleFileWithStats(rawFilePath, cleanOpts) // From internal/transcript.go
 	if err != nil {
-		return "", fmt.Errorf("failed to write cleaned transcript to %s: %w", cleanedFilePath, err)
+		return stats, fmt.Errorf("failed to clean subtitle file %s: %w", rawFilePath, err)
+	}
+	if cueOpts.Enabled() || cleanOpts.StitchCues {
+		raw, err := ReadTextFile(rawFilePath)
+		if err != nil {
+			return stats, fmt.Errorf("failed to read subtitle file %s for cue processing: %w", rawFilePath, err)
+		}
+		cleanedContent = CleanCues(ParseVTTCues(raw), cueOpts, cleanOpts)
 	}
 
-	return cleanedFilePath, nil
+	// 2. Write the cleaned content to the destination file
+	return stats, writeCleanedTranscript(cleanedContent, cleanedFilePath, cleanOpts)
+}
+
+// writeCleanedTranscript writes cleanedContent to cleanedFilePath, headed by
+// cleanOpts.Header, unless its checksum shows it already holds exactly this
+// (ErrUnchanged, leaving its mtime alone). Empty content returns
+// ErrEmptyTranscript, having been written only with cleanOpts.AllowEmpty.
+func writeCleanedTranscript(cleanedContent, cleanedFilePath string, cleanOpts CleanOptions) error {
+	empty := strings.TrimSpace(cleanedContent) == ""
+	if empty && !cleanOpts.AllowEmpty {
+		return ErrEmptyTranscript
+	}
+	if cleanOpts.Header != "" {
+		cleanedContent = cleanOpts.Header + "\n" + cleanedContent
+	}
+	output := EncodeOutput(withFinalNewline(cleanedContent), cleanOpts)
+	if cleanOpts.Checksum && isUnchanged(cleanedFilePath, output) {
+		return ErrUnchanged
+	}
+	err := WriteTextFileAtomic(cleanedFilePath, output) // A partial file would pass for a finished transcript on the next run
+	if err != nil {
+		return fmt.Errorf("failed to write cleaned transcript to %s: %w", cleanedFilePath, err)
+	}
+	if cleanOpts.Checksum {
+		if err := WriteChecksumFile(cleanedFilePath, ContentChecksum(output)); err != nil {
+			return fmt.Errorf("failed to write checksum of %s: %w", cleanedFilePath, err)
+		}
+	}
+	if empty {
+		return ErrEmptyTranscript
+	}
+	return nil
 }
 
 // Original ProcessTranscript and other helper funcs like handleJobCompletion,
@@ -1,332 +1,1459 @@
 package internal
 
 import (
+	"context"
 	"errors"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// mockProgressView can be used if we need to control ProgressView behavior, but for now, we use the real one.
-
-// Helper to create a default WorkflowState for testing
+// newTestWorkflowState creates a single-worker WorkflowState for urls.
 func newTestWorkflowState(urls []string) WorkflowState {
-	return NewWorkflow(urls, "test_raw_vtt", "test_cleaned")
+	return NewWorkflow(urls, Options{TempDir: "test_raw_vtt", CleanedDir: "test_cleaned", ParallelWorkers: 1})
+}
+
+// subtitleRunner is a YtDlpRunner standing in for yt-dlp, like
+// fakeYtDlpScript: it titles each video "Video <id>" and writes an English
+// VTT for any call that has an -o template.
+type subtitleRunner struct{}
+
+func (subtitleRunner) Run(ctx context.Context, stderr io.Writer, args ...string) ([]byte, error) {
+	var id, out string
+	for i, arg := range args {
+		if videoID, err := ExtractVideoID(arg); err == nil {
+			id = videoID
+		}
+		if arg == "-o" && i+1 < len(args) {
+			out = args[i+1]
+		}
+	}
+	if strings.Contains(strings.Join(args, " "), "--print title") {
+		return []byte("Video " + id + "\n"), nil
+	}
+	vtt := "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n"
+	if out == "-" {
+		return []byte(vtt), nil
+	}
+	if out != "" {
+		return nil, os.WriteFile(filepath.Join(filepath.Dir(out), id+".en.vtt"), []byte(vtt), 0644)
+	}
+	return nil, nil
+}
+
+// workJob runs job through both stages as a worker without clean workers
+// does (see runWorker): the download stage with retries, then the cleaning
+// stage, without a limiter or status callback.
+func workJob(job TranscriptJob, opts Options) TranscriptJob {
+	job, finish := startJobSafely(job, opts, nil, nil, nil)
+	if finish != nil {
+		job = finishSafely(finish, job)
+	}
+	return job
+}
+
+// receiveResult waits for the next result a worker sends to w.
+func receiveResult(t *testing.T, w WorkflowState) JobProcessingResult {
+	t.Helper()
+	select {
+	case result := <-w.resultsChan:
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("no worker result within 5s")
+		return JobProcessingResult{}
+	}
 }
 
 func TestWorkflowState_Init(t *testing.T) {
 	t.Run("no jobs", func(t *testing.T) {
 		wf := newTestWorkflowState([]string{})
+		if wf.CurrentStage != "completed" {
+			t.Errorf("CurrentStage = %q, want completed for no URLs", wf.CurrentStage)
+		}
 		cmd := wf.Init()
-		// Expect tea.Quit command or similar indicator for no jobs
-		if cmd == nil { // Based on current Init, it returns tea.Quit or the fetch func
-			// If NewWorkflow sets stage to completed and Init does nothing, this is fine.
-			// Let's check the stage from NewWorkflow for no URLs
-			if wf.CurrentStage != "completed" {
-				t.Errorf("Expected CurrentStage to be 'completed' for no URLs, got %s", wf.CurrentStage)
-			}
-		} else {
-			// If it returned tea.Quit directly (e.g. if TotalJobs == 0 then tea.Quit)
-			// For now, Init calls a func that might return TitleFetchResult
-			// Let's verify the expectation with the current Init logic more closely.
-			// If no URLs, TotalJobs is 0. Init returns tea.Quit.
-			// This needs to align with the actual tea.Quit command object if possible or type.
-			// Direct comparison of functions is tricky. Let's assume tea.Quit has a sentinel value or type.
-			// For now, a simple non-nil check for quit might be too vague.
-			// The actual Init returns tea.Quit, so cmd should not be nil.
+		if cmd == nil {
+			t.Fatal("Init() with no jobs returned nil, want tea.Quit")
+		}
+		if _, ok := cmd().(tea.QuitMsg); !ok {
+			t.Errorf("Init() with no jobs returned a command producing %T, want tea.QuitMsg", cmd())
 		}
 	})
 
 	t.Run("one job", func(t *testing.T) {
-		wf := newTestWorkflowState([]string{"http://example.com/video1"})
-		cmd := wf.Init()
-		if cmd == nil {
-			t.Fatal("Init() returned nil command, expected a command to fetch title")
-		}
-		// Execute the command and check the message type (this is more like integration testing the cmd)
-		// For a unit test, we'd ideally check that the cmd is a function that, when called,
-		// eventually calls FetchTitle("http://example.com/video1").
-		// This is hard without deeper inspection or refactoring FetchTitle to be mockable.
-		// For now, we trust Init sets up a func to do this.
-		// Let's at least check job status and stage were updated by Init itself (if applicable).
-		if wf.Jobs[0].Status != "fetching_title" {
-			t.Errorf("Init() did not set first job status to 'fetching_title', got: %s", wf.Jobs[0].Status)
+		installFakeRunner(t, subtitleRunner{})
+		wf := NewWorkflow([]string{"https://youtu.be/abc"}, Options{TempDir: t.TempDir(), CleanedDir: t.TempDir(), ParallelWorkers: 1})
+		if cmd := wf.Init(); cmd == nil {
+			t.Fatal("Init() returned nil, want a command waiting for worker results")
 		}
-		if wf.CurrentStage != "fetching_title" {
-			t.Errorf("Init() did not set wf.CurrentStage to 'fetching_title', got: %s", wf.CurrentStage)
+		result := receiveResult(t, wf)
+		if result.OriginalJobIndex != 0 || result.ProcessedJob.Status != "completed" {
+			t.Errorf("worker result = job %d %q (%v), want job 0 completed", result.OriginalJobIndex, result.ProcessedJob.Status, result.ProcessedJob.Error)
 		}
+		wf.wg.Wait()
 	})
 }
 
-func TestWorkflowState_Update_TitleFetchResult(t *testing.T) {
-	t.Run("successful title fetch", func(t *testing.T) {
-		wf := newTestWorkflowState([]string{"http://example.com/video1"})
-		wf.CurrentJobIndex = 0
-		wf.Jobs[0].Status = "fetching_title" // Set initial state
-		wf.CurrentStage = "fetching_title"
-
-		msg := TitleFetchResult{URL: "http://example.com/video1", Title: "Test Title", Err: nil}
-		newWfModel, cmd := wf.Update(msg)
-		newWf := newWfModel.(WorkflowState)
-
-		if newWf.Jobs[0].Title != "Test Title" {
-			t.Errorf("Update with TitleFetchResult did not set job title. Got %s, want %s", newWf.Jobs[0].Title, "Test Title")
+func TestWorkflowState_Update_JobProcessingResult(t *testing.T) {
+	wf := newTestWorkflowState([]string{"https://youtu.be/abc", "https://youtu.be/def"})
+	steps := []struct {
+		result       JobProcessingResult
+		wantStatus   string
+		wantDone     int
+		wantQuitting bool
+	}{
+		{JobProcessingResult{OriginalJobIndex: 1, ProcessedJob: TranscriptJob{URL: "https://youtu.be/def", Title: "B", Status: "no_subtitles"}}, "no_subtitles", 1, false},
+		{JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Title: "A", Status: "completed"}}, "completed", 2, true},
+	}
+	for _, step := range steps {
+		m, cmd := wf.Update(step.result)
+		wf = m.(WorkflowState)
+		if got := wf.Jobs[step.result.OriginalJobIndex].Status; got != step.wantStatus {
+			t.Errorf("job %d status = %q, want %q", step.result.OriginalJobIndex, got, step.wantStatus)
 		}
-		if newWf.Jobs[0].Status != "downloading_subtitles" {
-			t.Errorf("Update with TitleFetchResult did not set job status. Got %s, want %s", newWf.Jobs[0].Status, "downloading_subtitles")
+		if wf.jobsCompleted != step.wantDone {
+			t.Errorf("jobsCompleted = %d, want %d", wf.jobsCompleted, step.wantDone)
 		}
-		if newWf.CurrentStage != "downloading" {
-			t.Errorf("Update with TitleFetchResult did not set workflow stage. Got %s, want %s", newWf.CurrentStage, "downloading")
+		if wf.ReadyToQuit != step.wantQuitting {
+			t.Errorf("ReadyToQuit = %v after %d of 2 jobs, want %v", wf.ReadyToQuit, wf.jobsCompleted, step.wantQuitting)
 		}
 		if cmd == nil {
-			t.Error("Update with TitleFetchResult should return a command to download subtitles")
+			t.Errorf("Update(JobProcessingResult) returned nil, want progress commands")
 		}
-	})
+	}
+	if wf.CurrentStage != "completed" {
+		t.Errorf("CurrentStage = %q, want completed once every job finished", wf.CurrentStage)
+	}
 
-	t.Run("failed title fetch", func(t *testing.T) {
-		wf := newTestWorkflowState([]string{"http://example.com/video1"})
-		wf.CurrentJobIndex = 0
-		wf.Jobs[0].Status = "fetching_title"
-		wf.CurrentStage = "fetching_title"
+	// Once ready to quit, any further message quits
+	if _, cmd := wf.Update(tea.WindowSizeMsg{Width: 80}); cmd == nil {
+		t.Error("Update() after the last job returned nil, want tea.Quit")
+	} else if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Errorf("Update() after the last job returned a command producing %T, want tea.QuitMsg", cmd())
+	}
+}
 
-		testErr := errors.New("fetch failed")
-		msg := TitleFetchResult{URL: "http://example.com/video1", Title: "", Err: testErr}
-		newWfModel, cmd := wf.Update(msg)
-		newWf := newWfModel.(WorkflowState)
+func TestWorkflowState_RetryFailed(t *testing.T) {
+	installFakeRunner(t, subtitleRunner{})
+	cleanedDir := t.TempDir()
+	wf := NewWorkflow([]string{"https://youtu.be/abc", "https://youtu.be/def", "not-a-url"}, Options{TempDir: t.TempDir(), CleanedDir: cleanedDir, ParallelWorkers: 2, Interactive: true})
+	for _, result := range []JobProcessingResult{
+		{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Title: "A", Status: "completed"}},
+		{OriginalJobIndex: 1, ProcessedJob: TranscriptJob{URL: "https://youtu.be/def", Title: "B", Status: "failed", Error: errors.New("network down")}},
+	} {
+		m, _ := wf.Update(result)
+		wf = m.(WorkflowState)
+	}
 
-		if newWf.Jobs[0].Error != testErr {
-			t.Errorf("Update with failed TitleFetchResult did not set job error. Got %v, want %v", newWf.Jobs[0].Error, testErr)
-		}
-		if newWf.Jobs[0].Status != "failed" {
-			t.Errorf("Update with failed TitleFetchResult did not set job status. Got %s, want %s", newWf.Jobs[0].Status, "failed")
+	// Finished with a failure: wait for the user instead of quitting
+	if wf.ReadyToQuit {
+		t.Fatal("ReadyToQuit = true with a retryable failure, want the view to offer a retry")
+	}
+	if view := wf.View(); !strings.Contains(view, "Press r to retry 1 failed job(s)") {
+		t.Errorf("View() = %q, want the retry prompt for the one retryable job", view)
+	}
+
+	m, cmd := wf.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	wf = m.(WorkflowState)
+	if cmd == nil || wf.jobsCompleted != 2 || wf.Jobs[1].Status != "pending" || wf.Jobs[1].Error != nil {
+		t.Fatalf("after r: jobsCompleted = %d, job 1 = %q (%v), want 2 and a pending job 1", wf.jobsCompleted, wf.Jobs[1].Status, wf.Jobs[1].Error)
+	}
+	if wf.Jobs[0].Status != "completed" || wf.Jobs[2].Status != "failed" {
+		t.Errorf("after r: jobs 0 and 2 = %q, %q, want only the retryable failure re-run", wf.Jobs[0].Status, wf.Jobs[2].Status)
+	}
+
+	m, _ = wf.Update(receiveResult(t, wf))
+	wf = m.(WorkflowState)
+	wf.wg.Wait()
+	if wf.Jobs[1].Status != "completed" || wf.jobsCompleted != 3 || !wf.ReadyToQuit {
+		t.Errorf("retried job = %q (%v), jobsCompleted = %d, ReadyToQuit = %v, want completed, 3, true", wf.Jobs[1].Status, wf.Jobs[1].Error, wf.jobsCompleted, wf.ReadyToQuit)
+	}
+}
+
+func TestWorkflowState_FailFast(t *testing.T) {
+	wf := newTestWorkflowState([]string{"https://youtu.be/abc", "https://youtu.be/def", "https://youtu.be/ghi"})
+	wf.Options.FailFast = true
+	m, _ := wf.Update(JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Status: "completed"}})
+	if m.(WorkflowState).Aborted {
+		t.Fatal("Aborted = true after a completed job")
+	}
+	m, cmd := m.Update(JobProcessingResult{OriginalJobIndex: 1, ProcessedJob: TranscriptJob{URL: "https://youtu.be/def", Status: "failed", Error: errors.New("boom")}})
+	wf = m.(WorkflowState)
+	if !wf.Aborted || !wf.ReadyToQuit || cmd == nil {
+		t.Fatalf("after a failure with FailFast: Aborted = %v, ReadyToQuit = %v, want both and a quit", wf.Aborted, wf.ReadyToQuit)
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Errorf("Update() returned a command producing %T, want tea.QuitMsg", cmd())
+	}
+	select {
+	case <-wf.done:
+	default:
+		t.Error("done still open, want the workers stopped")
+	}
+	if !errors.Is(wf.Jobs[2].Error, ErrAborted) || wf.Jobs[0].Status != "completed" || errors.Is(wf.Jobs[1].Error, ErrAborted) {
+		t.Errorf("jobs = %q (%v), %q (%v), %q (%v), want only the unfinished one aborted", wf.Jobs[0].Status, wf.Jobs[0].Error, wf.Jobs[1].Status, wf.Jobs[1].Error, wf.Jobs[2].Status, wf.Jobs[2].Error)
+	}
+	if view := wf.View(); !strings.Contains(view, "aborted early at the first failure: 1 jobs not finished") {
+		t.Errorf("View() = %q, want the summary to note the early abort", view)
+	}
+}
+
+func TestWorkflowState_Interrupted(t *testing.T) {
+	wf := newTestWorkflowState([]string{"https://youtu.be/abc", "https://youtu.be/def", "https://youtu.be/ghi"})
+	m, _ := wf.Update(JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Title: "First", Status: "completed", ProcessedFile: "cleaned/First.txt"}})
+	m, _ = m.Update(Event{Index: 1, Status: "downloading_subtitles"})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	wf = m.(WorkflowState)
+	if !wf.Interrupted {
+		t.Fatal("Interrupted = false after quitting mid-batch")
+	}
+	if wf.Options.runContext().Err() == nil {
+		t.Error("run context still live after quitting, want downloads in flight killed")
+	}
+	view := wf.View()
+	for _, want := range []string{"⏹ Interrupted: 1 of 3 completed, 1 in progress cancelled, 1 not started\n", "cleaned/First.txt"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() = %q, want %q", view, want)
 		}
-		// This should trigger handleJobCompletion, which might start next job or finalize
-		// For a single job that fails at title fetch, it should go to finalizeWorkflow.
-		// finalizeWorkflow sets CurrentStage to "completed" (or "failed" if it checks errors).
-		// Let's check if CurrentJobIndex was incremented (so it's now >= TotalJobs)
-		if newWf.CurrentJobIndex < newWf.TotalJobs {
-			t.Errorf("Expected CurrentJobIndex to be >= TotalJobs after single job fails at title, got %d", newWf.CurrentJobIndex)
+	}
+	if strings.Contains(view, "All done") {
+		t.Errorf("View() = %q, want no success banner for an interrupted run", view)
+	}
+	if !wf.WaitForWorkers(time.Second) {
+		t.Error("WaitForWorkers() = false without any workers running")
+	}
+
+	// Quitting once everything has finished isn't an interruption
+	done := newTestWorkflowState([]string{"https://youtu.be/abc"})
+	done.Options.NoSummary = true
+	m, _ = done.Update(JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Status: "failed", Error: errors.New("boom")}})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if m.(WorkflowState).Interrupted {
+		t.Error("Interrupted = true after quitting a finished run")
+	}
+}
+
+func TestDownloadSubtitles_Cancelled(t *testing.T) {
+	installFakeYtDlp(t, "sleep 10\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := Options{TempDir: t.TempDir(), ctx: ctx}
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	started := time.Now()
+	job := TranscriptJob{URL: "https://youtu.be/abc"}
+	if _, err := downloadSubtitles(&job, "abc", opts, nil); err == nil {
+		t.Error("downloadSubtitles() after cancelling error = nil, want the download killed")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("downloadSubtitles() took %v after cancelling, want yt-dlp killed right away", elapsed)
+	}
+}
+
+func TestWorkflowState_WorkerActivity(t *testing.T) {
+	wf := NewWorkflow([]string{"https://youtu.be/abc", "https://youtu.be/def", "https://youtu.be/ghi"}, Options{ParallelWorkers: 4})
+	var m tea.Model = wf
+	for _, ev := range []Event{
+		{Index: 0, Status: "fetching_title"},
+		{Index: 1, Status: "fetching_title"},
+		{Index: 0, Status: "downloading_subtitles"},
+		{Index: 0, Status: "downloading_subtitles", Progress: 50}, // Progress within a phase isn't a new job
+		{Index: 1, Status: "downloading_subtitles"},
+		{Index: 0, Status: "processing_transcript"},
+		{Index: 2, Status: "fetching_title"},
+	} {
+		m, _ = m.Update(ev)
+	}
+	if view := m.View(); !strings.Contains(view, "active: 2 downloading, 1 processing, 0 idle") {
+		t.Errorf("View() = %q, want 2 jobs downloading and 1 processing, of 3 workers", view)
+	}
+
+	m, _ = m.Update(JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Status: "completed"}})
+	m, _ = m.Update(Event{Index: 0, Status: "processing_transcript"}) // Late, after the result
+	if got := m.(WorkflowState).activity; got != (workerActivity{downloading: 2}) {
+		t.Errorf("activity after job 0 finished = %+v, want 2 downloading", got)
+	}
+}
+
+func TestWorkflowState_QuitInsteadOfRetry(t *testing.T) {
+	wf := newTestWorkflowState([]string{"https://youtu.be/abc"})
+	wf.Options.Interactive = true
+	m, _ := wf.Update(JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Status: "failed", Error: errors.New("boom")}})
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if !m.(WorkflowState).ReadyToQuit || cmd == nil {
+		t.Fatal("Update(q) after a failed run did not quit")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Errorf("Update(q) returned a command producing %T, want tea.QuitMsg", cmd())
+	}
+
+	// Scripts reading stdout never get a prompt
+	wf = newTestWorkflowState([]string{"https://youtu.be/abc"})
+	wf.Options.Interactive = true
+	wf.Options.NoSummary = true
+	m, _ = wf.Update(JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Status: "failed", Error: errors.New("boom")}})
+	if !m.(WorkflowState).ReadyToQuit {
+		t.Error("ReadyToQuit = false with NoSummary, want no retry prompt")
+	}
+}
+
+func TestWorkflowState_NonInteractiveFailureQuits(t *testing.T) {
+	// Without a terminal on stdin (cron, CI, < /dev/null) no key will come to
+	// answer a retry prompt, so a failed run finishes like before
+	wf := newTestWorkflowState([]string{"https://youtu.be/abc", "https://youtu.be/def"})
+	m, _ := wf.Update(JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Status: "completed"}})
+	m, _ = m.Update(JobProcessingResult{OriginalJobIndex: 1, ProcessedJob: TranscriptJob{URL: "https://youtu.be/def", Status: "failed", Error: errors.New("network down")}})
+	wf = m.(WorkflowState)
+	if !wf.ReadyToQuit {
+		t.Fatal("ReadyToQuit = false after a failure without a terminal, want no retry prompt")
+	}
+	if view := wf.View(); strings.Contains(view, "Press r to retry") {
+		t.Errorf("View() = %q, want no retry prompt", view)
+	}
+	if _, cmd := wf.Update(tea.WindowSizeMsg{Width: 80}); cmd == nil {
+		t.Error("Update() after the last job returned nil, want tea.Quit")
+	} else if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Errorf("Update() after the last job returned a command producing %T, want tea.QuitMsg", cmd())
+	}
+}
+
+func TestWorkflowState_WorkerPath(t *testing.T) {
+	installFakeRunner(t, subtitleRunner{})
+	cleanedDir := t.TempDir()
+	wf := NewWorkflow([]string{"https://youtu.be/abc", "https://youtu.be/def"}, Options{TempDir: t.TempDir(), CleanedDir: cleanedDir, ParallelWorkers: 2})
+	wf.Init()
+
+	// Feed every worker result through Update, as the bubbletea loop would
+	for !wf.ReadyToQuit {
+		m, _ := wf.Update(receiveResult(t, wf))
+		wf = m.(WorkflowState)
+	}
+	wf.wg.Wait()
+
+	if wf.jobsCompleted != 2 {
+		t.Errorf("jobsCompleted = %d, want 2", wf.jobsCompleted)
+	}
+	for i, id := range []string{"abc", "def"} {
+		job := wf.Jobs[i]
+		if job.Status != "completed" || job.Title != "Video "+id {
+			t.Errorf("job %d = %q %q (%v), want completed %q", i, job.Status, job.Title, job.Error, "Video "+id)
 		}
-		if newWf.CurrentStage != "completed" && newWf.CurrentStage != "failed" { // finalizeWorkflow sets this
-			t.Errorf("Expected CurrentStage to be 'completed' or 'failed' after single job fails at title, got %s", newWf.CurrentStage)
+		if _, err := os.Stat(filepath.Join(cleanedDir, "Video-"+id+".txt")); err != nil {
+			t.Errorf("transcript of job %d not written: %v", i, err)
 		}
-		if cmd == nil {
-			t.Error("Update with failed TitleFetchResult should return a command (from finalize or next job)")
+	}
+}
+
+func TestWorkflowState_Update_KeyMsg(t *testing.T) {
+	wf := newTestWorkflowState([]string{"https://youtu.be/abc"})
+
+	m, cmd := wf.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil {
+		t.Fatal("Update(Ctrl+C) returned nil, want tea.Quit")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Errorf("Update(Ctrl+C) returned a command producing %T, want tea.QuitMsg", cmd())
+	}
+	if !m.(WorkflowState).ReadyToQuit {
+		t.Error("Update(Ctrl+C) did not set ReadyToQuit")
+	}
+	select {
+	case <-wf.done:
+	default:
+		t.Error("Update(Ctrl+C) did not close done, workers would keep going")
+	}
+}
+
+func TestWorkflowState_View(t *testing.T) {
+	t.Run("no jobs", func(t *testing.T) {
+		wf := newTestWorkflowState([]string{})
+		view := wf.View()
+		if view != "No URLs provided. Exiting." {
+			t.Errorf("View for no jobs: got %q, want %q", view, "No URLs provided. Exiting.")
 		}
 	})
 
-	t.Run("title fetch result for wrong URL", func(t *testing.T) {
-		wf := newTestWorkflowState([]string{"http://example.com/video1"})
-		wf.CurrentJobIndex = 0
-		wf.Jobs[0].Status = "fetching_title"
-		initialState := wf // Keep a copy for comparison
+	t.Run("in progress", func(t *testing.T) {
+		wf := newTestWorkflowState([]string{"https://youtu.be/abc"})
+		wf.Jobs[0].Status = "downloading_subtitles"
+		wf.Jobs[0].Title = "My Video"
+		view := wf.View()
+		if !strings.Contains(view, "My Video") || !strings.Contains(view, "downloading_subtitles") {
+			t.Errorf("View while downloading: got %q, want the job's title and status", view)
+		}
+	})
 
-		msg := TitleFetchResult{URL: "http://some.other/video", Title: "Wrong Title", Err: nil}
-		newWfModel, cmd := wf.Update(msg)
+	t.Run("completed state", func(t *testing.T) {
+		wf := newTestWorkflowState([]string{"https://youtu.be/abc"})
+		m, _ := wf.Update(JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Title: "My Video", Status: "completed"}})
+		view := m.(WorkflowState).View()
+		if !strings.Contains(view, "✅ All done!") {
+			t.Errorf("View for completed: got %q, want to contain %q", view, "✅ All done!")
+		}
+	})
 
-		if !reflect.DeepEqual(newWfModel.(WorkflowState).Jobs[0], initialState.Jobs[0]) {
-			t.Errorf("Update with wrong URL TitleFetchResult should not change job state. Got %+v, want %+v", newWfModel.(WorkflowState).Jobs[0], initialState.Jobs[0])
+	t.Run("completed state without summary", func(t *testing.T) {
+		wf := newTestWorkflowState([]string{"https://youtu.be/abc"})
+		wf.Options.NoSummary = true
+		wf.Jobs[0] = TranscriptJob{URL: "https://youtu.be/abc", Title: "My Video", Status: "completed", ProcessedFile: "cleaned/My-Video.txt"}
+		wf.jobsCompleted = 1
+		want := "My Video -> cleaned/My-Video.txt\n"
+		if view := wf.View(); view != want {
+			t.Errorf("View with NoSummary = %q, want %q", view, want)
 		}
-		if cmd != nil {
-			t.Errorf("Update with wrong URL TitleFetchResult should not return a command. Got %T", cmd)
+	})
+
+	t.Run("single job failed state", func(t *testing.T) {
+		wf := newTestWorkflowState([]string{"https://youtu.be/abc"})
+		m, _ := wf.Update(JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Title: "Failed Video", Status: "failed", Error: errors.New("epic fail")}})
+		view := m.(WorkflowState).View()
+		if !strings.Contains(view, "❌ Some jobs failed: Failed Video") {
+			t.Errorf("View for failed job: got %q, want to contain %q", view, "❌ Some jobs failed: Failed Video")
 		}
 	})
 }
 
-func TestWorkflowState_Update_DownloadCompletedMsg(t *testing.T) {
-	wf := newTestWorkflowState([]string{"http://example.com/video1"})
-	wf.CurrentJobIndex = 0
-	wf.Jobs[0] = TranscriptJob{URL: "http://example.com/video1", Title: "Test Video", Status: "downloading_subtitles"}
-	wf.CurrentStage = "downloading"
+func TestRunWorker_StopsWhenDone(t *testing.T) {
+	jobs := []TranscriptJob{{URL: "http://example.com/video1", Status: "pending"}}
+	jobQueue := make(chan int, 1)
+	jobQueue <- 0
+	close(jobQueue)
+	resultsChan := make(chan JobProcessingResult) // Unbuffered: a send would block forever
+	done := make(chan struct{})
+	close(done)
 
-	t.Run("successful download", func(t *testing.T) {
-		msg := DownloadCompletedMsg{Err: nil}
-		newWfModel, cmd := wf.Update(msg)
-		newWf := newWfModel.(WorkflowState)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	finished := make(chan struct{})
+	go func() {
+		runWorker(0, jobs, jobQueue, resultsChan, nil, done, Options{TempDir: "raw", CleanedDir: "cleaned"}, nil, nil, &wg)
+		close(finished)
+	}()
 
-		if newWf.Jobs[0].Status != "processing_transcript" {
-			t.Errorf("Job status after download: got %s, want %s", newWf.Jobs[0].Status, "processing_transcript")
-		}
-		if newWf.CurrentStage != "processing" {
-			t.Errorf("Workflow stage after download: got %s, want %s", newWf.CurrentStage, "processing")
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("runWorker did not exit after done was closed")
+	}
+	wg.Wait()
+}
+
+func TestResolveCleanedDir(t *testing.T) {
+	cleanedDir := t.TempDir()
+
+	t.Run("flat output", func(t *testing.T) {
+		job := TranscriptJob{URL: "http://example.com/video1"}
+		got, err := resolveCleanedDir(&job, Options{CleanedDir: cleanedDir}, nil)
+		if err != nil {
+			t.Fatalf("resolveCleanedDir() error = %v, wantErr nil", err)
 		}
-		if cmd == nil {
-			t.Error("Expected a command for processing transcript")
+		if got != cleanedDir {
+			t.Errorf("resolveCleanedDir() = %q, want %q", got, cleanedDir)
 		}
-		// Check progress update
-		// progressCmd := cmd.(tea.BatchMsg)[1].(tea.Cmd) // Assuming it's the SetProgress one
-		// Here we'd need a way to inspect the command or mock ProgressView.SetProgress
 	})
 
-	t.Run("failed download", func(t *testing.T) {
-		// Reset state for this sub-test if necessary, or use a fresh wf if tests modify it too much
-		wfFail := newTestWorkflowState([]string{"http://example.com/video1"})
-		wfFail.CurrentJobIndex = 0
-		wfFail.Jobs[0] = TranscriptJob{URL: "http://example.com/video1", Title: "Test Video", Status: "downloading_subtitles"}
-		wfFail.CurrentStage = "downloading"
-
-		testErr := errors.New("download error")
-		msg := DownloadCompletedMsg{Err: testErr}
-		newWfModel, _ := wfFail.Update(msg)
-		newWf := newWfModel.(WorkflowState)
-
-		if newWf.Jobs[0].Status != "failed" {
-			t.Errorf("Job status after failed download: got %s, want %s", newWf.Jobs[0].Status, "failed")
+	t.Run("by channel from metadata", func(t *testing.T) {
+		job := TranscriptJob{URL: "http://example.com/video1", Metadata: &VideoMetadata{Uploader: "AC/DC: Official"}}
+		got, err := resolveCleanedDir(&job, Options{CleanedDir: cleanedDir, ByChannel: true}, nil)
+		if err != nil {
+			t.Fatalf("resolveCleanedDir() error = %v, wantErr nil", err)
 		}
-		if newWf.Jobs[0].Error != testErr {
-			t.Errorf("Job error after failed download: got %v, want %v", newWf.Jobs[0].Error, testErr)
+		want := filepath.Join(cleanedDir, "AC-DC-Official")
+		if got != want {
+			t.Errorf("resolveCleanedDir() = %q, want %q", got, want)
 		}
-		// Check if it moves to next job or finalizes
-		if newWf.CurrentJobIndex < newWf.TotalJobs {
-			t.Errorf("Expected CurrentJobIndex to be >= TotalJobs after single job fails download, got %d", newWf.CurrentJobIndex)
+		if info, err := os.Stat(want); err != nil || !info.IsDir() {
+			t.Errorf("resolveCleanedDir() did not create channel directory %s", want)
+		}
+		if job.Uploader != "AC/DC: Official" {
+			t.Errorf("resolveCleanedDir() did not record uploader on job, got %q", job.Uploader)
 		}
 	})
 }
 
-func TestWorkflowState_Update_ProcessingCompletedMsg(t *testing.T) {
-	wf := newTestWorkflowState([]string{"http://example.com/video1"})
-	wf.CurrentJobIndex = 0
-	wf.Jobs[0] = TranscriptJob{URL: "http://example.com/video1", Title: "Test Video", Status: "processing_transcript"}
-	wf.CurrentStage = "processing"
+func TestWorkflowState_Update_WindowSizeMsg(t *testing.T) {
+	wf := NewWorkflow([]string{"http://example.com/video1"}, Options{ParallelWorkers: 1})
+
+	newWfModel, _ := wf.Update(tea.WindowSizeMsg{Width: 50, Height: 20})
+	newWf := newWfModel.(WorkflowState)
+
+	if newWf.ProgressView.Progress.Width != 50-progressMargin {
+		t.Errorf("Update(WindowSizeMsg) progress width = %d, want %d", newWf.ProgressView.Progress.Width, 50-progressMargin)
+	}
+}
+
+func TestDownloadSubtitles_AutoLangFallback(t *testing.T) {
+	tempDir := t.TempDir()
+	// A Japanese-only video: --dump-json lists its captions, and only a "ja" request writes a file.
+	installFakeYtDlp(t, `case "$*" in *--dump-json*) echo '{"automatic_captions": {"ja": []}}'; exit 0;; esac
+for a in "$@"; do [ "$prev" = "--sub-lang" ] && lang="$a"; prev="$a"; done
+[ "$lang" = "ja" ] && printf 'WEBVTT\n' > '`+filepath.Join(tempDir, "abc.ja.vtt")+"'\nexit 0\n")
+
+	job := TranscriptJob{URL: "https://youtu.be/abc"}
+	if _, err := downloadSubtitles(&job, "abc", Options{TempDir: tempDir}, nil); !errors.Is(err, ErrNoSubtitles) {
+		t.Errorf("downloadSubtitles() without AutoLang error = %v, want ErrNoSubtitles", err)
+	}
+
+	files, err := downloadSubtitles(&job, "abc", Options{TempDir: tempDir, AutoLang: true}, nil)
+	if err != nil {
+		t.Fatalf("downloadSubtitles() with AutoLang error = %v", err)
+	}
+	if job.Language != "ja" || len(files) != 1 || filepath.Base(files[0]) != "abc.ja.vtt" {
+		t.Errorf("downloadSubtitles() lang, files = %q, %v, want %q, [abc.ja.vtt]", job.Language, files, "ja")
+	}
+	if len(job.Warnings) != 1 {
+		t.Errorf("downloadSubtitles() should record the fallback as a warning, got %v", job.Warnings)
+	}
+}
+
+func TestDownloadSubtitles_RegionalVariant(t *testing.T) {
+	tempDir := t.TempDir()
+	// A video with only a US English track, which a plain "en" request misses.
+	installFakeYtDlp(t, `case "$*" in *--dump-json*) echo '{"subtitles": {"en-US": [], "fr": []}}'; exit 0;; esac
+for a in "$@"; do [ "$prev" = "--sub-lang" ] && lang="$a"; prev="$a"; done
+[ "$lang" = "en-US" ] && printf 'WEBVTT\n' > '`+filepath.Join(tempDir, "abc.en-US.vtt")+"'\nexit 0\n")
+
+	job := TranscriptJob{URL: "https://youtu.be/abc"}
+	files, err := downloadSubtitles(&job, "abc", Options{TempDir: tempDir, Lang: "en"}, nil)
+	if err != nil {
+		t.Fatalf("downloadSubtitles() error = %v", err)
+	}
+	if job.Language != "en-US" || len(files) != 1 || filepath.Base(files[0]) != "abc.en-US.vtt" {
+		t.Errorf("downloadSubtitles() lang, files = %q, %v, want %q, [abc.en-US.vtt]", job.Language, files, "en-US")
+	}
+	if len(job.Warnings) != 1 || !strings.Contains(job.Warnings[0], "'en-US' variant") {
+		t.Errorf("downloadSubtitles() warnings = %q, want the variant used named", job.Warnings)
+	}
+
+	if err := os.Remove(files[0]); err != nil {
+		t.Fatal(err)
+	}
+	job = TranscriptJob{URL: "https://youtu.be/abc"}
+	if _, err := downloadSubtitles(&job, "abc", Options{TempDir: tempDir, Lang: "en", ExactLang: true}, nil); !errors.Is(err, ErrNoSubtitles) {
+		t.Errorf("downloadSubtitles() with ExactLang error = %v, want ErrNoSubtitles", err)
+	}
+}
+
+func TestDownloadSubtitles_Track(t *testing.T) {
+	tempDir := t.TempDir()
+	// A video with a forced and a full English track.
+	installFakeYtDlp(t, `case "$*" in *--dump-json*) echo '{"subtitles": {"en": [], "en-full": []}}'; exit 0;; esac
+for a in "$@"; do [ "$prev" = "--sub-lang" ] && lang="$a"; prev="$a"; done
+printf 'WEBVTT\n' > "`+tempDir+`/abc.$lang.vtt"
+`)
+
+	job := TranscriptJob{URL: "https://youtu.be/abc"}
+	files, err := downloadSubtitles(&job, "abc", Options{TempDir: tempDir, Track: 2}, nil)
+	if err != nil {
+		t.Fatalf("downloadSubtitles(Track 2) error = %v", err)
+	}
+	if job.Language != "en-full" || len(files) != 1 || filepath.Base(files[0]) != "abc.en-full.vtt" {
+		t.Errorf("downloadSubtitles(Track 2) lang, files = %q, %v, want %q, [abc.en-full.vtt]", job.Language, files, "en-full")
+	}
+
+	if _, err := downloadSubtitles(&job, "abc", Options{TempDir: tempDir, Track: 3}, nil); !errors.Is(err, ErrNoSubtitles) {
+		t.Errorf("downloadSubtitles(Track 3) error = %v, want ErrNoSubtitles", err)
+	}
+}
+
+func TestDownloadSubtitles_TranslateTo(t *testing.T) {
+	tempDir := t.TempDir()
+	// A Spanish video whose captions YouTube can auto-translate into French but not German.
+	installFakeYtDlp(t, `case "$*" in *--dump-json*) echo '{"automatic_captions": {"es-orig": [], "es": [], "fr": []}}'; exit 0;; esac
+for a in "$@"; do [ "$prev" = "--sub-lang" ] && lang="$a"; prev="$a"; done
+printf 'WEBVTT\n' > "`+tempDir+`/abc.$lang.vtt"
+`)
+
+	tests := []struct {
+		name         string
+		target       string
+		wantLang     string
+		wantKind     string
+		wantWarnings int
+	}{
+		{"native track", "es", "es", "", 0},
+		{"auto-translated track", "fr", "fr", CaptionsTranslated, 0},
+		{"translation unavailable", "de", "es-orig", "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := TranscriptJob{URL: "https://youtu.be/abc"}
+			_, err := downloadSubtitles(&job, "abc", Options{TempDir: tempDir, TranslateTo: tt.target}, nil)
+			if err != nil {
+				t.Fatalf("downloadSubtitles() error = %v", err)
+			}
+			if lang := job.Language; lang != tt.wantLang || job.CaptionsKind != tt.wantKind {
+				t.Errorf("downloadSubtitles() lang, kind = %q, %q, want %q, %q", lang, job.CaptionsKind, tt.wantLang, tt.wantKind)
+			}
+			if len(job.Warnings) != tt.wantWarnings {
+				t.Errorf("downloadSubtitles() warnings = %v, want %d", job.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestWorkflowState_Update_Event(t *testing.T) {
+	wf := NewWorkflow([]string{"https://youtu.be/abc", "https://youtu.be/def"}, Options{ParallelWorkers: 1})
+
+	m, _ := wf.Update(Event{Event: EventDownloadStart, Index: 0, Title: "Video 1", Status: "downloading_subtitles"})
+	wf = m.(WorkflowState)
+	if wf.Jobs[0].Status != "downloading_subtitles" || wf.Jobs[0].Title != "Video 1" {
+		t.Errorf("Update(Event) job = %+v, want status and title applied", wf.Jobs[0])
+	}
+
+	m, _ = wf.Update(JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: TranscriptJob{URL: "https://youtu.be/abc", Status: "completed"}})
+	wf = m.(WorkflowState)
+	m, _ = wf.Update(Event{Event: EventProcessingStart, Index: 0, Status: "processing_transcript"})
+	if got := m.(WorkflowState).Jobs[0].Status; got != "completed" {
+		t.Errorf("late Event overwrote finished job, status = %q, want %q", got, "completed")
+	}
+}
+
+func TestNewWorkflow_RejectsUnrecognizedURLs(t *testing.T) {
+	urls := []string{"https://youtu.be/abc", "htps://yotube.com/oops"}
+
+	wf := NewWorkflow(urls, Options{ParallelWorkers: 1})
+	if wf.Jobs[0].Status != "pending" {
+		t.Errorf("valid URL status = %q, want pending", wf.Jobs[0].Status)
+	}
+	if wf.Jobs[1].Status != "failed" || !errors.Is(wf.Jobs[1].Error, ErrUnrecognizedURL) {
+		t.Errorf("invalid URL job = %+v, want failed with ErrUnrecognizedURL", wf.Jobs[1])
+	}
+	if wf.jobsCompleted != 1 {
+		t.Errorf("jobsCompleted = %d, want rejected job counted as done", wf.jobsCompleted)
+	}
+
+	anyURL := NewWorkflow(urls, Options{ParallelWorkers: 1, AllowAnyURL: true})
+	if anyURL.Jobs[1].Status != "pending" {
+		t.Errorf("with AllowAnyURL, status = %q, want pending", anyURL.Jobs[1].Status)
+	}
+}
+
+func TestNewWorkflow_SkipsDuplicateVideos(t *testing.T) {
+	urls := []string{
+		"https://www.youtube.com/watch?v=abc",
+		"https://youtu.be/def",
+		"https://youtu.be/abc", // Same video as the first, different URL form
+	}
+
+	wf := NewWorkflow(urls, Options{ParallelWorkers: 1})
+	want := []string{"pending", "pending", "skipped (duplicate)"}
+	for i, job := range wf.Jobs {
+		if job.Status != want[i] {
+			t.Errorf("job %d status = %q, want %q", i, job.Status, want[i])
+		}
+	}
+	if wf.jobsCompleted != 1 {
+		t.Errorf("jobsCompleted = %d, want the duplicate counted as done", wf.jobsCompleted)
+	}
+
+	allowed := NewWorkflow(urls, Options{ParallelWorkers: 1, AllowDuplicates: true})
+	if allowed.Jobs[2].Status != "pending" {
+		t.Errorf("with AllowDuplicates, status = %q, want pending", allowed.Jobs[2].Status)
+	}
+}
+
+func TestResolveVideoID_BothFail(t *testing.T) {
+	installFakeYtDlp(t, "echo 'ERROR: [youtube] oops: Video unavailable' >&2; exit 1\n")
+
+	_, err := resolveVideoID(TranscriptJob{URL: "https://www.youtube.com/live/oops"}, Options{}, nil)
+	if !errors.Is(err, ErrUnrecognizedURL) || !errors.Is(err, ErrVideoUnavailable) {
+		t.Errorf("resolveVideoID() error = %v, want both the parse and the yt-dlp error", err)
+	}
+}
+
+func TestNewWorkflow_AcceptsUnparsedYouTubeURLs(t *testing.T) {
+	urls := []string{"https://www.youtube.com/live/abc", "https://www.youtube.com/playlist?list=PL1"}
 
-	t.Run("successful processing", func(t *testing.T) {
-		msg := ProcessingCompletedMsg{Err: nil}
-		newWfModel, _ := wf.Update(msg)
-		newWf := newWfModel.(WorkflowState)
+	wf := NewWorkflow(urls, Options{ParallelWorkers: 1})
+	if wf.Jobs[0].Status != "pending" {
+		t.Errorf("live URL status = %q, want pending", wf.Jobs[0].Status)
+	}
+	if wf.Jobs[1].Status != "failed" {
+		t.Errorf("unexpanded playlist URL status = %q, want failed", wf.Jobs[1].Status)
+	}
+}
 
-		if newWf.Jobs[0].Status != "completed" {
-			t.Errorf("Job status after processing: got %s, want %s", newWf.Jobs[0].Status, "completed")
+func TestNewWorkflow_ClampsWorkers(t *testing.T) {
+	urls := []string{"https://youtu.be/a", "https://youtu.be/b", "not a url"}
+	tests := []struct {
+		configured, want int
+	}{
+		{8, 2}, // More workers than jobs that pass the pre-flight
+		{2, 2},
+		{1, 1},
+		{0, 1},
+		{-3, 1},
+	}
+	for _, tt := range tests {
+		wf := NewWorkflow(urls, Options{ParallelWorkers: tt.configured})
+		if got := wf.Options.ParallelWorkers; got != tt.want {
+			t.Errorf("NewWorkflow(ParallelWorkers: %d) workers = %d, want %d", tt.configured, got, tt.want)
 		}
-		// CurrentJobIndex should advance
-		if newWf.CurrentJobIndex != 1 {
-			t.Errorf("CurrentJobIndex after processing: got %d, want %d", newWf.CurrentJobIndex, 1)
+	}
+}
+
+func TestWorkflowState_Init_AllRejected(t *testing.T) {
+	wf := NewWorkflow([]string{"not a url"}, Options{ParallelWorkers: 1})
+	if cmd := wf.Init(); cmd == nil {
+		t.Fatal("Init() returned nil, want tea.Quit when every URL was rejected")
+	}
+}
+
+func TestResolveVideoID(t *testing.T) {
+	installFakeYtDlp(t, "echo 123456789\n")
+
+	if got, err := resolveVideoID(TranscriptJob{URL: "https://youtu.be/abc"}, Options{AllowAnyURL: true}, nil); err != nil || got != "abc" {
+		t.Errorf("resolveVideoID(youtube) = %q, %v, want parsed id without asking yt-dlp", got, err)
+	}
+	if _, err := resolveVideoID(TranscriptJob{URL: "https://vimeo.com/123456789"}, Options{}, nil); !errors.Is(err, ErrUnrecognizedURL) {
+		t.Errorf("resolveVideoID(vimeo) without AllowAnyURL error = %v, want ErrUnrecognizedURL", err)
+	}
+	if got, err := resolveVideoID(TranscriptJob{URL: "https://vimeo.com/123456789"}, Options{AllowAnyURL: true}, nil); err != nil || got != "123456789" {
+		t.Errorf("resolveVideoID(vimeo) = %q, %v, want id reported by yt-dlp", got, err)
+	}
+	if got, err := resolveVideoID(TranscriptJob{URL: "https://www.youtube.com/live/123456789"}, Options{}, nil); err != nil || got != "123456789" {
+		t.Errorf("resolveVideoID(live) = %q, %v, want id reported by yt-dlp", got, err)
+	}
+	withMeta := TranscriptJob{URL: "https://vimeo.com/1", Metadata: &VideoMetadata{ID: "from-metadata"}}
+	if got, _ := resolveVideoID(withMeta, Options{AllowAnyURL: true}, nil); got != "from-metadata" {
+		t.Errorf("resolveVideoID() = %q, want id from metadata", got)
+	}
+}
+
+func TestResolveCleanedPath_OutputFile(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "nested", "mytranscript.txt")
+	job := TranscriptJob{URL: "https://youtu.be/abc", Title: "Some Title"}
+
+	got, err := resolveCleanedPath(&job, Options{CleanedDir: t.TempDir(), OutputFile: out}, nil)
+	if err != nil {
+		t.Fatalf("resolveCleanedPath() error = %v", err)
+	}
+	if got != out {
+		t.Errorf("resolveCleanedPath() = %q, want %q", got, out)
+	}
+	if info, err := os.Stat(filepath.Dir(out)); err != nil || !info.IsDir() {
+		t.Errorf("resolveCleanedPath() did not create parent directory of %s", out)
+	}
+
+	cleanedDir := t.TempDir()
+	got, err = resolveCleanedPath(&job, Options{CleanedDir: cleanedDir}, nil)
+	if err != nil {
+		t.Fatalf("resolveCleanedPath() error = %v", err)
+	}
+	if want := filepath.Join(cleanedDir, "Some-Title.txt"); got != want {
+		t.Errorf("resolveCleanedPath() = %q, want %q", got, want)
+	}
+}
+
+func TestResolveCleanedPath_Manifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.csv")
+	if err := os.WriteFile(path, []byte("https://youtu.be/abc,talks/chosen.txt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanedDir := t.TempDir()
+	opts := Options{CleanedDir: cleanedDir, Manifest: manifest}
+
+	job := TranscriptJob{URL: "https://www.youtube.com/watch?v=abc", VideoID: "abc", Title: "Some Title"}
+	want := filepath.Join(cleanedDir, "talks", "chosen.txt")
+	if got, err := resolveCleanedPath(&job, opts, nil); err != nil || got != want {
+		t.Errorf("resolveCleanedPath() = %q, %v, want %q", got, err, want)
+	}
+	if _, err := os.Stat(filepath.Dir(want)); err != nil {
+		t.Errorf("resolveCleanedPath() didn't create the manifest output's directory: %v", err)
+	}
+
+	// An unlisted video: named from its title as usual
+	other := TranscriptJob{URL: "https://youtu.be/xyz", VideoID: "xyz", Title: "Some Title"}
+	if got, err := resolveCleanedPath(&other, opts, nil); err != nil || got != filepath.Join(cleanedDir, "Some-Title.txt") {
+		t.Errorf("resolveCleanedPath(unlisted) = %q, %v, want the title-based path", got, err)
+	}
+}
+
+func TestResolveCleanedPath_Alongside(t *testing.T) {
+	library := t.TempDir()
+	media := filepath.Join(library, "My Talk [abc].mkv")
+	if err := os.WriteFile(media, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	index, err := LoadMediaIndex(library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanedDir := t.TempDir()
+	opts := Options{CleanedDir: cleanedDir, Alongside: index}
+
+	job := TranscriptJob{VideoID: "abc", Title: "Some Title"}
+	if got, err := resolveCleanedPath(&job, opts, nil); err != nil || got != filepath.Join(library, "My Talk [abc].txt") {
+		t.Errorf("resolveCleanedPath() = %q, %v, want the transcript next to the media file", got, err)
+	}
+	opts.Format = FormatJSONL
+	if got, _ := resolveCleanedPath(&job, opts, nil); got != filepath.Join(library, "My Talk [abc].jsonl") {
+		t.Errorf("resolveCleanedPath(jsonl) = %q, want the .jsonl next to the media file", got)
+	}
+
+	// No media file for the video: the cleaned directory as usual
+	opts.Format = FormatText
+	other := TranscriptJob{VideoID: "xyz", Title: "Some Title"}
+	if got, err := resolveCleanedPath(&other, opts, nil); err != nil || got != filepath.Join(cleanedDir, "Some-Title.txt") {
+		t.Errorf("resolveCleanedPath(no match) = %q, %v, want the cleaned directory", got, err)
+	}
+}
+
+func TestWriteTranscript_PreserveStylingOnlyForSubtitles(t *testing.T) {
+	dir := t.TempDir()
+	rawFile := filepath.Join(dir, "abc.en.vtt")
+	if err := os.WriteFile(rawFile, []byte(styledVTT), 0644); err != nil {
+		t.Fatal(err)
+	}
+	job := TranscriptJob{VideoID: "abc"}
+	opts := Options{Clean: CleanOptions{PreserveStyling: true}}
+	for format, want := range map[string]string{FormatText: "hello there", FormatSRT: "<i>hello</i> there"} {
+		opts.Format = format
+		outFile := filepath.Join(dir, "out-"+format)
+		if _, err := writeTranscript(rawFile, outFile, job, opts); err != nil {
+			t.Fatalf("writeTranscript(%s) error = %v", format, err)
 		}
-		if newWf.CurrentStage != "completed" { // finalizeWorkflow should set this
-			t.Errorf("Workflow stage after processing single job: got %s, want %s", newWf.CurrentStage, "completed")
+		if got, err := os.ReadFile(outFile); err != nil || !strings.Contains(string(got), want) || strings.Contains(string(got), "<c") {
+			t.Errorf("writeTranscript(%s) wrote %q, %v, want %q and no color tags", format, got, err, want)
 		}
-	})
+	}
+}
+
+func TestWorkflowState_Update_SpinnerTick(t *testing.T) {
+	wf := NewWorkflow([]string{"https://youtu.be/abc"}, Options{ParallelWorkers: 1})
+	before := wf.Spinner.View()
+
+	m, cmd := wf.Update(wf.Spinner.Tick())
+	if cmd == nil {
+		t.Error("Update(spinner.TickMsg) returned nil cmd, want the next tick scheduled")
+	}
+	if after := m.(WorkflowState).Spinner.View(); after == before {
+		t.Errorf("Update(spinner.TickMsg) did not advance spinner frame %q", before)
+	}
+}
+
+func TestWorkflowState_Update_InterleavedMessages(t *testing.T) {
+	wf := NewWorkflow([]string{"https://youtu.be/abc", "https://youtu.be/def", "https://youtu.be/ghi"}, Options{ParallelWorkers: 2})
+	done := TranscriptJob{URL: "https://youtu.be/abc", Title: "A", Status: "completed"}
+
+	// Job 0 starts, finishes, then a stale start for it and a duplicate result arrive,
+	// interleaved with progress on job 1.
+	msgs := []tea.Msg{
+		Event{Event: EventJobStart, Index: 0, Status: "fetching_title"},
+		Event{Event: EventJobStart, Index: 1, Status: "fetching_title"},
+		JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: done},
+		Event{Event: EventDownloadStart, Index: 0, Status: "downloading_subtitles"},
+		Event{Event: EventDownloadStart, Index: 1, Status: "downloading_subtitles"},
+		JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: done},
+	}
+	for _, msg := range msgs {
+		m, _ := wf.Update(msg)
+		wf = m.(WorkflowState)
+	}
 
-	t.Run("failed processing", func(t *testing.T) {
-		wfFail := newTestWorkflowState([]string{"http://example.com/video1"})
-		wfFail.CurrentJobIndex = 0
-		wfFail.Jobs[0] = TranscriptJob{URL: "http://example.com/video1", Title: "Test Video", Status: "processing_transcript"}
-		wfFail.CurrentStage = "processing"
+	if wf.Jobs[0].Status != "completed" {
+		t.Errorf("job 0 status = %q, want completed to survive late start events", wf.Jobs[0].Status)
+	}
+	if wf.Jobs[1].Status != "downloading_subtitles" {
+		t.Errorf("job 1 status = %q, want downloading_subtitles", wf.Jobs[1].Status)
+	}
+	if wf.jobsCompleted != 1 {
+		t.Errorf("jobsCompleted = %d, want 1 (duplicate result must not be counted)", wf.jobsCompleted)
+	}
+}
+
+// fakeYtDlpScript answers title fetches and writes an English VTT next to the -o template.
+const fakeYtDlpScript = `case "$*" in *"--print title"*) echo 'Fake Title'; exit 0;; esac
+for a in "$@"; do [ "$prev" = "-o" ] && out="$a"; prev="$a"; done
+printf 'WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n' > "$(dirname "$out")/abc.en.vtt"
+`
+
+func TestProcessJob_Probe(t *testing.T) {
+	// Every yt-dlp call but the probe succeeds, so only a probing job is unavailable
+	installFakeYtDlp(t, `case "$*" in *"--print availability"*) echo 'ERROR: [youtube] abc: Private video. Sign in if you'"'"'ve been granted access' >&2; exit 1;; esac
+`+fakeYtDlpScript)
+	opts := Options{TempDir: t.TempDir(), CleanedDir: t.TempDir()}
+
+	if job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts); job.Status != "completed" {
+		t.Fatalf("workJob() without probe = %q, %v, want completed", job.Status, job.Error)
+	}
+	opts.CleanedDir = t.TempDir()
+	opts.Probe = true
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Status != "unavailable" || job.Error != nil || job.ProcessedFile != "" {
+		t.Errorf("workJob() of a private video = %q, %v, file %q, want unavailable", job.Status, job.Error, job.ProcessedFile)
+	}
+}
+
+func TestRunJob_Attempts(t *testing.T) {
+	retryBackoff = 0
+	t.Cleanup(func() { retryBackoff = 2 * time.Second })
+	counter := filepath.Join(t.TempDir(), "calls")
+	// The first title fetch fails with a network error, later calls succeed
+	installFakeYtDlp(t, `if [ ! -e "`+counter+`" ]; then touch "`+counter+`"; echo 'ERROR: unable to download webpage: <urlopen error timed out>' >&2; exit 1; fi
+`+fakeYtDlpScript)
+	opts := Options{TempDir: t.TempDir(), CleanedDir: t.TempDir()}
+
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Status != "failed" || job.Attempts != 1 || job.Category != CategoryNetwork {
+		t.Errorf("workJob() without retries = %q, %d attempts, category %q, want failed once with %q", job.Status, job.Attempts, job.Category, CategoryNetwork)
+	}
+
+	os.Remove(counter)
+	opts.Retries = 2
+	job = workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Status != "completed" || job.Attempts != 2 || job.Category != "" {
+		t.Errorf("workJob() with retries = %q, %d attempts, category %q, want completed on the 2nd attempt", job.Status, job.Attempts, job.Category)
+	}
+	job = workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Attempts != 1 {
+		t.Errorf("workJob() succeeding first time took %d attempts, want 1", job.Attempts)
+	}
+}
+
+func TestRunJob_PermanentFailureNotRetried(t *testing.T) {
+	installFakeYtDlp(t, "echo 'ERROR: Private video' >&2\nexit 1\n")
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, Options{TempDir: t.TempDir(), CleanedDir: t.TempDir(), Retries: 3})
+	if job.Attempts != 1 || job.Category != CategoryUnavailable {
+		t.Errorf("workJob() = %d attempts, category %q, want 1 attempt with %q", job.Attempts, job.Category, CategoryUnavailable)
+	}
+}
+
+func TestProcessJob_PrettyNamesAndTitleSidecar(t *testing.T) {
+	installFakeYtDlp(t, fakeYtDlpScript)
+	cleanedDir := t.TempDir()
+	opts := Options{TempDir: t.TempDir(), CleanedDir: cleanedDir, PrettyNames: true, TitleSidecar: true}
 
-		testErr := errors.New("processing error")
-		msg := ProcessingCompletedMsg{Err: testErr}
-		newWfModel, _ := wfFail.Update(msg)
-		newWf := newWfModel.(WorkflowState)
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if want := filepath.Join(cleanedDir, "Fake Title.txt"); job.Status != "completed" || job.ProcessedFile != want {
+		t.Fatalf("workJob() = %q, %v, file %q, want completed at %q", job.Status, job.Error, job.ProcessedFile, want)
+	}
+	if title, err := os.ReadFile(filepath.Join(cleanedDir, "Fake Title.title")); err != nil || string(title) != "Fake Title\n" {
+		t.Errorf("title sidecar = %q, %v, want the original title", title, err)
+	}
+}
+
+func TestProcessJob_PerJobTempDir(t *testing.T) {
+	installFakeYtDlp(t, fakeYtDlpScript)
 
-		if newWf.Jobs[0].Status != "failed" {
-			t.Errorf("Job status after failed processing: got %s, want %s", newWf.Jobs[0].Status, "failed")
+	for _, keepRaw := range []bool{false, true} {
+		tempDir, cleanedDir := t.TempDir(), t.TempDir()
+		job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, Options{TempDir: tempDir, CleanedDir: cleanedDir, KeepRaw: keepRaw})
+		if job.Error != nil || job.Status != "completed" {
+			t.Fatalf("workJob(KeepRaw=%v) = %q, %v, want completed", keepRaw, job.Status, job.Error)
 		}
-		// CurrentJobIndex should advance
-		if newWf.CurrentJobIndex != 1 {
-			t.Errorf("CurrentJobIndex after failed processing: got %d, want %d", newWf.CurrentJobIndex, 1)
+
+		raw, _ := filepath.Glob(filepath.Join(tempDir, "abc-*", "abc.en.vtt"))
+		if keepRaw && len(raw) != 1 {
+			t.Errorf("workJob(KeepRaw=true) left %v, want the raw VTT in a per-job subdirectory", raw)
 		}
-		if newWf.CurrentStage != "completed" { // finalizeWorkflow should set this
-			t.Errorf("Workflow stage after failed processing single job: got %s, want %s", newWf.CurrentStage, "completed")
+		if entries, _ := os.ReadDir(tempDir); !keepRaw && len(entries) != 0 {
+			t.Errorf("workJob(KeepRaw=false) left %d entries in TempDir, want it cleaned up", len(entries))
 		}
-	})
+	}
 }
 
-func TestWorkflowState_Update_KeyMsg(t *testing.T) {
-	wf := newTestWorkflowState([]string{"http://example.com/video1"})
+func TestProcessJob_AllLangs(t *testing.T) {
+	installFakeYtDlp(t, `case "$*" in *"--print title"*) echo 'Fake Title'; exit 0;; esac
+for a in "$@"; do [ "$prev" = "-o" ] && out="$a"; prev="$a"; done
+for l in de en; do printf 'WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello %s\n' "$l" > "$(dirname "$out")/abc.$l.vtt"; done
+`)
+	cleanedDir := t.TempDir()
+	opts := Options{TempDir: t.TempDir(), CleanedDir: cleanedDir, AllLangs: true}
+
+	// German was cleaned by an earlier run, so only English is (re)written
+	existing := filepath.Join(cleanedDir, "Fake-Title.de.txt")
+	if err := os.WriteFile(existing, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
 
-	ctrlCMsg := tea.KeyMsg{Type: tea.KeyCtrlC}
-	_, cmd := wf.Update(ctrlCMsg)
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Error != nil || job.Status != "completed" {
+		t.Fatalf("workJob(AllLangs) = %q, %v, want completed", job.Status, job.Error)
+	}
+	if job.Language != "de,en" {
+		t.Errorf("workJob(AllLangs) Language = %q, want %q", job.Language, "de,en")
+	}
+	if content, _ := os.ReadFile(existing); string(content) != "old" {
+		t.Errorf("existing German transcript was overwritten: %q", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(cleanedDir, "Fake-Title.en.txt")); string(content) != "hello en\n" {
+		t.Errorf("English transcript = %q, want %q", content, "hello en\n")
+	}
 
-	if cmd == nil { // tea.Quit is a function, so it won't be nil.
-		t.Fatalf("Expected a tea.Quit command on Ctrl+C, got nil")
+	if job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts); job.Status != "skipped (exists)" {
+		t.Errorf("workJob(AllLangs) rerun status = %q, want skipped (exists)", job.Status)
 	}
-	// To truly check if it's tea.Quit, you might need to compare func pointers or use a sentinel error/type
-	// if tea.Quit were, for example, a specific error. This is a limitation of testing functions directly.
-	// A common pattern is for tea.Quit to be a specific function value.
-	// rf := reflect.ValueOf(cmd)
-	// rq := reflect.ValueOf(tea.Quit)
-	// if rf.Pointer() != rq.Pointer() {
-	// 	t.Error("Expected tea.Quit command on Ctrl+C")
-	// }
-	// The above reflection is one way, but can be brittle. Assume non-nil is a good first step.
 }
 
-func TestProcessTranscript_Stub(t *testing.T) {
-	cmd := ProcessTranscript("raw", "cleaned")
-	if cmd == nil {
-		t.Fatal("ProcessTranscript should return a command")
+func TestProcessJob_LangDirs(t *testing.T) {
+	installFakeYtDlp(t, `case "$*" in *"--print title"*) echo 'Fake Title'; exit 0;; esac
+for a in "$@"; do [ "$prev" = "-o" ] && out="$a"; prev="$a"; done
+for l in de en; do printf 'WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello %s\n' "$l" > "$(dirname "$out")/abc.$l.vtt"; done
+`)
+	cleanedDir := t.TempDir()
+	opts := Options{TempDir: t.TempDir(), CleanedDir: cleanedDir, AllLangs: true, LangDirs: true}
+
+	// German was cleaned by an earlier run, so only English is written
+	existing := filepath.Join(cleanedDir, "de", "Fake-Title.txt")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatal(err)
 	}
-	msg := cmd() // Execute the command
-	if _, ok := msg.(ProcessingCompletedMsg); !ok {
-		t.Errorf("ProcessTranscript cmd() did not return ProcessingCompletedMsg, got %T", msg)
+	if err := os.WriteFile(existing, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Error != nil || job.Status != "completed" {
+		t.Fatalf("workJob(LangDirs) = %q, %v, want completed", job.Status, job.Error)
 	}
-	// Check if error is nil as per stub
-	if msg.(ProcessingCompletedMsg).Err != nil {
-		t.Errorf("ProcessTranscript stub returned error: %v", msg.(ProcessingCompletedMsg).Err)
+	english := filepath.Join(cleanedDir, "en", "Fake-Title.txt")
+	if want := []string{existing, english}; !reflect.DeepEqual(job.ProcessedFiles, want) {
+		t.Errorf("workJob(LangDirs) ProcessedFiles = %q, want %q", job.ProcessedFiles, want)
+	}
+	if content, _ := os.ReadFile(existing); string(content) != "old" {
+		t.Errorf("existing German transcript was overwritten: %q", content)
+	}
+	if content, _ := os.ReadFile(english); string(content) != "hello en\n" {
+		t.Errorf("English transcript = %q, want %q", content, "hello en\n")
 	}
 }
 
-func TestWorkflowState_View(t *testing.T) {
-	t.Run("no jobs", func(t *testing.T) {
-		wf := newTestWorkflowState([]string{})
-		view := wf.View()
-		if view != "No URLs provided. Exiting." {
-			t.Errorf("View for no jobs: got %q, want %q", view, "No URLs provided. Exiting.")
-		}
-	})
+func TestProcessJob_TitleFallsBackToID(t *testing.T) {
+	// yt-dlp prints no title, but the captions download fine
+	installFakeYtDlp(t, `case "$*" in *"--print title"*) exit 0;; esac
+for a in "$@"; do [ "$prev" = "-o" ] && out="$a"; prev="$a"; done
+printf 'WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n' > "$(dirname "$out")/abc.en.vtt"
+`)
+	cleanedDir := t.TempDir()
 
-	t.Run("fetching title state", func(t *testing.T) {
-		wf := newTestWorkflowState([]string{"http://example.com"})
-		// Init normally sets this up, but we can set manually for focused View test
-		wf.CurrentJobIndex = 0
-		wf.Jobs[0].Status = "fetching_title"
-		wf.Jobs[0].Title = "" // Title is initially empty when fetching
-		wf.CurrentStage = "fetching_title"
-		view := wf.View()
-		// Expected view now directly uses "Fetching title..." due to display.go change
-		expectedViewSegment := "[1/1] Fetching title..."
-		if !strings.Contains(view, expectedViewSegment) {
-			t.Errorf("View for fetching_title: got %q, want to contain %q", view, expectedViewSegment)
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, Options{TempDir: t.TempDir(), CleanedDir: cleanedDir})
+	if job.Status != "completed" || job.Title != "abc" || !job.TitleFellBack {
+		t.Errorf("workJob() = %q, title %q, TitleFellBack %v, want completed with the ID as title", job.Status, job.Title, job.TitleFellBack)
+	}
+	if want := filepath.Join(cleanedDir, "abc.txt"); job.ProcessedFile != want {
+		t.Errorf("workJob() wrote %q, want %q", job.ProcessedFile, want)
+	}
+	if len(job.Warnings) != 1 {
+		t.Errorf("workJob() warnings = %q, want one about the title", job.Warnings)
+	}
+}
+
+func TestProcessJob_NoSubtitles(t *testing.T) {
+	// yt-dlp succeeds but writes nothing for a video without captions
+	installFakeYtDlp(t, `case "$*" in *"--print title"*) echo 'Silent Film'; exit 0;; esac
+exit 0
+`)
+
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, Options{TempDir: t.TempDir(), CleanedDir: t.TempDir()})
+	if job.Status != "no_subtitles" || job.Error != nil {
+		t.Errorf("workJob() = %q, %v, want no_subtitles without an error", job.Status, job.Error)
+	}
+	if !isTerminalStatus(job.Status) {
+		t.Errorf("isTerminalStatus(%q) = false, want true", job.Status)
+	}
+}
+
+func TestProcessJob_ManualOnly(t *testing.T) {
+	installFakeRunner(t, subtitleRunner{})
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, Options{TempDir: t.TempDir(), CleanedDir: t.TempDir(), ManualOnly: true})
+	if job.Status != "completed" || job.CaptionsKind != CaptionsManual {
+		t.Errorf("workJob(ManualOnly) = %q (%v), CaptionsKind %q, want completed from %q captions", job.Status, job.Error, job.CaptionsKind, CaptionsManual)
+	}
+}
+
+func TestProcessJob_MaxBytes(t *testing.T) {
+	installFakeRunner(t, subtitleRunner{})
+	cleanedDir := t.TempDir()
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, Options{TempDir: t.TempDir(), CleanedDir: cleanedDir, MaxBytes: 10})
+	if job.Status != "oversized" || job.Error != nil || job.ProcessedFile != "" {
+		t.Errorf("workJob(MaxBytes 10) = %q, %v, file %q, want oversized without an error or file", job.Status, job.Error, job.ProcessedFile)
+	}
+	if len(job.Warnings) != 1 || !strings.Contains(job.Warnings[0], "over the 10 B limit") {
+		t.Errorf("workJob(MaxBytes 10) warnings = %q, want the size limit named", job.Warnings)
+	}
+	if entries, _ := os.ReadDir(cleanedDir); len(entries) != 0 {
+		t.Errorf("workJob(MaxBytes 10) wrote %d files, want none", len(entries))
+	}
+
+	job = workJob(TranscriptJob{URL: "https://youtu.be/abc"}, Options{TempDir: t.TempDir(), CleanedDir: cleanedDir, MaxBytes: 1 << 20})
+	if job.Status != "completed" {
+		t.Errorf("workJob(MaxBytes 1 MB) = %q, %v, want completed", job.Status, job.Error)
+	}
+}
+
+func TestProcessJob_NoRawFiles(t *testing.T) {
+	installFakeRunner(t, subtitleRunner{})
+	tempDir, cleanedDir := t.TempDir(), t.TempDir()
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, Options{TempDir: tempDir, CleanedDir: cleanedDir, NoRawFiles: true})
+	if job.Status != "completed" {
+		t.Fatalf("workJob(NoRawFiles) = %q (%v), want completed", job.Status, job.Error)
+	}
+	if got, _ := os.ReadFile(job.ProcessedFile); string(got) != "hello\n" {
+		t.Errorf("workJob(NoRawFiles) transcript = %q, want %q", got, "hello\n")
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Errorf("workJob(NoRawFiles) left %d entries in the temp dir, want none", len(entries))
+	}
+	if job.RawBytes == 0 || job.CleanedBytes != int64(len("hello\n")) || job.Stats == nil {
+		t.Errorf("workJob(NoRawFiles) bytes = %d -> %d, stats %v, want both counted and stats", job.RawBytes, job.CleanedBytes, job.Stats)
+	}
+
+	// yt-dlp writes nothing for a video without the language
+	installFakeRunner(t, runnerFunc(func(args []string) ([]byte, error) {
+		if strings.Contains(strings.Join(args, " "), "-o -") {
+			return nil, nil
 		}
-	})
+		return subtitleRunner{}.Run(context.Background(), io.Discard, args...)
+	}))
+	job = workJob(TranscriptJob{URL: "https://youtu.be/abc"}, Options{CleanedDir: t.TempDir(), NoRawFiles: true})
+	if job.Status != "no_subtitles" {
+		t.Errorf("workJob(NoRawFiles) without subtitles = %q (%v), want no_subtitles", job.Status, job.Error)
+	}
+}
 
-	t.Run("downloading state", func(t *testing.T) {
-		wf := newTestWorkflowState([]string{"http://example.com"})
-		wf.CurrentJobIndex = 0
-		wf.Jobs[0].Status = "downloading_subtitles"
-		wf.Jobs[0].Title = "My Video"
-		wf.CurrentStage = "downloading"
-		view := wf.View()
-		if !strings.Contains(view, "[1/1] Downloading subtitles for: My Video") {
-			t.Errorf("View for downloading: got %q, want to contain %q", view, "[1/1] Downloading subtitles for: My Video")
+func TestProcessJob_Timings(t *testing.T) {
+	// Each yt-dlp call takes a little while, so the title and download phases can't round to zero
+	installFakeRunner(t, runnerFunc(func(args []string) ([]byte, error) {
+		time.Sleep(5 * time.Millisecond)
+		return subtitleRunner{}.Run(context.Background(), io.Discard, args...)
+	}))
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, Options{TempDir: t.TempDir(), CleanedDir: t.TempDir()})
+	if job.Status != "completed" {
+		t.Fatalf("workJob() = %q (%v), want completed", job.Status, job.Error)
+	}
+	if job.Timings.Title < 5*time.Millisecond || job.Timings.Download < 5*time.Millisecond || job.Timings.Processing <= 0 {
+		t.Errorf("workJob() timings = %+v, want every phase timed", job.Timings)
+	}
+
+	// An archived video does no work, so it has no timings
+	archive, _ := LoadArchive(filepath.Join(t.TempDir(), "archive.txt"))
+	archive.AppendArchive("abcdefghijk")
+	job = workJob(TranscriptJob{URL: "https://youtu.be/abcdefghijk"}, Options{Archive: archive})
+	if job.Timings != (Timings{}) {
+		t.Errorf("workJob(archived) timings = %+v, want none", job.Timings)
+	}
+}
+
+func TestInDurationRange(t *testing.T) {
+	opts := Options{MinDuration: 10 * time.Minute, MaxDuration: time.Hour}
+	tests := []struct {
+		seconds float64
+		want    bool
+	}{
+		{0, true}, // Unknown duration
+		{599, false},
+		{600, true},
+		{3600, true},
+		{3601, false},
+	}
+	for _, tt := range tests {
+		if got := inDurationRange(tt.seconds, opts); got != tt.want {
+			t.Errorf("inDurationRange(%v) = %v, want %v", tt.seconds, got, tt.want)
 		}
-	})
+	}
+	if !inDurationRange(99999, Options{}) {
+		t.Error("inDurationRange() without filters = false, want true")
+	}
+}
 
-	t.Run("completed state", func(t *testing.T) {
-		wf := newTestWorkflowState([]string{"http://example.com"})
-		wf.CurrentJobIndex = 1 // All jobs done
-		wf.TotalJobs = 1
-		wf.CurrentStage = "completed"
-		view := wf.View()
-		if !strings.Contains(view, "✅ All done!") {
-			t.Errorf("View for completed: got %q, want to contain %q", view, "✅ All done!")
+func TestProcessJob_DurationFilter(t *testing.T) {
+	installFakeYtDlp(t, `case "$*" in *--dump-json*) echo '{"id": "abc", "title": "Short Clip", "duration": 42}'; exit 0;; esac
+echo "unexpected yt-dlp call: $*" >&2; exit 1
+`)
+
+	opts := Options{TempDir: t.TempDir(), CleanedDir: t.TempDir(), Metadata: true, MinDuration: time.Minute}
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Status != "filtered" || job.Error != nil || job.Title != "Short Clip" {
+		t.Errorf("workJob() = %q %q, %v, want filtered before downloading", job.Title, job.Status, job.Error)
+	}
+}
+
+func TestUploadedSince(t *testing.T) {
+	opts := Options{Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tests := map[string]bool{
+		"20231231": false,
+		"20240101": true, // The cutoff day itself is included
+		"20240615": true,
+		"":         true, // Unknown date
+		"NA":       true,
+	}
+	for date, want := range tests {
+		if got := uploadedSince(date, opts); got != want {
+			t.Errorf("uploadedSince(%q) = %v, want %v", date, got, want)
 		}
-	})
+	}
+	if !uploadedSince("19990101", Options{}) {
+		t.Error("uploadedSince() without a cutoff = false, want true")
+	}
+}
 
-	t.Run("single job failed state", func(t *testing.T) {
-		wf := newTestWorkflowState([]string{"http://example.com"})
-		wf.CurrentJobIndex = 0
-		wf.Jobs[0].Status = "failed"
-		wf.Jobs[0].Title = "Failed Video"
-		wf.Jobs[0].Error = errors.New("epic fail")
-		wf.CurrentStage = "failed"
-		view := wf.View()
-		if !strings.Contains(view, "❌ Error processing Failed Video:") {
-			t.Errorf("View for failed job: got %q, want to contain %q", view, "❌ Error processing Failed Video:")
+func TestProcessJob_SinceFilter(t *testing.T) {
+	installFakeYtDlp(t, `case "$*" in *--dump-json*) echo '{"id": "abc", "title": "Old Upload", "upload_date": "20230301"}'; exit 0;; esac
+echo "unexpected yt-dlp call: $*" >&2; exit 1
+`)
+
+	// Since alone makes the worker fetch metadata for the upload date
+	opts := Options{TempDir: t.TempDir(), CleanedDir: t.TempDir(), Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Status != "filtered" || job.Error != nil {
+		t.Errorf("workJob() = %q, %v, want filtered before downloading", job.Status, job.Error)
+	}
+}
+
+func TestWorkflowState_Update_CombinesInInputOrder(t *testing.T) {
+	jobs := writeTranscripts(t, "one", "two")
+	path := filepath.Join(t.TempDir(), "all.md")
+	combined, err := NewCombinedWriter(path, nil, CleanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var model tea.Model = NewWorkflow([]string{jobs[0].URL, jobs[1].URL}, Options{ParallelWorkers: 2, Combined: combined})
+	model, _ = model.Update(JobProcessingResult{OriginalJobIndex: 1, ProcessedJob: jobs[1]})
+	model, _ = model.Update(JobProcessingResult{OriginalJobIndex: 0, ProcessedJob: jobs[0]})
+	if err := combined.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got, want := readCombined(t, path), "## Video 1\n\none\n\n## Video 2\n\ntwo\n"; got != want {
+		t.Errorf("combined transcript = %q, want %q", got, want)
+	}
+}
+
+func TestWorkflowState_PercentComplete(t *testing.T) {
+	wf := NewWorkflow([]string{"https://youtu.be/abc", "https://youtu.be/def"}, Options{ParallelWorkers: 2})
+	if got := wf.percentComplete(); got != 0 {
+		t.Errorf("percentComplete() before any progress = %v, want 0", got)
+	}
+
+	m, cmd := wf.Update(Event{Event: EventDownloadStart, Index: 0, Status: "downloading_subtitles"})
+	wf = m.(WorkflowState)
+	if cmd == nil {
+		t.Error("Update(Event) returned no command, want the progress bar to move")
+	}
+	m, _ = wf.Update(JobProcessingResult{OriginalJobIndex: 1, ProcessedJob: TranscriptJob{URL: "https://youtu.be/def", Status: "completed"}})
+	wf = m.(WorkflowState)
+
+	// Job 0 is a fifth of the way through, job 1 is done: (0.2 + 1) / 2
+	if got, want := wf.percentComplete(), 0.6; got < want-1e-9 || got > want+1e-9 {
+		t.Errorf("percentComplete() = %v, want %v", got, want)
+	}
+}
+
+func TestProcessJob_RefreshOlderThan(t *testing.T) {
+	installFakeYtDlp(t, fakeYtDlpScript)
+	cleanedDir := t.TempDir()
+	existing := filepath.Join(cleanedDir, "Fake-Title.txt")
+	if err := os.WriteFile(existing, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{TempDir: t.TempDir(), CleanedDir: cleanedDir, RefreshOlderThan: 24 * time.Hour}
+
+	// Written just now, so still fresh
+	if job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts); job.Status != "skipped (exists)" {
+		t.Errorf("workJob() on a fresh transcript = %q, want skipped (exists)", job.Status)
+	}
+
+	weekAgo := time.Now().Add(-7 * 24 * time.Hour)
+	if err := os.Chtimes(existing, weekAgo, weekAgo); err != nil {
+		t.Fatal(err)
+	}
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Status != "completed" || !job.Refreshed {
+		t.Errorf("workJob() on a stale transcript = %q, refreshed %v, want completed and refreshed", job.Status, job.Refreshed)
+	}
+	if content, _ := os.ReadFile(existing); string(content) != "hello\n" {
+		t.Errorf("stale transcript = %q, want it re-downloaded", content)
+	}
+}
+
+func TestProcessJob_Archived(t *testing.T) {
+	installFakeYtDlp(t, "#!/bin/sh\necho 'yt-dlp should not run' >&2\nexit 1\n")
+	archive, err := LoadArchive(filepath.Join(t.TempDir(), "archive.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.AppendArchive("abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, Options{CleanedDir: t.TempDir(), Archive: archive})
+	if job.Status != "skipped (archived)" || job.Error != nil || job.VideoID != "abc" {
+		t.Errorf("workJob() = %q, %v, id %q, want skipped (archived) without calling yt-dlp", job.Status, job.Error, job.VideoID)
+	}
+
+	// Offline, an archived video is still skipped, ahead of the probe and metadata fetch
+	installFakeRunner(t, &fakeRunner{stderr: "ERROR: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>\n", err: errors.New("exit status 1")})
+	opts := Options{CleanedDir: t.TempDir(), Archive: archive, Probe: true, Metadata: true}
+	if job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts); job.Status != "skipped (archived)" || job.Error != nil {
+		t.Errorf("workJob() offline = %q, %v, want skipped (archived)", job.Status, job.Error)
+	}
+	if job := workJob(TranscriptJob{URL: "https://youtu.be/def"}, opts); !errors.Is(job.Error, ErrNetwork) {
+		t.Errorf("workJob() offline of a video not in the archive error = %v, want ErrNetwork", job.Error)
+	}
+}
+
+func TestProcessJob_ChecksumUnchanged(t *testing.T) {
+	installFakeYtDlp(t, fakeYtDlpScript)
+	cleanedDir := t.TempDir()
+	existing := filepath.Join(cleanedDir, "Fake-Title.txt")
+	opts := Options{TempDir: t.TempDir(), CleanedDir: cleanedDir, RefreshOlderThan: time.Hour, Clean: CleanOptions{Checksum: true}}
+
+	if job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts); job.Status != "completed" {
+		t.Fatalf("first workJob() = %q, %v, want completed", job.Status, job.Error)
+	}
+	weekAgo := time.Now().Add(-7 * 24 * time.Hour)
+	if err := os.Chtimes(existing, weekAgo, weekAgo); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stale, so it is downloaded again, but the content hasn't changed
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Status != "skipped (unchanged)" || job.Error != nil || job.ProcessedFile != existing {
+		t.Errorf("workJob() = %q, %v, file %q, want skipped (unchanged)", job.Status, job.Error, job.ProcessedFile)
+	}
+	if info, err := os.Stat(existing); err != nil || !info.ModTime().Equal(weekAgo) {
+		t.Errorf("unchanged transcript was rewritten (mtime %v)", info.ModTime())
+	}
+}
+
+func TestProcessJob_CountsBytes(t *testing.T) {
+	installFakeYtDlp(t, fakeYtDlpScript)
+	opts := Options{TempDir: t.TempDir(), CleanedDir: t.TempDir()}
+
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Status != "completed" {
+		t.Fatalf("workJob() = %q, %v, want completed", job.Status, job.Error)
+	}
+	info, err := os.Stat(job.ProcessedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.CleanedBytes != info.Size() || job.RawBytes <= job.CleanedBytes {
+		t.Errorf("workJob() counted %d raw, %d cleaned bytes, want more raw than the %d-byte transcript", job.RawBytes, job.CleanedBytes, info.Size())
+	}
+}
+
+func TestProcessSingleTranscript_FinalNewline(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.txt")
+	for vtt, want := range map[string]string{
+		"WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n\n00:00:01.000 --> 00:00:02.000\nworld\n": "hello\nworld\n",
+		"WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello":                                           "hello\n",
+	} {
+		raw := filepath.Join(dir, "abc.en.vtt")
+		if err := os.WriteFile(raw, []byte(vtt), 0644); err != nil {
+			t.Fatal(err)
 		}
-		if !strings.Contains(view, "epic fail") {
-			t.Errorf("View for failed job: got %q, want to contain %q", view, "epic fail")
+		if _, err := ProcessSingleTranscript(raw, out, CueOptions{}, CleanOptions{}); err != nil {
+			t.Fatalf("ProcessSingleTranscript() error = %v", err)
 		}
-	})
+		if got, _ := os.ReadFile(out); string(got) != want {
+			t.Errorf("ProcessSingleTranscript() of %q wrote %q, want %q", vtt, got, want)
+		}
+	}
+}
+
+func TestProcessSingleTranscript_Empty(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "abc.en.vtt")
+	if err := os.WriteFile(raw, []byte("WEBVTT\n\n00:00:00.000 --> 00:00:05.000\n[Music]\n\n00:00:05.000 --> 00:00:09.000\n[Music]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	music := []*regexp.Regexp{regexp.MustCompile(`\[Music\]`)}
+
+	out := filepath.Join(dir, "out.txt")
+	if _, err := ProcessSingleTranscript(raw, out, CueOptions{}, CleanOptions{StripPatterns: music}); !errors.Is(err, ErrEmptyTranscript) {
+		t.Fatalf("ProcessSingleTranscript() error = %v, want ErrEmptyTranscript", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("ProcessSingleTranscript() wrote %s, want no empty file (stat error %v)", out, err)
+	}
+
+	if _, err := ProcessSingleTranscript(raw, out, CueOptions{}, CleanOptions{StripPatterns: music, AllowEmpty: true}); !errors.Is(err, ErrEmptyTranscript) {
+		t.Fatalf("ProcessSingleTranscript(AllowEmpty) error = %v, want ErrEmptyTranscript", err)
+	}
+	if got, err := os.ReadFile(out); err != nil || len(got) != 0 {
+		t.Errorf("ProcessSingleTranscript(AllowEmpty) wrote %q, %v, want an empty file", got, err)
+	}
+}
+
+func TestProcessJob_Empty(t *testing.T) {
+	installFakeYtDlp(t, `for a in "$@"; do [ "$prev" = "-o" ] && out="$a"; prev="$a"; done
+case "$*" in *"--print title"*) echo "Only Music"; exit 0;; esac
+printf 'WEBVTT\n\n00:00:00.000 --> 00:00:05.000\n[Music]\n' > "$(dirname "$out")/abc.en.vtt"
+`)
+	opts := Options{CleanedDir: t.TempDir(), TempDir: t.TempDir(), Clean: CleanOptions{StripPatterns: []*regexp.Regexp{regexp.MustCompile(`\[Music\]`)}}}
+
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Status != "empty" || job.Error != nil || job.ProcessedFile != "" {
+		t.Errorf("workJob() = %q %v file=%q, want empty with no error and no file", job.Status, job.Error, job.ProcessedFile)
+	}
+	if summary := (ProgressView{}).RenderSummary([]TranscriptJob{job}); !strings.Contains(summary, "nothing left after cleaning (1): Only Music") {
+		t.Errorf("RenderSummary() = %q, want the empty transcript listed", summary)
+	}
+}
+
+func TestProcessSingleTranscript_Checksum(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "abc.en.vtt")
+	if err := os.WriteFile(raw, []byte("WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.txt")
+	opts := CleanOptions{Checksum: true}
+
+	if _, err := ProcessSingleTranscript(raw, out, CueOptions{}, opts); err != nil {
+		t.Fatalf("ProcessSingleTranscript() error = %v", err)
+	}
+	sidecar, err := os.ReadFile(out + ".sha256")
+	if want := ContentChecksum("hello\n") + "  out.txt\n"; err != nil || string(sidecar) != want {
+		t.Errorf("checksum sidecar = %q, %v, want %q", sidecar, err, want)
+	}
+	if _, err := ProcessSingleTranscript(raw, out, CueOptions{}, opts); !errors.Is(err, ErrUnchanged) {
+		t.Errorf("ProcessSingleTranscript() of identical content error = %v, want ErrUnchanged", err)
+	}
+
+	// A transcript edited since it was written no longer matches, so it is rewritten
+	if err := os.WriteFile(out, []byte("edited by hand"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProcessSingleTranscript(raw, out, CueOptions{}, opts); err != nil {
+		t.Errorf("ProcessSingleTranscript() after an edit error = %v, want it rewritten", err)
+	}
+	if _, err := ProcessSingleTranscript(raw, out, CueOptions{}, CleanOptions{}); err != nil {
+		t.Errorf("ProcessSingleTranscript() without Checksum error = %v, want it always written", err)
+	}
+}
+
+func TestProcessSingleTranscript_OutputEncoding(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "abc.en.vtt")
+	if err := os.WriteFile(raw, []byte("WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n\n00:00:01.000 --> 00:00:02.000\nworld\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "out.txt")
+	if _, err := ProcessSingleTranscript(raw, out, CueOptions{}, CleanOptions{BOM: true, Newline: NewlineCRLF}); err != nil {
+		t.Fatalf("ProcessSingleTranscript() error = %v", err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\xEF\xBB\xBFhello\r\nworld\r\n"; string(got) != want {
+		t.Errorf("written bytes = %q, want %q", got, want)
+	}
 }
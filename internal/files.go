@@ -1,11 +1,20 @@
 package internal
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
 )
 
 var invalidPathChars = regexp.MustCompile(`[^a-zA-Z0-9-_\.]+`)
@@ -48,6 +57,199 @@ func WriteTextFile(path string, content string) error {
 	return os.WriteFile(path, []byte(content), 0644)
 }
 
+// WriteTextFileAtomic is WriteTextFile for outputs whose existence means they
+// are complete, like transcripts the skip-existing check trusts: the content
+// goes to a temporary file in the same directory, which is renamed over path
+// only once fully written. A crash or error midway leaves path as it was.
+func WriteTextFileAtomic(path string, content string) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	})
+}
+
+// writeFileAtomic creates path with the content write produces, via a
+// temporary file renamed into place once write and the flush to disk succeed.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	err = write(tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644) // CreateTemp makes the file private
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// Line endings accepted by CleanOptions.Newline.
+const (
+	NewlineLF   = "lf"
+	NewlineCRLF = "crlf"
+)
+
+// Newlines lists the supported output line endings.
+var Newlines = []string{NewlineLF, NewlineCRLF}
+
+// withFinalNewline ends non-empty text with exactly one newline, as text
+// files conventionally do, and leaves empty text empty.
+func withFinalNewline(content string) string {
+	content = strings.TrimRight(content, "\n")
+	if content == "" {
+		return ""
+	}
+	return content + "\n"
+}
+
+// EncodeOutput applies the output-only settings in opts to a finished
+// transcript just before it is written: line endings, then an optional
+// UTF-8 byte order mark. Processing itself always works on plain LF text.
+func EncodeOutput(content string, opts CleanOptions) string {
+	if opts.Newline == NewlineCRLF {
+		content = strings.ReplaceAll(content, "\n", "\r\n")
+	}
+	if opts.BOM {
+		content = "\uFEFF" + content
+	}
+	return content
+}
+
+// checksumExt is appended to a transcript's path to name its checksum sidecar.
+const checksumExt = ".sha256"
+
+// ErrUnchanged reports that a transcript was not rewritten because its
+// checksum sidecar shows the file already holds exactly that content.
+var ErrUnchanged = errors.New("transcript unchanged")
+
+// ErrEmptyTranscript reports that cleaning left nothing of a subtitle file
+// (e.g. captions that were all music cues), so there is no transcript worth
+// writing unless CleanOptions.AllowEmpty asks for one anyway.
+var ErrEmptyTranscript = errors.New("cleaned transcript is empty")
+
+// ContentChecksum returns the hex-encoded SHA-256 of content.
+func ContentChecksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// WriteChecksumFile records sum as the checksum of the file at path, in a
+// sidecar next to it. The sidecar uses sha256sum's format, so
+// `sha256sum -c` can verify the file.
+func WriteChecksumFile(path, sum string) error {
+	return WriteTextFile(path+checksumExt, sum+"  "+filepath.Base(path)+"\n")
+}
+
+// isUnchanged reports whether the file at path already holds content, going
+// by its checksum sidecar and size rather than reading the file back.
+func isUnchanged(path, content string) bool {
+	recorded, err := ReadTextFile(path + checksumExt)
+	if err != nil {
+		return false
+	}
+	sum, _, _ := strings.Cut(strings.TrimSpace(recorded), " ")
+	info, err := os.Stat(path)
+	return err == nil && sum == ContentChecksum(content) && info.Size() == int64(len(content))
+}
+
+// archiveExtractor is the extractor name written before each id in an
+// archive file, matching the lines yt-dlp's --download-archive writes.
+const archiveExtractor = "youtube"
+
+// Archive is the set of video ids recorded in an archive file, like yt-dlp's
+// download archive: videos in it are skipped, and each newly processed video
+// is appended. Workers share one Archive, so it is safe for concurrent use.
+type Archive struct {
+	path string
+	mu   sync.Mutex
+	ids  map[string]bool
+}
+
+// LoadArchive reads the archive file at path; a missing file is an empty
+// archive, created on the first append. Lines are "<extractor> <id>" as
+// yt-dlp writes them, though a bare id is accepted too, so an existing
+// yt-dlp archive can be shared.
+func LoadArchive(path string) (*Archive, error) {
+	archive := &Archive{path: path, ids: make(map[string]bool)}
+	content, err := ReadTextFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return archive, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			archive.ids[fields[len(fields)-1]] = true
+		}
+	}
+	return archive, nil
+}
+
+// Contains reports whether videoID is in the archive. A nil Archive is empty.
+func (a *Archive) Contains(videoID string) bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.ids[videoID]
+}
+
+// AppendArchive records videoID in the archive and appends it to the archive
+// file, unless it is already there. Appends are serialized, so concurrent
+// workers never interleave their lines.
+func (a *Archive) AppendArchive(videoID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.ids[videoID] {
+		return nil
+	}
+	file, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	_, err = fmt.Fprintf(file, "%s %s\n", archiveExtractor, videoID)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to append to archive: %w", err)
+	}
+	a.ids[videoID] = true
+	return nil
+}
+
+// WriteMetadataFile writes video metadata as indented JSON to path
+func WriteMetadataFile(path string, meta VideoMetadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteTextFile(path, string(data)+"\n")
+}
+
+// transcriptBase returns a transcript path without its output extension
+// (".txt", or ".words.json" for word timings), for naming files next to it.
+func transcriptBase(path string) string {
+	if base, ok := strings.CutSuffix(path, wordsJSONExt); ok {
+		return base
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
 // ReadTextFile reads a text file and returns its content
 func ReadTextFile(path string) (string, error) {
 	bytes, err := os.ReadFile(path)
@@ -57,32 +259,83 @@ func ReadTextFile(path string) (string, error) {
 	return string(bytes), nil
 }
 
-// EnsureDirectories ensures that the required directories exist.
+// EnsureDirectories ensures that the required directories exist and can be
+// written to, so a permissions problem fails the run before any download
+// rather than deep inside each job. An empty tempDir (nothing is downloaded
+// to disk) is skipped.
 func EnsureDirectories(tempDir, cleanedDir string) error {
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
+	for _, dir := range []string{tempDir, cleanedDir} {
+		if dir == "" {
+			continue
+		}
+		if err := CheckWritable(dir); err != nil {
+			return fmt.Errorf("output directory %s is not writable: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// CheckWritable creates dir if needed and checks that a file can be written
+// into it, removing the probe file again.
+func CheckWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
-	if err := os.MkdirAll(cleanedDir, 0755); err != nil {
+	probe, err := os.CreateTemp(dir, ".yt-tx-write-test-*")
+	if err != nil {
 		return err
 	}
-	return nil
+	name := probe.Name()
+	_, err = probe.WriteString("ok")
+	if closeErr := probe.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(name); err == nil {
+		err = removeErr
+	}
+	return err
 }
 
-// CleanDirectories removes all files from the temporary directory.
-// The cleaned directory is no longer wiped.
-func CleanDirectories(tempDir, cleanedDir string) error {
-	if err := os.RemoveAll(tempDir); err != nil {
+// EmptyDirectory removes everything inside dir but keeps dir itself. A
+// missing dir is already empty.
+func EmptyDirectory(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
 		return err
 	}
-	if err := EnsureDirectories(tempDir, cleanedDir); err != nil {
-		return err
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// DefaultMaxFilename is the length SanitizeFilename caps names at.
+const DefaultMaxFilename = 100
+
+// maxFilenameBytes is the longest single path component common filesystems
+// (ext4, APFS, NTFS) accept.
+const maxFilenameBytes = 255
+
+// longestOutputSuffix is the longest extension a transcript or a file next to
+// it is written with (word timings), which must still fit within maxFilenameBytes.
+const longestOutputSuffix = len(wordsJSONExt)
+
 // SanitizeFilename replaces or removes characters that are typically problematic in filenames.
 // This is a basic version; more robust sanitization might be needed depending on OS and filesystems.
 func SanitizeFilename(name string) string {
+	return SanitizeFilenameN(name, DefaultMaxFilename)
+}
+
+// SanitizeFilenameN is SanitizeFilename with a configurable length cap. A
+// non-positive max means DefaultMaxFilename, and max is clamped so the name
+// plus any extension we write stays within filesystem limits. On every OS the
+// result can be copied to Windows: it never starts or ends with a dot, and a
+// Windows device name such as CON gets a "_" prefix.
+func SanitizeFilenameN(name string, max int) string {
 	// Replace common separators or problematic chars with hyphen
 	name = strings.ReplaceAll(name, " ", "-")
 	name = strings.ReplaceAll(name, "/", "-")
@@ -101,37 +354,223 @@ func SanitizeFilename(name string) string {
 	sanitized = multipleUnderscores.ReplaceAllString(sanitized, "_")
 	sanitized = multipleDots.ReplaceAllString(sanitized, ".")
 
-	// Trim leading/trailing hyphens or underscores
-	sanitized = strings.Trim(sanitized, "-_")
+	// Trim leading/trailing hyphens, underscores and dots (hidden files, and
+	// names Windows can't create)
+	sanitized = strings.Trim(sanitized, "-_.")
 
-	// Limit length (optional, but good practice)
-	maxLength := 100 // Arbitrary limit
+	// Limit length (sanitized is plain ASCII, so slicing by byte is safe)
+	maxLength := max
+	if maxLength <= 0 {
+		maxLength = DefaultMaxFilename
+	}
+	maxLength = min(maxLength, maxFilenameBytes-longestOutputSuffix)
 	if len(sanitized) > maxLength {
 		sanitized = sanitized[:maxLength]
 		// Ensure it doesn't end mid-UTF8 char if cutting aggressively; simple slice is okay for basic ASCII/common UTF-8
-		sanitized = strings.TrimRight(sanitized, "-_") // Clean up again if cut left a trailing hyphen
+		sanitized = strings.TrimRight(sanitized, "-_.") // Clean up again if cut left a trailing hyphen
 	}
 	if sanitized == "" {
 		return "default_filename"
 	}
+	if base, _, _ := strings.Cut(sanitized, "."); windowsReserved[strings.ToUpper(base)] {
+		sanitized = "_" + sanitized
+	}
 	return sanitized
 }
 
+// windowsReserved are the device names Windows refuses as a file's base name.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// PrettyFilename is SanitizeFilenameN for readable names: spaces, case,
+// punctuation and non-ASCII letters are kept, and only the characters the
+// current OS can't have in a filename are stripped.
+func PrettyFilename(name string, max int) string {
+	return prettyFilename(name, max, runtime.GOOS)
+}
+
+// prettyFilename is PrettyFilename for the filesystem rules of goos. A '/'
+// and control characters are illegal everywhere; Windows also rejects
+// <>:"\|?*, names ending in a dot or space, and reserved device names. The
+// name is trimmed of surrounding spaces and dots (so it is never hidden) and
+// capped at max bytes without splitting a character.
+func prettyFilename(name string, max int, goos string) string {
+	illegal := "/"
+	if goos == "windows" {
+		illegal = `<>:"/\|?*`
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' ' // Tabs and newlines are spaces in a title
+		}
+		if unicode.IsControl(r) || strings.ContainsRune(illegal, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Join(strings.Fields(name), " ") // Stripping can leave doubled spaces
+	name = strings.Trim(name, " .")
+
+	maxLength := max
+	if maxLength <= 0 {
+		maxLength = DefaultMaxFilename
+	}
+	maxLength = min(maxLength, maxFilenameBytes-longestOutputSuffix)
+	if len(name) > maxLength {
+		cut := maxLength
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = strings.TrimRight(name[:cut], " .")
+	}
+	if goos == "windows" {
+		base, _, _ := strings.Cut(name, ".")
+		if windowsReserved[strings.ToUpper(strings.TrimSpace(base))] {
+			name = "_" + name
+		}
+	}
+	if name == "" {
+		return "default_filename"
+	}
+	return name
+}
+
+// minimalEscaped are the characters SanitizeFilenameMinimal percent-encodes
+// wherever they appear: those no common OS allows in a filename, and '%'
+// itself so that distinct titles always give distinct names.
+const minimalEscaped = `%<>:"/\|?*`
+
+// SanitizeFilenameMinimal percent-encodes only the characters a filename
+// can't hold, keeping everything else as it is, so titles that differ only in
+// punctuation, like "A: Part 1" and "A - Part 1", still get distinct names
+// where SanitizeFilename would collapse both to "A-Part-1".
+func SanitizeFilenameMinimal(name string) string {
+	return SanitizeFilenameMinimalN(name, DefaultMaxFilename)
+}
+
+// SanitizeFilenameMinimalN is SanitizeFilenameMinimal with a length cap, like
+// SanitizeFilenameN. The result is the same on every OS: besides
+// minimalEscaped and control characters, a leading or trailing dot or space
+// and the first letter of a Windows device name such as CON are encoded, so
+// the file is never hidden and can be copied to Windows. A name over max
+// bytes is cut without splitting a character or an escape.
+func SanitizeFilenameMinimalN(name string, max int) string {
+	var b strings.Builder
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		edge := i == 0 || i+size == len(name)
+		if unicode.IsControl(r) || strings.ContainsRune(minimalEscaped, r) || (r == utf8.RuneError && size == 1) || (edge && (r == '.' || r == ' ')) {
+			for _, c := range []byte(name[i : i+size]) {
+				fmt.Fprintf(&b, "%%%02X", c)
+			}
+		} else {
+			b.WriteString(name[i : i+size])
+		}
+		i += size
+	}
+	escaped := b.String()
+	if base, _, _ := strings.Cut(escaped, "."); windowsReserved[strings.ToUpper(base)] {
+		escaped = fmt.Sprintf("%%%02X", escaped[0]) + escaped[1:]
+	}
+
+	maxLength := max
+	if maxLength <= 0 {
+		maxLength = DefaultMaxFilename
+	}
+	maxLength = min(maxLength, maxFilenameBytes-longestOutputSuffix)
+	if len(escaped) > maxLength {
+		cut := maxLength
+		for cut > 0 && !utf8.RuneStart(escaped[cut]) {
+			cut--
+		}
+		if i := strings.LastIndexByte(escaped[:cut], '%'); i >= 0 && i+3 > cut {
+			cut = i // Don't leave half an escape
+		}
+		escaped = strings.TrimRight(escaped[:cut], " .")
+	}
+	if escaped == "" {
+		return "default_filename"
+	}
+	return escaped
+}
+
 // GetLocalVTTPathByVideoID constructs the path for a raw VTT file based on its video ID.
 // Assumes VTT files are named <videoID>.en.vtt when downloaded for English.
 func GetLocalVTTPathByVideoID(videoID string, tempDir string) (string, error) {
+	return GetLocalVTTPath(videoID, DefaultLang, tempDir)
+}
+
+// GetLocalVTTPath constructs the path for a raw VTT file based on its video ID and language.
+func GetLocalVTTPath(videoID, lang, tempDir string) (string, error) {
+	return GetLocalSubtitlePath(videoID, lang, DefaultSubFormat, tempDir)
+}
+
+// GetLocalSubtitlePath constructs the path for a raw subtitle file based on its
+// video ID, language and format.
+func GetLocalSubtitlePath(videoID, lang, format, tempDir string) (string, error) {
 	if videoID == "" {
-		return "", fmt.Errorf("videoID cannot be empty when constructing raw VTT path")
+		return "", fmt.Errorf("videoID cannot be empty when constructing raw subtitle path")
 	}
-	// yt-dlp, with --sub-lang en --convert-subs vtt, saves as <videoID>.en.vtt
-	return filepath.Join(tempDir, videoID+".en.vtt"), nil
+	// yt-dlp, with --sub-lang <lang> --convert-subs <format>, saves as <videoID>.<lang>.<format>
+	return filepath.Join(tempDir, videoID+"."+lang+"."+format), nil
 }
 
 // GetCleanedFilePathByTitle constructs the path for a cleaned transcript file based on the video title.
 func GetCleanedFilePathByTitle(videoTitle string, cleanedDir string) (string, error) {
+	return GetCleanedFilePathByTitleN(videoTitle, cleanedDir, DefaultMaxFilename)
+}
+
+// GetCleanedFilePathByTitleN is GetCleanedFilePathByTitle with the filename capped at maxFilename characters.
+func GetCleanedFilePathByTitleN(videoTitle string, cleanedDir string, maxFilename int) (string, error) {
 	if videoTitle == "" {
 		return "", fmt.Errorf("videoTitle cannot be empty when constructing cleaned file path")
 	}
-	safeTitle := SanitizeFilename(videoTitle)
-	return filepath.Join(cleanedDir, safeTitle+".txt"), nil
+	safeTitle := SanitizeFilenameN(videoTitle, maxFilename)
+	return fitPathLength(filepath.Join(cleanedDir, safeTitle+".txt"), maxPathBytes(runtime.GOOS)), nil
+}
+
+// pathSuffixRoom is the room fitPathLength leaves after a transcript's name,
+// for the longest extension, a language tag (AllLangs) and a .sha256 sidecar.
+const pathSuffixRoom = longestOutputSuffix + len(".zh-Hans") + len(checksumExt)
+
+// pathHashLen is how many hex digits of the full name's hash fitPathLength
+// appends to a name it cuts.
+const pathHashLen = 8
+
+// maxPathBytes is the longest full path files can be written at on goos:
+// Windows' MAX_PATH, or elsewhere macOS's PATH_MAX, the lowest of the others.
+func maxPathBytes(goos string) int {
+	if goos == "windows" {
+		return 260
+	}
+	return 1024
+}
+
+// fitPathLength cuts the file name of a transcript path, keeping its
+// extension, so the full path (made absolute) leaves pathSuffixRoom within
+// maxPath, e.g. for a title in a deeply nested cleaned dir. A cut name ends in
+// "-" and a hash of the full name, so titles differing only past the cut still
+// get different files. A path that fits, or whose directory alone leaves no
+// room for a name, is returned as is.
+func fitPathLength(path string, maxPath int) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	stem := transcriptBase(path)
+	name := filepath.Base(stem)
+	room := maxPath - pathSuffixRoom - (len(transcriptBase(abs)) - len(name))
+	if len(name) <= room || room < pathHashLen+1+8 {
+		return path
+	}
+	cut := room - pathHashLen - 1
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	sum := sha256.Sum256([]byte(name))
+	short := strings.TrimRight(name[:cut], "-_. ") + "-" + hex.EncodeToString(sum[:])[:pathHashLen]
+	return filepath.Join(filepath.Dir(stem), short) + path[len(stem):]
 }
@@ -1,8 +1,14 @@
 package internal
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -31,44 +37,54 @@ func TestEnsureDirectories(t *testing.T) {
 	}
 }
 
-func TestCleanDirectories(t *testing.T) {
-	tempDir := t.TempDir()
-	rawDir := filepath.Join(tempDir, "test_raw_vtt")
-	cleanedDir := filepath.Join(tempDir, "test_cleaned")
-
-	// Create directories and some dummy files
-	if err := os.MkdirAll(rawDir, 0755); err != nil {
+func TestEnsureDirectories_NotWritable(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("directory permissions aren't enforced here")
+	}
+	readOnly := t.TempDir()
+	if err := os.Chmod(readOnly, 0555); err != nil {
 		t.Fatal(err)
 	}
-	if err := os.MkdirAll(cleanedDir, 0755); err != nil {
+	t.Cleanup(func() { os.Chmod(readOnly, 0755) })
+
+	err := EnsureDirectories(filepath.Join(t.TempDir(), "raw"), readOnly)
+	if want := "output directory " + readOnly + " is not writable"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("EnsureDirectories() error = %v, want %q", err, want)
+	}
+}
+
+func TestEmptyDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cleaned")
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0755); err != nil {
 		t.Fatal(err)
 	}
-	dummyFileRaw, _ := os.Create(filepath.Join(rawDir, "dummy.vtt"))
-	dummyFileRaw.Close()
-	dummyFileCleaned, _ := os.Create(filepath.Join(cleanedDir, "dummy.txt"))
-	dummyFileCleaned.Close()
+	for _, name := range []string{"a.txt", filepath.Join("nested", "b.txt")} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
 
-	err := CleanDirectories(rawDir, cleanedDir)
-	if err != nil {
-		t.Fatalf("CleanDirectories() error = %v, wantErr nil", err)
+	if err := EmptyDirectory(dir); err != nil {
+		t.Fatalf("EmptyDirectory() error = %v, wantErr nil", err)
 	}
 
-	// Check if directories exist (they should, but be empty)
-	if _, err := os.Stat(rawDir); os.IsNotExist(err) {
-		t.Errorf("Directory %s should exist after CleanDirectories", rawDir)
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		t.Fatalf("Directory %s should still exist after EmptyDirectory: %v", dir, err)
 	}
-	if _, err := os.Stat(cleanedDir); os.IsNotExist(err) {
-		t.Errorf("Directory %s should exist after CleanDirectories", cleanedDir)
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Directory %s should be empty after EmptyDirectory, got %d entries", dir, len(entries))
 	}
+}
 
-	// Check if directories are empty
-	rawEntries, _ := os.ReadDir(rawDir)
-	if len(rawEntries) != 0 {
-		t.Errorf("Directory %s should be empty after CleanDirectories, got %d entries", rawDir, len(rawEntries))
+func TestEmptyDirectoryMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	if err := EmptyDirectory(dir); err != nil {
+		t.Fatalf("EmptyDirectory() on a missing directory error = %v, wantErr nil", err)
 	}
-	cleanedEntries, _ := os.ReadDir(cleanedDir)
-	if len(cleanedEntries) != 0 {
-		t.Errorf("Directory %s should be empty after CleanDirectories, got %d entries", cleanedDir, len(cleanedEntries))
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("EmptyDirectory() should not create %s", dir)
 	}
 }
 
@@ -98,6 +114,44 @@ func TestWriteAndReadTextFile(t *testing.T) {
 	}
 }
 
+func TestWriteTextFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "transcript.txt")
+
+	// The target must not appear before the write has fully succeeded
+	errWrite := errors.New("disk full")
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("target exists mid-write (stat error %v)", err)
+		}
+		return errWrite
+	})
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("writeFileAtomic() error = %v, want %v", err, errWrite)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("target exists after a failed write (stat error %v)", err)
+	}
+
+	if err := WriteTextFileAtomic(path, "complete\n"); err != nil {
+		t.Fatalf("WriteTextFileAtomic() error = %v", err)
+	}
+	// A failed rewrite leaves the previous content in place
+	writeFileAtomic(path, func(w io.Writer) error { return errWrite })
+	if got, _ := ReadTextFile(path); got != "complete\n" {
+		t.Errorf("content after failed rewrite = %q, want %q", got, "complete\n")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("WriteTextFileAtomic() mode = %v (%v), want 0644", info.Mode().Perm(), err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("dir has %d entries, want only the transcript (temp files left behind?)", len(entries))
+	}
+}
+
 // Note: FindNewestFile is difficult to unit test reliably without extensive os call mocking
 // or creating actual files with controlled mod times, which can be flaky.
 // It's better suited for integration testing.
@@ -169,3 +223,302 @@ func TestFindNewestFile(t *testing.T) {
 		})
 	}
 }
+
+func TestSanitizeFilenameN(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	tests := []struct {
+		name    string
+		max     int
+		wantLen int
+	}{
+		{"default cap", 0, DefaultMaxFilename},
+		{"custom cap", 150, 150},
+		{"clamped to filesystem limit", 1000, maxFilenameBytes - longestOutputSuffix},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeFilenameN(long, tt.max); len(got) != tt.wantLen {
+				t.Errorf("SanitizeFilenameN(max=%d) length = %d, want %d", tt.max, len(got), tt.wantLen)
+			}
+		})
+	}
+	if got := SanitizeFilename(long); len(got) != DefaultMaxFilename {
+		t.Errorf("SanitizeFilename() length = %d, want %d", len(got), DefaultMaxFilename)
+	}
+}
+
+func TestSanitizeFilename_WindowsSafe(t *testing.T) {
+	// The result is the same on every OS, so these hold whatever the host
+	tests := map[string]string{
+		"CON":            "_CON",
+		"nul":            "_nul",
+		"Com1.part 2":    "_Com1.part-2",
+		"LPT9":           "_LPT9",
+		"CONSOLE":        "CONSOLE",
+		"COM10":          "COM10",
+		"The End.":       "The-End",
+		"...and more...": "and-more",
+		"Title . . .":    "Title",
+		"Con: the movie": "Con-the-movie",
+	}
+	for title, want := range tests {
+		got := SanitizeFilename(title)
+		if got != want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", title, got, want)
+		}
+		if base, _, _ := strings.Cut(got, "."); windowsReserved[strings.ToUpper(base)] || strings.HasSuffix(got, ".") || strings.HasSuffix(got, " ") {
+			t.Errorf("SanitizeFilename(%q) = %q, which Windows can't create", title, got)
+		}
+	}
+	if got := SanitizeFilenameN("abc."+strings.Repeat("x", 10), 4); got != "abc" {
+		t.Errorf("SanitizeFilenameN() truncated to %q, want no trailing dot", got)
+	}
+}
+
+func TestWithFinalNewline(t *testing.T) {
+	tests := map[string]string{
+		"":             "",
+		"\n\n":         "",
+		"hello":        "hello\n",
+		"hello\nworld": "hello\nworld\n",
+		"hello\n\n\n":  "hello\n",
+		"\nhello\n":    "\nhello\n",
+	}
+	for in, want := range tests {
+		if got := withFinalNewline(in); got != want {
+			t.Errorf("withFinalNewline(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestEncodeOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		opts CleanOptions
+		want string
+	}{
+		{"default is unchanged", CleanOptions{}, "one\ntwo"},
+		{"crlf", CleanOptions{Newline: NewlineCRLF}, "one\r\ntwo"},
+		{"bom", CleanOptions{BOM: true}, "\xEF\xBB\xBFone\ntwo"},
+		{"bom and crlf", CleanOptions{BOM: true, Newline: NewlineCRLF}, "\xEF\xBB\xBFone\r\ntwo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EncodeOutput("one\ntwo", tt.opts); got != tt.want {
+				t.Errorf("EncodeOutput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.txt")
+	if isUnchanged(path, "hello") {
+		t.Error("isUnchanged() of a missing transcript = true")
+	}
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if isUnchanged(path, "hello") {
+		t.Error("isUnchanged() without a checksum sidecar = true")
+	}
+	if err := WriteChecksumFile(path, ContentChecksum("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if !isUnchanged(path, "hello") {
+		t.Error("isUnchanged() of matching content = false")
+	}
+	if isUnchanged(path, "hello, world") {
+		t.Error("isUnchanged() of different content = true")
+	}
+	if err := os.WriteFile(path, []byte("hello!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if isUnchanged(path, "hello") {
+		t.Error("isUnchanged() of a transcript edited since its checksum = true")
+	}
+}
+
+func TestArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.txt")
+	if err := os.WriteFile(path, []byte("youtube aaa\n\nbbb\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := LoadArchive(path)
+	if err != nil {
+		t.Fatalf("LoadArchive() error = %v", err)
+	}
+	if !archive.Contains("aaa") || !archive.Contains("bbb") || archive.Contains("ccc") {
+		t.Errorf("LoadArchive() ids = %v, want aaa and bbb", archive.ids)
+	}
+
+	// Workers append concurrently; every id must land on a line of its own
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := archive.AppendArchive(fmt.Sprintf("id%d", i%10)); err != nil {
+				t.Errorf("AppendArchive() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := archive.AppendArchive("aaa"); err != nil {
+		t.Errorf("AppendArchive() of an archived id error = %v", err)
+	}
+
+	reloaded, err := LoadArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(path)
+	if lines := strings.Count(string(content), "\n"); lines != 13 || len(reloaded.ids) != 12 {
+		t.Errorf("archive after appends = %q, want each new id appended once", content)
+	}
+}
+
+func TestLoadArchive_Missing(t *testing.T) {
+	archive, err := LoadArchive(filepath.Join(t.TempDir(), "none.txt"))
+	if err != nil || archive.Contains("abc") {
+		t.Errorf("LoadArchive() of a missing file = %v, %v, want an empty archive", archive, err)
+	}
+	var none *Archive
+	if none.Contains("abc") {
+		t.Error("nil Archive Contains() = true")
+	}
+}
+
+func TestSanitizeFilenameMinimal(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"A: Part 1", "A%3A Part 1"},
+		{"What's new? 100% <live>", "What's new%3F 100%25 %3Clive%3E"},
+		{"AC/DC \\ Live", "AC%2FDC %5C Live"},
+		{"Café\tnight", "Café%09night"},
+		{".hidden ", "%2Ehidden%20"},
+		{"con.txt", "%63on.txt"},
+		{"", "default_filename"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilenameMinimal(tt.title); got != tt.want {
+			t.Errorf("SanitizeFilenameMinimal(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+
+	// Truncation never splits a character or an escape
+	if got := SanitizeFilenameMinimalN("ab:cd", 4); got != "ab" {
+		t.Errorf("SanitizeFilenameMinimalN() truncated to %q, want %q", got, "ab")
+	}
+	if got := SanitizeFilenameMinimalN(strings.Repeat("é", 10), 5); got != "éé" {
+		t.Errorf("SanitizeFilenameMinimalN() truncated to %q, want %q", got, "éé")
+	}
+}
+
+func TestSanitizeFilenameMinimal_KeepsTitlesDistinct(t *testing.T) {
+	a, b := "A: Part 1", "A - Part 1"
+	if SanitizeFilename(a) != SanitizeFilename(b) {
+		t.Fatalf("SanitizeFilename() no longer collides %q and %q; pick titles that do", a, b)
+	}
+	if SanitizeFilenameMinimal(a) == SanitizeFilenameMinimal(b) {
+		t.Errorf("SanitizeFilenameMinimal(%q) = SanitizeFilenameMinimal(%q) = %q, want distinct names", a, b, SanitizeFilenameMinimal(a))
+	}
+}
+
+func TestPrettyFilename(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		goos  string
+		want  string
+	}{
+		{"spaces and punctuation kept", "My Talk: Part 2 (2024)!", "linux", "My Talk: Part 2 (2024)!"},
+		{"slash stripped", "AC/DC Live", "linux", "ACDC Live"},
+		{"windows illegal stripped", `What? "Why" <now>|a*b\c`, "windows", "What Why nowabc"},
+		{"non-ASCII kept", "Café — déjà vu", "darwin", "Café — déjà vu"},
+		{"control characters and doubled spaces", "a\tb  \x00c", "linux", "a b c"},
+		{"never hidden or trailing dot", "..hidden.", "linux", "hidden"},
+		{"windows reserved name", "con", "windows", "_con"},
+		{"reserved only on windows", "con", "linux", "con"},
+		{"only illegal characters", "///", "linux", "default_filename"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prettyFilename(tt.title, DefaultMaxFilename, tt.goos); got != tt.want {
+				t.Errorf("prettyFilename(%q, %s) = %q, want %q", tt.title, tt.goos, got, tt.want)
+			}
+		})
+	}
+
+	// Truncation never splits a multi-byte character
+	if got := prettyFilename(strings.Repeat("é", 10), 5, "linux"); got != "éé" {
+		t.Errorf("prettyFilename() truncated to %q, want %q", got, "éé")
+	}
+}
+
+func TestFitPathLength(t *testing.T) {
+	dir := filepath.Join(string(filepath.Separator)+"archive", strings.Repeat("d", 100))
+	long := filepath.Join(dir, strings.Repeat("Talk-", 40)+".txt") // 200 byte name
+
+	// Fits: left alone
+	if got := fitPathLength(long, 1024); got != long {
+		t.Errorf("fitPathLength(fits) = %q, want it unchanged", got)
+	}
+
+	got := fitPathLength(long, 260)
+	if len(transcriptBase(got))+pathSuffixRoom > 260 {
+		t.Errorf("fitPathLength() = %q (%d bytes), want room for any suffix within 260", got, len(got))
+	}
+	if filepath.Dir(got) != dir || !strings.HasSuffix(got, ".txt") || !strings.HasPrefix(filepath.Base(got), "Talk-Talk") {
+		t.Errorf("fitPathLength() = %q, want the name cut in %s, keeping .txt", got, dir)
+	}
+	if again := fitPathLength(got, 260); again != got {
+		t.Errorf("fitPathLength() isn't stable: %q became %q", got, again)
+	}
+
+	// Titles differing only past the cut still get different files
+	other := filepath.Join(dir, strings.Repeat("Talk-", 39)+"Other.txt")
+	if fitPathLength(other, 260) == got {
+		t.Errorf("fitPathLength() gave %q for two different names", got)
+	}
+
+	// The extension is kept whole, even a double one
+	words := transcriptBase(long) + wordsJSONExt
+	if got := fitPathLength(words, 260); !strings.HasSuffix(got, wordsJSONExt) || strings.HasSuffix(got, ".words.words.json") {
+		t.Errorf("fitPathLength(words) = %q, want a single .words.json", got)
+	}
+
+	// No room for any name in the directory: nothing to do
+	deep := filepath.Join(string(filepath.Separator)+strings.Repeat("d", 300), "Talk.txt")
+	if got := fitPathLength(deep, 260); got != deep {
+		t.Errorf("fitPathLength(deep) = %q, want it unchanged", got)
+	}
+}
+
+func TestGetCleanedFilePathByTitle_LongDir(t *testing.T) {
+	// A cleaned dir just short of the OS limit forces the title to be cut
+	limit := maxPathBytes(runtime.GOOS)
+	cleanedDir := t.TempDir()
+	for len(cleanedDir) < limit-pathSuffixRoom-60 {
+		cleanedDir = filepath.Join(cleanedDir, "nested-directory")
+	}
+	title := strings.Repeat("A Very Long Lecture Title ", 4)
+	got, err := GetCleanedFilePathByTitle(title, cleanedDir)
+	if err != nil {
+		t.Fatalf("GetCleanedFilePathByTitle() error = %v", err)
+	}
+	if len(transcriptBase(got))+pathSuffixRoom > limit {
+		t.Errorf("GetCleanedFilePathByTitle() = %d bytes, want room for any suffix within %d", len(got), limit)
+	}
+	if filepath.Dir(got) != cleanedDir || !strings.HasPrefix(filepath.Base(got), "A-Very-Long") || !strings.HasSuffix(got, ".txt") {
+		t.Errorf("GetCleanedFilePathByTitle() = %q, want a cut title in the cleaned dir", got)
+	}
+	if err := os.MkdirAll(cleanedDir, 0755); err != nil {
+		t.Skipf("can't create the long cleaned dir here: %v", err)
+	}
+	if err := os.WriteFile(got, []byte("hello\n"), 0644); err != nil {
+		t.Errorf("writing the cut path failed: %v", err)
+	}
+}
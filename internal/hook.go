@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// hookConcurrency bounds how many hook commands run at once, so a slow hook
+// (e.g. indexing into a search engine) isn't started once per worker.
+const hookConcurrency = 2
+
+// hookTimeout bounds each hook command, so a hung one can't stall its worker
+// for the rest of the run.
+const hookTimeout = 5 * time.Minute
+
+// Hook runs a shell command for each transcript written, like yt-dlp's
+// --exec. The command may use {path}, {title} and {id}, which are replaced by
+// the transcript's path, the video's title and its id, each quoted for the
+// shell; other braces are left alone. A Hook is safe for concurrent use.
+type Hook struct {
+	command string
+	slots   chan struct{} // Held while a command runs, bounding them to hookConcurrency
+}
+
+// NewHook creates a Hook running command.
+func NewHook(command string) *Hook {
+	return &Hook{command: command, slots: make(chan struct{}, hookConcurrency)}
+}
+
+// Run runs the command for the transcript at path of job's video, waiting for
+// a free slot first. A command that exits non-zero or outlives hookTimeout
+// fails with its last line of output.
+func (h *Hook) Run(path string, job TranscriptJob) error {
+	h.slots <- struct{}{}
+	defer func() { <-h.slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	command := expandHook(h.command, map[string]string{"path": path, "title": job.Title, "id": job.VideoID}, runtime.GOOS)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("exec hook timed out after %v", hookTimeout)
+	}
+	if err != nil {
+		if last := lastLine(output); last != "" {
+			return fmt.Errorf("exec hook failed: %w: %s", err, last)
+		}
+		return fmt.Errorf("exec hook failed: %w", err)
+	}
+	return nil
+}
+
+// expandHook replaces each {name} placeholder of values in command with its
+// value quoted for the shell of goos.
+func expandHook(command string, values map[string]string, goos string) string {
+	var pairs []string
+	for name, value := range values {
+		pairs = append(pairs, "{"+name+"}", shellQuote(value, goos))
+	}
+	return strings.NewReplacer(pairs...).Replace(command)
+}
+
+// shellQuote quotes s as one argument: in single quotes for a POSIX shell, or
+// in double quotes (with embedded ones doubled) for cmd.exe on Windows.
+func shellQuote(s, goos string) string {
+	if goos == "windows" {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// lastLine returns the last non-blank line of output, trimmed.
+func lastLine(output []byte) string {
+	trimmed := strings.TrimSpace(string(output))
+	return strings.TrimSpace(trimmed[strings.LastIndexByte(trimmed, '\n')+1:])
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExpandHook(t *testing.T) {
+	values := map[string]string{"path": "out/it's here.txt", "title": `Say "hi"`, "id": "abc"}
+	tests := []struct {
+		goos, command, want string
+	}{
+		{"linux", "index {path} --id {id}", `index 'out/it'\''s here.txt' --id 'abc'`},
+		{"linux", "echo {title} | awk '{print}'", `echo 'Say "hi"' | awk '{print}'`},
+		{"windows", "index {path} {title}", `index "out/it's here.txt" "Say ""hi"""`},
+	}
+	for _, tt := range tests {
+		if got := expandHook(tt.command, values, tt.goos); got != tt.want {
+			t.Errorf("expandHook(%q, %s) = %q, want %q", tt.command, tt.goos, got, tt.want)
+		}
+	}
+}
+
+func TestHook_Run(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands here use a POSIX shell")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	job := TranscriptJob{Title: "A $(dangerous) title", VideoID: "abc"}
+
+	if err := NewHook("printf '%s|%s|%s' {path} {title} {id} > "+log).Run("/tmp/a b.txt", job); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got, _ := os.ReadFile(log); string(got) != "/tmp/a b.txt|A $(dangerous) title|abc" {
+		t.Errorf("hook saw %q, want each value as one unexpanded argument", got)
+	}
+
+	err := NewHook("echo indexing; echo 'index is down' >&2; exit 3").Run("x.txt", job)
+	if err == nil || !strings.Contains(err.Error(), "index is down") {
+		t.Errorf("Run() of a failing command error = %v, want its last line of output", err)
+	}
+}
+
+func TestProcessJob_ExecHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands here use a POSIX shell")
+	}
+	installFakeRunner(t, subtitleRunner{})
+	log := filepath.Join(t.TempDir(), "log")
+	opts := Options{CleanedDir: t.TempDir(), TempDir: t.TempDir(), Exec: NewHook("echo {id} >> " + log)}
+
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Status != "completed" || len(job.Warnings) != 0 {
+		t.Fatalf("workJob() = %q, warnings %q, want completed without warnings", job.Status, job.Warnings)
+	}
+	if got, _ := os.ReadFile(log); string(got) != "abc\n" {
+		t.Errorf("hook log = %q, want one run for the transcript", got)
+	}
+
+	// A failing hook only warns
+	opts.Exec = NewHook("exit 1")
+	opts.CleanedDir = t.TempDir()
+	job = workJob(TranscriptJob{URL: "https://youtu.be/def"}, opts)
+	if job.Status != "completed" || job.Error != nil || len(job.Warnings) != 1 || !strings.Contains(job.Warnings[0], "exec hook failed") {
+		t.Errorf("workJob() with a failing hook = %q %v, warnings %q, want completed with a warning", job.Status, job.Error, job.Warnings)
+	}
+}
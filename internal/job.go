@@ -1,6 +1,12 @@
 package internal
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+)
 
 // Messages for job state changes
 type DownloadCompletedMsg struct{ Err error }
@@ -11,14 +17,133 @@ type WorkflowCompletedMsg struct{}
 
 // TranscriptJob represents a single YouTube transcript processing job
 type TranscriptJob struct {
-	URL           string
-	Title         string
-	Status        string // "pending", "downloading", "processing", "completed", "failed"
-	Error         error
-	ProcessedFile string
+	URL            string
+	Title          string
+	VideoID        string      // Video id the subtitle files are named after, once resolved
+	Uploader       string      // Channel/uploader name, populated when output is organized by channel
+	Language       string      // Subtitle language that was actually downloaded (comma-separated with AllLangs)
+	CaptionsKind   string      // CaptionsTranslated for machine-translated captions, CaptionsManual with ManualOnly, CaptionsAuto for auto-generated ones with MarkAuto, empty otherwise
+	Stats          *CleanStats // What the cleaning pipeline dropped, set once the transcript is cleaned
+	Status         string      // "pending", "downloading", "processing", "completed", "no_subtitles", "filtered", "unavailable", "empty", "oversized", "failed"
+	Error          error
+	ProcessedFile  string
+	Refreshed      bool               // The transcript existed but was older than RefreshOlderThan, so it was downloaded again
+	TitleFellBack  bool               // No title was available, so the video ID stands in for it (and names the file)
+	ProcessedFiles []string           // With AllLangs, the transcript of every language (<title>.<lang>.txt, or <lang>/<title>.txt with LangDirs)
+	ThumbnailFile  string             // Path of the downloaded thumbnail, if requested and found
+	Metadata       *VideoMetadata     // Video metadata, populated when metadata fetching is enabled
+	Captions       *SubtitleLanguages // Caption tracks the video offers, listed with MarkAuto
+	Warnings       []string           // Non-fatal problems encountered while processing the job
+	Attempts       int                // Times the job was run, counting retries after transient failures
+	Category       string             // ClassifyError category of a failed job's final error
+	DownloadPct    float64            // Subtitle download progress reported by yt-dlp, with DownloadProgress
+	RawBytes       int64              // Size of the raw subtitle files cleaned into written transcripts
+	CleanedBytes   int64              // Size of the transcripts written
+	Timings        Timings            // Time the last attempt spent in each phase it reached
+	PanicStack     string             // Stack trace of a panic that failed the job, for the verbose summary
+	DetectedLang   string             // Language DetectLang found in the written transcript ("" if unknown; <lang>:<detected>,... with AllLangs)
+}
+
+// Timings breaks down where a job's time went, for telling whether downloads
+// or cleaning dominate a workload. A phase the job never reached stays zero.
+type Timings struct {
+	Title      time.Duration // Fetching the title or metadata and resolving the video ID
+	Download   time.Duration // Downloading the subtitles
+	Processing time.Duration // Cleaning and writing the transcript
+}
+
+// Options holds the user-configurable settings shared by every worker.
+type Options struct {
+	TempDir          string          // Directory for raw downloaded .vtt files
+	CleanedDir       string          // Directory for cleaned transcript files
+	DirTemplate      string          // Per-video directory for transcripts, e.g. "archive/{channel}/{date}" (see DirTemplateFields); within CleanedDir
+	ParallelWorkers  int             // Number of workers for parallel processing; NewWorkflow clamps it to 1..pending jobs
+	PrefetchTitles   int             // Fetch up to this many titles at once before their jobs are queued, so all show early (0 = each worker fetches its own)
+	CleanWorkers     int             // Clean in a separate pool of this many workers, leaving ParallelWorkers to download (0 = each worker cleans its own)
+	Thumbnail        bool            // Also download the video thumbnail next to the transcript
+	Metadata         bool            // Fetch video metadata and write a .info.json sidecar
+	RateLimit        int             // Max yt-dlp invocations per minute across all workers (0 = unlimited)
+	DownloadProgress bool            // Report yt-dlp's subtitle download percentage as download_progress events
+	PerHost          int             // Max jobs working on videos from the same host at once (0 = unlimited)
+	ByChannel        bool            // Write transcripts into a per-uploader subdirectory of CleanedDir
+	NoColor          bool            // Disable coloured job statuses
+	ProgressStyle    string          // Progress bar style, one of ProgressStyles ("" = gradient, or ascii without colour)
+	ShowIDs          bool            // Show video IDs in the job list and summary, to tell similar titles apart
+	Lang             string          // Subtitle language to download (defaults to DefaultLang)
+	AutoLang         bool            // Fall back to the video's primary caption language if Lang is unavailable
+	ExactLang        bool            // Don't fall back to the closest variant of Lang (see MatchLanguage) when its exact code is unavailable
+	TranslateTo      string          // Fetch captions machine-translated into this language when no native track exists
+	RawFormat        string          // Subtitle format yt-dlp converts to before cleaning, one of SubFormats
+	SubSource        string          // Preference order of the formats yt-dlp fetches before converting, e.g. "srv3/vtt/best"
+	ManualOnly       bool            // Use only uploaded (human) captions; videos without them count as having none
+	MarkAuto         bool            // Head text transcripts of auto-generated or machine-translated captions with a note saying so
+	Track            int             // Download this track (1-based) of the language's SubtitleLanguages.Tracks (0 = yt-dlp's pick)
+	Format           string          // Output format, one of Formats (defaults to FormatText)
+	Debug            bool            // Show per-job cleaning diagnostics in the final summary
+	DedupeReport     bool            // Show each transcript's line count as read, after artifact removal and after dedupe in the final summary
+	Verbose          bool            // Show per-job phase timings in the final summary
+	Tree             bool            // List output files as a tree of directories (e.g. channels) when done
+	NoSummary        bool            // Leave the completion banner, progress bar and counts out of the final view
+	Interactive      bool            // Stdin is a terminal, so the final view may wait for the user to retry failed jobs
+	Chapters         bool            // Insert a heading per video chapter into the transcript
+	MaxFilename      int             // Cap on transcript filename length (defaults to DefaultMaxFilename)
+	PrettyNames      bool            // Keep titles readable in filenames, stripping only characters the OS forbids
+	OriginalNames    bool            // Percent-encode only characters illegal in filenames, keeping titles distinct
+	TitleSidecar     bool            // Write the original video title to a .title file next to the transcript
+	Events           EventEmitter    // Receives job state transitions; nil means none
+	Combined         *CombinedWriter // Receives every finished job for the combined transcript; nil means none
+	ChooseLang       LangChooser     // Asked to pick among a video's caption languages; nil means use Lang
+	DetectLang       LangDetector    // Guesses the language of each transcript written; nil means none
+	AllowAnyURL      bool            // Accept any yt-dlp-supported URL, asking yt-dlp for the video id
+	OutputFile       string          // Write the (single) job's transcript exactly here instead of under CleanedDir
+	TrimIntro        time.Duration   // Drop captions that end before this point
+	TrimOutro        time.Duration   // Drop captions that start within this long of the video's end
+	Start            time.Duration   // Keep only captions that end after this point
+	End              time.Duration   // Keep only captions that start before this point (0 = the end of the video)
+	AllowDuplicates  bool            // Process every copy of a video listed more than once, instead of only the first
+	AllLangs         bool            // Download every subtitle language, cleaning each into <title>.<lang>.txt
+	LangDirs         bool            // With AllLangs, write <lang>/<title>.txt instead of <title>.<lang>.txt
+	RefreshOlderThan time.Duration   // Re-download existing transcripts last written longer ago than this (0 = always keep them)
+	MinDuration      time.Duration   // Skip videos shorter than this (needs metadata; 0 = no minimum)
+	MaxDuration      time.Duration   // Skip videos longer than this (needs metadata; 0 = no maximum)
+	Since            time.Time       // Skip videos uploaded before this day (zero = no cutoff)
+	Archive          *Archive        // Videos to skip, recording each one processed; nil means none
+	DB               *TranscriptDB   // Database each transcript written is also added to; nil means none
+	MaxBytes         int64           // Don't clean raw captions larger than this, marking the job oversized (0 = no limit)
+	Exec             *Hook           // Command run for each transcript written; nil means none
+	Manifest         *Manifest       // Output paths for listed videos, overriding the computed ones; nil means none
+	Alongside        *MediaIndex     // Write a video's transcript next to its media file here, if any, instead of in the cleaned directory
+	Retries          int             // Re-run a job up to this many times after a network error or timeout
+	FailFast         bool            // Stop the whole run at the first failed job, failing the unfinished ones with ErrAborted
+	Probe            bool            // Check each video is available before fetching anything else
+	KeepRaw          bool            // Keep each job's raw subtitle download in its TempDir subdirectory
+	NoRawFiles       bool            // Clean subtitles as yt-dlp writes them to its output, never to TempDir (single-language text transcripts only)
+	Reclean          bool            // Re-clean the raw subtitles an earlier KeepRaw run left in TempDir, overwriting the transcript, instead of downloading
+	Clean            CleanOptions
+
+	onDownloadProgress func(percent float64) // Set per job by startJob when DownloadProgress is on
+	ctx                context.Context       // Cancelled when the run stops, killing subtitle downloads in flight; nil means never
+}
+
+// runContext returns the context subtitle downloads run under.
+func (o Options) runContext() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
 }
 
-// TitleFetchResult is a message containing the fetched title for a URL
+// FetchesMetadata reports whether workers fetch each video's full metadata,
+// which the sidecar, chapters, outro trimming, the duration and date filters,
+// a directory template's channel and date fields and a combined transcript
+// sorted by date or duration rely on. The date filter
+// asks for it itself.
+func (o Options) FetchesMetadata() bool {
+	return o.Metadata || o.Chapters || o.TrimOutro > 0 || !o.Since.IsZero() || templateNeedsMetadata(o.DirTemplate) || o.Combined.NeedsMetadata()
+}
+
+// TitleFetchResult is a message containing the fetched title for a URL,
+// sent by prefetchTitles ahead of the URL's job
 type TitleFetchResult struct {
 	URL   string
 	Title string
@@ -32,56 +157,112 @@ type JobProcessingResult struct {
 	Err              error // An error that might have occurred during the entire job processing by the worker
 }
 
-// WorkflowState represents the application's workflow state
+// WorkflowState represents the application's workflow state.
+//
+// Jobs is owned by the bubbletea goroutine: only Update mutates it. Workers
+// process their own copies of the jobs and report back exclusively through
+// messages (Event for intermediate statuses, JobProcessingResult when done),
+// so a worker's progress can never race with the UI's view of the job.
 type WorkflowState struct {
-	Jobs            []TranscriptJob
-	CurrentJobIndex int // May become less central, represents last job detailed in UI or for sequential fallback
+	Jobs            []TranscriptJob // Always in input order; results are slotted back by OriginalJobIndex
+	CurrentJobIndex int             // May become less central, represents last job detailed in UI or for sequential fallback
 	TotalJobs       int
 	CurrentStage    string // Overall workflow stage e.g., "processing_parallel", "completed"
 	ProgressView    ProgressView
+	Spinner         spinner.Model // Animates in-progress jobs in the job list
 	ReadyToQuit     bool
+	Aborted         bool // Options.FailFast stopped the run at a failed job
+	Interrupted     bool // The user quit before every job had finished
 	ProcessedFiles  []string
-	TempDir         string
-	CleanedDir      string
-	ParallelWorkers int // Number of workers for parallel processing
+	Options         Options
 
 	// Fields for parallelism
 	jobQueue      chan int                 // Channel of job indices to process
 	resultsChan   chan JobProcessingResult // Channel for workers to send results
+	titlesChan    chan TitleFetchResult    // Titles prefetched with Options.PrefetchTitles, for the job list
+	done          chan struct{}            // Closed on quit so workers stop without blocking
+	cancel        context.CancelFunc       // Cancels Options.ctx on quit
 	jobsCompleted int                      // Counter for completed jobs
+	limiter       *RateLimiter             // Shared by all workers so the rate limit is global
+	hosts         *HostLimiter             // Shared by all workers so the per-host limit is global
+	startedAt     time.Time                // When the workflow was created, for the throughput summary
+	finishedAt    time.Time                // When the last job finished
+	activity      workerActivity           // Jobs the workers are busy with, by phase
+	langPrompts   []LangChoiceRequest      // Workers waiting for the user to pick a language; the first is shown
+	langCursor    int                      // Highlighted choice of the shown language prompt
 	wg            *sync.WaitGroup
 }
 
 // NewWorkflow creates a new workflow with initial state for the given URLs
-func NewWorkflow(urls []string, tempDir, cleanedDir string, parallelWorkers int) WorkflowState {
+func NewWorkflow(urls []string, opts Options) WorkflowState {
 	jobs := make([]TranscriptJob, len(urls))
+	rejected := 0
+	seen := make(map[string]bool)
 	for i, url := range urls {
 		jobs[i] = TranscriptJob{
 			URL:    url,
 			Status: "pending", // Initial status for each job
 		}
+		// Pre-flight: fail URLs that can't be YouTube videos now rather than slowly inside yt-dlp.
+		// YouTube URLs the parser doesn't know still go ahead; the worker asks yt-dlp for their id.
+		videoID, err := ExtractVideoID(url)
+		if err != nil && !opts.AllowAnyURL && (!IsYouTubeURL(url) || IsPlaylistURL(url)) {
+			jobs[i] = failJob(jobs[i], err)
+			rejected++
+			continue
+		}
+		// Later copies of the same video are skipped, so two workers never race on it
+		key := videoID
+		if err != nil {
+			key = url // No parsed id, so only identical URLs match
+		}
+		if seen[key] && !opts.AllowDuplicates {
+			jobs[i].Status = "skipped (duplicate)"
+			rejected++
+		}
+		seen[key] = true
 	}
 
+	// Only jobs that passed the pre-flight need a worker; more would sit idle
+	opts.ParallelWorkers = max(1, min(opts.ParallelWorkers, len(urls)-rejected))
+	ctx, cancel := context.WithCancel(context.Background())
+	opts.ctx = ctx
+
 	initialStage := "fetching_title" // Overall workflow starts by fetching title for the first job
 	if len(urls) == 0 {
 		initialStage = "completed" // Or some other appropriate state if no URLs
 	}
 
+	// Without colour a gradient is noise, so the bar falls back to plain ASCII
+	noColor := ColorDisabled(opts.NoColor)
+	progressStyle := opts.ProgressStyle
+	if progressStyle == "" && noColor {
+		progressStyle = ProgressASCII
+	}
+	progressView := NewProgressView(progressStyle)
+	progressView.NoColor = noColor
+	progressView.ShowIDs = opts.ShowIDs
+
 	return WorkflowState{
 		Jobs:            jobs,
 		CurrentJobIndex: 0,
 		TotalJobs:       len(urls),
 		CurrentStage:    initialStage,
-		ProgressView:    NewProgressView(),
+		ProgressView:    progressView,
+		Spinner:         spinner.New(spinner.WithSpinner(spinner.Dot)),
 		ReadyToQuit:     false,
 		ProcessedFiles:  []string{},
-		TempDir:         tempDir,
-		CleanedDir:      cleanedDir,
-		ParallelWorkers: parallelWorkers,
+		Options:         opts,
 		// Initialize new fields
-		jobQueue:      make(chan int, len(urls)),      // Buffered channel for all job indices
-		resultsChan:   make(chan JobProcessingResult), // Unbuffered for results
-		jobsCompleted: 0,
+		jobQueue:      make(chan int, len(urls)),                 // Buffered channel for all job indices
+		resultsChan:   make(chan JobProcessingResult, len(urls)), // Buffered so workers never block on a final send
+		titlesChan:    make(chan TitleFetchResult, len(urls)),    // Buffered so title prefetchers never block
+		done:          make(chan struct{}),
+		cancel:        cancel,
+		jobsCompleted: rejected, // Rejected and duplicate URLs are already finished; they never reach a worker
+		limiter:       NewRateLimiter(opts.RateLimit),
+		hosts:         NewHostLimiter(opts.PerHost),
+		startedAt:     time.Now(),
 		wg:            &sync.WaitGroup{},
 	}
 }
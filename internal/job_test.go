@@ -2,14 +2,14 @@ package internal
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
 func TestNewWorkflow(t *testing.T) {
 	type args struct {
-		urls       []string
-		rawVTTDir  string
-		cleanedDir string
+		urls []string
+		opts Options
 	}
 	tests := []struct {
 		name string
@@ -19,13 +19,12 @@ func TestNewWorkflow(t *testing.T) {
 		{
 			name: "single URL",
 			args: args{
-				urls:       []string{"http://example.com/video1"},
-				rawVTTDir:  "raw",
-				cleanedDir: "cleaned",
+				urls: []string{"https://youtu.be/video1xxxxx"},
+				opts: Options{TempDir: "raw", CleanedDir: "cleaned"},
 			},
 			want: WorkflowState{
 				Jobs: []TranscriptJob{
-					{URL: "http://example.com/video1", Status: "pending"},
+					{URL: "https://youtu.be/video1xxxxx", Status: "pending"},
 				},
 				CurrentJobIndex: 0,
 				TotalJobs:       1,
@@ -33,49 +32,41 @@ func TestNewWorkflow(t *testing.T) {
 				// ProgressView is initialized, so we can't directly compare it without deeper inspection
 				// ReadyToQuit is false by default
 				// ProcessedFiles is empty by default
-				RawVTTDir:  "raw",
-				CleanedDir: "cleaned",
 			},
 		},
 		{
 			name: "multiple URLs",
 			args: args{
-				urls:       []string{"http://example.com/video1", "http://example.com/video2"},
-				rawVTTDir:  "raw_data",
-				cleanedDir: "cleaned_data",
+				urls: []string{"https://youtu.be/video1xxxxx", "https://youtu.be/video2xxxxx"},
+				opts: Options{TempDir: "raw_data", CleanedDir: "cleaned_data"},
 			},
 			want: WorkflowState{
 				Jobs: []TranscriptJob{
-					{URL: "http://example.com/video1", Status: "pending"},
-					{URL: "http://example.com/video2", Status: "pending"},
+					{URL: "https://youtu.be/video1xxxxx", Status: "pending"},
+					{URL: "https://youtu.be/video2xxxxx", Status: "pending"},
 				},
 				CurrentJobIndex: 0,
 				TotalJobs:       2,
 				CurrentStage:    "fetching_title",
-				RawVTTDir:       "raw_data",
-				CleanedDir:      "cleaned_data",
 			},
 		},
 		{
 			name: "no URLs",
 			args: args{
-				urls:       []string{},
-				rawVTTDir:  "raw",
-				cleanedDir: "cleaned",
+				urls: []string{},
+				opts: Options{TempDir: "raw", CleanedDir: "cleaned"},
 			},
 			want: WorkflowState{
 				Jobs:            []TranscriptJob{},
 				CurrentJobIndex: 0,
 				TotalJobs:       0,
 				CurrentStage:    "completed", // As per NewWorkflow logic for empty URLs
-				RawVTTDir:       "raw",
-				CleanedDir:      "cleaned",
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := NewWorkflow(tt.args.urls, tt.args.rawVTTDir, tt.args.cleanedDir)
+			got := NewWorkflow(tt.args.urls, tt.args.opts)
 			// Compare field by field, excluding ProgressView as it contains unexported fields
 			// and is initialized internally. We assume NewProgressView() works.
 			if !reflect.DeepEqual(got.Jobs, tt.want.Jobs) {
@@ -90,11 +81,8 @@ func TestNewWorkflow(t *testing.T) {
 			if got.CurrentStage != tt.want.CurrentStage {
 				t.Errorf("NewWorkflow().CurrentStage = %v, want %v", got.CurrentStage, tt.want.CurrentStage)
 			}
-			if got.RawVTTDir != tt.want.RawVTTDir {
-				t.Errorf("NewWorkflow().RawVTTDir = %v, want %v", got.RawVTTDir, tt.want.RawVTTDir)
-			}
-			if got.CleanedDir != tt.want.CleanedDir {
-				t.Errorf("NewWorkflow().CleanedDir = %v, want %v", got.CleanedDir, tt.want.CleanedDir)
+			if got.Options.TempDir != tt.args.opts.TempDir || got.Options.CleanedDir != tt.args.opts.CleanedDir {
+				t.Errorf("NewWorkflow() dirs = %q, %q, want %q, %q", got.Options.TempDir, got.Options.CleanedDir, tt.args.opts.TempDir, tt.args.opts.CleanedDir)
 			}
 			if got.ReadyToQuit != tt.want.ReadyToQuit { // Explicitly check default
 				t.Errorf("NewWorkflow().ReadyToQuit = %v, want %v", got.ReadyToQuit, tt.want.ReadyToQuit)
@@ -113,3 +101,15 @@ func TestNewWorkflow(t *testing.T) {
 		})
 	}
 }
+
+func TestNewWorkflow_ProgressStyle(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	wf := NewWorkflow([]string{"https://youtu.be/abc"}, Options{ParallelWorkers: 1})
+	if bar := wf.ProgressView.Progress.ViewAs(1); !strings.HasPrefix(bar, "#") {
+		t.Errorf("progress bar with NO_COLOR = %q, want the plain ascii style", bar)
+	}
+	wf = NewWorkflow([]string{"https://youtu.be/abc"}, Options{ParallelWorkers: 1, ProgressStyle: ProgressSolid})
+	if bar := wf.ProgressView.Progress.ViewAs(1); strings.HasPrefix(bar, "#") {
+		t.Errorf("progress bar with an explicit style = %q, want that style kept under NO_COLOR", bar)
+	}
+}
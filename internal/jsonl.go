@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonlExt is the extension of JSON Lines cue output files.
+const jsonlExt = ".jsonl"
+
+// CueRecord is one line of JSON Lines output: a caption cue with its timing.
+type CueRecord struct {
+	Start float64 `json:"start"` // Seconds from the start of the video
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// WriteCuesJSONL writes cues to w as JSON Lines, one standalone CueRecord
+// object per line with no enclosing array, encoding each as it goes so even
+// huge tracks are never marshalled whole. Cue text has its tags and entities
+// removed and its lines joined by spaces. Like the text format, a line
+// repeating the one before it (as rolling auto-captions do from cue to cue)
+// is dropped unless opts.NoDedupe is set, and cues left empty are skipped.
+// With opts.StitchCues, overlapping cues are stitched first.
+func WriteCuesJSONL(w io.Writer, cues []Cue, opts CleanOptions) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return eachCueRecord(cues, opts, func(record CueRecord) error {
+		return enc.Encode(record)
+	})
+}
+
+// eachCueRecord cleans cues as WriteCuesJSONL describes and calls emit with
+// each one left non-empty, in order, stopping at the first error.
+func eachCueRecord(cues []Cue, opts CleanOptions, emit func(CueRecord) error) error {
+	return eachCleanCue(cues, opts, func(cue Cue, lines []string) error {
+		return emit(CueRecord{Start: cue.Start.Seconds(), End: cue.End.Seconds(), Text: strings.Join(lines, " ")})
+	})
+}
+
+// eachCleanCue is eachCueRecord keeping each cue's cleaned lines apart, for
+// formats that keep a cue's line breaks.
+func eachCleanCue(cues []Cue, opts CleanOptions, emit func(cue Cue, lines []string) error) error {
+	if opts.StitchCues {
+		cues = StitchOverlappingCues(cues)
+	}
+	var last string
+	for _, cue := range cues {
+		lines, _ := removeArtifacts(strings.Split(cue.Text, "\n"), vttArtifact, opts)
+		var kept []string
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if !opts.NoDedupe && line == last {
+				continue
+			}
+			kept = append(kept, line)
+			last = line
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		if err := emit(cue, kept); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCuesJSONLFile parses the cues of a raw subtitle file, keeps those
+// within the trim window and range in cueOpts and writes them to outPath as
+// JSON Lines (see WriteCuesJSONL). The file only appears once complete.
+func WriteCuesJSONLFile(rawFilePath, outPath string, cueOpts CueOptions, opts CleanOptions) error {
+	return writeCuesFile(rawFilePath, outPath, cueOpts, opts, WriteCuesJSONL)
+}
+
+// writeCuesFile parses the cues of a raw subtitle file, keeps those within
+// the trim window and range in cueOpts and writes them to outPath with write,
+// buffered. The file only appears once complete.
+func writeCuesFile(rawFilePath, outPath string, cueOpts CueOptions, opts CleanOptions, write func(io.Writer, []Cue, CleanOptions) error) error {
+	raw, err := ReadTextFile(rawFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read subtitle file %s: %w", rawFilePath, err)
+	}
+	cues := ParseVTTCues(raw)
+	cues = RangeCues(TrimCues(cues, cueOpts.TrimIntro, cueOpts.TrimOutro, cueOpts.Duration), cueOpts.Start, cueOpts.End)
+	return writeFileAtomic(outPath, func(w io.Writer) error {
+		buf := bufio.NewWriter(w)
+		if err := write(buf, cues, opts); err != nil {
+			return err
+		}
+		return buf.Flush()
+	})
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeJSONL parses JSON Lines output one line at a time, failing if any
+// line is not a standalone object.
+func decodeJSONL(t *testing.T, data []byte) []CueRecord {
+	t.Helper()
+	var records []CueRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var r CueRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("line %q is not valid JSON: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestWriteCuesJSONL(t *testing.T) {
+	cues := ParseVTTCues(autoSubVTT)
+
+	var buf bytes.Buffer
+	if err := WriteCuesJSONL(&buf, cues, CleanOptions{}); err != nil {
+		t.Fatalf("WriteCuesJSONL() error = %v", err)
+	}
+	if strings.HasPrefix(buf.String(), "[") || !strings.HasSuffix(buf.String(), "}\n") {
+		t.Errorf("WriteCuesJSONL() output should be bare objects, one per line: %q", buf.String())
+	}
+	want := []CueRecord{{1, 3, "hello world it's"}, {3.5, 5, "me again"}}
+	if got := decodeJSONL(t, buf.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteCuesJSONL() = %+v, want %+v", got, want)
+	}
+
+	buf.Reset()
+	if err := WriteCuesJSONL(&buf, cues, CleanOptions{NoDedupe: true}); err != nil {
+		t.Fatalf("WriteCuesJSONL(NoDedupe) error = %v", err)
+	}
+	want = []CueRecord{{1, 3, "hello world it's"}, {3, 3.5, "hello world it's"}, {3.5, 5, "hello world it's me again"}}
+	if got := decodeJSONL(t, buf.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteCuesJSONL(NoDedupe) = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteCuesJSONLFile(t *testing.T) {
+	dir := t.TempDir()
+	rawPath := filepath.Join(dir, "abc.en.vtt")
+	if err := os.WriteFile(rawPath, []byte(autoSubVTT), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(dir, "Talk.jsonl")
+	if err := WriteCuesJSONLFile(rawPath, outPath, CueOptions{Start: 3500 * time.Millisecond}, CleanOptions{}); err != nil {
+		t.Fatalf("WriteCuesJSONLFile() error = %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CueRecord{{3.5, 5, "hello world it's me again"}}
+	if got := decodeJSONL(t, data); !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteCuesJSONLFile(Start 3.5s) = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LangDetector guesses the language a cleaned transcript is written in,
+// returning its code (e.g. "en") or "" when it can't tell. DetectLang is
+// called from worker goroutines, so implementations must be safe for
+// concurrent use.
+type LangDetector interface {
+	DetectLang(text string) string
+}
+
+// minDetectWords is the fewest words StopwordDetector will guess from; below
+// that a handful of stopwords decides the answer.
+const minDetectWords = 20
+
+// StopwordDetector is a LangDetector that needs no model: it tells scripts
+// mostly used by one language (Japanese kana, Korean Hangul, Chinese Han,
+// Thai, ...) apart by their characters, taking any Cyrillic as Russian, and
+// Latin-script languages by how many of their most common words the text
+// uses. It knows only those languages and the ones listed in stopwords,
+// guesses no Latin-script language from fewer than minDetectWords words, and
+// can mistake closely related languages (Spanish and Portuguese, say) in
+// short or mixed-language transcripts.
+type StopwordDetector struct{}
+
+// stopwords lists frequent short words of each Latin-script language
+// StopwordDetector knows. Words common to several of them are left out.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "that", "it", "you", "was", "for", "with", "this", "have", "be", "are", "what", "they", "we", "not", "but"},
+	"es": {"el", "los", "las", "y", "es", "por", "con", "una", "pero", "muy", "como", "más", "esto", "sí", "también", "yo", "lo", "qué", "hay", "pues"},
+	"fr": {"les", "des", "et", "est", "pas", "une", "pour", "dans", "qui", "ce", "sur", "avec", "je", "vous", "nous", "mais", "c'est", "sont", "du", "on"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ich", "ein", "eine", "zu", "mit", "sich", "auf", "wir", "auch", "dass", "sie", "den", "von", "aber"},
+	"it": {"che", "di", "non", "per", "gli", "della", "sono", "questo", "io", "ma", "cosa", "molto", "perché", "nel", "ci", "anche", "quindi", "come", "ho", "mi"},
+	"pt": {"o", "os", "não", "um", "uma", "do", "em", "com", "mas", "isso", "você", "eu", "muito", "também", "ao", "então", "aqui", "tem", "vai", "né"},
+	"nl": {"het", "een", "van", "ik", "niet", "dat", "op", "zijn", "ook", "maar", "wat", "er", "met", "wij", "dit", "heb", "naar", "als", "wel", "nog"},
+}
+
+// stopwordSets indexes stopwords for lookup.
+var stopwordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(stopwords))
+	for lang, words := range stopwords {
+		sets[lang] = make(map[string]bool, len(words))
+		for _, word := range words {
+			sets[lang][word] = true
+		}
+	}
+	return sets
+}()
+
+// DetectLang returns the language text is most likely in, or "" if it is too
+// short or no language stands out.
+func (StopwordDetector) DetectLang(text string) string {
+	if lang := detectScript(text); lang != "" {
+		return lang
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < minDetectWords {
+		return ""
+	}
+	scores := make(map[string]int, len(stopwordSets))
+	for _, word := range words {
+		for lang, set := range stopwordSets {
+			if set[word] {
+				scores[lang]++
+			}
+		}
+	}
+	best, bestScore, runnerUp := "", 0, 0
+	for lang, score := range scores {
+		if score > bestScore || (score == bestScore && lang < best) {
+			best, bestScore, runnerUp = lang, score, bestScore
+		} else if score > runnerUp {
+			runnerUp = score
+		}
+	}
+	// Transcripts in any of these languages are full of their stopwords, so
+	// a text with few hits, or hits split evenly, is something else.
+	if bestScore*10 < len(words) || bestScore*2 < runnerUp*3 {
+		return ""
+	}
+	return best
+}
+
+// detectScript returns the language of text's letters when most of them are
+// in a script only one language (of those common on YouTube) is written in,
+// else "". Japanese is told from Chinese by its kana.
+func detectScript(text string) string {
+	var letters, kana, han, hangul, cyrillic, arabic, devanagari, thai int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Devanagari, r):
+			devanagari++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	majority := func(n int) bool { return n*2 > letters }
+	switch {
+	case kana > 0 && majority(kana+han):
+		return "ja"
+	case majority(han):
+		return "zh"
+	case majority(hangul):
+		return "ko"
+	case majority(cyrillic):
+		return "ru"
+	case majority(arabic):
+		return "ar"
+	case majority(devanagari):
+		return "hi"
+	case majority(thai):
+		return "th"
+	}
+	return ""
+}
+
+// detectTranscriptLang returns the language opts.DetectLang finds in the
+// transcript written to file, or "" if detection is off, the output format
+// isn't plain text, or it can't tell.
+func detectTranscriptLang(file string, opts Options) string {
+	if opts.DetectLang == nil || (opts.Format != "" && opts.Format != FormatText && opts.Format != FormatTimedText) {
+		return ""
+	}
+	text, err := ReadTextFile(file)
+	if err != nil {
+		return ""
+	}
+	return opts.DetectLang.DetectLang(text)
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStopwordDetector(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "So the thing is that we have to think about what they want, and it is not what you would expect. This was the plan for the team, but with the time we have it is hard to be sure that it works.", "en"},
+		{"spanish", "Bueno, el problema es que no hay tiempo para todo. Yo creo que esto es muy importante, pero también hay que pensar en los costos y en las personas que trabajan con nosotros por la mañana.", "es"},
+		{"french", "Alors je pense que c'est vraiment important pour nous, mais il faut voir les choses dans le bon sens. On est pas sûr que ce soit la solution avec les moyens que nous avons sur place.", "fr"},
+		{"german", "Also ich denke, dass wir das nicht so machen sollten. Die Frage ist, ob sie auch mit dem Ergebnis zufrieden sind, und wir müssen uns auf die Zahlen von letztem Jahr konzentrieren, aber das ist schwer.", "de"},
+		{"portuguese", "Então, eu acho que isso é muito importante para você entender. Não é só um detalhe, mas tem uma coisa aqui que vai mudar tudo, e eu também quero falar do resultado ao final do vídeo.", "pt"},
+		{"japanese", "今日はいい天気ですね。これから東京に行きます。", "ja"},
+		{"chinese", "今天天气很好我们去公园散步吧", "zh"},
+		{"korean", "오늘은 날씨가 좋네요", "ko"},
+		{"russian", "Сегодня хорошая погода", "ru"},
+		{"too short", "the thing is that it is", ""},
+		{"no stopwords", strings.Repeat("lorem ipsum dolor sit amet ", 10), ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (StopwordDetector{}).DetectLang(tt.text); got != tt.want {
+				t.Errorf("DetectLang(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// fixedDetector detects every transcript as the language named in its first
+// word, or as nothing if that word is "unknown".
+type fixedDetector struct{}
+
+func (fixedDetector) DetectLang(text string) string {
+	if first, _, _ := strings.Cut(strings.TrimSpace(text), " "); first != "unknown" {
+		return first
+	}
+	return ""
+}
+
+func TestProcessJob_DetectLang(t *testing.T) {
+	installFakeYtDlp(t, `case "$*" in *"--print title"*) echo 'Fake Title'; exit 0;; esac
+for a in "$@"; do [ "$prev" = "-o" ] && out="$a"; prev="$a"; done
+printf 'WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nfr words\n' > "$(dirname "$out")/abc.de.vtt"
+printf 'WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nunknown words\n' > "$(dirname "$out")/abc.en.vtt"
+`)
+	opts := Options{TempDir: t.TempDir(), CleanedDir: t.TempDir(), AllLangs: true, DetectLang: fixedDetector{}}
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Error != nil || job.Status != "completed" {
+		t.Fatalf("workJob(DetectLang) = %q, %v, want completed", job.Status, job.Error)
+	}
+	if job.DetectedLang != "de:fr" {
+		t.Errorf("workJob(AllLangs, DetectLang) DetectedLang = %q, want %q", job.DetectedLang, "de:fr")
+	}
+
+	opts = Options{TempDir: t.TempDir(), CleanedDir: t.TempDir(), Lang: "de", DetectLang: fixedDetector{}}
+	if job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts); job.DetectedLang != "fr" {
+		t.Errorf("workJob(DetectLang) DetectedLang = %q, want %q", job.DetectedLang, "fr")
+	}
+
+	opts.Format, opts.CleanedDir = FormatJSONL, t.TempDir()
+	if job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts); job.DetectedLang != "" {
+		t.Errorf("workJob(DetectLang, jsonl) DetectedLang = %q, want none", job.DetectedLang)
+	}
+}
+
+func TestDetectTranscriptLang_Off(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "t.txt")
+	if err := os.WriteFile(file, []byte("fr words\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := detectTranscriptLang(file, Options{}); got != "" {
+		t.Errorf("detectTranscriptLang() without DetectLang = %q, want none", got)
+	}
+	if got := detectTranscriptLang(file, Options{DetectLang: fixedDetector{}}); got != "fr" {
+		t.Errorf("detectTranscriptLang() = %q, want %q", got, "fr")
+	}
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"errors"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ErrLangChoiceCancelled is returned to a worker whose language prompt was
+// dismissed because the user quit.
+var ErrLangChoiceCancelled = errors.New("language selection cancelled")
+
+// LangChooser picks the caption language of a video that offers several, when
+// the user didn't ask for a specific one. ChooseLang is called from worker
+// goroutines and may block until the user has answered.
+type LangChooser interface {
+	ChooseLang(job TranscriptJob, langs []string) (string, error)
+}
+
+// LangChoiceRequest asks the TUI to let the user pick one of Langs for the
+// video titled Title. The answer is sent on Reply exactly once.
+type LangChoiceRequest struct {
+	Title string
+	Langs []string
+	Reply chan<- LangChoice
+}
+
+// LangChoice is the answer to a LangChoiceRequest.
+type LangChoice struct {
+	Lang string
+	Err  error
+}
+
+// ProgramLangChooser asks the user through a running bubbletea program,
+// where WorkflowState.Update shows the choices as a selection list.
+type ProgramLangChooser struct {
+	Program *tea.Program
+}
+
+// ChooseLang sends the choice to the program and waits for the user's answer.
+// Without a program it chooses nothing, so the configured language is used.
+func (c *ProgramLangChooser) ChooseLang(job TranscriptJob, langs []string) (string, error) {
+	if c.Program == nil {
+		return "", nil
+	}
+	reply := make(chan LangChoice, 1)
+	c.Program.Send(LangChoiceRequest{Title: job.Title, Langs: langs, Reply: reply})
+	choice := <-reply
+	return choice.Lang, choice.Err
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fixedLangChooser picks Lang and records the choices it was offered.
+type fixedLangChooser struct {
+	Lang    string
+	Offered []string
+}
+
+func (c *fixedLangChooser) ChooseLang(job TranscriptJob, langs []string) (string, error) {
+	c.Offered = langs
+	return c.Lang, nil
+}
+
+func TestSubtitleLanguages_Native(t *testing.T) {
+	available := SubtitleLanguages{Manual: []string{"de", "en"}, Auto: []string{"de-orig", "en", "fr", "ja-orig"}}
+	if got, want := available.Native(), []string{"de", "en", "ja-orig"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Native() = %q, want %q", got, want)
+	}
+}
+
+func TestDownloadSubtitles_ChooseLang(t *testing.T) {
+	// A video with German and Japanese captions; only a "ja" request writes a file.
+	installFakeYtDlp(t, `case "$*" in *--dump-json*) echo '{"subtitles": {"de": []}, "automatic_captions": {"ja-orig": [], "en": []}}'; exit 0;; esac
+case "$*" in *"--sub-lang ja-orig"*) ;; *) exit 0;; esac
+prev=""; for a in "$@"; do if [ "$prev" = "-o" ]; then out="$a"; fi; prev="$a"; done
+printf 'WEBVTT\n\nhello\n' > "$(dirname "$out")/abc.ja-orig.vtt"
+`)
+
+	chooser := &fixedLangChooser{Lang: "ja-orig"}
+	job := TranscriptJob{URL: "https://youtu.be/abc"}
+	files, err := downloadSubtitles(&job, "abc", Options{TempDir: t.TempDir(), ChooseLang: chooser}, nil)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("downloadSubtitles() = %v, %v, want the chosen language's file", files, err)
+	}
+	if want := []string{"de", "ja-orig"}; !reflect.DeepEqual(chooser.Offered, want) {
+		t.Errorf("offered %q, want %q", chooser.Offered, want)
+	}
+	if job.Language != "ja-orig" {
+		t.Errorf("job.Language = %q, want ja-orig", job.Language)
+	}
+}
+
+// installCountingYtDlp fakes a video with German, English and Japanese
+// captions whose language listings are counted in the returned file.
+func installCountingYtDlp(t *testing.T) string {
+	t.Helper()
+	listings := filepath.Join(t.TempDir(), "listings")
+	installFakeYtDlp(t, `case "$*" in *--dump-json*) echo x >> "`+listings+`"; echo '{"subtitles": {"de": [], "en": []}, "automatic_captions": {"ja-orig": []}}'; exit 0;; esac
+for a in "$@"; do [ "$prev" = "--sub-lang" ] && lang="$a"; [ "$prev" = "-o" ] && out="$a"; prev="$a"; done
+printf 'WEBVTT\n\nhello\n' > "$(dirname "$out")/abc.$lang.vtt"
+`)
+	return listings
+}
+
+// countListings returns how many times the fake yt-dlp listed languages.
+func countListings(t *testing.T, listings string) int {
+	t.Helper()
+	data, err := os.ReadFile(listings)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "x")
+}
+
+func TestDownloadSubtitles_NoChooserKeepsLang(t *testing.T) {
+	// A non-interactive run has no chooser: the configured language is
+	// fetched without listing the video's languages first.
+	listings := installCountingYtDlp(t)
+	job := TranscriptJob{URL: "https://youtu.be/abc"}
+	files, err := downloadSubtitles(&job, "abc", Options{TempDir: t.TempDir(), Lang: "de"}, nil)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("downloadSubtitles() = %v, %v, want the configured language's file", files, err)
+	}
+	if job.Language != "de" {
+		t.Errorf("job.Language = %q, want de", job.Language)
+	}
+	if n := countListings(t, listings); n != 0 {
+		t.Errorf("languages listed %d time(s), want 0", n)
+	}
+}
+
+func TestDownloadSubtitles_ChooseLangListsOnce(t *testing.T) {
+	listings := installCountingYtDlp(t)
+	chooser := &fixedLangChooser{Lang: "en"}
+	job := TranscriptJob{URL: "https://youtu.be/abc"}
+	if _, err := downloadSubtitles(&job, "abc", Options{TempDir: t.TempDir(), ChooseLang: chooser, Track: 1}, nil); err != nil {
+		t.Fatalf("downloadSubtitles() error = %v", err)
+	}
+	if job.Language != "en" {
+		t.Errorf("job.Language = %q, want en", job.Language)
+	}
+	if n := countListings(t, listings); n != 1 {
+		t.Errorf("languages listed %d time(s), want choosing and picking a track to share 1", n)
+	}
+}
+
+func TestWorkflowState_Update_LangPrompt(t *testing.T) {
+	var model tea.Model = NewWorkflow([]string{"https://youtu.be/abc"}, Options{ParallelWorkers: 1})
+	first, second := make(chan LangChoice, 1), make(chan LangChoice, 1)
+	model, _ = model.Update(LangChoiceRequest{Title: "First", Langs: []string{"de", "en", "ja"}, Reply: first})
+	model, _ = model.Update(LangChoiceRequest{Title: "Second", Langs: []string{"fr", "it"}, Reply: second})
+
+	view := model.View()
+	if !strings.Contains(view, "Choose a caption language for First") || !strings.Contains(view, "> de") || !strings.Contains(view, "1 more video(s) waiting") {
+		t.Errorf("View() = %q, want the first prompt with de highlighted", view)
+	}
+
+	for _, key := range []tea.KeyMsg{{Type: tea.KeyDown}, {Type: tea.KeyDown}, {Type: tea.KeyDown}, {Type: tea.KeyUp}, {Type: tea.KeyEnter}} {
+		model, _ = model.Update(key)
+	}
+	if choice := <-first; choice.Lang != "en" || choice.Err != nil {
+		t.Errorf("first choice = %+v, want en", choice)
+	}
+	if view := model.View(); !strings.Contains(view, "Choose a caption language for Second") || !strings.Contains(view, "> fr") {
+		t.Errorf("View() = %q, want the second prompt next, highlight reset", view)
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if choice := <-second; !errors.Is(choice.Err, ErrLangChoiceCancelled) {
+		t.Errorf("second choice after quitting = %+v, want ErrLangChoiceCancelled", choice)
+	}
+}
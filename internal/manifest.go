@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Manifest maps URLs to the output paths their transcripts are written to,
+// overriding the path computed from the title. A URL is matched by its video
+// id where it has one, so any form of a video's URL finds its entry.
+type Manifest struct {
+	outputs map[string]string // Output path by video id, or by URL if it has none
+}
+
+// manifestEntry is one url,output pair of a manifest file.
+type manifestEntry struct {
+	URL    string `json:"url"`
+	Output string `json:"output"`
+}
+
+// LoadManifest reads the manifest at path: a JSON array of {"url", "output"}
+// objects if path ends in .json, else CSV rows of url,output, with an
+// optional url,output header and "#" comment lines. A video listed twice, or
+// two videos given the same output, is an error.
+func LoadManifest(path string) (*Manifest, error) {
+	content, err := ReadTextFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var entries []manifestEntry
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal([]byte(content), &entries)
+	} else {
+		entries, err = parseManifestCSV(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	manifest := &Manifest{outputs: make(map[string]string)}
+	listed := make(map[string]string)  // URL listed under each key
+	claimed := make(map[string]string) // URL given each output
+	for _, entry := range entries {
+		url, output := strings.TrimSpace(entry.URL), strings.TrimSpace(entry.Output)
+		if url == "" || output == "" {
+			return nil, fmt.Errorf("manifest entry %q -> %q needs both a url and an output", url, output)
+		}
+		key := manifestKey(url)
+		if first, ok := listed[key]; ok {
+			return nil, fmt.Errorf("manifest lists %s twice (also as %s)", url, first)
+		}
+		output = filepath.Clean(output)
+		if first, ok := claimed[output]; ok {
+			return nil, fmt.Errorf("manifest gives %s and %s the same output %s", first, url, output)
+		}
+		listed[key], claimed[output] = url, url
+		manifest.outputs[key] = output
+	}
+	return manifest, nil
+}
+
+// parseManifestCSV reads the url,output rows of a CSV manifest.
+func parseManifestCSV(content string) ([]manifestEntry, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.Comment = '#'
+	r.FieldsPerRecord = 2
+	r.TrimLeadingSpace = true
+	var entries []manifestEntry
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 && strings.EqualFold(record[0], "url") && strings.EqualFold(record[1], "output") {
+			continue // Header
+		}
+		entries = append(entries, manifestEntry{URL: record[0], Output: record[1]})
+	}
+}
+
+// manifestKey is the key a URL is listed under: its video id, if it has one.
+func manifestKey(url string) string {
+	if videoID, err := ExtractVideoID(url); err == nil {
+		return videoID
+	}
+	return url
+}
+
+// Output returns the output path listed for a job's video, given its URL and
+// (if known) video id, or "" if it isn't listed. A relative output is
+// relative to dir. A nil Manifest lists nothing.
+func (m *Manifest) Output(url, videoID, dir string) string {
+	if m == nil {
+		return ""
+	}
+	output, ok := m.outputs[videoID]
+	if !ok {
+		if output, ok = m.outputs[manifestKey(url)]; !ok {
+			return ""
+		}
+	}
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(dir, output)
+	}
+	return output
+}
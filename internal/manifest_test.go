@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeManifest(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadManifest(t *testing.T) {
+	for name, content := range map[string]string{
+		"manifest.csv": "url,output\n# Talks\nhttps://www.youtube.com/watch?v=dQw4w9WgXcQ, talks/rick.txt\nhttps://example.com/video,/archive/other.txt\n",
+		"manifest.json": `[{"url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "output": "talks/rick.txt"},
+			{"url": "https://example.com/video", "output": "/archive/other.txt"}]`,
+	} {
+		manifest, err := LoadManifest(writeManifest(t, name, content))
+		if err != nil {
+			t.Fatalf("LoadManifest(%s) error = %v", name, err)
+		}
+		tests := []struct {
+			url, videoID, want string
+		}{
+			{"https://youtu.be/dQw4w9WgXcQ", "", filepath.Join("out", "talks", "rick.txt")},
+			{"https://www.youtube.com/shorts/whatever", "dQw4w9WgXcQ", filepath.Join("out", "talks", "rick.txt")},
+			{"https://example.com/video", "", "/archive/other.txt"},
+			{"https://youtu.be/jNQXAC9IVRw", "jNQXAC9IVRw", ""},
+		}
+		for _, tt := range tests {
+			if got := manifest.Output(tt.url, tt.videoID, "out"); got != tt.want {
+				t.Errorf("%s: Output(%q, %q) = %q, want %q", name, tt.url, tt.videoID, got, tt.want)
+			}
+		}
+	}
+
+	var nilManifest *Manifest
+	if got := nilManifest.Output("https://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ", "out"); got != "" {
+		t.Errorf("nil Manifest Output() = %q, want \"\"", got)
+	}
+}
+
+func TestLoadManifest_Invalid(t *testing.T) {
+	tests := []struct {
+		name, content, wantErr string
+	}{
+		{"duplicate video", "https://youtu.be/dQw4w9WgXcQ,a.txt\nhttps://www.youtube.com/watch?v=dQw4w9WgXcQ,b.txt\n", "twice"},
+		{"shared output", "https://youtu.be/dQw4w9WgXcQ,a.txt\nhttps://youtu.be/jNQXAC9IVRw,./a.txt\n", "same output"},
+		{"missing output", "https://youtu.be/dQw4w9WgXcQ, \n", "needs both"},
+		{"wrong field count", "https://youtu.be/dQw4w9WgXcQ\n", "parse"},
+	}
+	for _, tt := range tests {
+		_, err := LoadManifest(writeManifest(t, "manifest.csv", tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: LoadManifest() error = %v, want one mentioning %q", tt.name, err, tt.wantErr)
+		}
+	}
+	if _, err := LoadManifest(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("LoadManifest(missing file) error = nil, want an error")
+	}
+}
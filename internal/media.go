@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// mediaExts are the extensions of the video and audio files a media library
+// holds, which MediaIndex matches transcripts to.
+var mediaExts = []string{".mkv", ".mp4", ".m4v", ".webm", ".mov", ".avi", ".mp3", ".m4a", ".opus", ".ogg", ".flac", ".wav"}
+
+// MediaIndex lists the media files in a library directory (e.g. a Plex or
+// Jellyfin library), so a transcript can be written next to the file of the
+// same video.
+type MediaIndex struct {
+	files []string // Paths of the media files, in walk order
+}
+
+// LoadMediaIndex scans dir and its subdirectories for media files, skipping
+// hidden directories.
+func LoadMediaIndex(dir string) (*MediaIndex, error) {
+	index := &MediaIndex{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isMediaFile(d.Name()) {
+			index.files = append(index.files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Find returns the first media file whose name contains videoID as a whole
+// word, e.g. "My Talk [dQw4w9WgXcQ].mkv", or "" if none does.
+func (m *MediaIndex) Find(videoID string) string {
+	if m == nil || videoID == "" {
+		return ""
+	}
+	for _, path := range m.files {
+		if containsID(filepath.Base(path), videoID) {
+			return path
+		}
+	}
+	return ""
+}
+
+// isMediaFile reports whether name has a media file extension.
+func isMediaFile(name string) bool {
+	return slices.Contains(mediaExts, strings.ToLower(filepath.Ext(name)))
+}
+
+// containsID reports whether name contains id with no letter or digit right
+// before or after it, so one id isn't found inside a longer one.
+func containsID(name, id string) bool {
+	for start := 0; ; {
+		idx := strings.Index(name[start:], id)
+		if idx == -1 {
+			return false
+		}
+		idx += start
+		end := idx + len(id)
+		if (idx == 0 || !isAlnum(name[idx-1])) && (end == len(name) || !isAlnum(name[end])) {
+			return true
+		}
+		start = idx + 1
+	}
+}
+
+// isAlnum reports whether c is an ASCII letter or digit.
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMediaIndex_Find(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"Show/Season 1/My Talk [dQw4w9WgXcQ].mkv",
+		"Show/Season 1/My Talk [dQw4w9WgXcQ].txt", // Not media
+		"podcast/abc_def-123.mp3",
+		"other/xdQw4w9WgXcQ.mp4",   // Id inside a longer word
+		".hidden/jNQXAC9IVRw.webm", // Hidden directories are skipped
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index, err := LoadMediaIndex(dir)
+	if err != nil {
+		t.Fatalf("LoadMediaIndex() error = %v", err)
+	}
+	tests := map[string]string{
+		"dQw4w9WgXcQ": filepath.Join(dir, "Show/Season 1/My Talk [dQw4w9WgXcQ].mkv"),
+		"abc_def-123": filepath.Join(dir, "podcast/abc_def-123.mp3"),
+		"jNQXAC9IVRw": "",
+		"nope":        "",
+		"":            "",
+	}
+	for id, want := range tests {
+		if got := index.Find(id); got != want {
+			t.Errorf("Find(%q) = %q, want %q", id, got, want)
+		}
+	}
+	if got := (*MediaIndex)(nil).Find("dQw4w9WgXcQ"); got != "" {
+		t.Errorf("nil MediaIndex Find() = %q, want none", got)
+	}
+}
+
+func TestLoadMediaIndex_MissingDir(t *testing.T) {
+	if _, err := LoadMediaIndex(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadMediaIndex() error = nil, want an error for a missing directory")
+	}
+}
@@ -0,0 +1,108 @@
+package internal
+
+import "strings"
+
+// LineTransform is a cleaning stage that works on a transcript's caption
+// lines, e.g. collapsing repeated lines.
+type LineTransform func(lines []string) []string
+
+// TextTransform is a cleaning stage that works on the joined transcript,
+// e.g. re-casing it.
+type TextTransform func(text string) string
+
+// Pipeline is the ordered cleaning stages run on the caption lines left once
+// subtitle artifacts (headers, timings, cue numbers, HTML tags) are removed:
+// each line transform in turn, then the lines are joined, then each text
+// transform in turn. Stages can be appended or reordered before Run.
+type Pipeline struct {
+	Lines []LineTransform
+	Text  []TextTransform
+}
+
+// NewPipeline assembles the stages opts asks for, in order: ASCII
+// punctuation, word stutters, speaker turn splitting, dedupe, opts.Transforms,
+// then re-casing. With the zero
+// CleanOptions it only collapses consecutive repeated lines.
+func NewPipeline(opts CleanOptions) Pipeline {
+	return newPipeline(opts, nil)
+}
+
+// newPipeline is NewPipeline, adding the lines the dedupe stage collapses to
+// stats.Duplicates and the words the stutter stage drops to stats.Stutters if
+// stats is not nil.
+func newPipeline(opts CleanOptions, stats *CleanStats) Pipeline {
+	var p Pipeline
+	if opts.ASCII {
+		p.Lines = append(p.Lines, MapLines(NormalizeToASCII))
+	}
+	if opts.FixStutter {
+		var keep []string
+		if opts.KeepDoubles {
+			keep = IntentionalDoubles
+		}
+		p.Lines = append(p.Lines, func(lines []string) []string {
+			out := make([]string, len(lines))
+			for i, line := range lines {
+				var dropped int
+				out[i], dropped = collapseWordStutter(line, keep)
+				if stats != nil {
+					stats.Stutters += dropped
+				}
+			}
+			return out
+		})
+	}
+	if opts.Speakers {
+		p.Lines = append(p.Lines, func(lines []string) []string { return splitSpeakerTurns(lines, opts) })
+	}
+	if !opts.NoDedupe {
+		p.Lines = append(p.Lines, func(lines []string) []string {
+			out := dedupeLines(lines, opts)
+			if stats != nil {
+				stats.Duplicates += len(lines) - len(out)
+			}
+			return out
+		})
+	}
+	p.Lines = append(p.Lines, opts.Transforms...)
+	// With Speakers, labels are re-spaced even when the case is kept
+	if (opts.Case != "" && opts.Case != CaseKeep) || opts.Speakers {
+		p.Text = append(p.Text, func(text string) string { return applyCase(text, opts) })
+	}
+	return p
+}
+
+// MapLines turns a function of one line into a LineTransform applying it to
+// every line.
+func MapLines(fn func(string) string) LineTransform {
+	return func(lines []string) []string {
+		out := make([]string, len(lines))
+		for i, line := range lines {
+			out[i] = fn(line)
+		}
+		return out
+	}
+}
+
+// RunLines runs the line transforms on lines, returning the final lines.
+func (p Pipeline) RunLines(lines []string) []string {
+	for _, transform := range p.Lines {
+		lines = transform(lines)
+	}
+	return lines
+}
+
+// Run runs every stage on lines and returns the cleaned transcript.
+func (p Pipeline) Run(lines []string) string {
+	return p.join(p.RunLines(lines))
+}
+
+// join joins lines already through the line transforms and runs the text
+// transforms on the result.
+func (p Pipeline) join(lines []string) string {
+	text := strings.Join(lines, "\n")
+	for _, transform := range p.Text {
+		text = transform(text)
+	}
+	return text
+}
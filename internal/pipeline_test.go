@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewPipeline_Transforms(t *testing.T) {
+	bang := func(lines []string) []string {
+		out := make([]string, len(lines))
+		for i, line := range lines {
+			out[i] = line + "!"
+		}
+		return out
+	}
+	// Run after dedupe and before re-casing
+	got := NewPipeline(CleanOptions{Case: CaseUpper, Transforms: []LineTransform{bang}}).Run([]string{"hi", "hi", "there"})
+	if want := "HI!\nTHERE!"; got != want {
+		t.Errorf("NewPipeline(Transforms).Run() = %q, want %q", got, want)
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+	lines := []string{"hello there", "hello there", "“it’s” fine >> yes", "yes"}
+	tests := []struct {
+		name string
+		opts CleanOptions
+		want string
+	}{
+		{"defaults", CleanOptions{}, "hello there\n“it’s” fine >> yes\nyes"},
+		{"no dedupe", CleanOptions{NoDedupe: true}, "hello there\nhello there\n“it’s” fine >> yes\nyes"},
+		{"ascii", CleanOptions{ASCII: true}, "hello there\n\"it's\" fine >> yes\nyes"},
+		{"speakers then dedupe", CleanOptions{Speakers: true}, "hello there\n“it’s” fine\n>> yes\nyes"},
+		{"upper", CleanOptions{Case: CaseUpper}, "HELLO THERE\n“IT’S” FINE >> YES\nYES"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewPipeline(tt.opts).Run(lines); got != tt.want {
+				t.Errorf("NewPipeline(%+v).Run() = %q, want %q", tt.opts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeline_CustomStages(t *testing.T) {
+	// Stripping "!" before the default dedupe stage lets "a!" and "a" collapse
+	p := NewPipeline(CleanOptions{})
+	p.Lines = append([]LineTransform{MapLines(func(s string) string { return strings.TrimSuffix(s, "!") })}, p.Lines...)
+	p.Text = append(p.Text, strings.ToUpper)
+
+	if got, want := p.RunLines([]string{"a!", "a", "b"}), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RunLines() = %q, want %q", got, want)
+	}
+	if got, want := p.Run([]string{"a!", "a", "b"}), "A\nB"; got != want {
+		t.Errorf("Run() = %q, want %q", got, want)
+	}
+}
+
+func TestNewPipeline_FixStutter(t *testing.T) {
+	var stats CleanStats
+	// Collapsing stutters first lets the dedupe stage see the lines as repeats
+	got := newPipeline(CleanOptions{FixStutter: true}, &stats).Run([]string{"so so it had had", "so it had"})
+	if got != "so it had" {
+		t.Errorf("newPipeline(FixStutter).Run() = %q, want %q", got, "so it had")
+	}
+	if stats.Stutters != 2 || stats.Duplicates != 1 {
+		t.Errorf("newPipeline() counted %d stutters and %d duplicates, want 2 and 1", stats.Stutters, stats.Duplicates)
+	}
+
+	got = NewPipeline(CleanOptions{FixStutter: true, KeepDoubles: true}).Run([]string{"so so it had had"})
+	if got != "so it had had" {
+		t.Errorf("newPipeline(KeepDoubles).Run() = %q, want %q", got, "so it had had")
+	}
+}
+
+func TestNewPipeline_CountsDuplicates(t *testing.T) {
+	var stats CleanStats
+	newPipeline(CleanOptions{}, &stats).Run([]string{"a", "a", "b", "b", "b"})
+	if stats.Duplicates != 3 {
+		t.Errorf("newPipeline() counted %d duplicates, want 3", stats.Duplicates)
+	}
+}
@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// playlistEntryTemplate is the yt-dlp --print template FetchPlaylist uses:
+// one tab-separated line per video.
+const playlistEntryTemplate = "%(playlist_title)s\t%(id)s\t%(title)s"
+
+// PlaylistVideo is one video of a playlist, as listed by FetchPlaylist.
+type PlaylistVideo struct {
+	ID    string
+	Title string
+}
+
+// URL returns the video's YouTube watch URL.
+func (v PlaylistVideo) URL() string {
+	return "https://www.youtube.com/watch?v=" + v.ID
+}
+
+// Playlist is a playlist's title and its videos, in playlist order.
+type Playlist struct {
+	URL    string
+	Title  string
+	Videos []PlaylistVideo
+}
+
+// IsPlaylistURL reports whether url names a YouTube playlist rather than a
+// single video. A watch URL that also carries a list= parameter is a video.
+func IsPlaylistURL(url string) bool {
+	_, err := ExtractVideoID(url)
+	return err != nil && strings.Contains(url, "list=")
+}
+
+// FetchPlaylist uses yt-dlp to list a playlist's videos without resolving
+// each one, which is a single fast request.
+func FetchPlaylist(url string) (Playlist, error) {
+	return FetchPlaylistN(url, 0)
+}
+
+// FetchPlaylistN is FetchPlaylist listing only the first n videos, in
+// playlist order; a non-positive n lists them all. yt-dlp is asked for just
+// those entries, so a long playlist isn't paged through.
+func FetchPlaylistN(url string, n int) (Playlist, error) {
+	args := []string{"--quiet", "--flat-playlist", "--print", playlistEntryTemplate}
+	if n > 0 {
+		args = append(args, "--playlist-items", fmt.Sprintf("1:%d", n))
+	}
+	output, err := runYtDlp(context.Background(), append(args, url)...)
+	if err != nil {
+		return Playlist{}, fmt.Errorf("yt-dlp failed to list playlist: %w", err)
+	}
+	playlist := ParsePlaylist(output)
+	playlist.URL = url
+	if n > 0 && len(playlist.Videos) > n {
+		playlist.Videos = playlist.Videos[:n]
+	}
+	if len(playlist.Videos) == 0 {
+		return Playlist{}, fmt.Errorf("yt-dlp listed no videos in playlist %s", url)
+	}
+	return playlist, nil
+}
+
+// ParsePlaylist parses the output of FetchPlaylist's yt-dlp call. yt-dlp
+// prints "NA" for fields it doesn't know, which are left empty.
+func ParsePlaylist(output []byte) Playlist {
+	var playlist Playlist
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(strings.TrimRight(line, "\r"), "\t", 3)
+		if len(fields) < 2 || knownField(fields[1]) == "" {
+			continue
+		}
+		if playlist.Title == "" {
+			playlist.Title = knownField(fields[0])
+		}
+		video := PlaylistVideo{ID: fields[1]}
+		if len(fields) == 3 {
+			video.Title = knownField(fields[2])
+		}
+		playlist.Videos = append(playlist.Videos, video)
+	}
+	return playlist
+}
+
+// knownField returns a yt-dlp template field, or "" for its "NA" placeholder.
+func knownField(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "NA" {
+		return ""
+	}
+	return s
+}
+
+// channelTabs are the channel pages that list videos directly; a channel's
+// home page lists these tabs instead.
+var channelTabs = []string{"videos", "streams", "shorts"}
+
+// ChannelUploadsURL returns the URL listing a channel's uploads: the channel
+// URL itself if it already points at a tab of videos, else its /videos tab.
+func ChannelUploadsURL(channelURL string) string {
+	trimmed := strings.TrimRight(channelURL, "/")
+	if slices.Contains(channelTabs, path.Base(trimmed)) {
+		return trimmed
+	}
+	return trimmed + "/videos"
+}
+
+// FetchChannel lists a channel's uploads, newest first, like FetchPlaylist.
+func FetchChannel(channelURL string) (Playlist, error) {
+	return FetchChannelN(channelURL, 0)
+}
+
+// FetchChannelN is FetchChannel listing only the n newest uploads; a
+// non-positive n lists them all.
+func FetchChannelN(channelURL string, n int) (Playlist, error) {
+	return FetchPlaylistN(ChannelUploadsURL(channelURL), n)
+}
+
+// ExpandPlaylists replaces each playlist URL in urls with the URLs of its
+// videos, in playlist order, and returns the playlists it expanded.
+func ExpandPlaylists(urls []string) ([]string, []Playlist, error) {
+	return ExpandPlaylistsN(urls, 0)
+}
+
+// ExpandPlaylistsN is ExpandPlaylists taking only the first n videos of each
+// playlist (see FetchPlaylistN); a non-positive n takes them all.
+func ExpandPlaylistsN(urls []string, n int) ([]string, []Playlist, error) {
+	var expanded []string
+	var playlists []Playlist
+	for _, url := range urls {
+		if !IsPlaylistURL(url) {
+			expanded = append(expanded, url)
+			continue
+		}
+		playlist, err := FetchPlaylistN(url, n)
+		if err != nil {
+			return nil, nil, err
+		}
+		playlists = append(playlists, playlist)
+		for _, video := range playlist.Videos {
+			expanded = append(expanded, video.URL())
+		}
+	}
+	return expanded, playlists, nil
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsPlaylistURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://www.youtube.com/playlist?list=PL123":            true,
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PL123": false, // A video within a playlist
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ":            false,
+		"https://example.com/video":                              false,
+	}
+	for url, want := range tests {
+		if got := IsPlaylistURL(url); got != want {
+			t.Errorf("IsPlaylistURL(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
+
+func TestChannelUploadsURL(t *testing.T) {
+	tests := map[string]string{
+		"https://www.youtube.com/@name":          "https://www.youtube.com/@name/videos",
+		"https://www.youtube.com/@name/":         "https://www.youtube.com/@name/videos",
+		"https://www.youtube.com/channel/UC123":  "https://www.youtube.com/channel/UC123/videos",
+		"https://www.youtube.com/@name/videos":   "https://www.youtube.com/@name/videos",
+		"https://www.youtube.com/@name/streams/": "https://www.youtube.com/@name/streams",
+	}
+	for url, want := range tests {
+		if got := ChannelUploadsURL(url); got != want {
+			t.Errorf("ChannelUploadsURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestParsePlaylist(t *testing.T) {
+	output := "My List\taaa\tFirst Video\nMy List\tbbb\tNA\n\nMy List\tNA\tBroken\n"
+	want := Playlist{
+		Title:  "My List",
+		Videos: []PlaylistVideo{{ID: "aaa", Title: "First Video"}, {ID: "bbb"}},
+	}
+	if got := ParsePlaylist([]byte(output)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePlaylist() = %+v, want %+v", got, want)
+	}
+}
+
+func TestExpandPlaylists(t *testing.T) {
+	installFakeYtDlp(t, `printf 'My List\taaa\tFirst\nMy List\tbbb\tSecond\n'`)
+
+	urls := []string{"https://youtu.be/first", "https://www.youtube.com/playlist?list=PL123", "https://youtu.be/last"}
+	got, playlists, err := ExpandPlaylists(urls)
+	if err != nil {
+		t.Fatalf("ExpandPlaylists() error = %v", err)
+	}
+	want := []string{"https://youtu.be/first", "https://www.youtube.com/watch?v=aaa", "https://www.youtube.com/watch?v=bbb", "https://youtu.be/last"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandPlaylists() = %q, want %q", got, want)
+	}
+	if len(playlists) != 1 || playlists[0].Title != "My List" || playlists[0].URL != urls[1] {
+		t.Errorf("ExpandPlaylists() playlists = %+v, want My List from %s", playlists, urls[1])
+	}
+}
+
+func TestExpandPlaylistsN(t *testing.T) {
+	runner := &fakeRunner{stdout: "My List\taaa\tFirst\nMy List\tbbb\tSecond\nMy List\tccc\tThird\n"}
+	installFakeRunner(t, runner)
+
+	got, playlists, err := ExpandPlaylistsN([]string{"https://www.youtube.com/playlist?list=PL123"}, 2)
+	if err != nil {
+		t.Fatalf("ExpandPlaylistsN() error = %v", err)
+	}
+	want := []string{"https://www.youtube.com/watch?v=aaa", "https://www.youtube.com/watch?v=bbb"}
+	if !reflect.DeepEqual(got, want) || len(playlists) != 1 || len(playlists[0].Videos) != 2 {
+		t.Errorf("ExpandPlaylistsN(2) = %q, %+v, want the first two videos", got, playlists)
+	}
+	if args := strings.Join(runner.args, " "); !strings.Contains(args, "--playlist-items 1:2") {
+		t.Errorf("ExpandPlaylistsN(2) ran yt-dlp with %q, want only the first two entries asked for", args)
+	}
+}
+
+func TestFetchChannelN(t *testing.T) {
+	runner := &fakeRunner{stdout: "Uploads\tnew\tNewest\n"}
+	installFakeRunner(t, runner)
+
+	if _, err := FetchChannelN("https://www.youtube.com/@name", 1); err != nil {
+		t.Fatalf("FetchChannelN() error = %v", err)
+	}
+	args := strings.Join(runner.args, " ")
+	if !strings.Contains(args, "--playlist-items 1:1") || !strings.HasSuffix(args, "https://www.youtube.com/@name/videos") {
+		t.Errorf("FetchChannelN(1) ran yt-dlp with %q, want the newest upload of the /videos tab", args)
+	}
+
+	// All of them: no item range
+	if _, err := FetchChannelN("https://www.youtube.com/@name", 0); err != nil {
+		t.Fatalf("FetchChannelN(0) error = %v", err)
+	}
+	if args := strings.Join(runner.args, " "); strings.Contains(args, "--playlist-items") {
+		t.Errorf("FetchChannelN(0) ran yt-dlp with %q, want no item range", args)
+	}
+}
+
+func TestExpandPlaylists_Empty(t *testing.T) {
+	installFakeYtDlp(t, "exit 0")
+
+	if _, _, err := ExpandPlaylists([]string{"https://www.youtube.com/playlist?list=PL123"}); err == nil {
+		t.Error("ExpandPlaylists() of an empty playlist succeeded, want an error")
+	}
+}
@@ -0,0 +1,67 @@
+package internal
+
+import "sync"
+
+// queueJobs sends the jobs at indices to jobQueue and closes it. With
+// opts.PrefetchTitles set, their titles are first fetched up to that many at
+// once (see prefetchTitles) and each job is queued as soon as its title is
+// known; otherwise they are queued right away, in order.
+func queueJobs(jobs []TranscriptJob, indices []int, jobQueue chan<- int, titles chan<- TitleFetchResult, done <-chan struct{}, opts Options, limiter *RateLimiter) {
+	if opts.PrefetchTitles > 0 {
+		go prefetchTitles(jobs, indices, jobQueue, titles, done, opts.PrefetchTitles, limiter)
+		return
+	}
+	for _, i := range indices {
+		jobQueue <- i
+	}
+	close(jobQueue)
+	if titles != nil {
+		close(titles)
+	}
+}
+
+// prefetchTitles fetches the title of each job at indices on a pool of n
+// goroutines, so every title is known (and shown) long before its job's turn
+// to download comes. Each title is stored in the job in jobs, which its worker
+// only reads once the job is queued on jobQueue, and also sent on titles, if
+// not nil, which must be buffered for every job. A job whose title can't be
+// fetched is queued untitled, leaving its worker to fetch it (and report the
+// error). jobQueue and titles are closed once every job is queued; closing
+// done stops the fetches early, queuing the rest untitled.
+func prefetchTitles(jobs []TranscriptJob, indices []int, jobQueue chan<- int, titles chan<- TitleFetchResult, done <-chan struct{}, n int, limiter *RateLimiter) {
+	pending := make(chan int, len(indices))
+	for _, i := range indices {
+		pending <- i
+	}
+	close(pending)
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for range n {
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				select {
+				case <-done:
+					jobQueue <- i // Buffered for every job
+					continue
+				default:
+				}
+				limiter.Wait()
+				title, err := FetchTitle(jobs[i].URL)
+				if err == nil {
+					jobs[i].Title = title
+				}
+				if titles != nil {
+					titles <- TitleFetchResult{URL: jobs[i].URL, Title: title, Err: err}
+				}
+				jobQueue <- i
+			}
+		}()
+	}
+	wg.Wait()
+	close(jobQueue)
+	if titles != nil {
+		close(titles)
+	}
+}
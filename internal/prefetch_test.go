@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// titleCountingRunner is subtitleRunner counting its title fetches, which
+// fail for URLs containing "broken".
+type titleCountingRunner struct {
+	mu     sync.Mutex
+	titles int
+}
+
+func (r *titleCountingRunner) Run(ctx context.Context, stderr io.Writer, args ...string) ([]byte, error) {
+	joined := strings.Join(args, " ")
+	if strings.Contains(joined, "--print title") {
+		r.mu.Lock()
+		r.titles++
+		r.mu.Unlock()
+		if strings.Contains(joined, "broken") {
+			return nil, errors.New("title unavailable")
+		}
+	}
+	return subtitleRunner{}.Run(ctx, stderr, args...)
+}
+
+func TestPrefetchTitles(t *testing.T) {
+	runner := &titleCountingRunner{}
+	installFakeRunner(t, runner)
+	jobs := []TranscriptJob{{URL: "https://youtu.be/aaa"}, {URL: "https://youtu.be/broken"}, {URL: "https://youtu.be/ccc"}, {URL: "https://youtu.be/ddd"}}
+	jobQueue := make(chan int, len(jobs))
+	titles := make(chan TitleFetchResult, len(jobs))
+
+	prefetchTitles(jobs, []int{0, 1, 3}, jobQueue, titles, make(chan struct{}), 2, nil)
+
+	var queued []int
+	for i := range jobQueue { // Closed once every job is queued
+		queued = append(queued, i)
+	}
+	slices.Sort(queued)
+	if !slices.Equal(queued, []int{0, 1, 3}) {
+		t.Errorf("prefetchTitles() queued %v, want [0 1 3]", queued)
+	}
+	want := []string{"Video aaa", "", "", "Video ddd"} // A failed fetch is left to the worker; job 2 wasn't asked for
+	for i, job := range jobs {
+		if job.Title != want[i] {
+			t.Errorf("jobs[%d].Title = %q, want %q", i, job.Title, want[i])
+		}
+	}
+	sent := 0
+	for result := range titles {
+		sent++
+		if (result.Err != nil) != strings.Contains(result.URL, "broken") {
+			t.Errorf("TitleFetchResult for %s has Err = %v", result.URL, result.Err)
+		}
+	}
+	if sent != 3 || runner.titles != 3 {
+		t.Errorf("prefetchTitles() sent %d titles after %d fetches, want 3 of each", sent, runner.titles)
+	}
+}
+
+func TestProcessURLs_PrefetchTitles(t *testing.T) {
+	runner := &titleCountingRunner{}
+	installFakeRunner(t, runner)
+	urls := []string{"https://youtu.be/aaa", "https://youtu.be/bbb", "https://youtu.be/ccc"}
+
+	results, err := ProcessURLs(context.Background(), urls, Options{CleanedDir: t.TempDir(), ParallelWorkers: 2, PrefetchTitles: 3})
+	if err != nil {
+		t.Fatalf("ProcessURLs() error = %v", err)
+	}
+	for i, result := range results {
+		if result.Status != "completed" || result.Title != "Video "+urls[i][len("https://youtu.be/"):] {
+			t.Errorf("results[%d] = %q %q %v, want completed with its title", i, result.Title, result.Status, result.Err)
+		}
+	}
+	if runner.titles != len(urls) {
+		t.Errorf("ProcessURLs() fetched %d titles, want each once (%d)", runner.titles, len(urls))
+	}
+}
+
+func TestWorkflowState_TitleFetchResult(t *testing.T) {
+	w := newTestWorkflowState([]string{"https://youtu.be/aaa", "https://youtu.be/bbb"})
+	w.Jobs[1].Status = "completed"
+	w.Jobs[1].Title = "Finished"
+
+	model, _ := w.Update(TitleFetchResult{URL: "https://youtu.be/aaa", Title: "Video aaa"})
+	model, _ = model.(WorkflowState).Update(TitleFetchResult{URL: "https://youtu.be/bbb", Title: "Video bbb"})
+	got := model.(WorkflowState)
+	if got.Jobs[0].Title != "Video aaa" || got.Jobs[1].Title != "Finished" {
+		t.Errorf("titles after TitleFetchResult = %q, %q, want the pending job titled and the finished one untouched", got.Jobs[0].Title, got.Jobs[1].Title)
+	}
+}
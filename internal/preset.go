@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Presets are named bundles of flags for common uses, which -preset expands
+// so the combinations needn't be remembered. Each maps flag names (without
+// the dash) to the values they are set to.
+var Presets = map[string]map[string]string{
+	// Notes of a talk to read back: a timestamped line per cue, a heading per
+	// chapter, and a note heading transcripts of auto-generated captions
+	"lecture": {"format": FormatTimedText, "chapters": "true", "join-cue-lines": "true", "fix-stutter": "true", "mark-auto": "true"},
+	// Timed cues as close to the subtitles as possible: jsonl from yt-dlp's
+	// srt conversion, with repeated cues kept
+	"subtitle": {"format": FormatJSONL, "raw-format": "srt", "no-dedupe": "true"},
+	// Flowing text to read or search: near-duplicates and stutters collapsed,
+	// a line per speaker, sentence case
+	"prose": {"fuzzy-dedupe": "true", "fix-stutter": "true", "join-cue-lines": "true", "speakers": "true", "case": CaseSentence},
+}
+
+// PresetNames returns the names of Presets, sorted.
+func PresetNames() []string {
+	return slices.Sorted(maps.Keys(Presets))
+}
+
+// ApplyPreset sets the flags of the named preset on the parsed fs, except
+// those given on the command line, which override the preset's values.
+func ApplyPreset(fs *flag.FlagSet, name string) error {
+	preset, ok := Presets[name]
+	if !ok {
+		return fmt.Errorf("unknown preset %q (want one of: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for _, flagName := range slices.Sorted(maps.Keys(preset)) {
+		if explicit[flagName] {
+			continue
+		}
+		if err := fs.Set(flagName, preset[flagName]); err != nil {
+			return fmt.Errorf("preset %s: -%s: %w", name, flagName, err)
+		}
+	}
+	return nil
+}
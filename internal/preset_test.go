@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"flag"
+	"io"
+	"slices"
+	"testing"
+)
+
+// presetFlagSet returns a FlagSet defining every flag a preset sets, as
+// strings, parsed from args.
+func presetFlagSet(t *testing.T, args ...string) (*flag.FlagSet, map[string]*string) {
+	t.Helper()
+	fs := flag.NewFlagSet("yt-tx", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	values := make(map[string]*string)
+	for _, preset := range Presets {
+		for name := range preset {
+			if values[name] == nil {
+				values[name] = fs.String(name, "", "")
+			}
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return fs, values
+}
+
+func TestApplyPreset(t *testing.T) {
+	fs, values := presetFlagSet(t)
+	if err := ApplyPreset(fs, "lecture"); err != nil {
+		t.Fatalf("ApplyPreset(lecture) error = %v", err)
+	}
+	for name, want := range Presets["lecture"] {
+		if got := *values[name]; got != want {
+			t.Errorf("ApplyPreset(lecture) -%s = %q, want %q", name, got, want)
+		}
+	}
+	if got := *values["raw-format"]; got != "" {
+		t.Errorf("ApplyPreset(lecture) set -raw-format = %q, which it doesn't name", got)
+	}
+}
+
+func TestApplyPreset_ExplicitFlagsWin(t *testing.T) {
+	fs, values := presetFlagSet(t, "-format", FormatText, "-chapters=false")
+	if err := ApplyPreset(fs, "lecture"); err != nil {
+		t.Fatalf("ApplyPreset(lecture) error = %v", err)
+	}
+	if got := *values["format"]; got != FormatText {
+		t.Errorf("-format = %q, want the explicit %q", got, FormatText)
+	}
+	if got := *values["chapters"]; got != "false" {
+		t.Errorf("-chapters = %q, want the explicit false", got)
+	}
+	if got := *values["fix-stutter"]; got != "true" {
+		t.Errorf("-fix-stutter = %q, want the preset's true", got)
+	}
+}
+
+func TestApplyPreset_Unknown(t *testing.T) {
+	fs, _ := presetFlagSet(t)
+	if err := ApplyPreset(fs, "podcast"); err == nil {
+		t.Error("ApplyPreset(podcast) error = nil, want an unknown preset error")
+	}
+
+	// A preset naming a flag that isn't defined is an error, not silently skipped
+	if err := ApplyPreset(flag.NewFlagSet("empty", flag.ContinueOnError), "prose"); err == nil {
+		t.Error("ApplyPreset() on a FlagSet without the preset's flags error = nil, want an error")
+	}
+}
+
+func TestPresets_Values(t *testing.T) {
+	for name, preset := range Presets {
+		if format, ok := preset["format"]; ok && !slices.Contains(Formats, format) {
+			t.Errorf("preset %s -format = %q, not one of %v", name, format, Formats)
+		}
+		if rawFormat, ok := preset["raw-format"]; ok && !slices.Contains(SubFormats, rawFormat) {
+			t.Errorf("preset %s -raw-format = %q, not one of %v", name, rawFormat, SubFormats)
+		}
+	}
+	if got := PresetNames(); !slices.IsSorted(got) || len(got) != len(Presets) {
+		t.Errorf("PresetNames() = %v, want every preset, sorted", got)
+	}
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter spaces out yt-dlp invocations so that, across all workers,
+// no more than a fixed number are launched per minute. A nil *RateLimiter
+// imposes no limit.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // Minimum gap between two invocations
+	next     time.Time     // Earliest time the next invocation may start
+}
+
+// NewRateLimiter creates a limiter allowing perMinute invocations per minute.
+// It returns nil (unlimited) when perMinute is zero or negative.
+func NewRateLimiter(perMinute int) *RateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &RateLimiter{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// Wait blocks until the caller is allowed to launch the next invocation.
+func (l *RateLimiter) Wait() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval) // Reserve our slot before releasing the lock
+	l.mu.Unlock()
+
+	time.Sleep(delay)
+}
+
+// HostLimiter caps how many jobs work on videos from the same host at once,
+// across all workers, so a large worker pool for a mixed batch doesn't
+// hammer any one site. A nil *HostLimiter imposes no limit.
+type HostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	slots map[string]chan struct{} // One semaphore per host, created on first use
+}
+
+// NewHostLimiter creates a limiter allowing perHost concurrent jobs per host.
+// It returns nil (unlimited) when perHost is zero or negative.
+func NewHostLimiter(perHost int) *HostLimiter {
+	if perHost <= 0 {
+		return nil
+	}
+	return &HostLimiter{limit: perHost, slots: make(map[string]chan struct{})}
+}
+
+// Acquire blocks until a job for rawURL may start, and returns the function
+// that frees its slot again once the job is done.
+func (l *HostLimiter) Acquire(rawURL string) (release func()) {
+	if l == nil {
+		return func() {}
+	}
+	host := urlHost(rawURL)
+	l.mu.Lock()
+	slot, ok := l.slots[host]
+	if !ok {
+		slot = make(chan struct{}, l.limit)
+		l.slots[host] = slot
+	}
+	l.mu.Unlock()
+
+	slot <- struct{}{}
+	return func() { <-slot }
+}
+
+// urlHost returns the lowercased host of rawURL without a "www." prefix, so
+// www.youtube.com and youtube.com share a limit; unparseable URLs share "".
+func urlHost(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter_Unlimited(t *testing.T) {
+	if l := NewRateLimiter(0); l != nil {
+		t.Errorf("NewRateLimiter(0) = %v, want nil", l)
+	}
+
+	var l *RateLimiter
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		l.Wait() // Must not block or panic on a nil limiter
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("nil RateLimiter.Wait() took %v, want no delay", elapsed)
+	}
+}
+
+func TestRateLimiter_SpreadsCallsAcrossWorkers(t *testing.T) {
+	l := NewRateLimiter(1200) // One call every 50ms
+	const calls = 5
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(calls)
+	for i := 0; i < calls; i++ {
+		go func() {
+			defer wg.Done()
+			l.Wait()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// The first call goes through immediately, the remaining four are 50ms apart.
+	if elapsed < 200*time.Millisecond {
+		t.Errorf("%d calls finished in %v, want at least 200ms at 1200/min", calls, elapsed)
+	}
+	if elapsed > 600*time.Millisecond {
+		t.Errorf("%d calls took %v, want roughly 200ms at 1200/min", calls, elapsed)
+	}
+}
+
+func TestHostLimiter_CapsEachHost(t *testing.T) {
+	l := NewHostLimiter(1)
+	release := l.Acquire("https://www.youtube.com/watch?v=a")
+
+	// Another host is not held back
+	done := make(chan struct{})
+	go func() {
+		l.Acquire("https://vimeo.com/1")()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Acquire() for another host blocked")
+	}
+
+	// The same host (www. or not) waits for the first slot
+	acquired := make(chan struct{})
+	go func() {
+		l.Acquire("https://youtube.com/watch?v=b")()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("Acquire() for a busy host did not wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Acquire() did not proceed once the slot was released")
+	}
+}
+
+func TestNewHostLimiter_Unlimited(t *testing.T) {
+	if l := NewHostLimiter(0); l != nil {
+		t.Errorf("NewHostLimiter(0) = %v, want nil", l)
+	}
+	var l *HostLimiter
+	for i := 0; i < 10; i++ {
+		l.Acquire("https://youtube.com/") // Must not block or panic on a nil limiter
+	}
+}
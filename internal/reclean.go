@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// keptTitleFile is the file in a job's raw download directory that KeepRaw
+// records the video title in, so Reclean can name the transcript offline.
+const keptTitleFile = "title.txt"
+
+// keepTitle records job's title next to its kept raw download in jobTempDir.
+// It only warns on failure: Reclean then fetches the title instead.
+func keepTitle(job *TranscriptJob, jobTempDir string) {
+	if err := WriteTextFile(filepath.Join(jobTempDir, keptTitleFile), job.Title+"\n"); err != nil {
+		job.Warnings = append(job.Warnings, fmt.Sprintf("title not kept with raw subtitles: %v", err))
+	}
+}
+
+// startFromKeptRaw is startJob for Reclean: when an earlier KeepRaw run left
+// raw subtitles for the job's video in opts.TempDir, it skips the title fetch
+// (if the title was kept too) and the download, and returns a finishFunc that
+// cleans those files again, overwriting the existing transcript. ok is false
+// if nothing was kept for the video, which is then processed as usual.
+func startFromKeptRaw(job TranscriptJob, opts Options, limiter *RateLimiter, onStatus func(TranscriptJob)) (_ TranscriptJob, _ finishFunc, ok bool) {
+	videoID, err := ExtractVideoID(job.URL)
+	if err != nil {
+		return job, nil, false
+	}
+	rawDir, rawFiles := keptRawFiles(opts.TempDir, videoID, opts)
+	if len(rawFiles) == 0 {
+		return job, nil, false
+	}
+
+	job.Timings = Timings{}
+	job.VideoID = videoID
+	if title, err := ReadTextFile(filepath.Join(rawDir, keptTitleFile)); err == nil && strings.TrimSpace(title) != "" {
+		job.Title = strings.TrimSpace(title)
+	} else {
+		limiter.Wait()
+		title, err := FetchTitle(job.URL)
+		if err != nil && !errors.Is(err, ErrEmptyTitle) {
+			return failJob(job, fmt.Errorf("failed to fetch title: %w", err)), nil, true
+		}
+		job.Title = cmp.Or(title, videoID)
+	}
+	cleanedFile, err := resolveCleanedPath(&job, opts, limiter)
+	if err != nil {
+		return failJob(job, err), nil, true
+	}
+	return job, func(job TranscriptJob) TranscriptJob {
+		return finishJob(job, rawFiles, cleanedFile, opts, limiter, onStatus)
+	}, true
+}
+
+// keptRawFiles returns the newest of the <id>-* job directories KeepRaw left
+// in tempDir for videoID, and the raw subtitle files in it: every language
+// with AllLangs, else opts.Lang if kept, else the one language there.
+func keptRawFiles(tempDir, videoID string, opts Options) (string, []string) {
+	dirs, _ := filepath.Glob(filepath.Join(tempDir, SanitizeFilename(videoID)+"-*"))
+	var newest string
+	var newestTime int64
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() && (newest == "" || info.ModTime().UnixNano() > newestTime) {
+			newest, newestTime = dir, info.ModTime().UnixNano()
+		}
+	}
+	if newest == "" {
+		return "", nil
+	}
+	files, _ := filepath.Glob(filepath.Join(newest, videoID+".*."+subFormat(opts)))
+	if opts.AllLangs || len(files) <= 1 {
+		return newest, files
+	}
+	lang := cmp.Or(opts.Lang, DefaultLang)
+	if i := slices.IndexFunc(files, func(file string) bool { return subtitleLang(file, videoID) == lang }); i >= 0 {
+		return newest, files[i : i+1]
+	}
+	return newest, files[:1]
+}
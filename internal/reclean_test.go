@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestProcessJob_Reclean(t *testing.T) {
+	installFakeRunner(t, subtitleRunner{})
+	tempDir, cleanedDir := t.TempDir(), t.TempDir()
+	opts := Options{TempDir: tempDir, CleanedDir: cleanedDir, KeepRaw: true}
+	if job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts); job.Status != "completed" {
+		t.Fatalf("workJob(KeepRaw) = %q, %v, want completed", job.Status, job.Error)
+	}
+
+	// Offline now: only the kept raw VTT and title can produce the transcript
+	installFakeRunner(t, &fakeRunner{err: errors.New("offline")})
+	opts.Reclean = true
+	opts.Clean.Case = CaseUpper
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Error != nil || job.Status != "completed" || job.Title != "Video abc" {
+		t.Fatalf("workJob(Reclean) = %q, %q, %v, want completed with the kept title", job.Status, job.Title, job.Error)
+	}
+	if got, err := os.ReadFile(job.ProcessedFile); err != nil || string(got) != "HELLO\n" {
+		t.Errorf("re-cleaned transcript = %q, %v, want HELLO", got, err)
+	}
+
+	// A video with nothing kept is downloaded as usual, which fails offline
+	if job := workJob(TranscriptJob{URL: "https://youtu.be/xyz"}, opts); job.Status != "failed" {
+		t.Errorf("workJob(Reclean, nothing kept) = %q, want failed", job.Status)
+	}
+}
+
+func TestKeptRawFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	if dir, files := keptRawFiles(tempDir, "abc", Options{}); dir != "" || files != nil {
+		t.Errorf("keptRawFiles(empty) = %q, %v, want nothing", dir, files)
+	}
+
+	dir := filepath.Join(tempDir, "abc-1")
+	os.Mkdir(dir, 0755)
+	for _, name := range []string{"abc.de.vtt", "abc.en.vtt", "abc.fr.vtt", "abc.en.srt"} {
+		os.WriteFile(filepath.Join(dir, name), []byte("WEBVTT\n"), 0644)
+	}
+	tests := []struct {
+		opts Options
+		want []string
+	}{
+		{Options{}, []string{"abc.en.vtt"}},
+		{Options{Lang: "fr"}, []string{"abc.fr.vtt"}},
+		{Options{Lang: "ja"}, []string{"abc.de.vtt"}},
+		{Options{AllLangs: true}, []string{"abc.de.vtt", "abc.en.vtt", "abc.fr.vtt"}},
+		{Options{RawFormat: "srt"}, []string{"abc.en.srt"}},
+	}
+	for _, tt := range tests {
+		gotDir, files := keptRawFiles(tempDir, "abc", tt.opts)
+		var got []string
+		for _, file := range files {
+			got = append(got, filepath.Base(file))
+		}
+		if gotDir != dir || !slices.Equal(got, tt.want) {
+			t.Errorf("keptRawFiles(%+v) = %q, %v, want %q, %v", tt.opts, gotDir, got, dir, tt.want)
+		}
+	}
+}
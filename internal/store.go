@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite" // Registers the pure-Go "sqlite" driver, with FTS5
+)
+
+// storeSchema creates the transcripts table and its full-text index. The
+// index holds no copy of the text (it is an external content table) and is
+// kept in step with the table by triggers, so a video fetched again replaces
+// its old transcript in search results too.
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS transcripts (
+	id         TEXT NOT NULL,
+	title      TEXT NOT NULL,
+	url        TEXT NOT NULL,
+	lang       TEXT NOT NULL,
+	content    TEXT NOT NULL,
+	fetched_at TEXT NOT NULL,
+	PRIMARY KEY (id, lang)
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS transcripts_fts USING fts5(
+	title, content, content='transcripts', content_rowid='rowid'
+);
+CREATE TRIGGER IF NOT EXISTS transcripts_ai AFTER INSERT ON transcripts BEGIN
+	INSERT INTO transcripts_fts (rowid, title, content) VALUES (new.rowid, new.title, new.content);
+END;
+CREATE TRIGGER IF NOT EXISTS transcripts_ad AFTER DELETE ON transcripts BEGIN
+	INSERT INTO transcripts_fts (transcripts_fts, rowid, title, content) VALUES ('delete', old.rowid, old.title, old.content);
+END;
+CREATE TRIGGER IF NOT EXISTS transcripts_au AFTER UPDATE ON transcripts BEGIN
+	INSERT INTO transcripts_fts (transcripts_fts, rowid, title, content) VALUES ('delete', old.rowid, old.title, old.content);
+	INSERT INTO transcripts_fts (rowid, title, content) VALUES (new.rowid, new.title, new.content);
+END;
+`
+
+// storeBusyTimeout is how long a write waits for another process (say, a
+// second yt-tx run) to release the database before failing.
+const storeBusyTimeout = 5 * time.Second
+
+// storeInsert adds a transcript, replacing any of the same video and language.
+const storeInsert = `INSERT INTO transcripts (id, title, url, lang, content, fetched_at) VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (id, lang) DO UPDATE SET title = excluded.title, url = excluded.url, content = excluded.content, fetched_at = excluded.fetched_at`
+
+// TranscriptDB is an SQLite database each transcript is added to as it is
+// written, with a full-text index over titles and contents, so a transcript
+// archive can be searched with SQL:
+//
+//	SELECT id, transcripts.title FROM transcripts JOIN transcripts_fts ON transcripts.rowid = transcripts_fts.rowid
+//	WHERE transcripts_fts MATCH 'gradient descent' ORDER BY rank;
+//
+// SQLite is linked in as a pure-Go driver, so nothing needs installing.
+// Workers share one TranscriptDB; it holds a single connection, which
+// serializes their writes, so it is safe for concurrent use.
+type TranscriptDB struct {
+	db     *sql.DB
+	insert *sql.Stmt
+}
+
+// StoredTranscript is one row of the transcripts table. A video has one row
+// per language; adding a video and language again replaces the row.
+type StoredTranscript struct {
+	VideoID   string
+	Title     string
+	URL       string
+	Lang      string
+	Content   string
+	FetchedAt time.Time // Stored in UTC as RFC 3339
+}
+
+// OpenTranscriptDB opens the database at path, creating it and its tables if
+// needed. Close it once done.
+func OpenTranscriptDB(path string) (*TranscriptDB, error) {
+	db, err := sql.Open("sqlite", storeDSN(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(storeSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set up database: %w", err)
+	}
+	insert, err := db.Prepare(storeInsert)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to set up database: %w", err)
+	}
+	return &TranscriptDB{db: db, insert: insert}, nil
+}
+
+// storeDSN returns the driver's name for the database at path: a file: URI,
+// so a path containing '?' or '#' still names the file, that sets the busy
+// timeout on every connection.
+func storeDSN(path string) string {
+	file := (&url.URL{Path: filepath.ToSlash(path)}).EscapedPath()
+	return fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)", file, storeBusyTimeout.Milliseconds())
+}
+
+// Add inserts t, replacing any transcript of the same video and language.
+func (db *TranscriptDB) Add(t StoredTranscript) error {
+	_, err := db.insert.Exec(t.VideoID, t.Title, t.URL, t.Lang, t.Content, t.FetchedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to add transcript to database: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (db *TranscriptDB) Close() error {
+	db.insert.Close()
+	return db.db.Close()
+}
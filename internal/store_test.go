@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openTestDB opens a TranscriptDB in a temp dir, closed when the test ends.
+func openTestDB(t *testing.T) (*TranscriptDB, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "transcripts.sqlite")
+	db, err := OpenTranscriptDB(path)
+	if err != nil {
+		t.Fatalf("OpenTranscriptDB() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, path
+}
+
+// queryDB runs query against the database at path on a connection of its
+// own and returns its rows, one per line with columns separated by '|', as
+// the sqlite3 shell prints them.
+func queryDB(t *testing.T, path, query string, args ...any) string {
+	t.Helper()
+	db, err := sql.Open("sqlite", storeDSN(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("query %q: %v", query, err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for rows.Next() {
+		values := make([]string, len(cols))
+		dest := make([]any, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.Join(values, "|"))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("query %q: %v", query, err)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestTranscriptDB_AddAndSearch(t *testing.T) {
+	db, path := openTestDB(t)
+	fetched := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	for _, tr := range []StoredTranscript{
+		{VideoID: "abc", Title: "Intro to Optimisation", URL: "https://youtu.be/abc", Lang: "en", Content: "today: gradient descent", FetchedAt: fetched},
+		// Quotes, and a NUL byte SQL string literals can't hold
+		{VideoID: "def", Title: "It's Cooking", URL: "https://youtu.be/def", Lang: "en", Content: "add the 'salt'\x00\nthen stir", FetchedAt: fetched},
+		{VideoID: "abc", Title: "Intro to Optimisation", URL: "https://youtu.be/abc", Lang: "en", Content: "today: stochastic gradient descent", FetchedAt: fetched},
+	} {
+		if err := db.Add(tr); err != nil {
+			t.Fatalf("Add(%s) error = %v", tr.VideoID, err)
+		}
+	}
+
+	if got := queryDB(t, path, "SELECT id, lang, fetched_at FROM transcripts ORDER BY id"); got != "abc|en|2024-05-01T10:00:00Z\ndef|en|2024-05-01T10:00:00Z" {
+		t.Errorf("rows = %q, want one per video, fetched_at in UTC", got)
+	}
+	if got := queryDB(t, path, "SELECT content FROM transcripts WHERE id = ?", "def"); got != "add the 'salt'\x00\nthen stir" {
+		t.Errorf("content = %q, want it stored verbatim", got)
+	}
+	search := "SELECT id FROM transcripts JOIN transcripts_fts ON transcripts.rowid = transcripts_fts.rowid WHERE transcripts_fts MATCH ?"
+	if got := queryDB(t, path, search, "stochastic"); got != "abc" {
+		t.Errorf("search for the replaced transcript's words = %q, want abc", got)
+	}
+	if got := queryDB(t, path, search, "today NOT stochastic"); got != "" {
+		t.Errorf("search for the old transcript = %q, want it gone from the index", got)
+	}
+	if got := queryDB(t, path, search, "cooking"); got != "def" {
+		t.Errorf("search by title = %q, want def", got)
+	}
+
+	// Reopening an existing database keeps its rows
+	reopened, err := OpenTranscriptDB(path)
+	if err != nil {
+		t.Fatalf("OpenTranscriptDB() of an existing database error = %v", err)
+	}
+	reopened.Close()
+	if got := queryDB(t, path, "SELECT count(*) FROM transcripts"); got != "2" {
+		t.Errorf("rows after reopening = %s, want 2", got)
+	}
+}
+
+func TestTranscriptDB_ConcurrentAdd(t *testing.T) {
+	// A name the driver would otherwise read as the start of its options
+	path := filepath.Join(t.TempDir(), "talks?#1.sqlite")
+	db, err := OpenTranscriptDB(path)
+	if err != nil {
+		t.Fatalf("OpenTranscriptDB() error = %v", err)
+	}
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- db.Add(StoredTranscript{VideoID: fmt.Sprintf("v%d", i), Lang: "en", Content: "hello", FetchedAt: time.Now()})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent Add() error = %v", err)
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database file: %v", err)
+	}
+	if got := queryDB(t, path, "SELECT count(*) FROM transcripts"); got != "20" {
+		t.Errorf("rows = %s, want 20", got)
+	}
+}
+
+func TestProcessJob_DB(t *testing.T) {
+	db, path := openTestDB(t)
+	installFakeRunner(t, subtitleRunner{})
+	opts := Options{TempDir: t.TempDir(), CleanedDir: t.TempDir(), Lang: "en", DB: db}
+	job := workJob(TranscriptJob{URL: "https://youtu.be/abc"}, opts)
+	if job.Status != "completed" || len(job.Warnings) != 0 {
+		t.Fatalf("workJob() = %q (%v), warnings %q, want completed", job.Status, job.Error, job.Warnings)
+	}
+	if got := queryDB(t, path, "SELECT id, title, url, lang, content FROM transcripts"); got != "abc|Video abc|https://youtu.be/abc|en|hello\n" {
+		t.Errorf("stored row = %q, want the transcript just written", got)
+	}
+}
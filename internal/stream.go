@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// CleanVTTReader returns a reader of the transcript cleaned from the VTT
+// file read from r, exactly as CleanVTTFileWithOptions returns it, so a
+// caller can clean yt-dlp's output as it arrives (yt-dlp -o - ...) without
+// writing it to disk. Cleaned text becomes readable as soon as the following
+// captions show it can't change any more; with options that work on the
+// whole transcript (Case, Speakers or Transforms) it all comes once r is
+// exhausted, though the raw file is still never held in memory. A read error
+// from r is returned by Read.
+func CleanVTTReader(r io.Reader, opts CleanOptions) io.Reader {
+	return newCleanReader(r, vttArtifact, opts)
+}
+
+// CleanSRTReader is CleanVTTReader for an SRT file.
+func CleanSRTReader(r io.Reader, opts CleanOptions) io.Reader {
+	return newCleanReader(r, srtArtifact, opts)
+}
+
+// cleanReader runs the cleaning of a subtitle file as it is read. Caption
+// lines go through the artifact filter and, when the pipeline allows it, the
+// line stages one batch at a time; the dedupe stage holds back the lines
+// later ones may still replace or be compared with (see flush). Otherwise the
+// caption lines are collected and the whole pipeline runs at the end.
+type cleanReader struct {
+	scanner   *bufio.Scanner
+	filter    artifactFilter
+	opts      CleanOptions
+	stats     CleanStats
+	streaming bool     // The line stages can run a batch at a time
+	local     Pipeline // With streaming, the line stages before dedupe, which each line passes alone
+	held      []string // With streaming, deduped lines not yet safe to emit
+	lines     int      // Lines emitted
+	out       []byte   // Cleaned text not yet read
+	done      bool     // The input is exhausted and every line emitted
+	err       error    // Read error from the input
+}
+
+// newCleanReader returns a cleanReader of r for the artifacts classify
+// recognises.
+func newCleanReader(r io.Reader, classify func(string) artifactKind, opts CleanOptions) *cleanReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	scanner.Split(scanRawLines)
+	c := &cleanReader{
+		scanner:   scanner,
+		filter:    artifactFilter{classify: classify, opts: opts, lines: []string{}},
+		opts:      opts,
+		streaming: streamableClean(opts),
+	}
+	if c.streaming {
+		local := opts
+		local.NoDedupe = true
+		c.local = newPipeline(local, &c.stats)
+	}
+	return c
+}
+
+// streamableClean reports whether the pipeline for opts can run a batch of
+// lines at a time: its stages only look at a line and the few kept before
+// it, with no text stage needing the joined transcript.
+func streamableClean(opts CleanOptions) bool {
+	return len(opts.Transforms) == 0 && !opts.Speakers && (opts.Case == "" || opts.Case == CaseKeep)
+}
+
+func (c *cleanReader) Read(p []byte) (int, error) {
+	for len(c.out) == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if c.err != nil {
+			return 0, c.err
+		}
+		c.fill()
+	}
+	n := copy(p, c.out)
+	c.out = c.out[n:]
+	return n, nil
+}
+
+// fillLines is how many raw lines fill reads at a time.
+const fillLines = 256
+
+// fill reads up to fillLines more raw lines and emits whatever cleaned text
+// they settle.
+func (c *cleanReader) fill() {
+	for i := 0; i < fillLines; i++ {
+		if !c.scanner.Scan() {
+			if c.err = c.scanner.Err(); c.err == nil {
+				c.finish()
+			}
+			return
+		}
+		c.filter.add(c.scanner.Text())
+	}
+	if c.streaming && len(c.filter.lines) > 1 {
+		// The last kept line may still have the next caption line joined
+		// onto it (JoinCueLines), so it stays in the filter
+		last := len(c.filter.lines) - 1
+		c.flush(c.filter.lines[:last], false)
+		c.filter.lines = append(c.filter.lines[:0], c.filter.lines[last])
+	}
+}
+
+// finish cleans what is left once the input is exhausted.
+func (c *cleanReader) finish() {
+	c.filter.finish()
+	stats := c.filter.stats
+	stats.Stutters, stats.Duplicates = c.stats.Stutters, c.stats.Duplicates
+	c.stats = stats
+	if c.streaming {
+		c.flush(c.filter.lines, true)
+	} else {
+		pipeline := newPipeline(c.opts, &c.stats)
+		final := pipeline.RunLines(c.filter.lines)
+		c.lines = len(final)
+		c.out = append(c.out, pipeline.join(final)...)
+	}
+	c.stats.FinalLines = c.lines
+	c.filter.lines = nil
+	c.done = true
+}
+
+// flush runs caption lines through the line stages and emits the result,
+// except, unless last is set, the lines the dedupe stage may still change:
+// the last DedupeWindow (at least one), which a later variant may replace
+// and later lines are compared with. Running dedupe again over lines it
+// already kept leaves them as they are, so passing them back in with the next
+// batch gives what deduping the whole transcript at once would.
+func (c *cleanReader) flush(lines []string, last bool) {
+	lines = c.local.RunLines(lines)
+	if !c.opts.NoDedupe {
+		in := append(c.held, lines...)
+		lines = dedupeLines(in, c.opts)
+		c.stats.Duplicates += len(in) - len(lines)
+		c.held = nil
+		if keep := max(c.opts.DedupeWindow, 1); !last && len(lines) > keep {
+			c.held = append([]string{}, lines[len(lines)-keep:]...)
+			lines = lines[:len(lines)-keep]
+		} else if !last {
+			c.held, lines = lines, nil
+		}
+	}
+	for _, line := range lines {
+		if c.lines > 0 {
+			c.out = append(c.out, '\n')
+		}
+		c.out = append(c.out, line...)
+		c.lines++
+	}
+}
+
+// readCleaned reads the whole transcript from c, with the stats of its
+// cleaning.
+func readCleaned(c *cleanReader) (string, CleanStats, error) {
+	var b strings.Builder
+	if _, err := io.Copy(&b, c); err != nil {
+		return "", CleanStats{}, err
+	}
+	return b.String(), c.stats, nil
+}
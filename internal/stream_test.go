@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestCleanVTTReader(t *testing.T) {
+	vtt := "WEBVTT\n\n1\n00:00:00.000 --> 00:00:01.000\n<c>hello</c> world\n\n2\n00:00:01.000 --> 00:00:02.000\nhello world\nit&#39;s 2024\n"
+	got, err := io.ReadAll(CleanVTTReader(bytes.NewReader([]byte(vtt)), CleanOptions{}))
+	if err != nil {
+		t.Fatalf("reading CleanVTTReader() error = %v", err)
+	}
+	if want := "hello world\nit's 2024"; string(got) != want {
+		t.Errorf("CleanVTTReader() = %q, want %q", got, want)
+	}
+}
+
+func TestCleanSRTReader(t *testing.T) {
+	srt := "1\r\n00:00:00,000 --> 00:00:01,000\r\nhello\r\n\r\n2\r\n00:00:01,000 --> 00:00:02,000\r\nhello\r\nthere\r\n"
+	got, err := io.ReadAll(CleanSRTReader(strings.NewReader(srt), CleanOptions{}))
+	if err != nil || string(got) != "hello\nthere" {
+		t.Errorf("CleanSRTReader() = %q, %v, want %q", got, err, "hello\nthere")
+	}
+}
+
+// rollingVTT returns a VTT of n rolling auto-caption style cues, long enough
+// to be cleaned over many batches, with the artifacts and variants each
+// cleaning option acts on.
+func rollingVTT(n int) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\nKind: captions\n\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "%d\n00:%02d:%02d.000 --> 00:%02d:%02d.500\n", i+1, i/60%60, i%60, i/60%60, i%60)
+		switch i % 7 {
+		case 0:
+			fmt.Fprintf(&b, "line %d the the words\n", i/3) // Stutter, and a repeat of the previous cues
+		case 1:
+			fmt.Fprintf(&b, "Line %d the the words.\n", i/3) // Fuzzy variant
+		case 2:
+			fmt.Fprintf(&b, "<i>%d</i>\nsecond “line” %d\n", 1900+i, i) // Spoken number, joined cue line, smart quotes
+		case 3:
+			b.WriteString("[Music]\n")
+		case 4:
+			fmt.Fprintf(&b, "line %d the the words\n\n", i/3) // Paragraph break
+		default:
+			fmt.Fprintf(&b, "caption %d\n", i%5) // Repeats a few lines apart, for DedupeWindow
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestCleanVTTReader_MatchesWholeTranscript(t *testing.T) {
+	strip, err := CompileStripPatterns([]string{`\[Music\]`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vtt := rollingVTT(1500)
+	for name, opts := range map[string]CleanOptions{
+		"default":      {},
+		"no dedupe":    {NoDedupe: true},
+		"fuzzy":        {FuzzyDedupe: true},
+		"window":       {DedupeWindow: 4},
+		"fuzzy window": {FuzzyDedupe: true, DedupeWindow: 3},
+		"stutter":      {FixStutter: true, KeepDoubles: true},
+		"join breaks":  {JoinCueLines: true, KeepBreaks: true, StripPatterns: strip},
+		"ascii indent": {ASCII: true, KeepIndent: true, FuzzyDedupe: true},
+		"case":         {Case: CaseSentence}, // Runs at the end, on the whole transcript
+		"speakers":     {Speakers: true},
+		"transforms":   {Transforms: []LineTransform{MapLines(strings.ToUpper)}},
+	} {
+		// The reference cleans the whole transcript in memory at once
+		lines, _ := removeArtifacts(strings.Split(vtt, "\n"), vttArtifact, opts)
+		want := NewPipeline(opts).Run(lines)
+
+		for _, reader := range []func(io.Reader) io.Reader{
+			func(r io.Reader) io.Reader { return r },
+			iotest.OneByteReader, // Reads smaller than a line
+			iotest.HalfReader,
+		} {
+			got, err := io.ReadAll(reader(CleanVTTReader(strings.NewReader(vtt), opts)))
+			if err != nil {
+				t.Fatalf("%s: reading CleanVTTReader() error = %v", name, err)
+			}
+			if string(got) != want {
+				t.Errorf("%s: CleanVTTReader() differs from cleaning the whole transcript:\ngot  %.200q\nwant %.200q", name, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestCleanVTTReader_DedupeWindowAcrossBatches(t *testing.T) {
+	// Every other line repeats the line kept three before it, so a repeat
+	// straddles every point where the reader emits what it has cleaned
+	var b strings.Builder
+	b.WriteString("WEBVTT\nword 1\nword 2\n")
+	want := []string{"word 1", "word 2"}
+	for i := 3; i < 2000; i++ {
+		fmt.Fprintf(&b, "word %d\nword %d\n", i, i-2)
+		want = append(want, fmt.Sprintf("word %d", i))
+	}
+
+	got, err := io.ReadAll(CleanVTTReader(strings.NewReader(b.String()), CleanOptions{DedupeWindow: 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != strings.Join(want, "\n") {
+		t.Errorf("CleanVTTReader(DedupeWindow 3) kept repeats across batches:\ngot  %.300q\nwant %.300q", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestCleanVTTReader_Stats(t *testing.T) {
+	vtt := rollingVTT(700)
+	opts := CleanOptions{FixStutter: true, DedupeWindow: 3}
+	lines, want := removeArtifacts(strings.Split(vtt, "\n"), vttArtifact, opts)
+	final := newPipeline(opts, &want).RunLines(lines)
+	want.FinalLines = len(final)
+
+	c := newCleanReader(strings.NewReader(vtt), vttArtifact, opts)
+	if _, got, err := readCleaned(c); err != nil || got != want {
+		t.Errorf("readCleaned() stats = %+v, %v, want %+v", got, err, want)
+	}
+}
+
+func TestCleanVTTReader_Streams(t *testing.T) {
+	// Cleaned text is readable before the input ends, as from a yt-dlp still
+	// downloading
+	pr, pw := io.Pipe()
+	go func() {
+		io.WriteString(pw, rollingVTT(400))
+	}()
+	defer pw.Close()
+
+	read := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 64)
+		_, err := io.ReadFull(CleanVTTReader(pr, CleanOptions{}), buf)
+		read <- err
+	}()
+	select {
+	case err := <-read:
+		if err != nil {
+			t.Errorf("reading before the input ended: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing readable before the input ended")
+	}
+}
+
+func TestCleanVTTReader_ReadError(t *testing.T) {
+	boom := errors.New("connection reset")
+	r := io.MultiReader(strings.NewReader("WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n"), iotest.ErrReader(boom))
+	if _, err := io.ReadAll(CleanVTTReader(r, CleanOptions{})); !errors.Is(err, boom) {
+		t.Errorf("reading CleanVTTReader() error = %v, want %v", err, boom)
+	}
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Extensions of subtitle output files.
+const (
+	srtExt = ".srt"
+	vttExt = ".vtt"
+)
+
+// IsSubtitleFormat reports whether format writes a subtitle file (srt or
+// vtt), the only formats CleanOptions.PreserveStyling applies to.
+func IsSubtitleFormat(format string) bool {
+	return format == FormatSRT || format == FormatVTT
+}
+
+// WriteCuesSRT writes cues to w as SubRip subtitles: numbered blocks with
+// "HH:MM:SS,mmm --> HH:MM:SS,mmm" timings. Cues are cleaned as for JSON Lines
+// (see WriteCuesJSONL) but keep their line breaks. With opts.PreserveStyling
+// their <i>, <b> and <u> tags are kept too. SRT has no comments, so
+// opts.Header is left out.
+func WriteCuesSRT(w io.Writer, cues []Cue, opts CleanOptions) error {
+	n := 0
+	return eachCleanCue(cues, opts, func(cue Cue, lines []string) error {
+		n++
+		sep := "\n"
+		if n == 1 {
+			sep = ""
+		}
+		_, err := fmt.Fprintf(w, "%s%d\n%s --> %s\n%s\n", sep, n,
+			formatCueTimestamp(cue.Start, ','), formatCueTimestamp(cue.End, ','), strings.Join(lines, "\n"))
+		return err
+	})
+}
+
+// WriteCuesVTT writes cues to w as WebVTT, cleaned as WriteCuesSRT does. With
+// opts.PreserveStyling, each cue also keeps its settings (position, alignment
+// and so on). opts.Header, if set, is written as a NOTE block.
+func WriteCuesVTT(w io.Writer, cues []Cue, opts CleanOptions) error {
+	if _, err := io.WriteString(w, "WEBVTT\n"); err != nil {
+		return err
+	}
+	if opts.Header != "" {
+		if _, err := fmt.Fprintf(w, "\nNOTE %s\n", opts.Header); err != nil {
+			return err
+		}
+	}
+	return eachCleanCue(cues, opts, func(cue Cue, lines []string) error {
+		timing := formatCueTimestamp(cue.Start, '.') + " --> " + formatCueTimestamp(cue.End, '.')
+		if opts.PreserveStyling && cue.Settings != "" {
+			timing += " " + cue.Settings
+		}
+		_, err := fmt.Fprintf(w, "\n%s\n%s\n", timing, escapeVTTText(strings.Join(lines, "\n"), opts.PreserveStyling))
+		return err
+	})
+}
+
+// WriteCuesSRTFile parses the cues of a raw subtitle file, keeps those within
+// the trim window and range in cueOpts and writes them to outPath as SubRip
+// (see WriteCuesSRT). The file only appears once complete.
+func WriteCuesSRTFile(rawFilePath, outPath string, cueOpts CueOptions, opts CleanOptions) error {
+	return writeCuesFile(rawFilePath, outPath, cueOpts, opts, WriteCuesSRT)
+}
+
+// WriteCuesVTTFile is WriteCuesSRTFile for WebVTT (see WriteCuesVTT).
+func WriteCuesVTTFile(rawFilePath, outPath string, cueOpts CueOptions, opts CleanOptions) error {
+	return writeCuesFile(rawFilePath, outPath, cueOpts, opts, WriteCuesVTT)
+}
+
+// formatCueTimestamp formats d as HH:MM:SS followed by sep and milliseconds,
+// the timestamp of SRT (sep ',') and VTT (sep '.') timing lines.
+func formatCueTimestamp(d time.Duration, sep byte) string {
+	ms := int(d / time.Millisecond)
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// escapedStyleTagRe matches a <i>, <b> or <u> tag once escapeVTTText has
+// escaped it.
+var escapedStyleTagRe = regexp.MustCompile(`(?i)&lt;(/?[ibu])&gt;`)
+
+// escapeVTTText escapes the characters WebVTT cue text reserves, as the
+// cleaned text has its entities decoded. With keepStyle, the style tags kept
+// by CleanOptions.PreserveStyling are left intact.
+func escapeVTTText(s string, keepStyle bool) string {
+	if !strings.ContainsAny(s, "&<>") {
+		return s
+	}
+	s = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+	if !keepStyle {
+		return s
+	}
+	return escapedStyleTagRe.ReplaceAllStringFunc(s, func(tag string) string {
+		return "<" + strings.ToLower(escapedStyleTagRe.FindStringSubmatch(tag)[1]) + ">"
+	})
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// styledVTT has cosmetic tags and cue settings to preserve, and YouTube's
+// color classes and inline word timings to drop either way.
+const styledVTT = `WEBVTT
+
+00:00:01.000 --> 00:00:02.500 align:start position:10%
+<c.colorE5E5E5><i>hello</i></c> there
+<b>tom</b> &amp; <00:00:02.000><c>jerry</c>
+
+00:00:02.500 --> 00:00:04.000
+second cue
+`
+
+func TestWriteCuesSRT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCuesSRT(&buf, ParseVTTCues(styledVTT), CleanOptions{}); err != nil {
+		t.Fatalf("WriteCuesSRT() error = %v", err)
+	}
+	want := "1\n00:00:01,000 --> 00:00:02,500\nhello there\ntom & jerry\n\n2\n00:00:02,500 --> 00:00:04,000\nsecond cue\n"
+	if buf.String() != want {
+		t.Errorf("WriteCuesSRT() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteCuesSRT_PreserveStyling(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCuesSRT(&buf, ParseVTTCues(styledVTT), CleanOptions{PreserveStyling: true}); err != nil {
+		t.Fatalf("WriteCuesSRT() error = %v", err)
+	}
+	want := "1\n00:00:01,000 --> 00:00:02,500\n<i>hello</i> there\n<b>tom</b> & jerry\n\n2\n00:00:02,500 --> 00:00:04,000\nsecond cue\n"
+	if buf.String() != want {
+		t.Errorf("WriteCuesSRT() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteCuesVTT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCuesVTT(&buf, ParseVTTCues(styledVTT), CleanOptions{Header: autoCaptionsNote}); err != nil {
+		t.Fatalf("WriteCuesVTT() error = %v", err)
+	}
+	want := "WEBVTT\n\nNOTE " + autoCaptionsNote + "\n\n00:00:01.000 --> 00:00:02.500\nhello there\ntom &amp; jerry\n\n00:00:02.500 --> 00:00:04.000\nsecond cue\n"
+	if buf.String() != want {
+		t.Errorf("WriteCuesVTT() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteCuesVTT_PreserveStyling(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCuesVTT(&buf, ParseVTTCues(styledVTT), CleanOptions{PreserveStyling: true}); err != nil {
+		t.Fatalf("WriteCuesVTT() error = %v", err)
+	}
+	want := "WEBVTT\n\n00:00:01.000 --> 00:00:02.500 align:start position:10%\n<i>hello</i> there\n<b>tom</b> &amp; jerry\n\n00:00:02.500 --> 00:00:04.000\nsecond cue\n"
+	if buf.String() != want {
+		t.Errorf("WriteCuesVTT() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteCuesVTTFile_Dedupes(t *testing.T) {
+	dir := t.TempDir()
+	rawPath := filepath.Join(dir, "abc.en.vtt")
+	if err := os.WriteFile(rawPath, []byte(autoSubVTT), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(dir, "Talk.vtt")
+	if err := WriteCuesVTTFile(rawPath, outPath, CueOptions{}, CleanOptions{}); err != nil {
+		t.Fatalf("WriteCuesVTTFile() error = %v", err)
+	}
+	// The rolling caption's repeated line gets no cue of its own
+	want := "WEBVTT\n\n00:00:01.000 --> 00:00:03.000\nhello world it's\n\n00:00:03.500 --> 00:00:05.000\nme again\n"
+	if got, err := os.ReadFile(outPath); err != nil || string(got) != want {
+		t.Errorf("WriteCuesVTTFile() wrote %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestStripNoiseTags(t *testing.T) {
+	tests := map[string]string{
+		"plain":                                   "plain",
+		"<c.colorE5E5E5><I>hi</I></c>":            "<i>hi</i>",
+		"hello<00:00:01.500><c> world</c>":        "hello world",
+		"<u>under</u> <font color=red>red</font>": "<u>under</u> red",
+		">> speaker":                              ">> speaker",
+	}
+	for in, want := range tests {
+		if got := stripNoiseTags(in); got != want {
+			t.Errorf("stripNoiseTags(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatCueTimestamp(t *testing.T) {
+	d := time.Hour + 2*time.Minute + 3*time.Second + 45*time.Millisecond
+	if got := formatCueTimestamp(d, ','); got != "01:02:03,045" {
+		t.Errorf("formatCueTimestamp(%v, ',') = %q, want 01:02:03,045", d, got)
+	}
+	if got := formatCueTimestamp(0, '.'); got != "00:00:00.000" {
+		t.Errorf("formatCueTimestamp(0, '.') = %q, want 00:00:00.000", got)
+	}
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DirTemplateFields are the placeholders a cleaned directory template such as
+// "archive/{channel}/{date}" may use, each expanded per video:
+//
+//	{channel}  the uploader's name
+//	{date}     the upload date, as YYYY-MM-DD
+//	{year}     the upload year
+//	{month}    the upload month, as 01-12
+//	{id}       the video ID
+var DirTemplateFields = []string{"channel", "date", "year", "month", "id"}
+
+// templateField matches a {placeholder} in a directory template.
+var templateField = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// IsDirTemplate reports whether dir has placeholders to expand per video.
+func IsDirTemplate(dir string) bool {
+	return templateField.MatchString(dir)
+}
+
+// ValidateDirTemplate checks that a directory template only uses
+// DirTemplateFields and has no ".." component, so it can't point outside the
+// directory it names.
+func ValidateDirTemplate(tmpl string) error {
+	for _, m := range templateField.FindAllStringSubmatch(tmpl, -1) {
+		if !slices.Contains(DirTemplateFields, m[1]) {
+			return fmt.Errorf("unknown placeholder {%s} in %q (want one of: {%s})", m[1], tmpl, strings.Join(DirTemplateFields, "}, {"))
+		}
+	}
+	for _, part := range strings.FieldsFunc(filepath.ToSlash(tmpl), func(r rune) bool { return r == '/' }) {
+		if part == ".." {
+			return fmt.Errorf("directory template %q must not contain a .. component", tmpl)
+		}
+	}
+	return nil
+}
+
+// DirTemplateRoot returns the part of a directory template before its first
+// placeholder's path component, e.g. "archive" for "archive/{channel}/{date}":
+// the directory every expansion lies within.
+func DirTemplateRoot(tmpl string) string {
+	loc := templateField.FindStringIndex(tmpl)
+	if loc == nil {
+		return tmpl
+	}
+	return filepath.Dir(tmpl[:loc[0]] + "x") // The component the placeholder is in doesn't count
+}
+
+// templateNeedsMetadata reports whether a directory template uses fields that
+// only the video's metadata provides.
+func templateNeedsMetadata(tmpl string) bool {
+	for _, m := range templateField.FindAllStringSubmatch(tmpl, -1) {
+		if m[1] != "id" {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandDirTemplate fills in a directory template's placeholders from values,
+// keyed by DirTemplateFields. Each value is sanitized into a single path
+// component with sanitize, so a title or channel name can never add
+// directories or climb out with "..". Missing values become "unknown".
+func ExpandDirTemplate(tmpl string, values map[string]string, sanitize func(string) string) (string, error) {
+	if err := ValidateDirTemplate(tmpl); err != nil {
+		return "", err
+	}
+	expanded := templateField.ReplaceAllStringFunc(tmpl, func(field string) string {
+		value := values[strings.Trim(field, "{}")]
+		if value == "" {
+			value = "unknown"
+		}
+		return templateComponent(value, sanitize)
+	})
+	return filepath.Clean(expanded), nil
+}
+
+// templateComponent sanitizes value into one path component that is neither
+// "." nor "..".
+func templateComponent(value string, sanitize func(string) string) string {
+	component := strings.NewReplacer("/", "-", `\`, "-").Replace(sanitize(value))
+	if strings.Trim(component, ".") == "" {
+		return "_"
+	}
+	return component
+}
+
+// dirTemplateValues returns the values a job's directory template expands
+// with, from its metadata (if fetched) and video ID.
+func dirTemplateValues(job TranscriptJob, videoID string) map[string]string {
+	values := map[string]string{"id": videoID}
+	if job.Metadata == nil {
+		return values
+	}
+	values["channel"] = job.Metadata.Uploader
+	if date := job.Metadata.UploadDate; len(date) == len("20060102") {
+		values["date"] = date[:4] + "-" + date[4:6] + "-" + date[6:]
+		values["year"] = date[:4]
+		values["month"] = date[4:6]
+	}
+	return values
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandDirTemplate(t *testing.T) {
+	values := map[string]string{"channel": "Some Channel", "date": "2024-03-05", "id": "abc123def45"}
+	tests := []struct {
+		name   string
+		tmpl   string
+		values map[string]string
+		want   string
+	}{
+		{"channel and date", "archive/{channel}/{date}", values, filepath.Join("archive", "Some-Channel", "2024-03-05")},
+		{"id within a component", "out/v-{id}", values, filepath.Join("out", "v-abc123def45")},
+		{"missing value", "archive/{channel}/{year}", values, filepath.Join("archive", "Some-Channel", "unknown")},
+		{"traversal in a value", "archive/{channel}", map[string]string{"channel": "../../etc"}, filepath.Join("archive", "etc")},
+		{"separator in a value", "archive/{channel}", map[string]string{"channel": "a/b"}, filepath.Join("archive", "a-b")},
+		{"dots only", "archive/{channel}", map[string]string{"channel": ".."}, filepath.Join("archive", "default_filename")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandDirTemplate(tt.tmpl, tt.values, SanitizeFilename)
+			if err != nil {
+				t.Fatalf("ExpandDirTemplate(%q) error: %v", tt.tmpl, err)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandDirTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+			if !strings.HasPrefix(got, DirTemplateRoot(tt.tmpl)+string(filepath.Separator)) {
+				t.Errorf("ExpandDirTemplate(%q) = %q, escapes %q", tt.tmpl, got, DirTemplateRoot(tt.tmpl))
+			}
+		})
+	}
+
+	// A value that sanitizes to "." or ".." must not climb out either
+	got, err := ExpandDirTemplate("archive/{channel}", map[string]string{"channel": ".."}, func(s string) string { return s })
+	if err != nil || got != filepath.Join("archive", "_") {
+		t.Errorf("ExpandDirTemplate with \"..\" value = %q, %v; want %q", got, err, filepath.Join("archive", "_"))
+	}
+}
+
+func TestValidateDirTemplate(t *testing.T) {
+	valid := []string{"archive/{channel}/{date}", "{year}/{month}", "out/{id}", "plain"}
+	for _, tmpl := range valid {
+		if err := ValidateDirTemplate(tmpl); err != nil {
+			t.Errorf("ValidateDirTemplate(%q) = %v, want nil", tmpl, err)
+		}
+	}
+	invalid := []string{"archive/../{channel}", "../{date}", "{channel}/..", "archive/{title}", "archive/{}"}
+	for _, tmpl := range invalid {
+		if err := ValidateDirTemplate(tmpl); err == nil {
+			t.Errorf("ValidateDirTemplate(%q) = nil, want error", tmpl)
+		}
+		if _, err := ExpandDirTemplate(tmpl, nil, SanitizeFilename); err == nil {
+			t.Errorf("ExpandDirTemplate(%q) succeeded, want error", tmpl)
+		}
+	}
+}
+
+func TestDirTemplateRoot(t *testing.T) {
+	tests := map[string]string{
+		"archive/{channel}/{date}": "archive",
+		"a/b/v-{id}":               filepath.Join("a", "b"),
+		"{channel}":                ".",
+		"plain":                    "plain",
+	}
+	for tmpl, want := range tests {
+		if got := DirTemplateRoot(tmpl); got != want {
+			t.Errorf("DirTemplateRoot(%q) = %q, want %q", tmpl, got, want)
+		}
+	}
+}
+
+func TestDirTemplateValues(t *testing.T) {
+	job := TranscriptJob{Metadata: &VideoMetadata{Uploader: "Chan", UploadDate: "20240305"}}
+	got := dirTemplateValues(job, "abc123def45")
+	want := map[string]string{"id": "abc123def45", "channel": "Chan", "date": "2024-03-05", "year": "2024", "month": "03"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("dirTemplateValues()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+	if !templateNeedsMetadata("a/{channel}") || templateNeedsMetadata("a/{id}") {
+		t.Error("templateNeedsMetadata should only be true for metadata fields")
+	}
+}
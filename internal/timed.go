@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// WriteCuesTimedText writes cues to w as plain text, one line per cue prefixed
+// with its start time, e.g. "[01:02:03] and that's the key idea". Cues are
+// cleaned as for JSON Lines (see WriteCuesJSONL), so the lines rolling
+// auto-captions repeat are dropped rather than each getting a timestamp.
+// opts.Header, if set, is written first.
+func WriteCuesTimedText(w io.Writer, cues []Cue, opts CleanOptions) error {
+	if opts.Header != "" {
+		if _, err := fmt.Fprintln(w, opts.Header); err != nil {
+			return err
+		}
+	}
+	return eachCueRecord(cues, opts, func(record CueRecord) error {
+		_, err := fmt.Fprintf(w, "[%s] %s\n", formatClock(time.Duration(record.Start*float64(time.Second))), record.Text)
+		return err
+	})
+}
+
+// WriteCuesTimedTextFile parses the cues of a raw subtitle file, keeps those
+// within the trim window and range in cueOpts and writes them to outPath as
+// timed text (see WriteCuesTimedText). The file only appears once complete.
+func WriteCuesTimedTextFile(rawFilePath, outPath string, cueOpts CueOptions, opts CleanOptions) error {
+	return writeCuesFile(rawFilePath, outPath, cueOpts, opts, WriteCuesTimedText)
+}
+
+// formatClock formats d as HH:MM:SS, dropping any fraction of a second.
+func formatClock(d time.Duration) string {
+	s := int(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
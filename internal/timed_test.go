@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteCuesTimedText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCuesTimedText(&buf, ParseVTTCues(autoSubVTT), CleanOptions{}); err != nil {
+		t.Fatalf("WriteCuesTimedText() error = %v", err)
+	}
+	// The rolling caption's repeated line doesn't get a timestamp of its own
+	if want := "[00:00:01] hello world it's\n[00:00:03] me again\n"; buf.String() != want {
+		t.Errorf("WriteCuesTimedText() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteCuesTimedText_Header(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCuesTimedText(&buf, ParseVTTCues(autoSubVTT), CleanOptions{Header: autoCaptionsNote}); err != nil {
+		t.Fatalf("WriteCuesTimedText() error = %v", err)
+	}
+	if want := autoCaptionsNote + "\n[00:00:01] hello world it's\n"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("WriteCuesTimedText() = %q, want it to start with %q", buf.String(), want)
+	}
+}
+
+func TestWriteCuesTimedTextFile(t *testing.T) {
+	dir := t.TempDir()
+	rawPath := filepath.Join(dir, "abc.en.vtt")
+	vtt := "WEBVTT\n\n01:02:03.900 --> 01:02:05.000\n<i>and that's</i> the key idea\n"
+	if err := os.WriteFile(rawPath, []byte(vtt), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(dir, "Talk.txt")
+	if err := WriteCuesTimedTextFile(rawPath, outPath, CueOptions{}, CleanOptions{}); err != nil {
+		t.Fatalf("WriteCuesTimedTextFile() error = %v", err)
+	}
+	if got, err := os.ReadFile(outPath); err != nil || string(got) != "[01:02:03] and that's the key idea\n" {
+		t.Errorf("WriteCuesTimedTextFile() wrote %q, %v", got, err)
+	}
+}
+
+func TestFormatClock(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                                     "00:00:00",
+		59*time.Second + 999*time.Millisecond: "00:00:59",
+		time.Hour + 2*time.Minute + 3*time.Second: "01:02:03",
+		100 * time.Hour: "100:00:00",
+	} {
+		if got := formatClock(d); got != want {
+			t.Errorf("formatClock(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
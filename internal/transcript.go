@@ -1,10 +1,63 @@
 package internal
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// CleanOptions controls optional steps of the transcript cleaning pipeline.
+// The zero value gives the default, strict behaviour.
+type CleanOptions struct {
+	FuzzyDedupe     bool   // Treat consecutive lines differing only by case or trailing punctuation as duplicates
+	DedupeWindow    int    // Also drop a line repeating any of this many previous lines (1 or less = consecutive only)
+	KeepBreaks      bool   // Keep intentional gaps (two or more blank lines in the source) as a paragraph break
+	ASCII           bool   // Replace smart quotes, dashes and ellipses with plain ASCII equivalents
+	Case            string // Final case transform, one of CaseModes ("" means CaseKeep)
+	BOM             bool   // Start the written transcript with a UTF-8 byte order mark, for Windows tools
+	Newline         string // Line endings of the written transcript, one of Newlines ("" means NewlineLF)
+	Speakers        bool   // Start a line at each speaker label (">>", "JOHN:") and keep labels out of re-casing
+	NoDedupe        bool   // Keep every cleaned caption line, repeats included; overrides FuzzyDedupe and DedupeWindow
+	Checksum        bool   // Keep a .sha256 sidecar per transcript and leave unchanged transcripts untouched
+	KeepIndent      bool   // Keep caption lines' indentation beyond a single leading space, instead of trimming it
+	FixStutter      bool   // Collapse a word repeated back to back within a line ("the the cat" -> "the cat")
+	KeepDoubles     bool   // With FixStutter, leave IntentionalDoubles such as "had had" alone
+	JoinCueLines    bool   // Join the lines of one cue block into a single line, for sentences wrapped across lines
+	AllowEmpty      bool   // Still write a transcript that cleaned down to nothing, instead of no file
+	StitchCues      bool   // Drop the words a cue repeats from the end of the one before (see StitchOverlappingCues)
+	Header          string // Line written above a text or timed text transcript, uncleaned (e.g. the MarkAuto note)
+	PreserveStyling bool   // Keep <i>, <b> and <u> tags in caption lines rather than stripping every tag; only srt and vtt output honours it
+
+	// StripPatterns are removed from every caption line wherever they match,
+	// e.g. station boilerplate like "[CC BY XYZ]"; see CompileStripPatterns.
+	StripPatterns []*regexp.Regexp
+
+	// Transforms are extra line stages of the Pipeline, run after the
+	// built-in ones, for library users with cleaning rules of their own.
+	Transforms []LineTransform
+}
+
+// CompileStripPatterns compiles regular expressions for
+// CleanOptions.StripPatterns, failing on the first invalid one.
+func CompileStripPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid strip pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
 // DedupeLines removes consecutive duplicate lines from a slice of strings.
 func DedupeLines(lines []string) []string {
 	if len(lines) == 0 {
@@ -23,6 +76,278 @@ func DedupeLines(lines []string) []string {
 	return result
 }
 
+// DedupeLinesFunc removes consecutive lines that are equal after applying normalize.
+// When two lines collide, the longer (more complete) variant is kept; on a tie
+// a capitalized variant beats a lowercase one (auto-captions capitalize a
+// sentence start mid-stream), and otherwise the later one wins, since captions
+// tend to correct themselves as they roll.
+func DedupeLinesFunc(lines []string, normalize func(string) string) []string {
+	if len(lines) == 0 {
+		return []string{} // Ensure non-nil empty slice
+	}
+	result := make([]string, 0, len(lines))
+	result = append(result, lines[0])
+	lastKey := normalize(lines[0])
+
+	for i := 1; i < len(lines); i++ {
+		key := normalize(lines[i])
+		if key != lastKey {
+			result = append(result, lines[i])
+			lastKey = key
+			continue
+		}
+		if preferVariant(lines[i], result[len(result)-1]) {
+			result[len(result)-1] = lines[i]
+		}
+	}
+	return result
+}
+
+// DedupeLinesWindow removes lines equal, after normalize, to any of the last
+// window lines kept, catching a sentence that caption flicker repeats a few
+// lines later. As in DedupeLinesFunc the more complete variant is kept. Blank lines
+// are paragraph breaks, not captions, and are never dropped. A window of 1 or
+// less compares consecutive lines only, exactly like DedupeLinesFunc.
+func DedupeLinesWindow(lines []string, window int, normalize func(string) string) []string {
+	if window <= 1 {
+		return DedupeLinesFunc(lines, normalize)
+	}
+	result := make([]string, 0, len(lines))
+	keys := make([]string, 0, len(lines))
+next:
+	for _, line := range lines {
+		key := normalize(line)
+		if key != "" {
+			for j := len(result) - 1; j >= max(0, len(result)-window); j-- {
+				if keys[j] == key {
+					if preferVariant(line, result[j]) {
+						result[j] = line
+					}
+					continue next
+				}
+			}
+		}
+		result = append(result, line)
+		keys = append(keys, key)
+	}
+	return result
+}
+
+// preferVariant reports whether line should replace kept, a variant of it
+// seen earlier: if it is longer, or as long and not a lowercase form of a
+// capitalized kept line.
+func preferVariant(line, kept string) bool {
+	if len(line) != len(kept) {
+		return len(line) > len(kept)
+	}
+	return !(startsUpper(kept) && !startsUpper(line))
+}
+
+// startsUpper reports whether s begins with an upper-case letter.
+func startsUpper(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsUpper(r)
+}
+
+// NormalizeCaseAndPunctuation lowercases a line and strips trailing punctuation,
+// so "hello" and "Hello." compare equal.
+func NormalizeCaseAndPunctuation(s string) string {
+	return strings.TrimRight(strings.ToLower(s), " .,!?;:…")
+}
+
+// Case modes accepted by ApplyCase.
+const (
+	CaseKeep     = "keep"
+	CaseLower    = "lower"
+	CaseUpper    = "upper"
+	CaseSentence = "sentence"
+)
+
+// CaseModes lists every mode ApplyCase accepts.
+var CaseModes = []string{CaseKeep, CaseLower, CaseUpper, CaseSentence}
+
+// ApplyCase re-cases text. CaseSentence lowercases everything, then
+// capitalizes the first letter of the text and the first letter after each
+// '.', '!' or '?' that is followed by whitespace. It knows nothing about
+// abbreviations, so "e.g. this" becomes "E.g. This"; good enough for
+// auto-captions, which are rarely punctuated that carefully anyway. Unknown
+// modes leave text unchanged.
+func ApplyCase(text string, mode string) string {
+	switch mode {
+	case CaseLower:
+		return strings.ToLower(text)
+	case CaseUpper:
+		return strings.ToUpper(text)
+	case CaseSentence:
+		runes := []rune(strings.ToLower(text))
+		capitalize, afterStop := true, false
+		for i, r := range runes {
+			switch {
+			case r == '.' || r == '!' || r == '?':
+				afterStop = true
+			case unicode.IsSpace(r):
+				capitalize = capitalize || afterStop // Only a stop followed by a space ends a sentence ("3.5", "e.g")
+				afterStop = false
+			case capitalize && unicode.IsLetter(r):
+				runes[i] = unicode.ToUpper(r)
+				capitalize, afterStop = false, false
+			case capitalize && strings.ContainsRune(`"'([`, r):
+				// Opening punctuation: capitalize the letter after it instead
+			default:
+				capitalize, afterStop = false, false
+			}
+		}
+		return string(runes)
+	default:
+		return text
+	}
+}
+
+// speakerLabelRe matches a speaker label at the start of a caption line: the
+// ">>" speaker-change marker, optionally naming the speaker (">> Anna:"),
+// or an all-caps name of two or more letters ("JOHN:", "DR. SMITH:"). Ordinary
+// capitalized words ("Note: ...") are not labels.
+var speakerLabelRe = regexp.MustCompile(`^(>>(?:\s*\w[\w.'-]*(?: \w[\w.'-]*){0,2}:)?|[A-Z][A-Z0-9.'-]+(?: [A-Z][A-Z0-9.'-]*){0,2}:)(?:\s+|$)`)
+
+// SplitSpeakerLabel splits a leading speaker label off a line, returning an
+// empty label if the line doesn't start with one.
+func SplitSpeakerLabel(line string) (label, text string) {
+	m := speakerLabelRe.FindStringSubmatchIndex(line)
+	if m == nil {
+		return "", line
+	}
+	return line[m[2]:m[3]], line[m[1]:]
+}
+
+// splitSpeakerTurns puts each ">>" speaker change that appears mid-line on a
+// line of its own, so every turn starts a line. Without opts.Speakers the
+// lines are returned unchanged.
+func splitSpeakerTurns(lines []string, opts CleanOptions) []string {
+	if !opts.Speakers {
+		return lines
+	}
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		parts := strings.Split(line, ">>")
+		if len(parts) == 1 {
+			out = append(out, line)
+			continue
+		}
+		if first := strings.TrimSpace(parts[0]); first != "" {
+			out = append(out, first)
+		}
+		for _, part := range parts[1:] {
+			out = append(out, strings.TrimRight(">> "+strings.TrimSpace(part), " "))
+		}
+	}
+	return out
+}
+
+// applyCase re-cases text like ApplyCase. With opts.Speakers, labels keep
+// their case and each speaker's turn is cased on its own, so a turn always
+// starts a new sentence.
+func applyCase(text string, opts CleanOptions) string {
+	if !opts.Speakers {
+		return ApplyCase(text, opts.Case)
+	}
+	var out, turn []string
+	label := ""
+	flush := func() {
+		if len(turn) == 0 {
+			return
+		}
+		cased := strings.Split(ApplyCase(strings.Join(turn, "\n"), opts.Case), "\n")
+		if label != "" {
+			cased[0] = strings.TrimRight(label+" "+cased[0], " ")
+		}
+		out = append(out, cased...)
+		turn, label = nil, ""
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if l, rest := SplitSpeakerLabel(line); l != "" {
+			flush()
+			label, line = l, rest
+		}
+		turn = append(turn, line)
+	}
+	flush()
+	return strings.Join(out, "\n")
+}
+
+// IntentionalDoubles are words that English legitimately repeats back to back
+// ("he had had enough", "the idea that that works"), which CleanOptions.KeepDoubles
+// keeps when collapsing stutters.
+var IntentionalDoubles = []string{"had", "that", "is", "do"}
+
+// CollapseWordStutter collapses words repeated back to back within s, as
+// auto-captions produce ("I I think think" -> "I think"), comparing words
+// case-insensitively. The first occurrence keeps its case and the last its
+// trailing punctuation, so "the The." becomes "the."; a word ending in
+// punctuation is never merged with the next ("yes. Yes" stays). Every repeat
+// is collapsed, intentional doubles like "had had" included; see
+// CollapseWordStutterExcept to keep those.
+func CollapseWordStutter(s string) string {
+	out, _ := collapseWordStutter(s, nil)
+	return out
+}
+
+// CollapseWordStutterExcept is CollapseWordStutter leaving repeats of the
+// words in keep (compared case-insensitively) as they are.
+func CollapseWordStutterExcept(s string, keep []string) string {
+	out, _ := collapseWordStutter(s, keep)
+	return out
+}
+
+// collapseWordStutter is CollapseWordStutterExcept, also returning how many
+// words it dropped.
+func collapseWordStutter(s string, keep []string) (string, int) {
+	words := strings.Fields(s)
+	if len(words) < 2 {
+		return s, 0
+	}
+	out := words[:1:1]
+	for _, word := range words[1:] {
+		prev := out[len(out)-1]
+		bare := strings.TrimRightFunc(word, unicode.IsPunct)
+		if bare != "" && strings.EqualFold(prev, bare) && !containsFold(keep, bare) {
+			out[len(out)-1] = prev + word[len(bare):]
+			continue
+		}
+		out = append(out, word)
+	}
+	dropped := len(words) - len(out)
+	if dropped == 0 {
+		return s, 0
+	}
+	indent := s[:len(s)-len(strings.TrimLeftFunc(s, unicode.IsSpace))]
+	return indent + strings.Join(out, " "), dropped
+}
+
+// containsFold reports whether words contains word, ignoring case.
+func containsFold(words []string, word string) bool {
+	for _, w := range words {
+		if strings.EqualFold(w, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// asciiReplacer maps typographic punctuation to plain ASCII.
+var asciiReplacer = strings.NewReplacer(
+	"\u2018", "'", "\u2019", "'", "\u201a", "'", "\u201b", "'",
+	"\u201c", `"`, "\u201d", `"`, "\u201e", `"`, "\u201f", `"`,
+	"\u2013", "-", "\u2014", "-",
+	"\u2026", "...",
+	"\u00a0", " ",
+)
+
+// NormalizeToASCII replaces smart quotes, dashes, ellipses and non-breaking
+// spaces with their plain ASCII equivalents.
+func NormalizeToASCII(s string) string {
+	return asciiReplacer.Replace(s)
+}
+
 // IsNumber checks if a string consists only of digits.
 func IsNumber(s string) bool {
 	for _, r := range s {
@@ -39,8 +364,23 @@ func IsTimestamp(s string) bool {
 	return len(s) >= 29 && s[2] == ':' && s[5] == ':' && s[8] == '.' && strings.Contains(s, "-->")
 }
 
+// IsSRTTimestamp checks if a string looks like an SRT timestamp.
+func IsSRTTimestamp(s string) bool {
+	// Matches 00:00:00,000 --> 00:00:00,000
+	return len(s) >= 29 && s[2] == ':' && s[5] == ':' && s[8] == ',' && strings.Contains(s, "-->")
+}
+
 // StripHTMLTags removes HTML tags from a string.
 func StripHTMLTags(s string) string {
+	return stripHTMLTags(s, false)
+}
+
+// stripHTMLTags removes HTML tags from s. With keepStray, a '>' outside a
+// tag, like the ">>" speaker marker, is kept as text; otherwise it's dropped.
+func stripHTMLTags(s string, keepStray bool) string {
+	if !strings.ContainsAny(s, "<>") || (keepStray && !strings.Contains(s, "<")) {
+		return s // No tags, and no '>' to drop
+	}
 	var out strings.Builder
 	inTag := false
 	for _, r := range s {
@@ -48,7 +388,7 @@ func StripHTMLTags(s string) string {
 			inTag = true
 			continue
 		}
-		if r == '>' {
+		if r == '>' && (inTag || !keepStray) {
 			inTag = false
 			continue
 		}
@@ -59,51 +399,335 @@ func StripHTMLTags(s string) string {
 	return out.String()
 }
 
+// tagRe matches an HTML-like tag in a caption line.
+var tagRe = regexp.MustCompile(`<[^<>]*>`)
+
+// styleTags are the cosmetic tags stripNoiseTags keeps.
+var styleTags = map[string]bool{"<i>": true, "</i>": true, "<b>": true, "</b>": true, "<u>": true, "</u>": true}
+
+// stripNoiseTags removes the tags of a caption line but the cosmetic <i>, <b>
+// and <u> ones, dropping YouTube's color classes (<c.colorE5E5E5>) and the
+// inline word timings of auto-generated captions (<00:00:01.500>).
+func stripNoiseTags(s string) string {
+	if !strings.Contains(s, "<") {
+		return s
+	}
+	return tagRe.ReplaceAllStringFunc(s, func(tag string) string {
+		if styleTags[strings.ToLower(tag)] {
+			return strings.ToLower(tag)
+		}
+		return ""
+	})
+}
+
+// CleanStats describes what the cleaning pipeline did to a transcript, for diagnosing over-aggressive cleaning.
+type CleanStats struct {
+	RawLines   int // Lines read from the subtitle file
+	Blank      int // Blank lines dropped
+	Headers    int // WEBVTT header lines dropped
+	Numbers    int // Cue numbers dropped
+	Timestamps int // Timing lines dropped
+	HTMLOnly   int // Lines that were empty once HTML tags were stripped
+	Stripped   int // Lines that were empty once StripPatterns were removed
+	Duplicates int // Rolling duplicate lines collapsed
+	Stutters   int // Repeated words collapsed by FixStutter
+	FinalLines int // Lines in the cleaned transcript
+}
+
+// CaptionLines returns how many lines were left once subtitle artifacts
+// (blank, header, cue number, timestamp, HTML-only and stripped lines) were
+// removed, before dedupe and the rest of the pipeline.
+func (s CleanStats) CaptionLines() int {
+	return s.RawLines - s.Blank - s.Headers - s.Numbers - s.Timestamps - s.HTMLOnly - s.Stripped
+}
+
+// artifactKind classifies a structural subtitle line.
+type artifactKind int
+
+const (
+	notArtifact artifactKind = iota
+	headerArtifact
+	numberArtifact
+	timestampArtifact
+)
+
 // RemoveVTTArtifacts applies the cleaning logic to a slice of lines to remove VTT artifacts.
+// An all-digit line is only dropped as a cue number when a timing line
+// follows it, so spoken numbers like "2024" survive.
 func RemoveVTTArtifacts(lines []string) []string {
-	outLines := []string{} // Initialize as empty slice instead of nil
+	out, _ := removeArtifacts(lines, vttArtifact, CleanOptions{})
+	return out
+}
+
+// RemoveVTTArtifactsWithOptions is RemoveVTTArtifacts with opts applied to
+// the kept lines, e.g. KeepIndent to preserve indentation or StripPatterns to
+// remove boilerplate.
+func RemoveVTTArtifactsWithOptions(lines []string, opts CleanOptions) []string {
+	out, _ := removeArtifacts(lines, vttArtifact, opts)
+	return out
+}
+
+// RemoveSRTArtifacts applies the cleaning logic to a slice of lines to remove SRT
+// block numbers and timings.
+func RemoveSRTArtifacts(lines []string) []string {
+	out, _ := removeArtifacts(lines, srtArtifact, CleanOptions{})
+	return out
+}
+
+// vttArtifact classifies a trimmed, non-empty line of a VTT file.
+func vttArtifact(line string) artifactKind {
+	switch {
+	case line == "WEBVTT":
+		return headerArtifact
+	case IsNumber(line):
+		return numberArtifact
+	case IsTimestamp(line):
+		return timestampArtifact
+	}
+	return notArtifact
+}
+
+// srtArtifact classifies a trimmed, non-empty line of an SRT file.
+func srtArtifact(line string) artifactKind {
+	switch {
+	case IsNumber(line):
+		return numberArtifact
+	case IsSRTTimestamp(line):
+		return timestampArtifact
+	}
+	return notArtifact
+}
+
+// removeArtifacts keeps the caption text of lines, dropping blanks, structural
+// lines recognised by classify and HTML tags, and counts what it dropped. HTML
+// entities are always unescaped. With opts.KeepBreaks, a run of two or more
+// blank lines in the source is kept as a single "" paragraph break; the single
+// blank separating ordinary cues is still dropped.
+func removeArtifacts(lines []string, classify func(string) artifactKind, opts CleanOptions) ([]string, CleanStats) {
+	f := artifactFilter{classify: classify, opts: opts, lines: []string{}}
 	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || line == "WEBVTT" {
-			continue
-		}
-		if IsNumber(line) {
-			continue
-		}
-		if IsTimestamp(line) {
-			continue
+		f.add(line)
+	}
+	f.finish()
+	return f.lines, f.stats
+}
+
+// artifactFilter is removeArtifacts one line at a time, so a subtitle file
+// can be cleaned while it is read instead of being split in memory first.
+type artifactFilter struct {
+	classify     func(string) artifactKind
+	opts         CleanOptions
+	lines        []string // Caption text kept so far
+	stats        CleanStats
+	blanks       int    // Consecutive blank lines just seen
+	pendingBreak bool   // A paragraph break precedes the next kept line
+	number       string // All-digit line awaiting the next line to tell a cue number from speech
+	holding      bool   // number is set
+	inCue        bool   // The last kept line belongs to the current cue block, for JoinCueLines
+}
+
+// add processes the next raw line of the file. An all-digit line is held
+// back until the next one: followed by a timing line it is a cue number,
+// otherwise it is spoken text (a year, a count) and kept.
+func (f *artifactFilter) add(line string) {
+	f.stats.RawLines++
+	raw := strings.TrimPrefix(line, "\ufeff") // Some exporters prepend a BOM
+	line = strings.TrimSpace(raw)
+	if f.holding {
+		f.holding = false
+		if line != "" && f.classify(line) == timestampArtifact {
+			f.stats.Numbers++
+		} else {
+			f.keep(f.number)
 		}
-		line = StripHTMLTags(line)
-		if line == "" {
-			continue
+	}
+	if line == "" {
+		f.inCue = false
+		f.stats.Blank++
+		f.blanks++
+		if f.blanks >= 2 {
+			f.pendingBreak = true
 		}
-		outLines = append(outLines, line)
+		return
+	}
+	f.blanks = 0
+	switch f.classify(line) {
+	case headerArtifact:
+		f.stats.Headers++
+		return
+	case numberArtifact:
+		f.number, f.holding = raw, true
+		return
+	case timestampArtifact:
+		f.inCue = false
+		f.stats.Timestamps++
+		return
+	}
+	f.keep(raw)
+}
+
+// finish keeps a number still held back at the end of the file, since no
+// timing line follows it.
+func (f *artifactFilter) finish() {
+	if f.holding {
+		f.holding = false
+		f.keep(f.number)
 	}
-	return outLines
+}
+
+// keep strips HTML, StripPatterns and surrounding whitespace from a caption
+// line (with KeepIndent, all but its indentation) and appends it, unless
+// nothing is left. With JoinCueLines, a line following another of the same
+// cue block (no blank or timing line between them) is joined onto it instead.
+func (f *artifactFilter) keep(raw string) {
+	var text string
+	if f.opts.PreserveStyling {
+		text = html.UnescapeString(stripNoiseTags(raw))
+	} else {
+		text = html.UnescapeString(stripHTMLTags(raw, f.opts.Speakers))
+	}
+	if strings.TrimSpace(text) == "" {
+		f.stats.HTMLOnly++
+		return
+	}
+	for _, re := range f.opts.StripPatterns {
+		text = re.ReplaceAllString(text, "")
+	}
+	line := strings.TrimSpace(text)
+	if line == "" {
+		f.stats.Stripped++
+		return
+	}
+	if f.opts.KeepIndent {
+		line = captionIndent(text) + line
+	}
+	if f.opts.JoinCueLines && f.inCue {
+		f.lines[len(f.lines)-1] += " " + strings.TrimSpace(line)
+		return
+	}
+	f.inCue = true
+	if f.opts.KeepBreaks && f.pendingBreak && len(f.lines) > 0 {
+		f.lines = append(f.lines, "")
+	}
+	f.pendingBreak = false
+	f.lines = append(f.lines, line)
+}
+
+// captionIndent returns the indentation KeepIndent preserves for a caption
+// line: its leading whitespace, less the single space many caption writers
+// put before every line.
+func captionIndent(text string) string {
+	indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+	return strings.TrimPrefix(indent, " ")
+}
+
+// maxLineBytes bounds a single line of a subtitle file read by cleanFile.
+const maxLineBytes = 64 << 20
+
+// scanRawLines is a bufio.SplitFunc like bufio.ScanLines, except that it
+// also yields the empty line after a trailing newline, as strings.Split does,
+// so line counts match splitting the whole file. Like the CRLF normalization
+// it replaces, it drops a '\r' only before a '\n'.
+func scanRawLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, bytes.TrimSuffix(data[:i], []byte{'\r'}), nil
+	}
+	if atEOF {
+		return len(data), append([]byte{}, data...), bufio.ErrFinalToken
+	}
+	return 0, nil, nil // Request more data
 }
 
 // CleanVTTFile reads a VTT file, cleans and dedupes its lines, and returns the result as a string.
 func CleanVTTFile(vttPath string) (string, error) {
-	content, err := ReadTextFile(vttPath)
-	if err != nil {
-		return "", err
+	return CleanVTTFileWithOptions(vttPath, CleanOptions{})
+}
+
+// CleanVTTFileWithOptions is CleanVTTFile with the optional cleaning steps in opts applied.
+func CleanVTTFileWithOptions(vttPath string, opts CleanOptions) (string, error) {
+	out, _, err := cleanFile(vttPath, vttArtifact, opts)
+	return out, err
+}
+
+// CleanVTTFileWithStats is CleanVTTFile that also reports what each cleaning step dropped.
+func CleanVTTFileWithStats(vttPath string) (string, CleanStats, error) {
+	return cleanFile(vttPath, vttArtifact, CleanOptions{})
+}
+
+// CleanSRTFile reads an SRT file, cleans and dedupes its lines, and returns the result as a string.
+func CleanSRTFile(srtPath string) (string, error) {
+	return CleanSRTFileWithOptions(srtPath, CleanOptions{})
+}
+
+// CleanSRTFileWithOptions is CleanSRTFile with the optional cleaning steps in opts applied.
+func CleanSRTFileWithOptions(srtPath string, opts CleanOptions) (string, error) {
+	out, _, err := cleanFile(srtPath, srtArtifact, opts)
+	return out, err
+}
+
+// CleanSubtitleFile cleans a raw subtitle file, choosing the parser from its extension.
+func CleanSubtitleFile(path string, opts CleanOptions) (string, error) {
+	out, _, err := CleanSubtitleFileWithStats(path, opts)
+	return out, err
+}
+
+// CleanSubtitleFileWithStats is CleanSubtitleFile that also reports what each cleaning step dropped.
+func CleanSubtitleFileWithStats(path string, opts CleanOptions) (string, CleanStats, error) {
+	if strings.EqualFold(filepath.Ext(path), ".srt") {
+		return cleanFile(path, srtArtifact, opts)
 	}
+	return cleanFile(path, vttArtifact, opts)
+}
 
-	lines := strings.Split(content, "\n")
-	cleaned := RemoveVTTArtifacts(lines)
-	final := DedupeLines(cleaned)
-	return strings.Join(final, "\n"), nil
+// dedupeLines collapses repeated caption lines using the dedupe mode in opts.
+// With opts.NoDedupe the lines are returned unchanged.
+func dedupeLines(lines []string, opts CleanOptions) []string {
+	if opts.NoDedupe {
+		return lines
+	}
+	normalize := func(s string) string { return s }
+	if opts.FuzzyDedupe {
+		normalize = NormalizeCaseAndPunctuation
+	}
+	if opts.DedupeWindow > 1 {
+		return DedupeLinesWindow(lines, opts.DedupeWindow, normalize)
+	}
+	if opts.FuzzyDedupe {
+		return DedupeLinesFunc(lines, normalize)
+	}
+	return DedupeLines(lines)
+}
+
+// cleanFile reads a subtitle file, strips the artifacts recognised by classify
+// and runs the remaining caption lines through the cleaning Pipeline for opts.
+//
+// The file is streamed line by line (see CleanVTTReader), so the raw file is
+// never held in memory.
+func cleanFile(path string, classify func(string) artifactKind, opts CleanOptions) (string, CleanStats, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", CleanStats{}, err
+	}
+	defer file.Close()
+	return readCleaned(newCleanReader(file, classify, opts))
 }
 
 // SaveCleanedTranscript processes a VTT file and outputs a cleaned text file
 func SaveCleanedTranscript(vttPath, cleanedDir string) error {
-	output, err := CleanVTTFile(vttPath)
+	_, err := SaveCleanedTranscriptWithOptions(vttPath, cleanedDir, CleanOptions{})
+	return err
+}
+
+// SaveCleanedTranscriptWithOptions is SaveCleanedTranscript with the optional
+// cleaning steps in opts applied. It returns the path written.
+func SaveCleanedTranscriptWithOptions(vttPath, cleanedDir string, opts CleanOptions) (string, error) {
+	output, err := CleanSubtitleFile(vttPath, opts)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	outPath := GetOutputFilePath(vttPath, cleanedDir)
-	return WriteTextFile(outPath, output)
+	return outPath, WriteTextFileAtomic(outPath, EncodeOutput(withFinalNewline(output), opts))
 }
 
 // GetNewestVTTPattern returns a glob pattern for finding VTT files
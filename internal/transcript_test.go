@@ -1,9 +1,13 @@
 package internal
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDedupeLines(t *testing.T) {
@@ -113,7 +117,9 @@ func TestRemoveVTTArtifacts(t *testing.T) {
 	}{
 		{"empty lines", []string{}, []string{}},
 		{"only WEBVTT", []string{"WEBVTT"}, []string{}},
-		{"only numbers", []string{"1", "23"}, []string{}},
+		{"numbers without timings are speech", []string{"1", "23"}, []string{"1", "23"}},
+		{"cue numbers before timings", []string{"1", "00:00:00.000 --> 00:00:01.000", "hello", "", "2", "00:00:01.000 --> 00:00:02.000", "world"}, []string{"hello", "world"}},
+		{"spoken year", []string{"1", "00:00:00.000 --> 00:00:01.000", "it was", "2024", "", "2", "00:00:01.000 --> 00:00:02.000", "2024"}, []string{"it was", "2024", "2024"}},
 		{"only timestamps", []string{"00:00:00.000 --> 00:00:01.000"}, []string{}},
 		{"text with tags", []string{"<c>hello</c>"}, []string{"hello"}},
 		{"mixed content", []string{"WEBVTT", "", "1", "00:00:00.000 --> 00:00:01.000", "hello world", "<c>another</c> line"}, []string{"hello world", "another line"}},
@@ -128,6 +134,66 @@ func TestRemoveVTTArtifacts(t *testing.T) {
 	}
 }
 
+func TestRemoveVTTArtifactsWithOptions_KeepIndent(t *testing.T) {
+	lines := []string{"WEBVTT", "", "00:00:00.000 --> 00:00:01.000", " first line  ", "     continued", "\t<c>tabbed</c>"}
+	if got, want := RemoveVTTArtifacts(lines), []string{"first line", "continued", "tabbed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RemoveVTTArtifacts() = %q, want %q", got, want)
+	}
+	got := RemoveVTTArtifactsWithOptions(lines, CleanOptions{KeepIndent: true})
+	if want := []string{"first line", "    continued", "\ttabbed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RemoveVTTArtifactsWithOptions(KeepIndent) = %q, want %q", got, want)
+	}
+}
+
+func TestRemoveVTTArtifactsWithOptions_StripPatterns(t *testing.T) {
+	patterns, err := CompileStripPatterns([]string{`\[CC BY [^]]*\]`, `(?i)\(music\)`})
+	if err != nil {
+		t.Fatalf("CompileStripPatterns() error = %v", err)
+	}
+	lines := []string{"WEBVTT", "", "00:00:00.000 --> 00:00:01.000", "[CC BY XYZ] welcome back", "(Music) and (music) again", "", "00:00:01.000 --> 00:00:02.000", "[CC BY XYZ]", "no match here [CC]"}
+	got, stats := removeArtifacts(lines, vttArtifact, CleanOptions{StripPatterns: patterns})
+	if want := []string{"welcome back", "and  again", "no match here [CC]"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("removeArtifacts(StripPatterns) = %q, want %q", got, want)
+	}
+	if stats.Stripped != 1 {
+		t.Errorf("removeArtifacts(StripPatterns) stripped %d lines, want 1", stats.Stripped)
+	}
+
+	if _, err := CompileStripPatterns([]string{"ok", "[unclosed"}); err == nil || !strings.Contains(err.Error(), "[unclosed") {
+		t.Errorf("CompileStripPatterns(invalid) error = %v, want one naming the pattern", err)
+	}
+}
+
+func TestCleanVTTFileWithOptions_JoinCueLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wrapped.vtt")
+	content := "WEBVTT\n\n1\n00:00:00.000 --> 00:00:02.000\nwe went to the\nstore yesterday\n\n2\n00:00:02.000 --> 00:00:04.000\nit was closed\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := CleanVTTFileWithOptions(path, CleanOptions{})
+	if err != nil {
+		t.Fatalf("CleanVTTFileWithOptions() error = %v", err)
+	}
+	if want := "we went to the\nstore yesterday\nit was closed"; got != want {
+		t.Errorf("CleanVTTFileWithOptions() = %q, want %q", got, want)
+	}
+
+	got, err = CleanVTTFileWithOptions(path, CleanOptions{JoinCueLines: true})
+	if err != nil {
+		t.Fatalf("CleanVTTFileWithOptions(JoinCueLines) error = %v", err)
+	}
+	if want := "we went to the store yesterday\nit was closed"; got != want {
+		t.Errorf("CleanVTTFileWithOptions(JoinCueLines) = %q, want %q", got, want)
+	}
+
+	// Cues parsed for chapters or trimming keep their boundaries too
+	cues := ParseVTTCues(content)
+	if got, want := CleanCuesWithChapters(cues, nil, CleanOptions{JoinCueLines: true}), "we went to the store yesterday\nit was closed"; got != want {
+		t.Errorf("CleanCuesWithChapters(JoinCueLines) = %q, want %q", got, want)
+	}
+}
+
 func TestGetNewestVTTPattern(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -191,3 +257,406 @@ func TestGetOutputFilePath(t *testing.T) {
 		})
 	}
 }
+
+func TestDedupeLinesFunc(t *testing.T) {
+	identity := func(s string) string { return s }
+	tests := []struct {
+		name      string
+		lines     []string
+		normalize func(string) string
+		want      []string
+	}{
+		{"empty input", []string{}, identity, []string{}},
+		{"identity matches DedupeLines", []string{"a", "a", "b", "a"}, identity, []string{"a", "b", "a"}},
+		{"case and punctuation differ", []string{"hello", "Hello."}, NormalizeCaseAndPunctuation, []string{"Hello."}},
+		{"keeps more complete variant regardless of order", []string{"Hello!", "hello"}, NormalizeCaseAndPunctuation, []string{"Hello!"}},
+		{"equal length keeps later", []string{"the cat", "The cat"}, NormalizeCaseAndPunctuation, []string{"The cat"}},
+		{"capitalized kept over later lowercase", []string{"The cat", "the cat"}, NormalizeCaseAndPunctuation, []string{"The cat"}},
+		{"case-only flip without fuzzy is kept", []string{"the cat", "The cat"}, identity, []string{"the cat", "The cat"}},
+		{"distinct lines untouched", []string{"hello", "world"}, NormalizeCaseAndPunctuation, []string{"hello", "world"}},
+		{"non-consecutive kept", []string{"hello", "world", "Hello."}, NormalizeCaseAndPunctuation, []string{"hello", "world", "Hello."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DedupeLinesFunc(tt.lines, tt.normalize); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DedupeLinesFunc() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupeLinesWindow(t *testing.T) {
+	identity := func(s string) string { return s }
+	tests := []struct {
+		name   string
+		lines  []string
+		window int
+		want   []string
+	}{
+		{"window 1 is consecutive only", []string{"a", "b", "c", "a"}, 1, []string{"a", "b", "c", "a"}},
+		{"window 3 drops dupe two lines back", []string{"a", "b", "a", "c"}, 3, []string{"a", "b", "c"}},
+		{"window 3 drops dupe three lines back", []string{"a", "b", "c", "a"}, 3, []string{"a", "b", "c"}},
+		{"beyond the window is kept", []string{"a", "b", "c", "d", "a"}, 3, []string{"a", "b", "c", "d", "a"}},
+		{"blank lines are never dropped", []string{"a", "", "b", "", "c"}, 3, []string{"a", "", "b", "", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DedupeLinesWindow(tt.lines, tt.window, identity); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DedupeLinesWindow(%d) = %q, want %q", tt.window, got, tt.want)
+			}
+		})
+	}
+
+	// With fuzzy matching the more complete variant still wins
+	got := DedupeLinesWindow([]string{"hello", "world", "Hello."}, 3, NormalizeCaseAndPunctuation)
+	if want := []string{"Hello.", "world"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DedupeLinesWindow(fuzzy) = %q, want %q", got, want)
+	}
+	got = DedupeLinesWindow([]string{"The cat", "sat", "the cat"}, 3, NormalizeCaseAndPunctuation)
+	if want := []string{"The cat", "sat"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DedupeLinesWindow(fuzzy) = %q, want %q", got, want)
+	}
+}
+
+func TestCleanVTTFileWithOptions_FuzzyDedupe(t *testing.T) {
+	vttPath := filepath.Join(t.TempDir(), "video.en.vtt")
+	content := "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n\n00:00:01.000 --> 00:00:02.000\nHello.\n"
+	if err := WriteTextFile(vttPath, content); err != nil {
+		t.Fatal(err)
+	}
+
+	strict, err := CleanVTTFile(vttPath)
+	if err != nil {
+		t.Fatalf("CleanVTTFile() error = %v", err)
+	}
+	if strict != "hello\nHello." {
+		t.Errorf("CleanVTTFile() = %q, want strict dedupe to keep both lines", strict)
+	}
+
+	fuzzy, err := CleanVTTFileWithOptions(vttPath, CleanOptions{FuzzyDedupe: true})
+	if err != nil {
+		t.Fatalf("CleanVTTFileWithOptions() error = %v", err)
+	}
+	if fuzzy != "Hello." {
+		t.Errorf("CleanVTTFileWithOptions(FuzzyDedupe) = %q, want %q", fuzzy, "Hello.")
+	}
+}
+
+func TestIsSRTTimestamp(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"00:00:01,000 --> 00:00:02,500", true},
+		{"00:00:01.000 --> 00:00:02.500", false},
+		{"00:00:01,000 00:00:02,500", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsSRTTimestamp(tt.s); got != tt.want {
+			t.Errorf("IsSRTTimestamp(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestCleanSubtitleFile_SRT(t *testing.T) {
+	srtPath := filepath.Join(t.TempDir(), "video.en.srt")
+	content := "1\r\n00:00:00,000 --> 00:00:01,000\r\n<i>hello</i>\r\n\r\n2\r\n00:00:01,000 --> 00:00:02,000\r\nhello\r\nworld\r\n"
+	if err := WriteTextFile(srtPath, content); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := CleanSubtitleFile(srtPath, CleanOptions{})
+	if err != nil {
+		t.Fatalf("CleanSubtitleFile() error = %v", err)
+	}
+	if got != "hello\nworld" {
+		t.Errorf("CleanSubtitleFile(srt) = %q, want %q", got, "hello\nworld")
+	}
+}
+
+func TestCleanVTTFileWithOptions_KeepBreaks(t *testing.T) {
+	vttPath := filepath.Join(t.TempDir(), "video.en.vtt")
+	content := "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nfirst\n\n00:00:01.000 --> 00:00:02.000\nsecond\n\n\n00:00:05.000 --> 00:00:06.000\nthird\n"
+	if err := WriteTextFile(vttPath, content); err != nil {
+		t.Fatal(err)
+	}
+
+	flat, err := CleanVTTFileWithOptions(vttPath, CleanOptions{})
+	if err != nil {
+		t.Fatalf("CleanVTTFileWithOptions() error = %v", err)
+	}
+	if flat != "first\nsecond\nthird" {
+		t.Errorf("CleanVTTFileWithOptions() = %q, want breaks dropped by default", flat)
+	}
+
+	kept, err := CleanVTTFileWithOptions(vttPath, CleanOptions{KeepBreaks: true})
+	if err != nil {
+		t.Fatalf("CleanVTTFileWithOptions(KeepBreaks) error = %v", err)
+	}
+	if kept != "first\nsecond\n\nthird" {
+		t.Errorf("CleanVTTFileWithOptions(KeepBreaks) = %q, want %q", kept, "first\nsecond\n\nthird")
+	}
+}
+
+func TestCleanVTTFileWithStats(t *testing.T) {
+	vttPath := filepath.Join(t.TempDir(), "video.en.vtt")
+	content := "WEBVTT\n\n1\n00:00:00.000 --> 00:00:01.000\nhello\n\n2\n00:00:01.000 --> 00:00:02.000\nhello\n<c></c>\nworld"
+	if err := WriteTextFile(vttPath, content); err != nil {
+		t.Fatal(err)
+	}
+
+	got, stats, err := CleanVTTFileWithStats(vttPath)
+	if err != nil {
+		t.Fatalf("CleanVTTFileWithStats() error = %v", err)
+	}
+	if got != "hello\nworld" {
+		t.Errorf("CleanVTTFileWithStats() = %q, want %q", got, "hello\nworld")
+	}
+	want := CleanStats{RawLines: 11, Blank: 2, Headers: 1, Numbers: 2, Timestamps: 2, HTMLOnly: 1, Duplicates: 1, FinalLines: 2}
+	if stats != want {
+		t.Errorf("CleanVTTFileWithStats() stats = %+v, want %+v", stats, want)
+	}
+	if got := stats.CaptionLines(); got != 3 { // hello, hello, world
+		t.Errorf("CaptionLines() = %d, want 3", got)
+	}
+}
+
+func TestSaveCleanedTranscriptWithOptions(t *testing.T) {
+	srcDir, cleanedDir := t.TempDir(), t.TempDir()
+	vttPath := filepath.Join(srcDir, "abc--My Video.en.vtt")
+	if err := WriteTextFile(vttPath, "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath, err := SaveCleanedTranscriptWithOptions(vttPath, cleanedDir, CleanOptions{})
+	if err != nil {
+		t.Fatalf("SaveCleanedTranscriptWithOptions() error = %v", err)
+	}
+	if want := filepath.Join(cleanedDir, "My Video.txt"); outPath != want {
+		t.Errorf("SaveCleanedTranscriptWithOptions() path = %q, want %q", outPath, want)
+	}
+	if got, _ := ReadTextFile(outPath); got != "hello\n" {
+		t.Errorf("SaveCleanedTranscriptWithOptions() wrote %q, want %q", got, "hello\n")
+	}
+}
+
+func TestRemoveVTTArtifacts_UnescapesEntities(t *testing.T) {
+	got := RemoveVTTArtifacts([]string{"rock &amp; roll", "it&#39;s <c>fine</c>", "&nbsp;"})
+	want := []string{"rock & roll", "it's fine"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RemoveVTTArtifacts() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeToASCII(t *testing.T) {
+	got := NormalizeToASCII("“don’t” — wait…")
+	if want := `"don't" - wait...`; got != want {
+		t.Errorf("NormalizeToASCII() = %q, want %q", got, want)
+	}
+}
+
+func TestApplyCase(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		mode string
+		want string
+	}{
+		{"keep", "Hello World", CaseKeep, "Hello World"},
+		{"empty mode keeps", "Hello World", "", "Hello World"},
+		{"lower", "Hello World", CaseLower, "hello world"},
+		{"upper", "Hello World", CaseUpper, "HELLO WORLD"},
+		{"sentence", "so THIS is it. and then? yes!\nnext line", CaseSentence, "So this is it. And then? Yes!\nNext line"},
+		{"sentence skips quotes", `he said. "wow"`, CaseSentence, `He said. "Wow"`},
+		{"sentence ignores decimals", "it rose 3.5 percent", CaseSentence, "It rose 3.5 percent"},
+		// Documented imperfection: abbreviations end a "sentence".
+		{"sentence abbreviations", "e.g. this", CaseSentence, "E.g. This"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ApplyCase(tt.text, tt.mode); got != tt.want {
+				t.Errorf("ApplyCase(%q, %q) = %q, want %q", tt.text, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitSpeakerLabel(t *testing.T) {
+	tests := []struct {
+		line, label, text string
+	}{
+		{"JOHN: hello there", "JOHN:", "hello there"},
+		{"DR. SMITH: take two", "DR. SMITH:", "take two"},
+		{">> Anna: hi", ">> Anna:", "hi"},
+		{">> so anyway", ">>", "so anyway"},
+		{">>", ">>", ""},
+		{"Note: this is a sentence", "", "Note: this is a sentence"},
+		{"I: not a label", "", "I: not a label"},
+		{"The END: fin", "", "The END: fin"},
+		{"HTTP://example.com", "", "HTTP://example.com"},
+	}
+	for _, tt := range tests {
+		label, text := SplitSpeakerLabel(tt.line)
+		if label != tt.label || text != tt.text {
+			t.Errorf("SplitSpeakerLabel(%q) = %q, %q, want %q, %q", tt.line, label, text, tt.label, tt.text)
+		}
+	}
+}
+
+func TestCleanVTTFileWithOptions_Speakers(t *testing.T) {
+	vttPath := filepath.Join(t.TempDir(), "video.en.vtt")
+	content := "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nJOHN: WELL, WE THINK SO. &gt;&gt; MARY: NOT ME.\n\n00:00:01.000 --> 00:00:02.000\nANYWAY.\n"
+	if err := WriteTextFile(vttPath, content); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := CleanVTTFileWithOptions(vttPath, CleanOptions{Speakers: true, Case: CaseSentence})
+	if err != nil {
+		t.Fatalf("CleanVTTFileWithOptions() error = %v", err)
+	}
+	if want := "JOHN: Well, we think so.\n>> MARY: Not me.\nAnyway."; got != want {
+		t.Errorf("CleanVTTFileWithOptions(Speakers) = %q, want %q", got, want)
+	}
+}
+
+func TestCleanVTTFileWithOptions_SpeakerMarkersOnlyWithSpeakers(t *testing.T) {
+	vttPath := filepath.Join(t.TempDir(), "video.en.vtt")
+	content := "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\n>> hello there\n\n00:00:01.000 --> 00:00:02.000\n<c>so</c> >> anyway\n"
+	if err := WriteTextFile(vttPath, content); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := CleanVTTFileWithOptions(vttPath, CleanOptions{})
+	if err != nil {
+		t.Fatalf("CleanVTTFileWithOptions() error = %v", err)
+	}
+	if strings.Contains(got, ">") {
+		t.Errorf("CleanVTTFileWithOptions() = %q, want no '>' without Speakers", got)
+	}
+
+	got, err = CleanVTTFileWithOptions(vttPath, CleanOptions{Speakers: true})
+	if err != nil {
+		t.Fatalf("CleanVTTFileWithOptions() error = %v", err)
+	}
+	if want := ">> hello there\nso\n>> anyway"; got != want {
+		t.Errorf("CleanVTTFileWithOptions(Speakers) = %q, want %q", got, want)
+	}
+}
+
+func TestCleanVTTFileWithStats_MatchesSplitContent(t *testing.T) {
+	tests := map[string]string{
+		"trailing newline":    "WEBVTT\n\n1\n00:00:00.000 --> 00:00:01.000\nhello\n\n\n2\n00:00:01.000 --> 00:00:02.000\n<i>world</i>\n",
+		"no trailing newline": "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\nhello",
+		"crlf":                "WEBVTT\r\n\r\n00:00:00.000 --> 00:00:01.000\r\nhello\r\n\r\n",
+		"lone cr":             "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\rworld\n",
+		"bom":                 "\ufeffWEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n",
+		"empty":               "",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			vttPath := filepath.Join(t.TempDir(), "video.en.vtt")
+			if err := WriteTextFile(vttPath, content); err != nil {
+				t.Fatal(err)
+			}
+			got, stats, err := CleanVTTFileWithStats(vttPath)
+			if err != nil {
+				t.Fatalf("CleanVTTFileWithStats() error = %v", err)
+			}
+
+			lines, wantStats := removeArtifacts(strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n"), vttArtifact, CleanOptions{})
+			final := DedupeLines(lines)
+			wantStats.Duplicates = len(lines) - len(final)
+			wantStats.FinalLines = len(final)
+			if want := strings.Join(final, "\n"); got != want {
+				t.Errorf("CleanVTTFileWithStats() = %q, want %q", got, want)
+			}
+			if stats != wantStats {
+				t.Errorf("CleanVTTFileWithStats() stats = %+v, want %+v", stats, wantStats)
+			}
+		})
+	}
+}
+
+func TestCleanVTTFileWithOptions_NoDedupe(t *testing.T) {
+	vttPath := filepath.Join(t.TempDir(), "video.en.vtt")
+	content := "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n\n00:00:01.000 --> 00:00:02.000\nhello\nworld\n"
+	if err := WriteTextFile(vttPath, content); err != nil {
+		t.Fatal(err)
+	}
+
+	got, stats, err := cleanFile(vttPath, vttArtifact, CleanOptions{NoDedupe: true, FuzzyDedupe: true})
+	if err != nil {
+		t.Fatalf("cleanFile() error = %v", err)
+	}
+	if want := "hello\nhello\nworld"; got != want {
+		t.Errorf("cleanFile(NoDedupe) = %q, want %q", got, want)
+	}
+	if stats.Duplicates != 0 {
+		t.Errorf("cleanFile(NoDedupe) Duplicates = %d, want 0", stats.Duplicates)
+	}
+	if got, _ := CleanVTTFile(vttPath); got != "hello\nworld" {
+		t.Errorf("CleanVTTFile() = %q, want consecutive repeats still collapsed by default", got)
+	}
+}
+
+// writeLargeVTT writes a synthetic multi-hour auto-caption file of about n
+// lines, with the rolling duplicates and inline tags real ones have.
+func writeLargeVTT(b *testing.B, n int) string {
+	b.Helper()
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\nKind: captions\nLanguage: en\n\n")
+	for i := 0; i*4 < n; i++ {
+		ts := time.Duration(i) * time.Second
+		fmt.Fprintf(&sb, "%s --> %s align:start position:0%%\n", formatVTTTime(ts), formatVTTTime(ts+time.Second))
+		fmt.Fprintf(&sb, "line number %d of the stream\n", i-1)
+		fmt.Fprintf(&sb, "line<%s><c> number</c><c> %d</c> of the stream\n\n", formatVTTTime(ts), i)
+	}
+	path := filepath.Join(b.TempDir(), "large.en.vtt")
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+// formatVTTTime formats d as a VTT hh:mm:ss.mmm timestamp.
+func formatVTTTime(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, d.Milliseconds()%1000)
+}
+
+func BenchmarkCleanVTTFile(b *testing.B) {
+	path := writeLargeVTT(b, 100_000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := CleanVTTFile(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCollapseWordStutter(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"the the cat", "the cat"},
+		{"I I think think so", "I think so"},
+		{"The the the end.", "The end."},
+		{"we are done done.", "we are done."},
+		{"yes. Yes it is", "yes. Yes it is"},
+		{"  indented indented line", "  indented line"},
+		{"no repeats here", "no repeats here"},
+		// Intentional doubles are collapsed too; see CollapseWordStutterExcept
+		{"he had had enough", "he had enough"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := CollapseWordStutter(tt.in); got != tt.want {
+			t.Errorf("CollapseWordStutter(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if got, want := CollapseWordStutterExcept("he had had the the idea that that works", IntentionalDoubles), "he had had the idea that that works"; got != want {
+		t.Errorf("CollapseWordStutterExcept() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// seenExt is appended to a URL list's path to name its record of the URLs
+// already processed.
+const seenExt = ".seen"
+
+// URLList is a file of URLs, one per line, that grows over time (a watch list
+// a cron job runs on), together with the record of the lines already
+// processed, kept next to it as <path>.seen. Blank lines and lines starting
+// with "#" are ignored.
+type URLList struct {
+	path string
+	seen map[string]bool
+	New  []string // Lines not processed by an earlier run, in file order, each once
+}
+
+// LoadURLList reads the URL list at path and its seen record, which is empty
+// if missing, and collects the new lines.
+func LoadURLList(path string) (*URLList, error) {
+	content, err := ReadTextFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read URL list: %w", err)
+	}
+	list := &URLList{path: path, seen: make(map[string]bool)}
+	seen, err := ReadTextFile(path + seenExt)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read seen URLs: %w", err)
+	}
+	for _, line := range strings.Split(seen, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			list.seen[line] = true
+		}
+	}
+	queued := make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || list.seen[line] || queued[line] {
+			continue
+		}
+		queued[line] = true
+		list.New = append(list.New, line)
+	}
+	return list, nil
+}
+
+// MarkSeen appends the given URLs to the seen record, so later runs skip
+// them. URLs already recorded are left out.
+func (l *URLList) MarkSeen(urls []string) error {
+	var b strings.Builder
+	for _, url := range urls {
+		if !l.seen[url] {
+			b.WriteString(url + "\n")
+		}
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(l.path+seenExt, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open seen URLs: %w", err)
+	}
+	_, err = file.WriteString(b.String())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record seen URLs: %w", err)
+	}
+	for _, url := range urls {
+		l.seen[url] = true
+	}
+	return nil
+}
+
+// Finished returns the new lines a run's jobs are done with: all of them,
+// except lines whose job failed or never finished, so the next run retries
+// those. Lines that didn't become a job of their own (playlists and channels,
+// which expand into their videos) count as done.
+func (l *URLList) Finished(jobs []TranscriptJob) []string {
+	retry := make(map[string]bool)
+	for _, job := range jobs {
+		if job.Status == "failed" || !isTerminalStatus(job.Status) {
+			retry[job.URL] = true
+		}
+	}
+	var done []string
+	for _, url := range l.New {
+		if !retry[url] {
+			done = append(done, url)
+		}
+	}
+	return done
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestURLList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.txt")
+	if err := os.WriteFile(path, []byte("# my list\nhttps://youtu.be/aaa\n\nhttps://youtu.be/bbb\nhttps://youtu.be/aaa\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := LoadURLList(path)
+	if err != nil {
+		t.Fatalf("LoadURLList() error = %v", err)
+	}
+	if want := []string{"https://youtu.be/aaa", "https://youtu.be/bbb"}; !reflect.DeepEqual(list.New, want) {
+		t.Fatalf("LoadURLList() first run New = %q, want %q", list.New, want)
+	}
+
+	// bbb failed, so only aaa is recorded and bbb is retried next time
+	jobs := []TranscriptJob{{URL: "https://youtu.be/aaa", Status: "completed"}, {URL: "https://youtu.be/bbb", Status: "failed"}}
+	if err := list.MarkSeen(list.Finished(jobs)); err != nil {
+		t.Fatalf("MarkSeen() error = %v", err)
+	}
+
+	// The list grows between runs
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("https://youtu.be/ccc\n")
+	f.Close()
+
+	list, err = LoadURLList(path)
+	if err != nil {
+		t.Fatalf("LoadURLList() error = %v", err)
+	}
+	if want := []string{"https://youtu.be/bbb", "https://youtu.be/ccc"}; !reflect.DeepEqual(list.New, want) {
+		t.Errorf("LoadURLList() second run New = %q, want %q", list.New, want)
+	}
+
+	// Unfinished jobs (an interrupted run) are retried too; lines that expanded
+	// into other jobs, like playlists, count as done
+	list.New = append(list.New, "https://www.youtube.com/playlist?list=PL1")
+	jobs = []TranscriptJob{{URL: "https://youtu.be/bbb", Status: "skipped (exists)"}, {URL: "https://youtu.be/ccc", Status: "downloading_subtitles"}}
+	if got, want := list.Finished(jobs), []string{"https://youtu.be/bbb", "https://www.youtube.com/playlist?list=PL1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Finished() = %q, want %q", got, want)
+	}
+
+	if _, err := LoadURLList(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadURLList() of a missing list should fail")
+	}
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Output formats for a processed video.
+const (
+	FormatText      = "text"       // Cleaned, deduplicated plain text
+	FormatWordsJSON = "words-json" // Per-word timings from auto-generated captions
+	FormatJSONL     = "jsonl"      // One {start,end,text} JSON object per cue, for streaming
+	FormatTimedText = "timed-txt"  // One "[HH:MM:SS] text" line per cue, for readable notes
+	FormatSRT       = "srt"        // Cleaned cues as SubRip subtitles
+	FormatVTT       = "vtt"        // Cleaned cues as WebVTT subtitles
+)
+
+// Formats lists the supported output formats.
+var Formats = []string{FormatText, FormatWordsJSON, FormatJSONL, FormatTimedText, FormatSRT, FormatVTT}
+
+// FormatForPath infers the output format of a transcript written to path from
+// its extension: .jsonl for jsonl, .json (as in .words.json) for words-json,
+// .srt and .vtt for those subtitle formats, and .txt, .md or none for text. ok is false for any other extension, which
+// no format writes.
+func FormatForPath(path string) (format string, ok bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case jsonlExt:
+		return FormatJSONL, true
+	case ".json":
+		return FormatWordsJSON, true
+	case srtExt:
+		return FormatSRT, true
+	case vttExt:
+		return FormatVTT, true
+	case ".txt", ".md", "":
+		return FormatText, true
+	}
+	return FormatText, false
+}
+
+// wordsJSONExt is the extension of word-timing output files.
+const wordsJSONExt = ".words.json"
+
+// Word is one spoken word of an auto-generated caption track.
+type Word struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"` // Seconds from the start of the video
+}
+
+// wordTimingRe matches the inline <hh:mm:ss.mmm> markers YouTube puts before
+// each word of an auto-generated caption.
+var wordTimingRe = regexp.MustCompile(`<(?:\d+:)?\d{2}:\d{2}\.\d{3}>`)
+
+// ParseWordTimings extracts per-word start times from subtitle content. Words
+// before a cue's first marker start with the cue. Only lines carrying markers
+// are read: YouTube's rolling auto-captions repeat the previous line, without
+// markers, as context, so this yields each word once. Manual captions have no
+// markers and so produce no words.
+func ParseWordTimings(content string) []Word {
+	var words []Word
+	for _, cue := range ParseVTTCues(content) {
+		for _, line := range strings.Split(cue.Text, "\n") {
+			markers := wordTimingRe.FindAllStringIndex(line, -1)
+			if len(markers) == 0 {
+				continue
+			}
+			start, pos := cue.Start, 0
+			for _, m := range markers {
+				words = appendWords(words, line[pos:m[0]], start)
+				start, _ = parseCueTimestamp(line[m[0]+1 : m[1]-1])
+				pos = m[1]
+			}
+			words = appendWords(words, line[pos:], start)
+		}
+	}
+	return words
+}
+
+// appendWords appends each word of a caption fragment, all starting at start.
+func appendWords(words []Word, fragment string, start time.Duration) []Word {
+	for _, w := range strings.Fields(html.UnescapeString(StripHTMLTags(fragment))) {
+		words = append(words, Word{Word: w, Start: start.Seconds()})
+	}
+	return words
+}
+
+// WriteWordTimingsFile parses the word timings of a raw subtitle file and
+// writes them to outPath as a JSON array. It fails if the captions carry no
+// word timings, i.e. they were not auto-generated.
+func WriteWordTimingsFile(rawFilePath, outPath string) error {
+	raw, err := ReadTextFile(rawFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read subtitle file %s: %w", rawFilePath, err)
+	}
+	words := ParseWordTimings(raw)
+	if len(words) == 0 {
+		return fmt.Errorf("no word timings in %s (only auto-generated captions have them)", rawFilePath)
+	}
+	data, err := json.MarshalIndent(words, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteTextFileAtomic(outPath, string(data)+"\n")
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// autoSubVTT mimics YouTube's rolling auto-captions: each line first appears
+// with word timings, then is repeated without them above the next line.
+const autoSubVTT = `WEBVTT
+Kind: captions
+Language: en
+
+00:00:01.000 --> 00:00:03.000 align:start position:0%
+ 
+hello<00:00:01.500><c> world</c><00:00:02.000><c> it&#39;s</c>
+
+00:00:03.000 --> 00:00:03.500 align:start position:0%
+hello world it&#39;s
+ 
+
+00:00:03.500 --> 00:00:05.000 align:start position:0%
+hello world it&#39;s
+me<00:00:03.750><c> again</c>
+`
+
+func TestParseWordTimings(t *testing.T) {
+	want := []Word{
+		{"hello", 1}, {"world", 1.5}, {"it's", 2},
+		{"me", 3.5}, {"again", 3.75},
+	}
+	if got := ParseWordTimings(autoSubVTT); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseWordTimings() = %v, want %v", got, want)
+	}
+
+	manual := "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nNo timings here\n"
+	if got := ParseWordTimings(manual); len(got) != 0 {
+		t.Errorf("ParseWordTimings(manual captions) = %v, want no words", got)
+	}
+}
+
+func TestWriteWordTimingsFile(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "abc.en.vtt")
+	if err := os.WriteFile(raw, []byte(autoSubVTT), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "Title.words.json")
+	if err := WriteWordTimingsFile(raw, out); err != nil {
+		t.Fatalf("WriteWordTimingsFile() error = %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var words []Word
+	if err := json.Unmarshal(data, &words); err != nil || len(words) != 5 || words[1] != (Word{"world", 1.5}) {
+		t.Errorf("WriteWordTimingsFile() wrote %s, want the 5 timed words", data)
+	}
+
+	if err := os.WriteFile(raw, []byte("WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nManual\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteWordTimingsFile(raw, out); err == nil || !strings.Contains(err.Error(), "auto-generated") {
+		t.Errorf("WriteWordTimingsFile(manual captions) error = %v, want a no word timings error", err)
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"talk.txt", FormatText, true},
+		{"notes/Talk.MD", FormatText, true},
+		{"talk", FormatText, true},
+		{"talk.jsonl", FormatJSONL, true},
+		{"talk.words.json", FormatWordsJSON, true},
+		{"talk.json", FormatWordsJSON, true},
+		{"talk.srt", FormatSRT, true},
+		{"talk.VTT", FormatVTT, true},
+		{"talk.ass", FormatText, false},
+	}
+	for _, tt := range tests {
+		if got, ok := FormatForPath(tt.path); got != tt.want || ok != tt.wantOK {
+			t.Errorf("FormatForPath(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -1,18 +1,345 @@
 package internal
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	neturl "net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
+// Sentinel errors for well-known yt-dlp failures. They are returned wrapped,
+// so callers should test for them with errors.Is.
+var (
+	ErrNoSubtitles      = errors.New("no subtitles available")
+	ErrVideoUnavailable = errors.New("video unavailable")
+	ErrNetwork          = errors.New("network error")
+	ErrUnrecognizedURL  = errors.New("not a recognized YouTube URL")
+	ErrEmptyTitle       = errors.New("yt-dlp returned an empty title")
+)
+
+// Failure categories used to group failed jobs in the summary.
+const (
+	CategoryNoSubtitles = "no subtitles"
+	CategoryNetwork     = "network"
+	CategoryUnavailable = "video unavailable"
+	CategoryTimeout     = "timeout"
+	CategoryInvalidURL  = "invalid url"
+	CategoryOther       = "other"
+)
+
+// FailureCategories lists every category in the order the summary shows them.
+var FailureCategories = []string{CategoryInvalidURL, CategoryNoSubtitles, CategoryUnavailable, CategoryNetwork, CategoryTimeout, CategoryOther}
+
+// Lowercased fragments of yt-dlp's stderr that identify a failure's cause.
+var (
+	noSubtitlesMarkers = []string{"there are no subtitles", "no subtitles for the requested languages"}
+	unavailableMarkers = []string{"video unavailable", "private video", "this video is private", "has been removed", "members-only", "not available in your country"}
+	networkMarkers     = []string{"unable to download webpage", "urlopen error", "connection reset", "connection refused", "name or service not known", "temporary failure in name resolution", "getaddrinfo failed", "http error 429", "http error 5", "timed out"}
+)
+
+// ClassifyError returns the failure category for a job error.
+func ClassifyError(err error) string {
+	switch {
+	case errors.Is(err, ErrNoSubtitles):
+		return CategoryNoSubtitles
+	case errors.Is(err, ErrVideoUnavailable):
+		return CategoryUnavailable
+	case errors.Is(err, ErrNetwork):
+		return CategoryNetwork
+	case errors.Is(err, context.DeadlineExceeded):
+		return CategoryTimeout
+	case errors.Is(err, ErrUnrecognizedURL):
+		return CategoryInvalidURL
+	default:
+		return CategoryOther
+	}
+}
+
+// ytDlpError wraps a failed yt-dlp run with the matching sentinel error (if
+// any) and the last line yt-dlp printed to stderr, which names the cause.
+func ytDlpError(err error, stderr []byte) error {
+	lastLine := ""
+	if lines := strings.Split(strings.TrimSpace(string(stderr)), "\n"); len(lines) > 0 {
+		lastLine = strings.TrimSpace(lines[len(lines)-1])
+	}
+
+	var sentinel error
+	lower := strings.ToLower(string(stderr))
+	switch {
+	case containsAny(lower, unavailableMarkers):
+		sentinel = ErrVideoUnavailable
+	case containsAny(lower, noSubtitlesMarkers):
+		sentinel = ErrNoSubtitles
+	case containsAny(lower, networkMarkers):
+		sentinel = ErrNetwork
+	}
+
+	switch {
+	case sentinel != nil && lastLine != "":
+		return fmt.Errorf("%w (%s): %w", sentinel, lastLine, err)
+	case sentinel != nil:
+		return fmt.Errorf("%w: %w", sentinel, err)
+	case lastLine != "":
+		return fmt.Errorf("%s: %w", lastLine, err)
+	default:
+		return err
+	}
+}
+
+// containsAny reports whether s contains any of the given substrings.
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// YtDlpArgs are extra arguments appended to every yt-dlp invocation, after
+// the built-in ones, so user flags win wherever yt-dlp honours the last
+// occurrence. Set it before any job starts.
+var YtDlpArgs []string
+
+// proxyEnv are the environment variables yt-dlp takes the proxy for YouTube's
+// HTTPS requests from, in the order it looks at them. HTTP_PROXY only covers
+// plain-HTTP URLs, so it isn't one of them.
+var proxyEnv = []string{"HTTPS_PROXY", "https_proxy"}
+
+// EffectiveProxy returns the proxy yt-dlp will use: flagVal if set (see
+// ProxyArgs), else the proxy from the environment, or "" for a direct
+// connection.
+func EffectiveProxy(flagVal string) string {
+	if flagVal != "" {
+		return flagVal
+	}
+	for _, name := range proxyEnv {
+		if proxy := os.Getenv(name); proxy != "" {
+			return proxy
+		}
+	}
+	return ""
+}
+
+// ProxyArgs returns the yt-dlp arguments for a -proxy of flagVal. With no
+// flag it returns none, leaving yt-dlp to pick up a proxy from the
+// environment itself.
+func ProxyArgs(flagVal string) []string {
+	if flagVal == "" {
+		return nil
+	}
+	return []string{"--proxy", flagVal}
+}
+
+// CookieBrowsers are the browsers yt-dlp's --cookies-from-browser can read.
+var CookieBrowsers = []string{"brave", "chrome", "chromium", "edge", "firefox", "opera", "safari", "vivaldi", "whale"}
+
+// cookieKeyrings are the keyrings yt-dlp can decrypt Linux Chromium cookies with.
+var cookieKeyrings = []string{"basictext", "gnomekeyring", "kwallet", "kwallet5", "kwallet6"}
+
+// CookiesFromBrowserArgs returns the yt-dlp arguments for a
+// -cookies-from-browser of flagVal, yt-dlp's BROWSER[+KEYRING][:PROFILE]
+// (e.g. "chrome", "firefox:work"), or none without the flag. The browser and
+// keyring must be ones yt-dlp knows, so a typo fails up front rather than in
+// every job.
+func CookiesFromBrowserArgs(flagVal string) ([]string, error) {
+	if flagVal == "" {
+		return nil, nil
+	}
+	browser, _, _ := strings.Cut(flagVal, ":")
+	browser, keyring, hasKeyring := strings.Cut(browser, "+")
+	if !slices.Contains(CookieBrowsers, strings.ToLower(browser)) {
+		return nil, fmt.Errorf("unknown browser %q (want one of %s)", browser, strings.Join(CookieBrowsers, ", "))
+	}
+	if hasKeyring && !slices.Contains(cookieKeyrings, strings.ToLower(keyring)) {
+		return nil, fmt.Errorf("unknown keyring %q (want one of %s)", keyring, strings.Join(cookieKeyrings, ", "))
+	}
+	return []string{"--cookies-from-browser", flagVal}, nil
+}
+
+// YtDlpRunner runs yt-dlp. Every yt-dlp invocation goes through Runner, so
+// tests can swap in a fake that returns canned output instead of a binary.
+type YtDlpRunner interface {
+	// Run runs yt-dlp with args, copying its stderr to stderr (which may be
+	// nil) as it is written, and returns its stdout. Cancelling ctx kills it.
+	Run(ctx context.Context, stderr io.Writer, args ...string) (stdout []byte, err error)
+}
+
+// killWaitDelay bounds how long a yt-dlp killed by its context's
+// cancellation is waited for: its children (ffmpeg converting subtitles) can
+// hold its output open after it is gone.
+const killWaitDelay = time.Second
+
+// execRunner runs the yt-dlp found on PATH.
+type execRunner struct{}
+
+func (execRunner) Run(ctx context.Context, stderr io.Writer, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "yt-dlp", args...)
+	cmd.Stderr = stderr
+	cmd.WaitDelay = killWaitDelay
+	return cmd.Output()
+}
+
+// YtDlpStreamer is a YtDlpRunner that can also hand over yt-dlp's stdout as
+// it is written, for StreamSubtitles. A Runner that isn't one has its whole
+// output streamed once yt-dlp exits instead.
+type YtDlpStreamer interface {
+	// Stream starts yt-dlp with args, copying its stderr to stderr (which may
+	// be nil), and returns its stdout. Close waits for yt-dlp to exit and
+	// returns its error, killing it first if stdout wasn't read to the end.
+	// Cancelling ctx kills it.
+	Stream(ctx context.Context, stderr io.Writer, args ...string) (io.ReadCloser, error)
+}
+
+func (execRunner) Stream(ctx context.Context, stderr io.Writer, args ...string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	cmd := exec.CommandContext(ctx, "yt-dlp", args...)
+	cmd.Stderr = stderr
+	cmd.WaitDelay = killWaitDelay
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &commandOutput{stdout: stdout, cmd: cmd, cancel: cancel}, nil
+}
+
+// commandOutput is the stdout of a started command, which Close waits for.
+type commandOutput struct {
+	stdout io.Reader
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+	eof    bool // stdout was read to the end
+}
+
+func (o *commandOutput) Read(p []byte) (int, error) {
+	n, err := o.stdout.Read(p)
+	if err == io.EOF {
+		o.eof = true
+	}
+	return n, err
+}
+
+func (o *commandOutput) Close() error {
+	if !o.eof {
+		o.cancel() // Nobody will read the rest, so yt-dlp could block writing it
+	}
+	err := o.cmd.Wait()
+	o.cancel()
+	if !o.eof {
+		return nil // Killed above; the reader gave up for its own reasons
+	}
+	return err
+}
+
+// bufferedOutput is the output of a Runner that can't stream, with its error
+// returned by Close.
+type bufferedOutput struct {
+	*bytes.Reader
+	err error
+}
+
+func (o bufferedOutput) Close() error { return o.err }
+
+// Runner runs every yt-dlp invocation. Set it before any job starts.
+var Runner YtDlpRunner = execRunner{}
+
+// streamYtDlp runs yt-dlp with args, returning its stdout as it is written
+// if Runner is a YtDlpStreamer.
+func streamYtDlp(ctx context.Context, stderr io.Writer, args ...string) (io.ReadCloser, error) {
+	if streamer, ok := Runner.(YtDlpStreamer); ok {
+		return streamer.Stream(ctx, stderr, args...)
+	}
+	output, err := Runner.Run(ctx, stderr, args...)
+	return bufferedOutput{Reader: bytes.NewReader(output), err: err}, nil
+}
+
+// runYtDlp runs yt-dlp with args followed by YtDlpArgs and returns its
+// stdout, classifying a failure by what yt-dlp wrote to stderr.
+func runYtDlp(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	output, err := Runner.Run(ctx, &stderr, slices.Concat(args, YtDlpArgs)...)
+	if err != nil {
+		return nil, ytDlpError(err, stderr.Bytes())
+	}
+	return output, nil
+}
+
+// SplitArgs splits a command line into arguments the way a POSIX shell
+// would: on unquoted whitespace, with single quotes, double quotes and
+// backslash escapes respected, e.g. `--sleep-requests 2 -o "a b"`.
+func SplitArgs(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, s)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash in %q", s)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
+
 // FetchTitle uses yt-dlp to get the video title
 func FetchTitle(url string) (string, error) {
-	cmd := exec.Command("yt-dlp", "--quiet", "--print", "title", url)
-	output, err := cmd.Output()
+	return FetchTitleCtx(context.Background(), url)
+}
+
+// FetchTitleCtx is FetchTitle with a context; cancelling ctx kills yt-dlp.
+func FetchTitleCtx(ctx context.Context, url string) (string, error) {
+	output, err := runYtDlp(ctx, "--quiet", "--print", "title", url)
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return "", fmt.Errorf("yt-dlp title fetch interrupted: %w", ctxErr)
+	}
 	if err != nil {
 		// Return an error and an empty title if yt-dlp fails
 		// The caller can then decide to use ExtractVideoID as a fallback
@@ -22,41 +349,600 @@ func FetchTitle(url string) (string, error) {
 	if title == "" {
 		// If yt-dlp returns an empty title, this is also an issue
 		// Caller can use ExtractVideoID as a fallback
-		return "", fmt.Errorf("yt-dlp returned an empty title")
+		return "", ErrEmptyTitle
 	}
 	return title, nil
 }
 
+// FetchVideoID uses yt-dlp to get the id it assigns a video, which works for
+// any site yt-dlp supports rather than just YouTube URLs
+func FetchVideoID(url string) (string, error) {
+	return FetchVideoIDCtx(context.Background(), url)
+}
+
+// FetchVideoIDCtx is FetchVideoID with a context; cancelling ctx kills yt-dlp.
+func FetchVideoIDCtx(ctx context.Context, url string) (string, error) {
+	output, err := runYtDlp(ctx, "--quiet", "--print", "id", url)
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return "", fmt.Errorf("yt-dlp id fetch interrupted: %w", ctxErr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("yt-dlp failed to fetch video id: %w", err)
+	}
+	id := strings.TrimSpace(string(output))
+	if id == "" {
+		return "", fmt.Errorf("yt-dlp returned an empty video id")
+	}
+	return id, nil
+}
+
+// SearchVideos uses yt-dlp's "ytsearchN:" scheme to find the top n YouTube
+// search results for query, returning their video URLs in result order. Like
+// FetchPlaylist it lists the results flat, in a single request.
+func SearchVideos(query string, n int) ([]string, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+	if n < 1 {
+		return nil, fmt.Errorf("search count must be at least 1, got %d", n)
+	}
+	output, err := runYtDlp(context.Background(), "--quiet", "--flat-playlist", "--print", "url", fmt.Sprintf("ytsearch%d:%s", n, query))
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp failed to search for %q: %w", query, err)
+	}
+	var urls []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if url := knownField(line); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls, nil
+}
+
+// ProbeAvailability asks yt-dlp, without downloading anything, how
+// available a video is: "public", "unlisted", "private", "needs_auth" and so
+// on, or "NA" if YouTube doesn't say. A private or removed video that can't
+// be accessed fails with ErrVideoUnavailable; a private video that the
+// -yt-dlp-extra cookies grant access to reports "private" without error.
+func ProbeAvailability(url string) (string, error) {
+	output, err := runYtDlp(context.Background(), "--quiet", "--skip-download", "--print", "availability", url)
+	if err != nil {
+		return "", fmt.Errorf("yt-dlp failed to probe video: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// YtDlpVersion returns the version of the yt-dlp found on PATH, which also
+// confirms it can be run at all.
+func YtDlpVersion() (string, error) {
+	var stderr bytes.Buffer
+	output, err := Runner.Run(context.Background(), &stderr, "--version")
+	if err != nil {
+		return "", fmt.Errorf("yt-dlp failed to report its version: %w", ytDlpError(err, stderr.Bytes()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// FetchUploader uses yt-dlp to get the name of the channel that uploaded the video
+func FetchUploader(url string) (string, error) {
+	output, err := runYtDlp(context.Background(), "--quiet", "--print", "uploader", url)
+	if err != nil {
+		return "", fmt.Errorf("yt-dlp failed to fetch uploader: %w", err)
+	}
+	uploader := strings.TrimSpace(string(output))
+	if uploader == "NA" {
+		// yt-dlp prints NA for fields the extractor doesn't provide
+		return "", nil
+	}
+	return uploader, nil
+}
+
+// VideoMetadata is the subset of yt-dlp's --dump-json output that we keep.
+type VideoMetadata struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Uploader    string    `json:"uploader"`
+	UploadDate  string    `json:"upload_date"` // YYYYMMDD, as reported by yt-dlp
+	Duration    float64   `json:"duration"`    // Seconds
+	Description string    `json:"description"`
+	ViewCount   int64     `json:"view_count"`
+	Chapters    []Chapter `json:"chapters,omitempty"`
+}
+
+// Chapter is a chapter marker from a video's description, as reported by yt-dlp.
+type Chapter struct {
+	Title     string  `json:"title"`
+	StartTime float64 `json:"start_time"` // Seconds
+	EndTime   float64 `json:"end_time"`   // Seconds
+}
+
+// Start returns the chapter's start time as a duration.
+func (c Chapter) Start() time.Duration {
+	return time.Duration(c.StartTime * float64(time.Second))
+}
+
+// FetchMetadata uses yt-dlp to dump a video's metadata as JSON and returns the fields we care about
+func FetchMetadata(url string) (VideoMetadata, error) {
+	output, err := runYtDlp(context.Background(), "--quiet", "--dump-json", "--skip-download", url)
+	if err != nil {
+		return VideoMetadata{}, fmt.Errorf("yt-dlp failed to fetch metadata: %w", err)
+	}
+	return ParseMetadata(output)
+}
+
+// ParseMetadata unmarshals yt-dlp --dump-json output into a VideoMetadata.
+func ParseMetadata(data []byte) (VideoMetadata, error) {
+	var meta VideoMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return VideoMetadata{}, fmt.Errorf("failed to parse yt-dlp metadata: %w", err)
+	}
+	return meta, nil
+}
+
+// DefaultLang is the subtitle language requested when none is configured.
+const DefaultLang = "en"
+
+// AllLangs is the subtitle language that asks yt-dlp for every language a video offers.
+const AllLangs = "all"
+
+// DefaultSubFormat is the subtitle format yt-dlp converts downloads to when none is configured.
+const DefaultSubFormat = "vtt"
+
+// SubFormats lists the subtitle formats the cleaner can ingest.
+var SubFormats = []string{"vtt", "srt"}
+
+// SubtitleOptions controls which subtitles DownloadSubtitlesWithOptions asks yt-dlp for.
+type SubtitleOptions struct {
+	Lang   string // Subtitle language code, e.g. "en", or AllLangs (defaults to DefaultLang)
+	Format string // Format yt-dlp converts subtitles to, one of SubFormats (defaults to DefaultSubFormat)
+	Source string // yt-dlp --sub-format preference for the format fetched before conversion, e.g. "srv3/vtt/best" ("" lets yt-dlp choose)
+
+	// ManualOnly asks for uploaded (human) captions only, never YouTube's
+	// auto-generated ones, so a video without them fails with ErrNoSubtitles.
+	ManualOnly bool
+
+	// OnProgress, if set, is called with each download percentage yt-dlp
+	// reports (0-100). It is called on the downloading goroutine.
+	OnProgress func(percent float64)
+}
+
+// SubtitleLanguages lists the caption languages a video offers.
+type SubtitleLanguages struct {
+	Manual []string // Uploaded (human) caption tracks
+	Auto   []string // Auto-generated caption tracks
+}
+
+// CaptionsTranslated marks a transcript built from YouTube's machine-translated captions.
+const CaptionsTranslated = "translated"
+
+// CaptionsAuto marks a transcript built from YouTube's auto-generated captions,
+// as found out with MarkAuto.
+const CaptionsAuto = "auto"
+
+// CaptionsManual marks a transcript built only from uploaded (human) captions,
+// as guaranteed by ManualOnly.
+const CaptionsManual = "manual"
+
+// HasNative reports whether lang is offered as an uploaded track or as the
+// auto-generated track of the video's own spoken language.
+func (l SubtitleLanguages) HasNative(lang string) bool {
+	return slices.Contains(l.Manual, lang) || slices.Contains(l.Auto, lang+"-orig")
+}
+
+// HasTranslation reports whether YouTube offers an auto-translated track into lang.
+func (l SubtitleLanguages) HasTranslation(lang string) bool {
+	return slices.Contains(l.Auto, lang)
+}
+
+// Native lists the tracks in the video's own languages: every uploaded track,
+// plus the auto-generated track of the spoken language ("<lang>-orig"), but
+// none of YouTube's auto-translations.
+func (l SubtitleLanguages) Native() []string {
+	langs := slices.Clone(l.Manual)
+	for _, lang := range l.Auto {
+		if strings.HasSuffix(lang, "-orig") && !slices.Contains(langs, strings.TrimSuffix(lang, "-orig")) {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
+}
+
+// Primary guesses the video's own spoken language: the auto-caption track
+// marked "-orig" by YouTube if present, else the first manual track, else
+// the first auto track. It returns "" if the video has no captions at all.
+func (l SubtitleLanguages) Primary() string {
+	for _, lang := range l.Auto {
+		if strings.HasSuffix(lang, "-orig") {
+			return lang
+		}
+	}
+	if len(l.Manual) > 0 {
+		return l.Manual[0]
+	}
+	if len(l.Auto) > 0 {
+		return l.Auto[0]
+	}
+	return ""
+}
+
+// Codes lists every track code: the uploaded ones, then (unless manualOnly)
+// the auto-generated ones.
+func (l SubtitleLanguages) Codes(manualOnly bool) []string {
+	if manualOnly {
+		return slices.Clone(l.Manual)
+	}
+	return slices.Concat(l.Manual, l.Auto)
+}
+
+// MatchLanguage picks the track of available that best serves a request for
+// the requested language code: an exact match (ignoring case) if there is
+// one, else the closest variant sharing its primary subtag, so "en" finds
+// "en-US" and "pt-BR" finds "pt". Closest means, in order: the bare language
+// ("en"), a regional variant ("en-GB", "es-419"), a script variant
+// ("zh-Hans"), then anything else ("en-orig", "en-nP7-2PuUl7o"), the first
+// listed winning a tie. ok is false if no track is in the language.
+func MatchLanguage(requested string, available []string) (match string, ok bool) {
+	if i := slices.IndexFunc(available, func(code string) bool { return strings.EqualFold(code, requested) }); i >= 0 {
+		return available[i], true
+	}
+	primary, _, _ := strings.Cut(requested, "-")
+	best := -1
+	for _, code := range available {
+		codePrimary, subtag, hasSubtag := strings.Cut(code, "-")
+		if !strings.EqualFold(codePrimary, primary) {
+			continue
+		}
+		rank := 3
+		switch {
+		case !hasSubtag:
+			rank = 0
+		case isRegionSubtag(subtag):
+			rank = 1
+		case len(subtag) == 4 && allLetters(subtag):
+			rank = 2
+		}
+		if best < 0 || rank < best {
+			match, best = code, rank
+		}
+	}
+	return match, best >= 0
+}
+
+// isRegionSubtag reports whether subtag is a region: two letters ("US") or
+// three digits ("419").
+func isRegionSubtag(subtag string) bool {
+	switch len(subtag) {
+	case 2:
+		return allLetters(subtag)
+	case 3:
+		return strings.Trim(subtag, "0123456789") == ""
+	}
+	return false
+}
+
+// allLetters reports whether s is made of letters only.
+func allLetters(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) }) < 0
+}
+
+// SubtitleTrack is one caption track a video offers.
+type SubtitleTrack struct {
+	Lang string // Track code yt-dlp selects it by, e.g. "en" or "en-nP7-2PuUl7o"
+	Auto bool   // Auto-generated rather than uploaded
+}
+
+// Tracks numbers the tracks in lang, for videos with more than one (e.g. a
+// forced and a full English track): those whose code is lang or starts with
+// lang and a "-", uploaded tracks first, each group in code order. Track n of
+// the result is the one requested by Options.Track n. An auto-generated track
+// sharing an uploaded track's code is left out, as yt-dlp always picks the
+// uploaded one for that code; with manualOnly, auto-generated tracks are left
+// out entirely.
+func (l SubtitleLanguages) Tracks(lang string, manualOnly bool) []SubtitleTrack {
+	inLang := func(code string) bool { return code == lang || strings.HasPrefix(code, lang+"-") }
+	var tracks []SubtitleTrack
+	for _, code := range l.Manual {
+		if inLang(code) {
+			tracks = append(tracks, SubtitleTrack{Lang: code})
+		}
+	}
+	if manualOnly {
+		return tracks
+	}
+	for _, code := range l.Auto {
+		if inLang(code) && !slices.Contains(l.Manual, code) {
+			tracks = append(tracks, SubtitleTrack{Lang: code, Auto: true})
+		}
+	}
+	return tracks
+}
+
+// downloadPercentRe matches the percentage of a yt-dlp progress line, e.g.
+// "[download]  42.5% of 1.20MiB at 300.00KiB/s ETA 00:02".
+var downloadPercentRe = regexp.MustCompile(`^\[download\]\s+(\d+(?:\.\d+)?)%`)
+
+// progressWriter scans the yt-dlp stderr written to it for progress lines
+// and passes each percentage to onProgress. A '\r' ends a line as well as a
+// '\n', as yt-dlp redraws its progress line with one when --newline isn't
+// honoured.
+type progressWriter struct {
+	onProgress func(float64)
+	line       []byte
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b == '\r' || b == '\n' {
+			w.Flush()
+			continue
+		}
+		w.line = append(w.line, b)
+	}
+	return len(p), nil
+}
+
+// Flush reports the line written so far, if it is a progress line.
+func (w *progressWriter) Flush() {
+	if m := downloadPercentRe.FindStringSubmatch(strings.TrimSpace(string(w.line))); m != nil {
+		if percent, err := strconv.ParseFloat(m[1], 64); err == nil {
+			w.onProgress(percent)
+		}
+	}
+	w.line = w.line[:0]
+}
+
+// ValidateSubSource checks a --sub-format preference list such as
+// "srv3/vtt/best": formats separated by "/", none of them empty.
+func ValidateSubSource(source string) error {
+	for _, format := range strings.Split(source, "/") {
+		if strings.TrimSpace(format) == "" {
+			return fmt.Errorf("invalid subtitle format preference %q (want formats separated by /, e.g. srv3/vtt/best)", source)
+		}
+	}
+	return nil
+}
+
+// ListSubtitleLanguages uses yt-dlp to list the caption languages available for a video
+func ListSubtitleLanguages(url string) (SubtitleLanguages, error) {
+	output, err := runYtDlp(context.Background(), "--quiet", "--dump-json", "--skip-download", url)
+	if err != nil {
+		return SubtitleLanguages{}, fmt.Errorf("yt-dlp failed to list subtitles: %w", err)
+	}
+	return ParseSubtitleLanguages(output)
+}
+
+// ParseSubtitleLanguages extracts the sorted manual and automatic caption
+// languages from yt-dlp --dump-json output.
+func ParseSubtitleLanguages(data []byte) (SubtitleLanguages, error) {
+	var info struct {
+		Subtitles         map[string]json.RawMessage `json:"subtitles"`
+		AutomaticCaptions map[string]json.RawMessage `json:"automatic_captions"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return SubtitleLanguages{}, fmt.Errorf("failed to parse yt-dlp subtitle list: %w", err)
+	}
+	return SubtitleLanguages{
+		Manual: sortedKeys(info.Subtitles),
+		Auto:   sortedKeys(info.AutomaticCaptions),
+	}, nil
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if k == "live_chat" { // Not a caption track
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // DownloadSubtitles downloads subtitles for a YouTube video using yt-dlp
 // It now accepts videoID to confirm file creation.
 func DownloadSubtitles(url, videoID, outputDir string) error {
-	// Output template uses video ID for the raw VTT filename for predictability.
-	// yt-dlp will add the .vtt extension.
+	return DownloadSubtitlesCtx(context.Background(), url, videoID, outputDir)
+}
+
+// DownloadSubtitlesCtx is DownloadSubtitles with a context; cancelling ctx kills yt-dlp.
+func DownloadSubtitlesCtx(ctx context.Context, url, videoID, outputDir string) error {
+	_, err := DownloadSubtitlesWithOptions(ctx, url, videoID, outputDir, SubtitleOptions{})
+	return err
+}
+
+// DownloadSubtitlesWithOptions is DownloadSubtitlesCtx with the subtitle
+// selection in opts applied. It returns the subtitle files yt-dlp created:
+// one per language with AllLangs, otherwise exactly one.
+func DownloadSubtitlesWithOptions(ctx context.Context, url, videoID, outputDir string, opts SubtitleOptions) ([]string, error) {
+	lang := opts.Lang
+	if lang == "" {
+		lang = DefaultLang
+	}
+	format := opts.Format
+	if format == "" {
+		format = DefaultSubFormat
+	}
+
+	// Output template uses video ID for the raw subtitle filename for predictability.
+	// yt-dlp will add the .<lang>.<format> extension.
 	outputTemplate := filepath.Join(outputDir, "%(id)s")
 
-	cmd := exec.Command("yt-dlp", "--quiet", url,
-		"--skip-download", "--write-sub", "--write-auto-sub",
-		"--sub-lang", "en", "--convert-subs", "vtt",
+	args := []string{"--quiet", url, "--skip-download", "--write-sub"}
+	if !opts.ManualOnly {
+		args = append(args, "--write-auto-sub")
+	}
+	args = append(args,
+		"--sub-lang", lang, "--convert-subs", format,
 		"--restrict-filenames",
 		"-o", outputTemplate,
 	)
-	err := cmd.Run()
+	if opts.Source != "" {
+		args = append(args, "--sub-format", opts.Source)
+	}
+	if opts.OnProgress != nil {
+		args = append(args, "--progress", "--newline") // With --quiet, progress goes to stderr
+	}
+	var stderr bytes.Buffer
+	var w io.Writer = &stderr
+	var progress *progressWriter
+	if opts.OnProgress != nil {
+		progress = &progressWriter{onProgress: opts.OnProgress}
+		w = io.MultiWriter(&stderr, progress)
+	}
+	_, err := Runner.Run(ctx, w, slices.Concat(args, YtDlpArgs)...)
+	if progress != nil {
+		progress.Flush() // The last line may lack a line ending
+	}
 	if err != nil {
-		return err // yt-dlp command itself failed
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr // yt-dlp was killed because the context ended
+		}
+		return nil, ytDlpError(err, stderr.Bytes()) // yt-dlp command itself failed
+	}
+
+	if lang == AllLangs {
+		// Each language lands in <videoID>.<lang>.<format>
+		files, err := filepath.Glob(filepath.Join(outputDir, videoID+".*."+format))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("%w: yt-dlp completed but wrote no subtitle files", ErrNoSubtitles)
+		}
+		return files, nil
 	}
 
 	// After yt-dlp command runs, verify the expected file was created
-	// It should be named <videoID>.en.vtt when --sub-lang en and --convert-subs vtt are used.
-	expectedVTTPath := filepath.Join(outputDir, videoID+".en.vtt")
-	if _, statErr := os.Stat(expectedVTTPath); os.IsNotExist(statErr) {
-		// yt-dlp ran successfully but the file doesn't exist.
-		return fmt.Errorf("yt-dlp completed but subtitle file %s was not created (likely no subtitles found for lang 'en')", expectedVTTPath)
-	} else if statErr != nil {
+	// It should be named <videoID>.<lang>.<format> when --sub-lang <lang> and --convert-subs <format> are used.
+	expectedVTTPath, _ := GetLocalSubtitlePath(videoID, lang, format, outputDir)
+	if _, statErr := os.Stat(expectedVTTPath); statErr == nil {
+		return []string{expectedVTTPath}, nil
+	} else if !os.IsNotExist(statErr) {
 		// Some other error trying to stat the file (e.g., permissions)
-		return fmt.Errorf("error checking for subtitle file %s after download: %w", expectedVTTPath, statErr)
+		return nil, fmt.Errorf("error checking for subtitle file %s after download: %w", expectedVTTPath, statErr)
 	}
 
-	return nil // File exists
+	// Depending on the yt-dlp version and caption kind the file may instead
+	// land as <videoID>.<format> or with a regional code like <videoID>.en-US.<format>
+	matches, err := filepath.Glob(filepath.Join(outputDir, videoID+"*."+format))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		// yt-dlp ran successfully but the file doesn't exist.
+		kind := ""
+		if opts.ManualOnly {
+			kind = "manual "
+		}
+		return nil, fmt.Errorf("%w: yt-dlp completed but subtitle file %s was not created (likely no %ssubtitles found for lang '%s')", ErrNoSubtitles, expectedVTTPath, kind, lang)
+	}
+	return matches[:1], nil
+}
+
+// StreamSubtitles is DownloadSubtitlesWithOptions without the files: yt-dlp
+// writes the subtitles in opts.Lang to its stdout (-o -), which is returned
+// for the caller to read, say through CleanVTTReader, and close. They are
+// always VTT, since yt-dlp can only convert files, so opts.Format is ignored,
+// and AllLangs can't be streamed. A video without subtitles in the language
+// gets ErrNoSubtitles, as yt-dlp then writes nothing; a yt-dlp failure once
+// subtitles have arrived is returned by Close.
+func StreamSubtitles(ctx context.Context, url string, opts SubtitleOptions) (io.ReadCloser, error) {
+	lang := opts.Lang
+	if lang == "" {
+		lang = DefaultLang
+	}
+	if lang == AllLangs {
+		return nil, errors.New("subtitles in every language can't be streamed")
+	}
+
+	args := []string{"--quiet", url, "--skip-download", "--write-sub"}
+	if !opts.ManualOnly {
+		args = append(args, "--write-auto-sub")
+	}
+	args = append(args, "--sub-lang", lang, "--sub-format", "vtt", "-o", "-")
+	if opts.OnProgress != nil {
+		args = append(args, "--progress", "--newline") // With --quiet, progress goes to stderr
+	}
+	s := &subtitleStream{ctx: ctx}
+	var w io.Writer = &s.stderr
+	if opts.OnProgress != nil {
+		s.progress = &progressWriter{onProgress: opts.OnProgress}
+		w = io.MultiWriter(&s.stderr, s.progress)
+	}
+	out, err := streamYtDlp(ctx, w, slices.Concat(args, YtDlpArgs)...)
+	if err != nil {
+		return nil, err
+	}
+	s.out, s.r = out, bufio.NewReader(out)
+
+	if _, err := s.r.Peek(1); err == io.EOF {
+		if err := s.Close(); err != nil {
+			return nil, err
+		}
+		kind := ""
+		if opts.ManualOnly {
+			kind = "manual "
+		}
+		return nil, fmt.Errorf("%w: yt-dlp completed but wrote no subtitles (likely no %ssubtitles found for lang '%s')", ErrNoSubtitles, kind, lang)
+	}
+	return s, nil
+}
+
+// subtitleStream is the yt-dlp output StreamSubtitles returns.
+type subtitleStream struct {
+	ctx      context.Context
+	out      io.ReadCloser
+	r        *bufio.Reader // Reads out, after StreamSubtitles peeked for anything at all
+	stderr   bytes.Buffer
+	progress *progressWriter
+}
+
+func (s *subtitleStream) Read(p []byte) (int, error) {
+	return s.r.Read(p)
+}
+
+// Close waits for yt-dlp to exit, returning its failure classified like
+// DownloadSubtitlesWithOptions's.
+func (s *subtitleStream) Close() error {
+	err := s.out.Close()
+	if s.progress != nil {
+		s.progress.Flush() // The last line may lack a line ending
+	}
+	if err == nil {
+		return nil
+	}
+	if ctxErr := s.ctx.Err(); ctxErr != nil {
+		return ctxErr // yt-dlp was killed because the context ended
+	}
+	return ytDlpError(err, s.stderr.Bytes())
+}
+
+// DownloadThumbnail downloads the thumbnail for a YouTube video using yt-dlp
+// and returns the path of the image it wrote as <videoID>.<ext> in outputDir.
+func DownloadThumbnail(url, videoID, outputDir string) (string, error) {
+	outputTemplate := filepath.Join(outputDir, "%(id)s")
+
+	if _, err := runYtDlp(context.Background(), "--quiet", url,
+		"--skip-download", "--write-thumbnail",
+		"-o", outputTemplate,
+	); err != nil {
+		return "", err
+	}
+
+	// The extension depends on what YouTube serves (webp, jpg, png...), so glob for it.
+	matches, err := filepath.Glob(filepath.Join(outputDir, videoID+".*"))
+	if err != nil {
+		return "", err
+	}
+	for _, match := range matches {
+		switch strings.ToLower(filepath.Ext(match)) {
+		case ".webp", ".jpg", ".jpeg", ".png":
+			return match, nil
+		}
+	}
+	return "", fmt.Errorf("yt-dlp completed but no thumbnail was written for %s", videoID)
 }
 
 // ExtractVideoID extracts the video ID from a YouTube URL
@@ -67,35 +953,51 @@ func ExtractVideoID(url string) (string, error) {
 
 	// Check for standard YouTube URL format (v= parameter)
 	if idx := strings.Index(url, "v="); idx != -1 {
-		vidID := url[idx+2:] // Get everything after v=
-		if ampIdx := strings.Index(vidID, "&"); ampIdx != -1 {
-			// Cut at first ampersand if there are other parameters
-			return vidID[:ampIdx], nil
-		}
-		return vidID, nil
+		// Cut at the next parameter or fragment
+		return pathVideoID(url[idx+2:]), nil
 	}
 
 	// Check for youtu.be format
 	if idx := strings.Index(url, "youtu.be/"); idx != -1 {
-		vidID := url[idx+len("youtu.be/"):]
-		if questionIdx := strings.Index(vidID, "?"); questionIdx != -1 {
-			// Cut at question mark if there are query parameters
-			return vidID[:questionIdx], nil
-		}
-		return vidID, nil
+		// Cut at the first slash, query or fragment
+		return pathVideoID(url[idx+len("youtu.be/"):]), nil
 	}
 
-	// Check for embed format
-	if idx := strings.Index(url, "/embed/"); idx != -1 {
-		vidID := url[idx+len("/embed/"):]
-		if questionIdx := strings.Index(vidID, "?"); questionIdx != -1 {
-			return vidID[:questionIdx], nil
+	// Check for embed, old flash embed (/v/) and shorts formats, which other
+	// sites' paths can look like too
+	if !IsYouTubeURL(url) && (strings.Contains(url, "://") || !IsYouTubeURL("https://"+url)) {
+		return "", fmt.Errorf("%w: %s", ErrUnrecognizedURL, url)
+	}
+	for _, prefix := range []string{"/embed/", "/v/", "/shorts/"} {
+		if idx := strings.Index(url, prefix); idx != -1 {
+			if vidID := pathVideoID(url[idx+len(prefix):]); vidID != "" {
+				return vidID, nil
+			}
 		}
-		return vidID, nil
 	}
 
 	// If we can't extract cleanly, it's not a recognized YouTube URL
-	return "", fmt.Errorf("not a recognized YouTube URL: %s", url)
+	return "", fmt.Errorf("%w: %s", ErrUnrecognizedURL, url)
+}
+
+// pathVideoID returns the path segment at the start of rest, cut at the first
+// slash, query or fragment, e.g. "abc" for "abc/?feature=share".
+func pathVideoID(rest string) string {
+	if end := strings.IndexAny(rest, "/?#&"); end != -1 {
+		return rest[:end]
+	}
+	return rest
+}
+
+// IsYouTubeURL reports whether rawURL points at YouTube, including URL forms
+// ExtractVideoID doesn't parse (e.g. /live/<id>).
+func IsYouTubeURL(rawURL string) bool {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == "youtube.com" || strings.HasSuffix(host, ".youtube.com") || host == "youtu.be" || host == "youtube-nocookie.com" || strings.HasSuffix(host, ".youtube-nocookie.com")
 }
 
 var langAndVttExtRegex = regexp.MustCompile(`(?:\.[a-zA-Z]{2,3})?\.vtt$`) // Matches .vtt and optional .lang.vtt
@@ -1,6 +1,19 @@
 package internal
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestExtractVideoID(t *testing.T) {
 	tests := []struct {
@@ -16,6 +29,18 @@ func TestExtractVideoID(t *testing.T) {
 		{"shortened URL no www", "http://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
 		{"embedded URL", "https://www.youtube.com/embed/dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
 		{"embedded URL with params", "https://www.youtube.com/embed/dQw4w9WgXcQ?autoplay=1", "dQw4w9WgXcQ", false},
+		{"embedded URL with trailing slash", "https://www.youtube.com/embed/dQw4w9WgXcQ/", "dQw4w9WgXcQ", false},
+		{"old flash embed URL", "youtube.com/v/dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
+		{"old flash embed URL with params", "https://www.youtube.com/v/dQw4w9WgXcQ?version=3&hl=en", "dQw4w9WgXcQ", false},
+		{"shorts URL", "https://www.youtube.com/shorts/dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
+		{"shorts URL with trailing slash", "youtube.com/shorts/dQw4w9WgXcQ/", "dQw4w9WgXcQ", false},
+		{"shorts URL with extra path and fragment", "https://www.youtube.com/shorts/dQw4w9WgXcQ/extra#t=5", "dQw4w9WgXcQ", false},
+		{"shortened URL with fragment", "https://youtu.be/dQw4w9WgXcQ#t=10", "dQw4w9WgXcQ", false},
+		{"standard URL with fragment", "https://www.youtube.com/watch?v=dQw4w9WgXcQ#t=10", "dQw4w9WgXcQ", false},
+		{"nocookie embed URL", "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
+		{"non-YouTube /v/ URL", "https://vimeo.com/v/12345", "", true},
+		{"non-YouTube shorts URL", "https://example.com/shorts/abc", "", true},
+		{"channel shorts tab (no video ID)", "https://www.youtube.com/@someone/shorts/", "", true},
 		{"no video ID param (not a valid video watch/embed/short URL)", "https://www.youtube.com/", "", true},
 		{"malformed URL with v= (still extracts if v= is present)", "htps:/www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ", false},
 		{"malformed URL no v= (not a YouTube URL)", "htps:/www.youtube.com/watch?id=dQw4w9WgXcQ", "", true},
@@ -38,6 +63,24 @@ func TestExtractVideoID(t *testing.T) {
 	}
 }
 
+func TestIsYouTubeURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://www.youtube.com/shorts/abc":            true,
+		"https://m.youtube.com/live/abc":                true,
+		"https://youtu.be/abc":                          true,
+		"https://www.youtube-nocookie.com/embed/abc":    true,
+		"https://vimeo.com/123":                         false,
+		"https://notyoutube.com/watch?v=abc":            false,
+		"https://example.com/?next=https://youtube.com": false,
+		"not a url": false,
+	}
+	for url, want := range tests {
+		if got := IsYouTubeURL(url); got != want {
+			t.Errorf("IsYouTubeURL(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
+
 func TestExtractDisplayTitle(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -68,3 +111,634 @@ func TestExtractDisplayTitle(t *testing.T) {
 		})
 	}
 }
+
+func TestParseMetadata(t *testing.T) {
+	data := []byte(`{"id":"dQw4w9WgXcQ","title":"Never Gonna Give You Up","uploader":"Rick Astley","upload_date":"20091025","duration":212,"description":"The official video","view_count":1500000000,"chapters":[{"title":"Chorus","start_time":43.5,"end_time":60}],"formats":[{"format_id":"18"}]}`)
+
+	got, err := ParseMetadata(data)
+	if err != nil {
+		t.Fatalf("ParseMetadata() error = %v, wantErr nil", err)
+	}
+	want := VideoMetadata{
+		ID:          "dQw4w9WgXcQ",
+		Title:       "Never Gonna Give You Up",
+		Uploader:    "Rick Astley",
+		UploadDate:  "20091025",
+		Duration:    212,
+		Description: "The official video",
+		ViewCount:   1500000000,
+		Chapters:    []Chapter{{Title: "Chorus", StartTime: 43.5, EndTime: 60}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMetadata() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseMetadata([]byte("not json")); err == nil {
+		t.Error("ParseMetadata() on invalid JSON, got nil error, want error")
+	}
+}
+
+// installFakeYtDlp puts a shell script named yt-dlp first on PATH for the
+// duration of the test.
+func installFakeYtDlp(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake yt-dlp relies on a POSIX shell script")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "yt-dlp"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// fakeRunner is a YtDlpRunner that records its arguments and answers with
+// canned output, without running anything.
+type fakeRunner struct {
+	stdout string
+	stderr string
+	err    error
+	args   []string
+}
+
+func (r *fakeRunner) Run(ctx context.Context, stderr io.Writer, args ...string) ([]byte, error) {
+	r.args = args
+	if stderr != nil {
+		io.WriteString(stderr, r.stderr)
+	}
+	return []byte(r.stdout), r.err
+}
+
+// runnerFunc adapts a function to YtDlpRunner, for fakes that act on their arguments.
+type runnerFunc func(args []string) ([]byte, error)
+
+func (f runnerFunc) Run(ctx context.Context, stderr io.Writer, args ...string) ([]byte, error) {
+	return f(args)
+}
+
+// installFakeRunner swaps Runner for r for the duration of the test.
+func installFakeRunner(t testing.TB, r YtDlpRunner) {
+	t.Helper()
+	old := Runner
+	Runner = r
+	t.Cleanup(func() { Runner = old })
+}
+
+func TestRunner_Fake(t *testing.T) {
+	runner := &fakeRunner{stdout: "Fake Title\n"}
+	installFakeRunner(t, runner)
+
+	got, err := FetchTitle("https://youtu.be/abc")
+	if err != nil || got != "Fake Title" {
+		t.Errorf("FetchTitle() = %q, %v, want %q", got, err, "Fake Title")
+	}
+	if want := []string{"--quiet", "--print", "title", "https://youtu.be/abc"}; !reflect.DeepEqual(runner.args, want) {
+		t.Errorf("yt-dlp args = %q, want %q", runner.args, want)
+	}
+
+	// Failures are classified from what the runner wrote to stderr
+	installFakeRunner(t, &fakeRunner{stderr: "ERROR: [youtube] abc: Private video\n", err: errors.New("exit status 1")})
+	if _, err := FetchTitle("https://youtu.be/abc"); !errors.Is(err, ErrVideoUnavailable) {
+		t.Errorf("FetchTitle() of a private video error = %v, want ErrVideoUnavailable", err)
+	}
+
+	// Progress lines written to stderr reach OnProgress, the last one without a line ending
+	installFakeRunner(t, &fakeRunner{stderr: "[download]  10.0% of 1KiB\r[download]  100% of 1KiB"})
+	var progress []float64
+	opts := SubtitleOptions{OnProgress: func(p float64) { progress = append(progress, p) }}
+	DownloadSubtitlesWithOptions(context.Background(), "https://youtu.be/abc", "abc", t.TempDir(), opts)
+	if want := []float64{10, 100}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("OnProgress() got %v, want %v", progress, want)
+	}
+}
+
+func TestFetchTitleCtx_CancelKillsCommand(t *testing.T) {
+	installFakeYtDlp(t, "exec sleep 10\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := FetchTitleCtx(ctx, "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("FetchTitleCtx() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("FetchTitleCtx() took %v, yt-dlp was not killed on cancel", elapsed)
+	}
+}
+
+func TestDownloadSubtitlesCtx_CancelKillsCommand(t *testing.T) {
+	installFakeYtDlp(t, "exec sleep 10\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	err := DownloadSubtitlesCtx(ctx, "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ", t.TempDir())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("DownloadSubtitlesCtx() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("DownloadSubtitlesCtx() took %v, yt-dlp was not killed on cancel", elapsed)
+	}
+}
+
+func TestFetchTitle_DelegatesToCtxVariant(t *testing.T) {
+	installFakeYtDlp(t, "echo 'Fake Title'\n")
+
+	got, err := FetchTitle("https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatalf("FetchTitle() error = %v, wantErr nil", err)
+	}
+	if got != "Fake Title" {
+		t.Errorf("FetchTitle() = %q, want %q", got, "Fake Title")
+	}
+}
+
+func TestProbeAvailability(t *testing.T) {
+	installFakeYtDlp(t, "echo unlisted\n")
+	if got, err := ProbeAvailability("https://youtu.be/abc"); err != nil || got != "unlisted" {
+		t.Errorf("ProbeAvailability() = %q, %v, want unlisted", got, err)
+	}
+
+	installFakeYtDlp(t, "echo 'ERROR: [youtube] abc: Video unavailable. This video has been removed by the uploader' >&2; exit 1\n")
+	if _, err := ProbeAvailability("https://youtu.be/abc"); !errors.Is(err, ErrVideoUnavailable) {
+		t.Errorf("ProbeAvailability() of a removed video error = %v, want ErrVideoUnavailable", err)
+	}
+}
+
+func TestYtDlpError_Classification(t *testing.T) {
+	runErr := errors.New("exit status 1")
+	tests := []struct {
+		name         string
+		stderr       string
+		wantCategory string
+		wantInMsg    string
+	}{
+		{"private video", "ERROR: [youtube] abc: Private video. Sign in if you've been granted access", CategoryUnavailable, "Private video"},
+		{"removed video", "WARNING: something\nERROR: [youtube] abc: Video unavailable. This video has been removed", CategoryUnavailable, "Video unavailable"},
+		{"dns failure", "ERROR: Unable to download webpage: <urlopen error [Errno -2] Name or service not known>", CategoryNetwork, "Unable to download webpage"},
+		{"rate limited", "ERROR: [youtube] abc: HTTP Error 429: Too Many Requests", CategoryNetwork, "HTTP Error 429"},
+		{"unknown failure", "ERROR: something odd happened", CategoryOther, "something odd happened"},
+		{"no stderr", "", CategoryOther, "exit status 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ytDlpError(runErr, []byte(tt.stderr))
+			if got := ClassifyError(err); got != tt.wantCategory {
+				t.Errorf("ClassifyError(ytDlpError(...)) = %q, want %q", got, tt.wantCategory)
+			}
+			if !errors.Is(err, runErr) {
+				t.Errorf("ytDlpError() = %v, should still wrap the original error", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantInMsg) {
+				t.Errorf("ytDlpError() = %q, want message to contain %q", err.Error(), tt.wantInMsg)
+			}
+		})
+	}
+}
+
+func TestClassifyError_Timeout(t *testing.T) {
+	err := fmt.Errorf("failed to download subtitles: %w", context.DeadlineExceeded)
+	if got := ClassifyError(err); got != CategoryTimeout {
+		t.Errorf("ClassifyError() = %q, want %q", got, CategoryTimeout)
+	}
+}
+
+func TestFetchTitle_UnavailableVideo(t *testing.T) {
+	installFakeYtDlp(t, "echo 'ERROR: [youtube] abc: Video unavailable' >&2\nexit 1\n")
+
+	_, err := FetchTitle("https://www.youtube.com/watch?v=abc")
+	if !errors.Is(err, ErrVideoUnavailable) {
+		t.Errorf("FetchTitle() error = %v, want ErrVideoUnavailable", err)
+	}
+}
+
+func TestDownloadSubtitles_NoSubtitles(t *testing.T) {
+	// yt-dlp exits successfully but writes nothing when a video has no captions
+	installFakeYtDlp(t, "echo 'WARNING: [youtube] abc: There are no subtitles for the requested languages' >&2\nexit 0\n")
+
+	err := DownloadSubtitles("https://www.youtube.com/watch?v=abc", "abc", t.TempDir())
+	if !errors.Is(err, ErrNoSubtitles) {
+		t.Fatalf("DownloadSubtitles() error = %v, want ErrNoSubtitles", err)
+	}
+	if got := ClassifyError(err); got != CategoryNoSubtitles {
+		t.Errorf("ClassifyError() = %q, want %q", got, CategoryNoSubtitles)
+	}
+}
+
+func TestDownloadSubtitles_SubtitlesPresent(t *testing.T) {
+	dir := t.TempDir()
+	// Fake yt-dlp writes the file the real one would produce for --sub-lang en
+	installFakeYtDlp(t, "printf 'WEBVTT\\n' > '"+filepath.Join(dir, "abc.en.vtt")+"'\n")
+
+	if err := DownloadSubtitles("https://www.youtube.com/watch?v=abc", "abc", dir); err != nil {
+		t.Errorf("DownloadSubtitles() error = %v, wantErr nil", err)
+	}
+}
+
+func TestDownloadSubtitlesWithOptions_ActualFileName(t *testing.T) {
+	// yt-dlp's naming varies by version and caption kind; the file found is returned
+	for _, name := range []string{"abc.en.vtt", "abc.vtt", "abc.en-US.vtt"} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			installFakeYtDlp(t, "printf 'WEBVTT\\n' > '"+filepath.Join(dir, name)+"'\n")
+
+			files, err := DownloadSubtitlesWithOptions(context.Background(), "https://youtu.be/abc", "abc", dir, SubtitleOptions{})
+			if err != nil {
+				t.Fatalf("DownloadSubtitlesWithOptions() error = %v", err)
+			}
+			if want := filepath.Join(dir, name); len(files) != 1 || files[0] != want {
+				t.Errorf("DownloadSubtitlesWithOptions() files = %v, want [%s]", files, want)
+			}
+		})
+	}
+}
+
+func TestDownloadSubtitles_VideoUnavailable(t *testing.T) {
+	installFakeYtDlp(t, "echo 'ERROR: [youtube] abc: Private video' >&2\nexit 1\n")
+
+	err := DownloadSubtitles("https://www.youtube.com/watch?v=abc", "abc", t.TempDir())
+	if !errors.Is(err, ErrVideoUnavailable) {
+		t.Errorf("DownloadSubtitles() error = %v, want ErrVideoUnavailable", err)
+	}
+	if errors.Is(err, ErrNoSubtitles) {
+		t.Errorf("DownloadSubtitles() error = %v, should not be ErrNoSubtitles", err)
+	}
+}
+
+func TestStreamSubtitles(t *testing.T) {
+	// The subtitles arrive on stdout; the args are echoed to stderr, and so
+	// must not end up in them
+	installFakeYtDlp(t, `echo "$@" >&2
+for a in "$@"; do [ "$prev" = "-o" ] && out="$a"; prev="$a"; done
+[ "$out" = "-" ] || exit 1
+printf 'WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n'
+`)
+
+	stream, err := StreamSubtitles(context.Background(), "https://youtu.be/abc", SubtitleOptions{Lang: "de", Format: "srt"})
+	if err != nil {
+		t.Fatalf("StreamSubtitles() error = %v", err)
+	}
+	got, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if want := "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhello\n"; string(got) != want {
+		t.Errorf("StreamSubtitles() read %q, want %q", got, want)
+	}
+}
+
+func TestStreamSubtitles_NoSubtitles(t *testing.T) {
+	installFakeYtDlp(t, "echo 'WARNING: [youtube] abc: There are no subtitles for the requested languages' >&2\nexit 0\n")
+
+	if _, err := StreamSubtitles(context.Background(), "https://youtu.be/abc", SubtitleOptions{}); !errors.Is(err, ErrNoSubtitles) {
+		t.Errorf("StreamSubtitles() error = %v, want ErrNoSubtitles", err)
+	}
+}
+
+func TestStreamSubtitles_FailsPartway(t *testing.T) {
+	installFakeYtDlp(t, "printf 'WEBVTT\\n'\necho 'ERROR: unable to download video subtitles: HTTP Error 429: Too Many Requests' >&2\nexit 1\n")
+
+	stream, err := StreamSubtitles(context.Background(), "https://youtu.be/abc", SubtitleOptions{})
+	if err != nil {
+		t.Fatalf("StreamSubtitles() error = %v", err)
+	}
+	io.Copy(io.Discard, stream)
+	if err := stream.Close(); !errors.Is(err, ErrNetwork) {
+		t.Errorf("Close() error = %v, want ErrNetwork", err)
+	}
+}
+
+func TestStreamSubtitles_BufferedRunner(t *testing.T) {
+	// A Runner that can't stream has its output read once yt-dlp is done
+	runner := &fakeRunner{stdout: "WEBVTT\n"}
+	installFakeRunner(t, runner)
+
+	stream, err := StreamSubtitles(context.Background(), "https://youtu.be/abc", SubtitleOptions{ManualOnly: true})
+	if err != nil {
+		t.Fatalf("StreamSubtitles() error = %v", err)
+	}
+	if got, _ := io.ReadAll(stream); string(got) != "WEBVTT\n" {
+		t.Errorf("StreamSubtitles() read %q, want %q", got, "WEBVTT\n")
+	}
+	want := []string{"--quiet", "https://youtu.be/abc", "--skip-download", "--write-sub", "--sub-lang", "en", "--sub-format", "vtt", "-o", "-"}
+	if !reflect.DeepEqual(runner.args, want) {
+		t.Errorf("yt-dlp args = %q, want %q", runner.args, want)
+	}
+}
+
+func TestParseSubtitleLanguages(t *testing.T) {
+	data := []byte(`{"subtitles": {"fr": [], "de": [], "live_chat": []}, "automatic_captions": {"es-orig": [], "en": []}}`)
+	got, err := ParseSubtitleLanguages(data)
+	if err != nil {
+		t.Fatalf("ParseSubtitleLanguages() error = %v", err)
+	}
+	want := SubtitleLanguages{Manual: []string{"de", "fr"}, Auto: []string{"en", "es-orig"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSubtitleLanguages() = %+v, want %+v", got, want)
+	}
+	if _, err := ParseSubtitleLanguages([]byte("not json")); err == nil {
+		t.Error("ParseSubtitleLanguages() expected error for invalid JSON")
+	}
+}
+
+func TestSubtitleLanguages_Primary(t *testing.T) {
+	tests := []struct {
+		name  string
+		langs SubtitleLanguages
+		want  string
+	}{
+		{"original auto track wins", SubtitleLanguages{Manual: []string{"en"}, Auto: []string{"de", "ja-orig"}}, "ja-orig"},
+		{"manual before auto", SubtitleLanguages{Manual: []string{"fr"}, Auto: []string{"de"}}, "fr"},
+		{"auto only", SubtitleLanguages{Auto: []string{"pt"}}, "pt"},
+		{"no captions", SubtitleLanguages{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.langs.Primary(); got != tt.want {
+				t.Errorf("Primary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubtitleLanguages_Tracks(t *testing.T) {
+	langs := SubtitleLanguages{Manual: []string{"de", "en", "en-forced"}, Auto: []string{"en", "en-orig", "english", "es"}}
+	want := []SubtitleTrack{{Lang: "en"}, {Lang: "en-forced"}, {Lang: "en-orig", Auto: true}}
+	if got := langs.Tracks("en", false); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tracks(en) = %+v, want %+v", got, want)
+	}
+	if got := langs.Tracks("en", true); !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("Tracks(en, manualOnly) = %+v, want %+v", got, want[:2])
+	}
+	if got := langs.Tracks("fr", false); len(got) != 0 {
+		t.Errorf("Tracks(fr) = %+v, want none", got)
+	}
+}
+
+func TestDownloadSubtitlesWithOptions_Lang(t *testing.T) {
+	dir := t.TempDir()
+	// Only writes a file when asked for German, mimicking a German-only video.
+	installFakeYtDlp(t, `for a in "$@"; do [ "$prev" = "--sub-lang" ] && lang="$a"; prev="$a"; done
+[ "$lang" = "de" ] && printf 'WEBVTT\n' > '`+filepath.Join(dir, "abc.de.vtt")+"'\nexit 0\n")
+
+	if _, err := DownloadSubtitlesWithOptions(context.Background(), "https://youtu.be/abc", "abc", dir, SubtitleOptions{}); !errors.Is(err, ErrNoSubtitles) {
+		t.Errorf("DownloadSubtitlesWithOptions(default lang) error = %v, want ErrNoSubtitles", err)
+	}
+	files, err := DownloadSubtitlesWithOptions(context.Background(), "https://youtu.be/abc", "abc", dir, SubtitleOptions{Lang: "de"})
+	if err != nil {
+		t.Errorf("DownloadSubtitlesWithOptions(de) error = %v", err)
+	}
+	if want := filepath.Join(dir, "abc.de.vtt"); len(files) != 1 || files[0] != want {
+		t.Errorf("DownloadSubtitlesWithOptions(de) files = %v, want [%s]", files, want)
+	}
+}
+
+func TestDownloadSubtitlesWithOptions_Progress(t *testing.T) {
+	dir := t.TempDir()
+	// Progress lines on stderr, one redrawn with \r as a terminal progress bar would be
+	installFakeYtDlp(t, `printf '[download] Destination: abc.en.vtt\n[download]   4.5%% of 1.00MiB\r[download]  50.0%% of 1.00MiB\n[download] 100%% of 1.00MiB\n' >&2
+case "$*" in *--progress*--newline*) ;; *) exit 0;; esac
+printf 'WEBVTT\n' > '`+filepath.Join(dir, "abc.en.vtt")+"'\n")
+
+	var got []float64
+	files, err := DownloadSubtitlesWithOptions(context.Background(), "https://youtu.be/abc", "abc", dir, SubtitleOptions{
+		OnProgress: func(percent float64) { got = append(got, percent) },
+	})
+	if err != nil || len(files) != 1 {
+		t.Fatalf("DownloadSubtitlesWithOptions() = %v, %v, want one file", files, err)
+	}
+	if want := []float64{4.5, 50, 100}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DownloadSubtitlesWithOptions() reported progress %v, want %v", got, want)
+	}
+
+	installFakeYtDlp(t, "printf '[download]  10.0%% of 1.00MiB\\nERROR: [youtube] abc: Private video\\n' >&2; exit 1\n")
+	_, err = DownloadSubtitlesWithOptions(context.Background(), "https://youtu.be/abc", "abc", dir, SubtitleOptions{OnProgress: func(float64) {}})
+	if !errors.Is(err, ErrVideoUnavailable) {
+		t.Errorf("DownloadSubtitlesWithOptions() with progress error = %v, want ErrVideoUnavailable", err)
+	}
+}
+
+func TestDownloadSubtitlesWithOptions_Source(t *testing.T) {
+	dir := t.TempDir()
+	// Only writes a file when asked for srv3 first
+	installFakeYtDlp(t, `for a in "$@"; do [ "$prev" = "--sub-format" ] && src="$a"; prev="$a"; done
+[ "$src" = "srv3/best" ] && printf 'WEBVTT\n' > '`+filepath.Join(dir, "abc.en.vtt")+"'\nexit 0\n")
+
+	if _, err := DownloadSubtitlesWithOptions(context.Background(), "https://youtu.be/abc", "abc", dir, SubtitleOptions{}); !errors.Is(err, ErrNoSubtitles) {
+		t.Errorf("DownloadSubtitlesWithOptions() without Source error = %v, want ErrNoSubtitles", err)
+	}
+	if _, err := DownloadSubtitlesWithOptions(context.Background(), "https://youtu.be/abc", "abc", dir, SubtitleOptions{Source: "srv3/best"}); err != nil {
+		t.Errorf("DownloadSubtitlesWithOptions(Source: srv3/best) error = %v, want the --sub-format passed", err)
+	}
+}
+
+func TestDownloadSubtitlesWithOptions_ManualOnly(t *testing.T) {
+	// A video with only auto captions: yt-dlp writes a file only when asked for them
+	dir := t.TempDir()
+	var gotArgs []string
+	installFakeRunner(t, runnerFunc(func(args []string) ([]byte, error) {
+		gotArgs = args
+		if slices.Contains(args, "--write-auto-sub") {
+			return nil, os.WriteFile(filepath.Join(dir, "abc.en.vtt"), []byte("WEBVTT\n"), 0644)
+		}
+		return nil, nil
+	}))
+	if _, err := DownloadSubtitlesWithOptions(context.Background(), "https://youtu.be/abc", "abc", dir, SubtitleOptions{ManualOnly: true}); !errors.Is(err, ErrNoSubtitles) {
+		t.Errorf("DownloadSubtitlesWithOptions(ManualOnly) of an auto-only video error = %v, want ErrNoSubtitles", err)
+	}
+	if !slices.Contains(gotArgs, "--write-sub") || slices.Contains(gotArgs, "--write-auto-sub") {
+		t.Errorf("DownloadSubtitlesWithOptions(ManualOnly) args = %q, want --write-sub without --write-auto-sub", gotArgs)
+	}
+
+	// A manual track, possibly with a regional code, is still found
+	installFakeRunner(t, runnerFunc(func(args []string) ([]byte, error) {
+		return nil, os.WriteFile(filepath.Join(dir, "abc.en-GB.vtt"), []byte("WEBVTT\n"), 0644)
+	}))
+	files, err := DownloadSubtitlesWithOptions(context.Background(), "https://youtu.be/abc", "abc", dir, SubtitleOptions{ManualOnly: true})
+	if want := filepath.Join(dir, "abc.en-GB.vtt"); err != nil || len(files) != 1 || files[0] != want {
+		t.Errorf("DownloadSubtitlesWithOptions(ManualOnly) = %v, %v, want [%s]", files, err, want)
+	}
+}
+
+func TestValidateSubSource(t *testing.T) {
+	for _, source := range []string{"vtt", "srv3/vtt/best"} {
+		if err := ValidateSubSource(source); err != nil {
+			t.Errorf("ValidateSubSource(%q) error = %v", source, err)
+		}
+	}
+	for _, source := range []string{"", " ", "vtt//best", "vtt/"} {
+		if err := ValidateSubSource(source); err == nil {
+			t.Errorf("ValidateSubSource(%q) = nil, want an error", source)
+		}
+	}
+}
+
+func TestDownloadSubtitlesWithOptions_AllLangs(t *testing.T) {
+	dir := t.TempDir()
+	// Writes one file per language, plus a live chat that isn't converted to VTT.
+	installFakeYtDlp(t, `for a in "$@"; do [ "$prev" = "--sub-lang" ] && lang="$a"; prev="$a"; done
+[ "$lang" = "all" ] || exit 0
+for l in de en; do printf 'WEBVTT\n' > "`+dir+`/abc.$l.vtt"; done
+echo '{}' > "`+dir+`/abc.live_chat.json"
+`)
+
+	files, err := DownloadSubtitlesWithOptions(context.Background(), "https://youtu.be/abc", "abc", dir, SubtitleOptions{Lang: AllLangs})
+	if err != nil {
+		t.Fatalf("DownloadSubtitlesWithOptions(all) error = %v", err)
+	}
+	want := []string{filepath.Join(dir, "abc.de.vtt"), filepath.Join(dir, "abc.en.vtt")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("DownloadSubtitlesWithOptions(all) files = %v, want %v", files, want)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"--sleep-requests 2", []string{"--sleep-requests", "2"}, false},
+		{`  --cookies "my cookies.txt"  `, []string{"--cookies", "my cookies.txt"}, false},
+		{`--format 'best[height<=720]' --no-part`, []string{"--format", "best[height<=720]", "--no-part"}, false},
+		{`a\ b "say \"hi\"" ''`, []string{"a b", `say "hi"`, ""}, false},
+		{`--proxy "socks5://x`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SplitArgs(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSearchVideos(t *testing.T) {
+	runner := &fakeRunner{stdout: "https://www.youtube.com/watch?v=aaa\nNA\n\nhttps://www.youtube.com/watch?v=bbb\n"}
+	installFakeRunner(t, runner)
+
+	urls, err := SearchVideos("go concurrency", 5)
+	if err != nil {
+		t.Fatalf("SearchVideos() error = %v", err)
+	}
+	if want := []string{"https://www.youtube.com/watch?v=aaa", "https://www.youtube.com/watch?v=bbb"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("SearchVideos() = %q, want %q", urls, want)
+	}
+	if want := []string{"--quiet", "--flat-playlist", "--print", "url", "ytsearch5:go concurrency"}; !reflect.DeepEqual(runner.args, want) {
+		t.Errorf("yt-dlp args = %q, want %q", runner.args, want)
+	}
+
+	for _, tt := range []struct {
+		query string
+		n     int
+	}{{"", 5}, {"go", 0}} {
+		if _, err := SearchVideos(tt.query, tt.n); err == nil {
+			t.Errorf("SearchVideos(%q, %d) error = nil, want an error", tt.query, tt.n)
+		}
+	}
+}
+
+func TestEffectiveProxy(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("https_proxy", "")
+	t.Setenv("HTTP_PROXY", "http://plain:3128")
+	if got := EffectiveProxy(""); got != "" {
+		t.Errorf("EffectiveProxy() with only HTTP_PROXY = %q, want none (it doesn't cover HTTPS)", got)
+	}
+	t.Setenv("https_proxy", "http://env:3128")
+	if got := EffectiveProxy(""); got != "http://env:3128" {
+		t.Errorf("EffectiveProxy() = %q, want the environment's proxy", got)
+	}
+	if got := EffectiveProxy("socks5://flag:1080"); got != "socks5://flag:1080" {
+		t.Errorf("EffectiveProxy(flag) = %q, want the flag to win", got)
+	}
+}
+
+func TestProxyArgs(t *testing.T) {
+	if got := ProxyArgs(""); got != nil {
+		t.Errorf("ProxyArgs(\"\") = %q, want none so yt-dlp uses the environment", got)
+	}
+	if got, want := ProxyArgs("socks5://x:1080"), []string{"--proxy", "socks5://x:1080"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ProxyArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	tests := []struct {
+		requested string
+		available []string
+		want      string
+		wantOK    bool
+	}{
+		{"en", []string{"fr", "en-US"}, "en-US", true},
+		{"pt", []string{"pt-BR", "es"}, "pt-BR", true},
+		{"en", []string{"en-GB", "en"}, "en", true},
+		{"EN-us", []string{"en", "en-US"}, "en-US", true},
+		{"pt-BR", []string{"pt-PT", "pt"}, "pt", true},
+		{"en", []string{"en-orig", "en-GB", "en-US"}, "en-GB", true},
+		{"es", []string{"es-419"}, "es-419", true},
+		{"zh", []string{"zh-Hant", "zh-TW"}, "zh-TW", true},
+		{"en", []string{"en-nP7-2PuUl7o"}, "en-nP7-2PuUl7o", true},
+		{"en", []string{"eng", "fr"}, "", false},
+		{"en", nil, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := MatchLanguage(tt.requested, tt.available)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("MatchLanguage(%q, %q) = %q, %v, want %q, %v", tt.requested, tt.available, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCookiesFromBrowserArgs(t *testing.T) {
+	if got, err := CookiesFromBrowserArgs(""); got != nil || err != nil {
+		t.Errorf("CookiesFromBrowserArgs(\"\") = %q, %v, want none", got, err)
+	}
+	for _, spec := range []string{"chrome", "Firefox", "firefox:work", "chromium+kwallet6:Profile 1"} {
+		if got, err := CookiesFromBrowserArgs(spec); err != nil || !reflect.DeepEqual(got, []string{"--cookies-from-browser", spec}) {
+			t.Errorf("CookiesFromBrowserArgs(%q) = %q, %v, want it passed through", spec, got, err)
+		}
+	}
+	for _, spec := range []string{"netscape", "chrom:work", "chrome+wallet", ":work"} {
+		if _, err := CookiesFromBrowserArgs(spec); err == nil {
+			t.Errorf("CookiesFromBrowserArgs(%q) error = nil, want an unknown browser or keyring", spec)
+		}
+	}
+}
+
+func TestYtDlpArgs_AppendedAfterBuiltins(t *testing.T) {
+	// Echo the arguments back as the title
+	installFakeYtDlp(t, `echo "$*"`)
+	YtDlpArgs = []string{"--sleep-requests", "2"}
+	t.Cleanup(func() { YtDlpArgs = nil })
+
+	got, err := FetchTitle("https://youtu.be/abc")
+	if err != nil {
+		t.Fatalf("FetchTitle() error = %v", err)
+	}
+	if want := "--quiet --print title https://youtu.be/abc --sleep-requests 2"; got != want {
+		t.Errorf("yt-dlp args = %q, want %q", got, want)
+	}
+}
+
+func TestYtDlpVersion(t *testing.T) {
+	installFakeYtDlp(t, `[ "$1" = "--version" ] && echo 2025.06.09`)
+
+	got, err := YtDlpVersion()
+	if err != nil || got != "2025.06.09" {
+		t.Errorf("YtDlpVersion() = %q, %v, want %q", got, err, "2025.06.09")
+	}
+}
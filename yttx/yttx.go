@@ -0,0 +1,59 @@
+// Package yttx lets other Go programs download and clean YouTube transcripts
+// without the yt-tx TUI.
+//
+//	results, err := yttx.ProcessURLs(ctx, urls, yttx.Options{
+//		CleanedDir:      "transcripts",
+//		ParallelWorkers: 4,
+//		Lang:            "en",
+//	})
+//
+// yt-dlp must be on PATH.
+package yttx
+
+import (
+	"context"
+	"io"
+
+	"github.com/mattlemmone/yt-tx/internal"
+)
+
+type (
+	// Options configures a run; see the yt-tx flags of the same names.
+	Options = internal.Options
+	// CleanOptions tunes how transcripts are cleaned.
+	CleanOptions = internal.CleanOptions
+	// Result is the outcome of one URL.
+	Result = internal.Result
+	// LangDetector guesses a transcript's language, for Options.DetectLang.
+	LangDetector = internal.LangDetector
+	// StopwordDetector is the built-in LangDetector that -detect-lang uses.
+	StopwordDetector = internal.StopwordDetector
+	// TranscriptDB is an SQLite database of transcripts, for Options.DB.
+	TranscriptDB = internal.TranscriptDB
+)
+
+// OpenTranscriptDB opens the SQLite database at path that -db writes,
+// creating it if needed. Close it once done.
+func OpenTranscriptDB(path string) (*TranscriptDB, error) {
+	return internal.OpenTranscriptDB(path)
+}
+
+// CleanVTTReader returns a reader of the transcript cleaned from the VTT file
+// read from r, as it arrives, so captions piped from yt-dlp or fetched over
+// HTTP can be cleaned without writing them to disk.
+func CleanVTTReader(r io.Reader, opts CleanOptions) io.Reader {
+	return internal.CleanVTTReader(r, opts)
+}
+
+// CleanSRTReader is CleanVTTReader for an SRT file.
+func CleanSRTReader(r io.Reader, opts CleanOptions) io.Reader {
+	return internal.CleanSRTReader(r, opts)
+}
+
+// ProcessURLs downloads and cleans a transcript for each URL and returns one
+// Result per URL in input order. Per-URL failures are reported in Result.Err;
+// the returned error is set only if the run could not start or ctx was
+// cancelled.
+func ProcessURLs(ctx context.Context, urls []string, opts Options) ([]Result, error) {
+	return internal.ProcessURLs(ctx, urls, opts)
+}